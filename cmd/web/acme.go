@@ -0,0 +1,18 @@
+package main
+
+import (
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// newACME returns a client that gets certificates for the given domains, and only
+// those, from Let's Encrypt on the first handshake that needs one, renewing them
+// before they expire. Accounts and certificates are kept in cacheDir, so a restart
+// doesn't ask for them again.
+func newACME(domains []string, email, cacheDir string) acmeCerts {
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(cacheDir),
+		HostPolicy: autocert.HostWhitelist(domains...),
+		Email:      email,
+	}
+}
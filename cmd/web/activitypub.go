@@ -0,0 +1,459 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/federicopalou/sacrif-station/internal/activitypub"
+	"github.com/federicopalou/sacrif-station/internal/models"
+	"github.com/federicopalou/sacrif-station/internal/storage"
+)
+
+const jobActivityPub = "activitypub"
+
+// actorKeyFile holds the actor's private key, relative to the data root. Followers
+// verify every post against it, so it has to outlive restarts and redeploys.
+const actorKeyFile = "activitypub/actor.pem"
+
+// outboxLength is how many recent posts the outbox lists.
+const outboxLength = 20
+
+// federation is the station's ActivityPub actor, which publishes the Organic
+// Thoughts Sector; nil on the application when SACRIF_ACTIVITYPUB_USER is unset.
+type federation struct {
+	username string // preferredUsername, the "thoughts" in @thoughts@station.example
+	host     string // Of SACRIF_BASE_URL, the other half of the handle
+	base     string // SACRIF_BASE_URL without a trailing slash
+	key      *rsa.PrivateKey
+	client   *activitypub.Client
+}
+
+// activityPubJob is the payload of an "activitypub" job: one activity, frozen when
+// it happened, for one inbox.
+type activityPubJob struct {
+	Inbox    string          `json:"inbox"`
+	Activity json.RawMessage `json:"activity"`
+}
+
+// newFederation sets up the actor from SACRIF_ACTIVITYPUB_USER, loading its key
+// from the data root or making one on first start.
+func newFederation(root *storage.Root, baseURL string) (*federation, error) {
+	username := os.Getenv("SACRIF_ACTIVITYPUB_USER")
+	if username == "" {
+		return nil, nil
+	}
+	if strings.ContainsAny(username, "@/: ") {
+		return nil, fmt.Errorf("SACRIF_ACTIVITYPUB_USER %q must be a bare username", username)
+	}
+	u, err := url.Parse(baseURL)
+	if baseURL == "" || err != nil || u.Host == "" {
+		return nil, errors.New("SACRIF_ACTIVITYPUB_USER needs SACRIF_BASE_URL, which every ActivityPub ID is built from")
+	}
+
+	key, err := loadActorKey(root)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &federation{username: username, host: u.Host, base: strings.TrimRight(baseURL, "/"), key: key}
+	f.client = activitypub.NewClient(f.keyID(), key)
+	return f, nil
+}
+
+func loadActorKey(root *storage.Root) (*rsa.PrivateKey, error) {
+	data, err := root.ReadFile(actorKeyFile)
+	if err == nil {
+		return activitypub.ParsePrivateKey(data)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	key, err := activitypub.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	data, err = activitypub.EncodePrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := root.MkdirAll("activitypub", 0o750); err != nil {
+		return nil, err
+	}
+	if err := root.WriteFile(actorKeyFile, data, 0o600); err != nil {
+		return nil, err
+	}
+	slog.Info("Generated ActivityPub actor key", "file", actorKeyFile)
+	return key, nil
+}
+
+func (f *federation) actorID() string      { return f.base + "/ap/actor" }
+func (f *federation) keyID() string        { return f.actorID() + "#main-key" }
+func (f *federation) followersID() string  { return f.base + "/ap/followers" }
+func (f *federation) noteID(id int) string { return f.base + "/ap/notes/" + strconv.Itoa(id) }
+
+// federated reports whether an entry is published to followers: thoughts that
+// would appear in the feeds.
+func federated(e *models.Entry) bool {
+	return e.TypeInfo().Thought && e.InFeeds()
+}
+
+// note renders an entry as the post followers see. Mastodon shows no title, so it
+// leads the content, and the permalink closes it.
+func (app *application) note(e *models.Entry) *activitypub.Note {
+	f := app.federation
+	permalink := app.absoluteURL(e.Permalink())
+	content := "<p><strong>" + template.HTMLEscapeString(e.Title) + "</strong></p>" + string(entryContent(e)) +
+		`<p><a href="` + template.HTMLEscapeString(permalink) + `">` + template.HTMLEscapeString(permalink) + `</a></p>`
+
+	n := &activitypub.Note{
+		ID:           f.noteID(e.ID),
+		Type:         "Note",
+		AttributedTo: f.actorID(),
+		Content:      content,
+		URL:          permalink,
+		Published:    e.CreatedAt.UTC(),
+		Updated:      e.UpdatedAt,
+		To:           []string{activitypub.Public},
+		Cc:           []string{f.followersID()},
+	}
+	if e.ParentID != nil {
+		n.InReplyTo = f.noteID(*e.ParentID)
+	}
+	return n
+}
+
+// create wraps a note in the Create activity that announces it.
+func (app *application) create(e *models.Entry) *activitypub.Activity {
+	n := app.note(e)
+	return &activitypub.Activity{
+		ID:        n.ID + "/create",
+		Type:      "Create",
+		Actor:     n.AttributedTo,
+		Object:    n,
+		Published: n.Published,
+		To:        n.To,
+		Cc:        n.Cc,
+	}
+}
+
+// writeActivity answers with an ActivityPub document.
+func writeActivity(w http.ResponseWriter, status int, doc any) {
+	body, err := json.Marshal(doc)
+	if err != nil {
+		http.Error(w, "Internal Server Error", 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", activitypub.ContentType+"; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// webfingerHandler resolves @user@host to the actor GET /.well-known/webfinger?resource=
+func (app *application) webfingerHandler(w http.ResponseWriter, r *http.Request) {
+	f := app.federation
+	if f == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	resource := r.URL.Query().Get("resource")
+	if resource != "acct:"+f.username+"@"+f.host && resource != f.actorID() {
+		writeAPIError(w, http.StatusNotFound, "no such account")
+		return
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"subject": "acct:" + f.username + "@" + f.host,
+		"aliases": []string{f.actorID()},
+		"links": []map[string]string{
+			{"rel": "self", "type": activitypub.ContentType, "href": f.actorID()},
+			{"rel": "http://webfinger.net/rel/profile-page", "type": "text/html", "href": f.base + "/thoughts"},
+		},
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/jrd+json; charset=utf-8")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Write(body)
+}
+
+// actorHandler describes the station's account GET /ap/actor
+func (app *application) actorHandler(w http.ResponseWriter, r *http.Request) {
+	f := app.federation
+	if f == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	pem, err := activitypub.EncodePublicKey(&f.key.PublicKey)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	writeActivity(w, http.StatusOK, &activitypub.Actor{
+		Context:           activitypub.Context,
+		ID:                f.actorID(),
+		Type:              "Person",
+		PreferredUsername: f.username,
+		Name:              app.siteIdentity().Title + ": Organic Thoughts",
+		Summary:           "<p>Internal logs, written thoughts, and unstructured notes, relayed from the station.</p>",
+		URL:               f.base + "/thoughts",
+		Inbox:             f.base + "/ap/inbox",
+		Outbox:            f.base + "/ap/outbox",
+		Followers:         f.followersID(),
+		Endpoints:         &activitypub.Endpoints{SharedInbox: f.base + "/ap/inbox"},
+		PublicKey:         activitypub.PublicKey{ID: f.keyID(), Owner: f.actorID(), PublicKeyPem: pem},
+		Discoverable:      true,
+	})
+}
+
+// outboxHandler lists the latest posts GET /ap/outbox
+func (app *application) outboxHandler(w http.ResponseWriter, r *http.Request) {
+	f := app.federation
+	if f == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	// Some thoughts are kept out of the feeds, so read a few more than are shown
+	entries, _, err := app.entries.List(models.EntryFilter{Section: "thoughts", Limit: outboxLength * 2})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	items := []any{}
+	for _, e := range entries {
+		if federated(e) && len(items) < outboxLength {
+			items = append(items, app.create(e))
+		}
+	}
+
+	writeActivity(w, http.StatusOK, &activitypub.OrderedCollection{
+		Context:      activitypub.Context,
+		ID:           f.base + "/ap/outbox",
+		Type:         "OrderedCollection",
+		TotalItems:   len(items),
+		OrderedItems: items,
+	})
+}
+
+// followersHandler tells how many accounts follow GET /ap/followers
+//
+// Who they are isn't published.
+func (app *application) followersHandler(w http.ResponseWriter, r *http.Request) {
+	f := app.federation
+	if f == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	n, err := app.followers.Count()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	writeActivity(w, http.StatusOK, &activitypub.OrderedCollection{
+		Context:    activitypub.Context,
+		ID:         f.followersID(),
+		Type:       "OrderedCollection",
+		TotalItems: n,
+	})
+}
+
+// noteHandler serves one post GET /ap/notes/{id}
+func (app *application) noteHandler(w http.ResponseWriter, r *http.Request) {
+	f := app.federation
+	if f == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w, r)
+		return
+	}
+	entry, err := app.entries.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+	if !federated(entry) {
+		app.notFound(w, r)
+		return
+	}
+
+	n := app.note(entry)
+	n.Context = activitypub.Context
+	writeActivity(w, http.StatusOK, n)
+}
+
+// inboxPostHandler takes activities from other servers POST /ap/inbox
+//
+// Only what a publish-only account needs is acted on: Follow and Undo of one.
+// Everything else is acknowledged and dropped.
+func (app *application) inboxPostHandler(w http.ResponseWriter, r *http.Request) {
+	f := app.federation
+	if f == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		writeAPIError(w, http.StatusRequestEntityTooLarge, "activity too large")
+		return
+	}
+	var in activitypub.Incoming
+	if err := json.Unmarshal(body, &in); err != nil || in.Type == "" || in.Actor == "" {
+		writeAPIError(w, http.StatusBadRequest, "not an activity")
+		return
+	}
+
+	switch in.Type {
+	case "Follow", "Undo":
+	default:
+		// Deleted accounts announce it to every server they ever talked to, and their
+		// key can't be fetched any more to check; nothing else is handled either
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	// The signature must come from the actor the activity claims to be from
+	var sender *activitypub.Actor
+	_, err = activitypub.Verify(r, body, func(keyID string) (*rsa.PublicKey, error) {
+		owner, _, _ := strings.Cut(keyID, "#")
+		if owner != in.Actor {
+			return nil, fmt.Errorf("key belongs to %s, not %s", owner, in.Actor)
+		}
+		actor, err := f.client.FetchActor(r.Context(), owner)
+		if err != nil {
+			return nil, err
+		}
+		if actor.PublicKey.ID != keyID {
+			return nil, fmt.Errorf("%s doesn't publish key %s", owner, keyID)
+		}
+		sender = actor
+		return activitypub.ParsePublicKey(actor.PublicKey.PublicKeyPem)
+	})
+	if err != nil {
+		slog.WarnContext(r.Context(), "Rejected unsigned ActivityPub activity", "type", in.Type, "actor", in.Actor, "err", err)
+		writeAPIError(w, http.StatusUnauthorized, "signature rejected")
+		return
+	}
+
+	switch {
+	case in.Type == "Follow" && in.ObjectID() == f.actorID():
+		if err := app.followers.Add(sender.ID, sender.DeliveryInbox()); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		slog.InfoContext(r.Context(), "New ActivityPub follower", "actor", sender.ID)
+
+		// Follows are accepted automatically, echoing the Follow back as its object
+		app.enqueueActivity(sender.Inbox, &activitypub.Activity{
+			Context: activitypub.Context,
+			ID:      f.actorID() + "#accepts/" + rand.Text(),
+			Type:    "Accept",
+			Actor:   f.actorID(),
+			Object:  json.RawMessage(body),
+		})
+
+	case in.Type == "Undo" && in.ObjectType() == "Follow":
+		if err := app.followers.Remove(sender.ID); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		slog.InfoContext(r.Context(), "ActivityPub follower left", "actor", sender.ID)
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// federateEntry sends a new thought to every follower's inbox. Like syndication,
+// failures are logged rather than surfaced: the entry itself is already safely stored.
+func (app *application) federateEntry(entryID int) {
+	if app.federation == nil {
+		return
+	}
+
+	entry, err := app.entries.Get(entryID)
+	if err != nil {
+		slog.Error("Failed to load entry for ActivityPub", "entry", entryID, "err", err)
+		return
+	}
+	if !federated(entry) {
+		return
+	}
+
+	inboxes, err := app.followers.Inboxes()
+	if err != nil {
+		slog.Error("Failed to list ActivityPub followers", "err", err)
+		return
+	}
+
+	activity := app.create(entry)
+	activity.Context = activitypub.Context
+	for _, inbox := range inboxes {
+		app.enqueueActivity(inbox, activity)
+	}
+}
+
+// enqueueActivity queues one delivery of an activity.
+func (app *application) enqueueActivity(inbox string, activity *activitypub.Activity) {
+	body, err := json.Marshal(activity)
+	if err != nil {
+		slog.Error("Failed to encode ActivityPub activity", "err", err)
+		return
+	}
+	if _, err := app.jobs.Enqueue(jobActivityPub, activityPubJob{Inbox: inbox, Activity: body}); err != nil {
+		slog.Error("Failed to enqueue ActivityPub delivery", "err", err)
+	}
+}
+
+// runActivityPubJob delivers one activity. Returning the error lets the job runner
+// retry with backoff, except for an inbox its server says is gone for good, whose
+// followers are dropped instead.
+func (app *application) runActivityPubJob(ctx context.Context, payload []byte) error {
+	var job activityPubJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return err
+	}
+	if app.federation == nil {
+		return errors.New("ActivityPub is no longer configured")
+	}
+
+	status, err := app.federation.client.Deliver(ctx, job.Inbox, job.Activity)
+	if status == http.StatusGone {
+		n, rmErr := app.followers.RemoveInbox(job.Inbox)
+		if rmErr != nil {
+			return rmErr
+		}
+		slog.Info("Dropped ActivityPub followers whose inbox is gone", "inbox", job.Inbox, "followers", n)
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+	"github.com/federicopalou/sacrif-station/internal/scraper"
+)
+
+// alertsPage is the data handed to alerts.tmpl
+type alertsPage struct {
+	Rules   []*models.AlertRule
+	Alerts  []*models.Alert
+	Unseen  int
+	Sources []*models.Source
+	Result  string
+}
+
+// SourceName names the source a rule or alert is tied to.
+func (p alertsPage) SourceName(id *int) string {
+	if id == nil {
+		return ""
+	}
+	for _, s := range p.Sources {
+		if s.ID == *id {
+			return s.Name
+		}
+	}
+	return fmt.Sprintf("source #%d (deleted)", *id)
+}
+
+// alertsHandler lists the alert rules and the alerts they raised GET /admin/alerts
+func (app *application) alertsHandler(w http.ResponseWriter, r *http.Request) {
+	rules, err := app.alerts.Rules()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	alerts, err := app.alerts.Recent(100)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	unseen, err := app.alerts.CountUnseen()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	sources, err := app.sources.All()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	page := alertsPage{Rules: rules, Alerts: alerts, Unseen: unseen, Sources: sources, Result: r.URL.Query().Get("result")}
+
+	app.render(w, r, page, "pages/alerts.tmpl")
+}
+
+// alertsPostHandler adds an alert rule POST /admin/alerts
+func (app *application) alertsPostHandler(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	rule := &models.AlertRule{
+		Field:   r.PostForm.Get("field"),
+		Op:      r.PostForm.Get("op"),
+		Operand: strings.TrimSpace(r.PostForm.Get("operand")),
+		Note:    models.NullString(strings.TrimSpace(r.PostForm.Get("note"))),
+	}
+	if v := r.PostForm.Get("source_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id < 1 {
+			http.Error(w, "Bad Request", 400)
+			return
+		}
+		rule.SourceID = &id
+	}
+
+	if _, err := scraper.CompileAlertRule(rule); err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), 400)
+		return
+	}
+
+	if err := app.alerts.InsertRule(rule); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/alerts?result="+url.QueryEscape("Watching for "+rule.String()), http.StatusSeeOther)
+}
+
+// alertRuleDeletePostHandler removes an alert rule POST /admin/alerts/rules/{id}/delete
+func (app *application) alertRuleDeletePostHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w, r)
+		return
+	}
+
+	if err := app.alerts.DeleteRule(id); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/alerts?result="+url.QueryEscape("Rule removed"), http.StatusSeeOther)
+}
+
+// alertSeenPostHandler acknowledges one alert POST /admin/alerts/{id}/seen
+func (app *application) alertSeenPostHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w, r)
+		return
+	}
+
+	if err := app.alerts.MarkSeen(id); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, alertsReturnPath(r), http.StatusSeeOther)
+}
+
+// alertsSeenPostHandler acknowledges every waiting alert POST /admin/alerts/seen
+func (app *application) alertsSeenPostHandler(w http.ResponseWriter, r *http.Request) {
+	n, err := app.alerts.MarkAllSeen()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	result := fmt.Sprintf("%d alert(s) acknowledged", n)
+	http.Redirect(w, r, alertsReturnPath(r)+"?result="+url.QueryEscape(result), http.StatusSeeOther)
+}
+
+// alertsReturnPath sends acknowledgements made from the scraper banner back there.
+func alertsReturnPath(r *http.Request) string {
+	if r.PostFormValue("from") == "scraper" {
+		return "/scraper"
+	}
+	return "/admin/alerts"
+}
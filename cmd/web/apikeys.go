@@ -0,0 +1,84 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// newAPIKey is a key just issued, shown once on the page that created it
+type newAPIKey struct {
+	Name  string
+	Scope string
+	Key   string
+}
+
+// apiKeyCreatePostHandler issues a key for the given scope POST /admin/api/keys
+//
+// The key is rendered straight into the response instead of redirecting, so it never
+// ends up in a URL or the browser history; only its hash is kept.
+func (app *application) apiKeyCreatePostHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	name := strings.TrimSpace(r.PostForm.Get("name"))
+	scope := r.PostForm.Get("scope")
+	if name == "" || (scope != scopeAPI && scope != scopeIngest) {
+		http.Error(w, "Bad Request: a key needs a name and a scope of api or ingest", 400)
+		return
+	}
+
+	key := "sacrif_" + rand.Text()
+	sum := sha256.Sum256([]byte(key))
+	if _, err := app.apiKeys.Insert(&models.APIKey{Name: name, Scope: scope, Hash: hex.EncodeToString(sum[:])}); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	page, err := app.buildAPIPage()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	page.NewKey = &newAPIKey{Name: name, Scope: scope, Key: key}
+	page.Result = "Issued " + scope + " key " + tokenFingerprint(sum) + " for " + name
+
+	w.Header().Set("Cache-Control", "no-store")
+	app.render(w, r, page, "pages/api.tmpl")
+}
+
+// apiKeyDeletePostHandler withdraws an issued key POST /admin/api/keys/{id}/delete
+func (app *application) apiKeyDeletePostHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		app.notFound(w, r)
+		return
+	}
+
+	key, err := app.apiKeys.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.apiKeys.Delete(id); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	result := "Deleted key " + key.Fingerprint() + " (" + key.Name + ")"
+	http.Redirect(w, r, "/admin/api?result="+url.QueryEscape(result), http.StatusSeeOther)
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// Token scopes: which endpoints a token opens
+const (
+	scopeIngest = "ingest" // POST /api/scraper/ingest
+	scopeAPI    = "api"    // Writes to /api/v1/entries
+)
+
+// apiToken is one bearer token the API accepts
+type apiToken struct {
+	Label       string // Where it comes from: the scope for environment tokens, the name for issued keys
+	Scope       string
+	Fingerprint string
+	sum         [sha256.Size]byte
+}
+
+// tokenFingerprint identifies a token on the usage dashboard without revealing it
+func tokenFingerprint(sum [sha256.Size]byte) string {
+	return hex.EncodeToString(sum[:6])
+}
+
+// apiTokens lists the tokens from the environment and the keys issued from the admin
+// page; empty means the API is switched off
+func (app *application) apiTokens() ([]apiToken, error) {
+	var tokens []apiToken
+	if app.ingestToken != "" {
+		sum := sha256.Sum256([]byte(app.ingestToken))
+		tokens = append(tokens, apiToken{Label: scopeIngest, Scope: scopeIngest, Fingerprint: tokenFingerprint(sum), sum: sum})
+	}
+	if app.apiToken != "" {
+		sum := sha256.Sum256([]byte(app.apiToken))
+		tokens = append(tokens, apiToken{Label: scopeAPI, Scope: scopeAPI, Fingerprint: tokenFingerprint(sum), sum: sum})
+	}
+
+	keys, err := app.apiKeys.All()
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		var sum [sha256.Size]byte
+		if b, err := hex.DecodeString(k.Hash); err != nil || len(b) != sha256.Size {
+			slog.Warn("Skipping API key with a malformed hash", "key", k.ID)
+			continue
+		} else {
+			copy(sum[:], b)
+		}
+		tokens = append(tokens, apiToken{Label: k.Name, Scope: k.Scope, Fingerprint: tokenFingerprint(sum), sum: sum})
+	}
+	return tokens, nil
+}
+
+// requireAPIToken lets through callers with a valid "Authorization: Bearer" token
+// for the given scope that hasn't been revoked or gone over its hourly limit, and
+// counts every request made with a known token for /admin/api. Without a token for
+// the scope configured or issued, the endpoint doesn't exist.
+func (app *application) requireAPIToken(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tokens, err := app.apiTokens()
+		if err != nil {
+			slog.ErrorContext(r.Context(), "API token lookup failed", "err", err)
+			writeAPIError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+		if !slices.ContainsFunc(tokens, func(t apiToken) bool { return t.Scope == scope }) {
+			http.NotFound(w, r)
+			return
+		}
+
+		// Compare fixed-length digests so the check runs in constant time regardless of input length
+		given, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		givenSum := sha256.Sum256([]byte(given))
+		var token *apiToken
+		for i := range tokens {
+			if subtle.ConstantTimeCompare(givenSum[:], tokens[i].sum[:]) == 1 {
+				token = &tokens[i]
+			}
+		}
+		if token == nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+			writeAPIError(w, http.StatusUnauthorized, "invalid or missing token")
+			return
+		}
+
+		rec := &statusRecorder{ResponseWriter: w}
+		now := time.Now()
+		defer func() {
+			write := r.Method != http.MethodGet && r.Method != http.MethodHead
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+			if err := app.apiUsage.Record(token.Fingerprint, token.Label, write, rec.status, now); err != nil {
+				slog.ErrorContext(r.Context(), "API usage record failed", "err", err)
+			}
+		}()
+
+		// A token for another endpoint is known, so its use still counts
+		if token.Scope != scope {
+			writeAPIError(rec, http.StatusForbidden, "token not valid for this endpoint")
+			return
+		}
+
+		revoked, limit, err := app.apiUsage.Policy(token.Fingerprint)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "API usage policy lookup failed", "err", err)
+			writeAPIError(rec, http.StatusInternalServerError, "internal error")
+			return
+		}
+		if revoked {
+			writeAPIError(rec, http.StatusForbidden, "token revoked")
+			return
+		}
+		if limit > 0 {
+			used, err := app.apiUsage.HourlyRequests(token.Fingerprint, now)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "API usage count failed", "err", err)
+				writeAPIError(rec, http.StatusInternalServerError, "internal error")
+				return
+			}
+			if used >= limit {
+				// The allowance resets on the hour
+				wait := now.Truncate(time.Hour).Add(time.Hour).Sub(now)
+				rec.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+				writeAPIError(rec, http.StatusTooManyRequests, "hourly request limit reached")
+				return
+			}
+		}
+
+		next(rec, r)
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, msg string) {
+	body, err := json.Marshal(map[string]string{"error": msg})
+	if err != nil {
+		http.Error(w, "Internal Server Error", 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(append(body, '\n'))
+}
+
+// apiPage is the data handed to api.tmpl
+type apiPage struct {
+	Usage  []*apiTokenUsage
+	Keys   []*models.APIKey
+	NewKey *newAPIKey // Set only on the response to creating a key
+	Result string     // Outcome of the last action, if any
+}
+
+// apiTokenUsage is one row of the dashboard
+type apiTokenUsage struct {
+	*models.APIUsage
+	Configured bool // False once the token has been rotated out of the environment
+}
+
+// apiUsageHandler shows what each API token has been doing GET /admin/api
+func (app *application) apiUsageHandler(w http.ResponseWriter, r *http.Request) {
+	page, err := app.buildAPIPage()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	page.Result = r.URL.Query().Get("result")
+
+	app.render(w, r, page, "pages/api.tmpl")
+}
+
+// buildAPIPage gathers the usage rows and issued keys for the dashboard.
+func (app *application) buildAPIPage() (*apiPage, error) {
+	usage, err := app.apiUsage.All()
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := app.apiTokens()
+	if err != nil {
+		return nil, err
+	}
+
+	page := &apiPage{}
+	seen := make(map[string]bool)
+	for _, u := range usage {
+		row := &apiTokenUsage{APIUsage: u}
+		for _, t := range tokens {
+			row.Configured = row.Configured || t.Fingerprint == u.Fingerprint
+		}
+		seen[u.Fingerprint] = true
+		page.Usage = append(page.Usage, row)
+	}
+	// Configured tokens nobody has used yet still get a row, so they can be limited up front
+	for _, t := range tokens {
+		if !seen[t.Fingerprint] {
+			page.Usage = append(page.Usage, &apiTokenUsage{
+				APIUsage:   &models.APIUsage{Fingerprint: t.Fingerprint, Label: t.Label},
+				Configured: true,
+			})
+		}
+	}
+
+	if page.Keys, err = app.apiKeys.All(); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// tokenLabel finds the label for a fingerprint posted from the dashboard
+func (app *application) tokenLabel(fingerprint string) string {
+	tokens, err := app.apiTokens()
+	if err != nil {
+		slog.Error("API token lookup failed", "err", err)
+	}
+	for _, t := range tokens {
+		if t.Fingerprint == fingerprint {
+			return t.Label
+		}
+	}
+	return "retired"
+}
+
+// apiLimitPostHandler sets or lifts a token's hourly request limit POST /admin/api/{token}/limit
+func (app *application) apiLimitPostHandler(w http.ResponseWriter, r *http.Request) {
+	fingerprint := r.PathValue("token")
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	limit := 0
+	if v := strings.TrimSpace(r.PostForm.Get("limit")); v != "" {
+		var err error
+		if limit, err = strconv.Atoi(v); err != nil || limit < 0 {
+			http.Error(w, "Bad Request", 400)
+			return
+		}
+	}
+
+	if err := app.apiUsage.SetLimit(fingerprint, app.tokenLabel(fingerprint), limit); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	result := "Lifted the limit on " + fingerprint
+	if limit > 0 {
+		result = "Limited " + fingerprint + " to " + strconv.Itoa(limit) + " requests an hour"
+	}
+	http.Redirect(w, r, "/admin/api?result="+url.QueryEscape(result), http.StatusSeeOther)
+}
+
+// apiRevokePostHandler refuses a token from now on POST /admin/api/{token}/revoke
+func (app *application) apiRevokePostHandler(w http.ResponseWriter, r *http.Request) {
+	app.setTokenRevoked(w, r, true)
+}
+
+// apiRestorePostHandler accepts a revoked token again POST /admin/api/{token}/restore
+func (app *application) apiRestorePostHandler(w http.ResponseWriter, r *http.Request) {
+	app.setTokenRevoked(w, r, false)
+}
+
+func (app *application) setTokenRevoked(w http.ResponseWriter, r *http.Request, revoked bool) {
+	fingerprint := r.PathValue("token")
+	if err := app.apiUsage.SetRevoked(fingerprint, app.tokenLabel(fingerprint), revoked); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	result := "Restored " + fingerprint
+	if revoked {
+		result = "Revoked " + fingerprint
+	}
+	http.Redirect(w, r, "/admin/api?result="+url.QueryEscape(result), http.StatusSeeOther)
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// maxUploadBytes caps a single attachment upload.
+const maxUploadBytes = 32 << 20
+
+// duplicatePage is the data handed to duplicate.tmpl when an upload matches a stored file
+type duplicatePage struct {
+	Entry    *models.Entry
+	Upload   *models.Attachment   // What would be attached, not yet saved
+	Existing []*models.Attachment // Attachments on other entries sharing the same blob
+}
+
+// attachmentUploadPostHandler stores an uploaded file and attaches it POST /admin/entries/{id}/attachments
+func (app *application) attachmentUploadPostHandler(w http.ResponseWriter, r *http.Request) {
+	entry, ok := app.entryFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	// A large file over a slow link can take longer than the server's read timeout
+	http.NewResponseController(w).SetReadDeadline(time.Now().Add(uploadTimeout))
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+	defer file.Close()
+
+	// Sniff the type from the bytes rather than trusting the browser's guess
+	br := bufio.NewReader(file)
+	head, _ := br.Peek(512)
+
+	upload := &models.Attachment{
+		EntryID:     entry.ID,
+		Filename:    cleanFilename(header.Filename),
+		ContentType: http.DetectContentType(head),
+	}
+
+	upload.BlobHash, upload.Size, err = app.blobs.Put(br)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	existing, err := app.attachments.WithHash(upload.BlobHash)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	var elsewhere []*models.Attachment
+	for _, a := range existing {
+		if a.EntryID == entry.ID {
+			// Already attached here, nothing to do
+			http.Redirect(w, r, entry.Permalink(), http.StatusSeeOther)
+			return
+		}
+		elsewhere = append(elsewhere, a)
+	}
+
+	// The same file is already on another entry: ask before linking it here as well
+	if len(elsewhere) > 0 {
+		page := duplicatePage{Entry: entry, Upload: upload, Existing: elsewhere}
+
+		app.render(w, r, page, "pages/duplicate.tmpl")
+		return
+	}
+
+	if _, err := app.attachments.Insert(upload); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, entry.Permalink(), http.StatusSeeOther)
+}
+
+// attachmentLinkPostHandler attaches an already stored blob to an entry POST /admin/entries/{id}/attachments/link
+func (app *application) attachmentLinkPostHandler(w http.ResponseWriter, r *http.Request) {
+	entry, ok := app.entryFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	// Type and size come from the copy we already have, not from the form
+	existing, err := app.attachments.WithHash(r.PostForm.Get("hash"))
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if len(existing) == 0 {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	link := &models.Attachment{
+		EntryID:     entry.ID,
+		BlobHash:    existing[0].BlobHash,
+		Filename:    cleanFilename(r.PostForm.Get("filename")),
+		ContentType: existing[0].ContentType,
+		Size:        existing[0].Size,
+	}
+
+	if _, err := app.attachments.Insert(link); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, entry.Permalink(), http.StatusSeeOther)
+}
+
+// attachmentHandler serves an attachment's bytes GET /attachments/{id}
+func (app *application) attachmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w, r)
+		return
+	}
+
+	a, err := app.attachments.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	f, err := app.blobs.Open(a.BlobHash)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Attachment blob unreadable", "attachment", a.ID, "blob", a.BlobHash, "err", err)
+		app.notFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	// Only pictures render in the browser; anything else downloads, so an uploaded
+	// HTML file can never run as part of the station
+	disposition := "attachment"
+	if a.IsImage() {
+		disposition = "inline"
+	}
+
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": a.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	http.ServeContent(w, r, a.Filename, a.CreatedAt, f)
+}
+
+// entryFromPath loads the entry named by the {id} path segment, writing an error response if it can't.
+func (app *application) entryFromPath(w http.ResponseWriter, r *http.Request) (*models.Entry, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w, r)
+		return nil, false
+	}
+
+	entry, err := app.entries.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return nil, false
+	}
+
+	return entry, true
+}
+
+// cleanFilename keeps just the base name of an uploaded file.
+func cleanFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(strings.TrimSpace(name), "\\", "/"))
+	if name == "." || name == "/" || name == "" {
+		return "attachment"
+	}
+	return name
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/auth"
+)
+
+const (
+	sessionCookie = "sacrif_session"
+	sessionTTL    = 30 * 24 * time.Hour
+)
+
+// isAdmin reports whether the request carries a valid signed admin cookie. With no
+// admin password configured nobody can log in, so the admin routes stay locked,
+// except in development where requests made on this machine are let through.
+func (app *application) isAdmin(r *http.Request) bool {
+	if app.localAdmin && fromLoopback(r) {
+		return true
+	}
+	return app.hasAdminSession(r)
+}
+
+// fromLoopback reports whether the request was made on this machine. One passed on
+// by a reverse proxy is connected from loopback too, so forwarded requests never are.
+func fromLoopback(r *http.Request) bool {
+	if r.Header.Get("X-Forwarded-For") != "" || r.Header.Get("Forwarded") != "" {
+		return false
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// loginHandler renders the operator login form GET /admin/login
+func (app *application) loginHandler(w http.ResponseWriter, r *http.Request) {
+	app.renderLogin(w, r, r.URL.Query().Get("next"), "")
+}
+
+// loginPostHandler checks the operator password and issues a signed session cookie POST /admin/login
+func (app *application) loginPostHandler(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	next := r.PostForm.Get("next")
+
+	// Compare fixed-length digests so the check runs in constant time regardless of input length
+	given := sha256.Sum256([]byte(r.PostForm.Get("password")))
+	want := sha256.Sum256([]byte(app.adminPassword))
+	if app.adminPassword == "" || subtle.ConstantTimeCompare(given[:], want[:]) != 1 {
+		w.WriteHeader(http.StatusUnauthorized)
+		app.renderLogin(w, r, next, "Access denied. Credentials rejected.")
+		return
+	}
+
+	value, err := app.cookies.Sign(auth.Session{Subject: "admin", Expires: time.Now().Add(sessionTTL)})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(sessionTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, safeRedirect(next, "/admin"), http.StatusSeeOther)
+}
+
+// logoutPostHandler clears the session cookie POST /admin/logout
+func (app *application) logoutPostHandler(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (app *application) renderLogin(w http.ResponseWriter, r *http.Request, next, errMsg string) {
+	data := struct {
+		Next  string
+		Error string
+	}{Next: next, Error: errMsg}
+
+	w.Header().Set("Cache-Control", "no-store")
+	app.render(w, r, data, "pages/login.tmpl")
+}
+
+// safeRedirect only allows local paths, so ?next= can't bounce visitors to another site.
+func safeRedirect(next, fallback string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return fallback
+	}
+	return next
+}
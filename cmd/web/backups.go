@@ -0,0 +1,174 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/backup"
+)
+
+// backupDir holds the archives, relative to the data root.
+const backupDir = "backups"
+
+// backupFile describes one archive on disk for backups.tmpl
+type backupFile struct {
+	Name      string
+	Size      int64
+	CreatedAt time.Time
+}
+
+// backupsPage is the data handed to backups.tmpl
+type backupsPage struct {
+	Backups   []backupFile
+	PublicKey string // minisign public key, empty when backups aren't signed
+	KeyID     string
+	Result    string // Outcome of the last backup, if any
+}
+
+// backupsHandler lists the archives and how to verify them GET /admin/backups
+func (app *application) backupsHandler(w http.ResponseWriter, r *http.Request) {
+	page := backupsPage{Result: r.URL.Query().Get("result")}
+	if app.backupSigner != nil {
+		page.PublicKey = app.backupSigner.PublicKey()
+		page.KeyID = app.backupSigner.KeyID()
+	}
+
+	entries, err := fs.ReadDir(app.data.FS(), backupDir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		app.serverError(w, r, err)
+		return
+	}
+	for _, e := range entries {
+		if !validBackupName(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		page.Backups = append(page.Backups, backupFile{Name: e.Name(), Size: info.Size(), CreatedAt: info.ModTime()})
+	}
+
+	// Names embed the timestamp, so reverse name order is newest first
+	slices.Reverse(page.Backups)
+
+	app.render(w, r, page, "pages/backups.tmpl")
+}
+
+// backupsPostHandler writes a new archive POST /admin/backups
+func (app *application) backupsPostHandler(w http.ResponseWriter, r *http.Request) {
+	entries, err := app.entries.All()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	attachments, err := app.attachments.All()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	externalIDs, err := app.externalIDs.All()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	snap := &backup.Snapshot{
+		Name:        "sacrif-" + now.Format("20060102-150405") + ".tar.gz",
+		CreatedAt:   now,
+		Entries:     entries,
+		Attachments: attachments,
+		ExternalIDs: externalIDs,
+		Blobs:       app.blobs,
+	}
+
+	result := "backup " + snap.Name + " written"
+	if err := app.writeBackup(snap); err != nil {
+		slog.ErrorContext(r.Context(), "Backup failed", "err", err)
+		result = "backup failed (" + err.Error() + ")"
+	}
+
+	http.Redirect(w, r, "/admin/backups?result="+url.QueryEscape(result), http.StatusSeeOther)
+}
+
+// writeBackup writes the archive under a temporary name and renames it into place,
+// so a half-written file never shows up in the list.
+func (app *application) writeBackup(snap *backup.Snapshot) error {
+	if err := app.data.MkdirAll(backupDir, 0o750); err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 8)
+	rand.Read(suffix)
+	tmp := path.Join(backupDir, "tmp-"+hex.EncodeToString(suffix))
+
+	f, err := app.data.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer app.data.Remove(tmp) // No-op once renamed
+
+	err = backup.Write(f, snap, app.backupSigner)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	return app.data.Rename(tmp, path.Join(backupDir, snap.Name))
+}
+
+// backupDownloadHandler serves an archive GET /admin/backups/{name}
+func (app *application) backupDownloadHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if !validBackupName(name) {
+		app.notFound(w, r)
+		return
+	}
+
+	f, err := app.data.Open(path.Join(backupDir, name))
+	if err != nil {
+		app.notFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+
+	http.ServeContent(w, r, name, info.ModTime(), f)
+}
+
+// backupPublicKeyHandler serves the key backups are signed with as a minisign.pub file GET /admin/backups/minisign.pub
+func (app *application) backupPublicKeyHandler(w http.ResponseWriter, r *http.Request) {
+	if app.backupSigner == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(app.backupSigner.PublicKeyFile()))
+}
+
+// validBackupName only accepts the names backupsPostHandler generates.
+func validBackupName(name string) bool {
+	return strings.HasPrefix(name, "sacrif-") && strings.HasSuffix(name, ".tar.gz") && !strings.ContainsAny(name, `/\`)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// adminEntriesPageSize is how many entries the admin listing shows at once.
+const adminEntriesPageSize = 100
+
+// adminEntriesPage is the data handed to entries.tmpl
+type adminEntriesPage struct {
+	templateData
+	Counts  []*models.TypeCount // Every stored type key, unregistered ones included, to filter by
+	Targets []models.EntryType  // Types the selection can be moved to
+	Type    string
+	Result  string // Outcome of the last bulk action, if any
+}
+
+// adminEntriesHandler lists every entry with a checkbox each, for acting on many at
+// once GET /admin/entries
+//
+// ?type= narrows the listing to one stored type key, registered or not, which is how
+// a botched import is usually found.
+func (app *application) adminEntriesHandler(w http.ResponseWriter, r *http.Request) {
+	page := &adminEntriesPage{
+		Targets: models.SelectableTypes(),
+		Type:    r.URL.Query().Get("type"),
+		Result:  r.URL.Query().Get("result"),
+	}
+	filter := url.Values{}
+	if page.Type != "" {
+		filter.Set("type", page.Type)
+	}
+
+	var err error
+	if page.Counts, err = app.typeMigrations.Counts(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	n := sectorPageNumber(r)
+	entries, total, err := app.entries.List(models.EntryFilter{
+		Type:   page.Type,
+		Limit:  adminEntriesPageSize,
+		Offset: (n - 1) * adminEntriesPageSize,
+	})
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	page.templateData = listPage("/admin/entries", filter, entries, n, adminEntriesPageSize, total)
+
+	app.render(w, r, page, "partials/pager.tmpl", "pages/entries.tmpl")
+}
+
+// adminEntriesBulkPostHandler retypes or deletes the checked entries POST /admin/entries/bulk
+//
+// Either way the selection changes in one transaction, all of it or none. Webhook
+// subscribers hear about each entry afterwards, as they would for a one-off edit.
+func (app *application) adminEntriesBulkPostHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	var ids []int
+	for _, v := range r.PostForm["id"] {
+		id, err := strconv.Atoi(v)
+		if err != nil || id < 1 {
+			http.Error(w, "Bad Request", 400)
+			return
+		}
+		ids = append(ids, id)
+	}
+	ids = slices.Compact(slices.Sorted(slices.Values(ids)))
+
+	back := safeRedirect(r.PostForm.Get("next"), "/admin/entries")
+	redirect := func(result string) {
+		u, _ := url.Parse(back)
+		q := u.Query()
+		q.Set("result", result)
+		u.RawQuery = q.Encode()
+		http.Redirect(w, r, u.String(), http.StatusSeeOther)
+	}
+
+	if len(ids) == 0 {
+		redirect("Nothing selected")
+		return
+	}
+
+	var result string
+	var err error
+	switch r.PostForm.Get("action") {
+	case "retype":
+		result, err = app.bulkRetype(ids, r.PostForm.Get("to"))
+	case "delete":
+		result, err = app.bulkDelete(ids)
+	default:
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Bulk action failed", "action", r.PostForm.Get("action"), "entries", len(ids), "err", err)
+		if errors.Is(err, models.ErrNoRecord) {
+			err = errors.New("an entry in the selection no longer exists")
+		}
+		redirect("Bulk action refused: " + err.Error())
+		return
+	}
+	redirect(result)
+}
+
+func (app *application) bulkRetype(ids []int, to string) (string, error) {
+	detached, err := app.entries.BulkUpdateType(ids, to)
+	if err != nil {
+		return "", err
+	}
+
+	for _, id := range ids {
+		app.emitEntryEvent(eventEntryUpdated, id)
+	}
+
+	result := fmt.Sprintf("Moved %d entries to %s", len(ids), to)
+	if detached > 0 {
+		result += fmt.Sprintf(", detaching %d from their threads", detached)
+	}
+	return result, nil
+}
+
+func (app *application) bulkDelete(ids []int) (string, error) {
+	// Snapshots are taken first: once the entries are gone there is nothing left to send
+	var snapshots []*entryResource
+	if len(app.webhookURLs) > 0 {
+		for _, id := range ids {
+			entry, err := app.entries.Get(id)
+			if err != nil {
+				return "", err
+			}
+			snapshot, err := app.loadEntryResource(entry)
+			if err != nil {
+				return "", err
+			}
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+
+	if err := app.entries.BulkDelete(ids); err != nil {
+		return "", err
+	}
+	app.entriesDeleted(ids...)
+
+	for _, snapshot := range snapshots {
+		app.emitEntrySnapshot(eventEntryDeleted, snapshot)
+	}
+	return fmt.Sprintf("Deleted %d entries", len(ids)), nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cachePolicy is the Cache-Control the station answers with where a handler doesn't
+// choose one itself. Admin pages, error pages and other per-visitor answers set
+// their own no-store and keep it.
+type cachePolicy struct {
+	pages  string // Public HTML pages and their JSON forms
+	feeds  string // RSS, Atom and JSON Feed
+	static string // Static files under their hashed names, which never change
+}
+
+// newCachePolicy reads SACRIF_CACHE_PAGES and SACRIF_CACHE_FEEDS, how long browsers
+// and proxies may reuse a page or feed without asking again (defaults 0 and 5m), and
+// SACRIF_CACHE_STATIC for hashed static files (default a year). With 0, copies are
+// still kept but revalidated every time, which the ETags make a cheap 304.
+func newCachePolicy() (*cachePolicy, error) {
+	pages, err := cacheMaxAge("SACRIF_CACHE_PAGES", 0)
+	if err != nil {
+		return nil, err
+	}
+	feeds, err := cacheMaxAge("SACRIF_CACHE_FEEDS", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	static, err := cacheMaxAge("SACRIF_CACHE_STATIC", 365*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &cachePolicy{pages: publicCache(pages), feeds: publicCache(feeds), static: publicCache(static)}
+	if static > 0 {
+		p.static += ", immutable"
+	}
+	return p, nil
+}
+
+func cacheMaxAge(name string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%s %q must be a duration like 10m, or 0", name, v)
+	}
+	return d, nil
+}
+
+func publicCache(maxAge time.Duration) string {
+	if maxAge <= 0 {
+		return "public, no-cache"
+	}
+	return "public, max-age=" + strconv.Itoa(int(maxAge.Seconds()))
+}
+
+// isFeedPath reports whether a path is one of the feeds.
+func isFeedPath(p string) bool {
+	return strings.HasSuffix(p, "/feed.xml") || strings.HasSuffix(p, "/feed.atom") || p == "/feed.json"
+}
+
+// cacheHeaders gives successful GETs the policy's Cache-Control unless the handler
+// set one. Anyone signed in gets private copies only: their pages show controls no
+// shared cache should hand to the next visitor.
+func (app *application) cacheHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		value := app.cache.pages
+		switch {
+		case app.hasAdminSession(r):
+			value = "private, no-cache"
+		case isFeedPath(r.URL.Path):
+			value = app.cache.feeds
+		}
+		next.ServeHTTP(&cacheWriter{ResponseWriter: w, value: value}, r)
+	})
+}
+
+// cacheWriter adds Cache-Control as the headers go out, once the status is known:
+// only answers a cache may keep get one, so a 400 or a redirect is never stored.
+type cacheWriter struct {
+	http.ResponseWriter
+	value       string
+	wroteHeader bool
+}
+
+func (cw *cacheWriter) WriteHeader(status int) {
+	if !cw.wroteHeader {
+		cw.wroteHeader = true
+		h := cw.Header()
+		if h.Get("Cache-Control") == "" && (status == http.StatusOK || status == http.StatusNotModified) {
+			h.Set("Cache-Control", cw.value)
+		}
+	}
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *cacheWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the real writer, so streams still flush.
+func (cw *cacheWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
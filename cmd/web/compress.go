@@ -0,0 +1,138 @@
+package main
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// compressibleTypes are the non-text media types worth compressing; text/* is too,
+// except event streams, which must reach the browser frame by frame.
+var compressibleTypes = map[string]bool{
+	"application/json":       true,
+	"application/feed+json":  true,
+	"application/xml":        true,
+	"application/atom+xml":   true,
+	"application/rss+xml":    true,
+	"application/javascript": true,
+	"image/svg+xml":          true,
+}
+
+// compressResponses gzips (or, for clients that only take that, deflates) HTML,
+// JSON and feeds on the way out. Responses that already carry a Content-Encoding,
+// such as the snapshot, and partial or binary content pass through untouched.
+func compressResponses(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := ""
+		switch {
+		case acceptsEncoding(r, "gzip"):
+			encoding = "gzip"
+		case acceptsEncoding(r, "deflate"):
+			encoding = "deflate"
+		}
+		if encoding == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// acceptsEncoding reports whether the client takes responses in the given content
+// coding, e.g. "gzip".
+func acceptsEncoding(r *http.Request, coding string) bool {
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(enc), ";")
+		if strings.EqualFold(strings.TrimSpace(name), coding) && strings.ReplaceAll(params, " ", "") != "q=0" {
+			return true
+		}
+	}
+	return false
+}
+
+// compressWriter decides on the first write whether the response is worth
+// compressing, from the headers the handler set by then.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	decided  bool
+	enc      io.WriteCloser // nil while the response goes out as is
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if !cw.decided {
+		cw.decide(status)
+	}
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.decided {
+		// Sniff now, as net/http would, or the type would only be known after the decision
+		if cw.Header().Get("Content-Type") == "" {
+			cw.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.enc != nil {
+		return cw.enc.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+func (cw *compressWriter) decide(status int) {
+	cw.decided = true
+
+	h := cw.Header()
+	if status < 200 || status == http.StatusNoContent || status == http.StatusNotModified ||
+		h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" || !compressible(h.Get("Content-Type")) {
+		return
+	}
+
+	h.Del("Content-Length")
+	h.Set("Content-Encoding", cw.encoding)
+	h.Add("Vary", "Accept-Encoding")
+	if cw.encoding == "gzip" {
+		cw.enc = gzip.NewWriter(cw.ResponseWriter)
+	} else {
+		cw.enc = zlib.NewWriter(cw.ResponseWriter) // HTTP's "deflate" is the zlib format
+	}
+}
+
+// Flush pushes out whatever the compressor holds, so flushed responses still stream.
+func (cw *compressWriter) Flush() {
+	if f, ok := cw.enc.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the real writer.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Close finishes the compressed stream, if there is one.
+func (cw *compressWriter) Close() error {
+	if cw.enc == nil {
+		return nil
+	}
+	return cw.enc.Close()
+}
+
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		return mediaType != "text/event-stream"
+	}
+	return compressibleTypes[mediaType]
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// corsMaxAge is how long browsers may cache a preflight answer, in seconds.
+const corsMaxAge = 600
+
+// corsPolicy lets pages on other origins call the JSON API from the browser. Only
+// /api/ is covered: the site itself and the admin pages are same-origin only.
+// Credentials are never allowed, so a foreign page can't ride the admin session;
+// it has to bring its own bearer token.
+type corsPolicy struct {
+	origins []string // Exact origins, or just "*" for any
+	methods string   // Access-Control-Allow-Methods
+	headers string   // Access-Control-Allow-Headers
+}
+
+// newCORSPolicy reads SACRIF_CORS_ORIGINS, a comma-separated list of origins (or
+// "*"), and optionally SACRIF_CORS_METHODS and SACRIF_CORS_HEADERS. It returns nil,
+// leaving the API same-origin only, when no origins are set.
+func newCORSPolicy() (*corsPolicy, error) {
+	var origins []string
+	for _, o := range strings.Split(os.Getenv("SACRIF_CORS_ORIGINS"), ",") {
+		o = strings.TrimRight(strings.TrimSpace(o), "/")
+		if o == "" {
+			continue
+		}
+		if o != "*" {
+			u, err := url.Parse(o)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" {
+				return nil, fmt.Errorf("SACRIF_CORS_ORIGINS: %q is not an origin like https://dashboard.example", o)
+			}
+			o = strings.ToLower(o)
+		}
+		origins = append(origins, o)
+	}
+	if len(origins) == 0 {
+		return nil, nil
+	}
+	if len(origins) > 1 && slices.Contains(origins, "*") {
+		return nil, fmt.Errorf("SACRIF_CORS_ORIGINS: * can't be combined with other origins")
+	}
+
+	return &corsPolicy{
+		origins: origins,
+		methods: corsList(os.Getenv("SACRIF_CORS_METHODS"), "GET, POST, PUT, DELETE"),
+		headers: corsList(os.Getenv("SACRIF_CORS_HEADERS"), "Authorization, Content-Type"),
+	}, nil
+}
+
+// corsList normalizes a comma-separated list, falling back to def when it is empty.
+func corsList(v, def string) string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 {
+		return def
+	}
+	return strings.Join(items, ", ")
+}
+
+// allowed reports whether a request's Origin may call the API.
+func (c *corsPolicy) allowed(origin string) bool {
+	return origin != "" && (c.origins[0] == "*" || slices.Contains(c.origins, strings.ToLower(origin)))
+}
+
+// Handler adds CORS headers to API responses for allowed origins and answers their
+// preflight requests itself, ahead of the rate limiter and the API's token checks.
+func (c *corsPolicy) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		origin := r.Header.Get("Origin")
+		h := w.Header()
+		if c.origins[0] != "*" {
+			h.Add("Vary", "Origin")
+		}
+		if !c.allowed(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if c.origins[0] == "*" {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			h.Set("Access-Control-Allow-Methods", c.methods)
+			h.Set("Access-Control-Allow-Headers", c.headers)
+			h.Set("Access-Control-Max-Age", strconv.Itoa(corsMaxAge))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		// Scripts can only read the headers they're told about
+		h.Set("Access-Control-Expose-Headers", "Location, Retry-After, X-Request-ID")
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// Sizes of the dashboard's lists; each links to the full page for the rest.
+const (
+	dashboardEntries = 15
+	dashboardPending = 5
+	dashboardRuns    = 8
+)
+
+// dashboardPage is the data handed to dashboard.tmpl
+type dashboardPage struct {
+	Entries      []*models.Entry
+	Pending      []*models.Transmission // The oldest few awaiting moderation
+	PendingCount int
+	Runs         []*models.ScrapeRun
+	Alerts       int // Unseen scraper alerts
+	Types        []models.EntryType
+	Moods        []models.Mood
+}
+
+// dashboardHandler gathers what the operator checks most in one page GET /admin
+//
+// The station keeps no drafts: guest transmissions waiting for moderation are what
+// sits unpublished, so they stand in the pending list.
+func (app *application) dashboardHandler(w http.ResponseWriter, r *http.Request) {
+	page := dashboardPage{Types: models.SelectableTypes(), Moods: models.Moods}
+	var err error
+
+	if page.Entries, err = app.entries.Latest(dashboardEntries); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	pending, err := app.transmissions.Pending()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	page.PendingCount = len(pending)
+	page.Pending = pending[:min(len(pending), dashboardPending)]
+
+	if page.Runs, err = app.scrapeRuns.Latest(dashboardRuns); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if page.Alerts, err = app.alerts.CountUnseen(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.render(w, r, page, "pages/dashboard.tmpl")
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+const (
+	decayMonth = 30 * 24 * time.Hour
+	maxDecay   = 60 // Past this a log is noise, and the permalink stays clean to read it
+)
+
+// decayPolicy is how fast each sector's entries corrupt as they age, in severity
+// points per month since they were logged. Only the sector listings decay; an
+// entry's own page always shows it intact.
+type decayPolicy struct {
+	thoughts int
+	media    int
+}
+
+// newDecayPolicy reads SACRIF_DECAY_THOUGHTS and SACRIF_DECAY_MEDIA (defaults 2 and
+// 0); 0 keeps a sector pristine.
+func newDecayPolicy() (*decayPolicy, error) {
+	thoughts, err := decayRate("SACRIF_DECAY_THOUGHTS", 2)
+	if err != nil {
+		return nil, err
+	}
+	media, err := decayRate("SACRIF_DECAY_MEDIA", 0)
+	if err != nil {
+		return nil, err
+	}
+	return &decayPolicy{thoughts: thoughts, media: media}, nil
+}
+
+func decayRate(name string, def int) (int, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 || n > 100 {
+		return 0, fmt.Errorf("%s %q must be a number of severity points per month, 0 to 100", name, v)
+	}
+	return n, nil
+}
+
+// decay sets each entry's Decay from its age at rate points a month, replies included.
+func decay(entries []*models.Entry, rate int, now time.Time) {
+	for _, e := range entries {
+		months := int(now.Sub(e.CreatedAt) / decayMonth)
+		e.Decay = min(max(months*rate, 0), maxDecay)
+		decay(e.Replies, rate, now)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// editEntryHandler renders the admin form prefilled with an existing entry GET /admin/edit/{id}
+func (app *application) editEntryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w, r)
+		return
+	}
+
+	entry, err := app.entries.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	page := entryFormPage{
+		Action: fmt.Sprintf("/admin/edit/%d", entry.ID),
+		Entry:  entry,
+		Types:  models.SelectableTypes(),
+		Moods:  models.Moods,
+		Fields: entry.Fields.Text(),
+	}
+
+	app.renderEntryForm(w, r, http.StatusOK, page)
+}
+
+// editEntryPostHandler saves changes to an existing entry POST /admin/edit/{id}
+func (app *application) editEntryPostHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w, r)
+		return
+	}
+
+	err = r.ParseForm()
+	if err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	// Look at the stored entry first: its type may stay retired, and a changed link
+	// refreshes (or clears) the quoted context
+	previous, err := app.entries.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	entry, v := entryFromForm(r.PostForm, previous.Type)
+	entry.ID = id
+	if !v.Valid() {
+		page := entryFormPage{
+			Action: fmt.Sprintf("/admin/edit/%d", id),
+			Entry:  entry,
+			Types:  models.SelectableTypes(),
+			Moods:  models.Moods,
+			Fields: r.PostForm.Get("fields"),
+			Errors: v.FieldErrors,
+		}
+		app.renderEntryForm(w, r, http.StatusUnprocessableEntity, page)
+		return
+	}
+
+	err = app.entries.Update(entry)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	if models.StringValue(previous.URL) != models.StringValue(entry.URL) {
+		app.enqueueReplyContext(entry, true)
+	}
+	app.emitEntryEvent(eventEntryUpdated, id)
+	app.setFlash(w, r, "Entry updated: "+entry.Title)
+
+	// Slugs don't follow title edits, so the permalink stays stable
+	http.Redirect(w, r, previous.Permalink(), http.StatusSeeOther)
+}
+
+// entryDeletePostHandler removes an entry POST /admin/entries/{id}/delete
+//
+// Its replies move up to its own parent, so the rest of the thread holds together.
+func (app *application) entryDeletePostHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w, r)
+		return
+	}
+
+	entry, err := app.entries.Get(id)
+	if err == nil {
+		err = app.deleteEntry(entry)
+	}
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w, r)
+		} else {
+			app.serverError(w, r, err, "entry", id)
+		}
+		return
+	}
+
+	app.setFlash(w, r, "Entry deleted: "+entry.Title)
+	http.Redirect(w, r, safeRedirect(r.PostFormValue("next"), "/admin"), http.StatusSeeOther)
+}
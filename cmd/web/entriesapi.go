@@ -0,0 +1,401 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+	"github.com/federicopalou/sacrif-station/internal/validator"
+)
+
+const (
+	maxAPIEntryBytes = 1 << 20
+	defaultAPILimit  = 20
+	maxAPILimit      = 100
+)
+
+// entryInput is the body of POST and PUT /api/v1/entries. A PUT replaces every
+// editable field, so omitted optional fields are cleared.
+type entryInput struct {
+	Title           string        `json:"title"`
+	Type            string        `json:"type"`
+	Content         *string       `json:"content"`
+	URL             *string       `json:"url"`
+	Mood            *string       `json:"mood"`
+	ParentID        *int          `json:"parent_id"` // Only on creation; replies are only allowed between thoughts
+	FeedSummary     *string       `json:"feed_summary"`
+	ExcludeFromFeed bool          `json:"exclude_from_feed"`
+	CanonicalURL    *string       `json:"canonical_url"`
+	Fields          models.Fields `json:"fields"` // Replaces all of the entry's custom fields
+}
+
+// entryList is the body of GET /api/v1/entries
+type entryList struct {
+	Entries []*entryResource `json:"entries"`
+	Total   int              `json:"total"` // Matching entries across all pages
+	Limit   int              `json:"limit"`
+	Offset  int              `json:"offset"`
+}
+
+// apiEntriesHandler lists entries, newest first GET /api/v1/entries
+//
+// Filters: ?type=, ?section=thoughts|media, ?mood=, ?since= and ?until= (RFC 3339 or
+// a date), with ?limit= (up to 100) and ?offset= for paging. Replies aren't nested
+// here; every entry is listed on its own with its parent_id.
+func (app *application) apiEntriesHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := models.EntryFilter{Type: q.Get("type"), Section: q.Get("section"), Mood: q.Get("mood"), Limit: defaultAPILimit}
+
+	if f.Section != "" && f.Section != "thoughts" && f.Section != "media" {
+		writeAPIError(w, http.StatusBadRequest, "section must be thoughts or media")
+		return
+	}
+
+	var err error
+	if f.Since, err = parseAPITime(q.Get("since")); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "since: "+err.Error())
+		return
+	}
+	if f.Until, err = parseAPITime(q.Get("until")); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "until: "+err.Error())
+		return
+	}
+	if v := q.Get("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil || f.Limit < 1 || f.Limit > maxAPILimit {
+			writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxAPILimit))
+			return
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if f.Offset, err = strconv.Atoi(v); err != nil || f.Offset < 0 {
+			writeAPIError(w, http.StatusBadRequest, "offset must be a non-negative number")
+			return
+		}
+	}
+
+	entries, total, err := app.entries.List(f)
+	if err == nil {
+		err = app.badgeEpochs(entries)
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "API entry list error", "err", err)
+		writeAPIError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	list := entryList{Entries: []*entryResource{}, Total: total, Limit: f.Limit, Offset: f.Offset}
+	for _, e := range entries {
+		page, err := app.loadEntryPage(e, false)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "API entry list error", "err", err)
+			writeAPIError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+		list.Entries = append(list.Entries, app.newEntryResource(page))
+	}
+
+	writeAPIJSON(w, http.StatusOK, list)
+}
+
+// apiEntryHandler returns one entry, with its replies for thoughts GET /api/v1/entries/{id}
+func (app *application) apiEntryHandler(w http.ResponseWriter, r *http.Request) {
+	entry, ok := app.apiEntryFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	res, err := app.loadEntryResource(entry)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "API entry error", "err", err)
+		writeAPIError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	writeAPIJSON(w, http.StatusOK, res)
+}
+
+// apiEntryCreateHandler adds an entry POST /api/v1/entries
+func (app *application) apiEntryCreateHandler(w http.ResponseWriter, r *http.Request) {
+	in, ok := decodeEntryInput(w, r)
+	if !ok {
+		return
+	}
+
+	entry, err := entryFromInput(in, "")
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if in.ParentID != nil {
+		parent, err := app.entries.Get(*in.ParentID)
+		if errors.Is(err, models.ErrNoRecord) {
+			writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("unknown parent_id %d", *in.ParentID))
+			return
+		} else if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+		if !models.IsThoughtType(parent.Type) || !models.IsThoughtType(entry.Type) {
+			writeAPIError(w, http.StatusBadRequest, "replies are only allowed between thoughts")
+			return
+		}
+		entry.ParentID = &parent.ID
+	}
+
+	id, err := app.entries.Insert(entry)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Database insert error", "err", err)
+		writeAPIError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	entry.ID = id
+	app.entryCreated(entry)
+
+	app.writeStoredEntry(w, id, http.StatusCreated)
+}
+
+// apiEntryUpdateHandler replaces an entry's editable fields PUT /api/v1/entries/{id}
+func (app *application) apiEntryUpdateHandler(w http.ResponseWriter, r *http.Request) {
+	previous, ok := app.apiEntryFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	in, ok := decodeEntryInput(w, r)
+	if !ok {
+		return
+	}
+
+	entry, err := entryFromInput(in, previous.Type)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if in.ParentID != nil && (previous.ParentID == nil || *previous.ParentID != *in.ParentID) {
+		writeAPIError(w, http.StatusBadRequest, "parent_id can't be changed")
+		return
+	}
+	entry.ID = previous.ID
+
+	if err := app.entries.Update(entry); err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			writeAPIError(w, http.StatusNotFound, "no such entry")
+		} else {
+			slog.ErrorContext(r.Context(), "Database update error", "err", err)
+			writeAPIError(w, http.StatusInternalServerError, "internal error")
+		}
+		return
+	}
+
+	if models.StringValue(previous.URL) != models.StringValue(entry.URL) {
+		app.enqueueReplyContext(entry, true)
+	}
+	app.emitEntryEvent(eventEntryUpdated, entry.ID)
+
+	app.writeStoredEntry(w, entry.ID, http.StatusOK)
+}
+
+// apiEntryDeleteHandler removes an entry DELETE /api/v1/entries/{id}
+func (app *application) apiEntryDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	entry, ok := app.apiEntryFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := app.deleteEntry(entry); err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			writeAPIError(w, http.StatusNotFound, "no such entry")
+		} else {
+			slog.ErrorContext(r.Context(), "Database delete error", "err", err)
+			writeAPIError(w, http.StatusInternalServerError, "internal error")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteEntry removes an entry and tells webhook receivers, who get the entry as it
+// was since it can't be loaded afterwards.
+func (app *application) deleteEntry(entry *models.Entry) error {
+	var snapshot *entryResource
+	if len(app.webhookURLs) > 0 {
+		var err error
+		if snapshot, err = app.loadEntryResource(entry); err != nil {
+			return err
+		}
+	}
+
+	if err := app.entries.Delete(entry.ID); err != nil {
+		return err
+	}
+	app.entriesDeleted(entry.ID)
+
+	if snapshot != nil {
+		app.emitEntrySnapshot(eventEntryDeleted, snapshot)
+	}
+	return nil
+}
+
+// apiEntryFromPath loads the entry named by the {id} path segment, writing a JSON
+// error response if it can't.
+func (app *application) apiEntryFromPath(w http.ResponseWriter, r *http.Request) (*models.Entry, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		writeAPIError(w, http.StatusNotFound, "no such entry")
+		return nil, false
+	}
+
+	entry, err := app.entries.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			writeAPIError(w, http.StatusNotFound, "no such entry")
+		} else {
+			writeAPIError(w, http.StatusInternalServerError, "internal error")
+		}
+		return nil, false
+	}
+
+	return entry, true
+}
+
+func decodeEntryInput(w http.ResponseWriter, r *http.Request) (*entryInput, bool) {
+	var in entryInput
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIEntryBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&in); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "malformed JSON: "+err.Error())
+		return nil, false
+	}
+	return &in, true
+}
+
+// entryFromInput checks an API body the way the admin form is checked, but reports
+// problems instead of quietly dropping them. storedType is the entry's current type
+// on updates, which may stay on a retired type; new entries can't pick one.
+func entryFromInput(in *entryInput, storedType string) (*models.Entry, error) {
+	entry := &models.Entry{
+		Title:           strings.TrimSpace(in.Title),
+		Type:            in.Type,
+		Content:         models.NullString(models.StringValue(in.Content)),
+		URL:             models.NullString(strings.TrimSpace(models.StringValue(in.URL))),
+		Mood:            models.NullString(models.StringValue(in.Mood)),
+		FeedSummary:     models.NullString(strings.TrimSpace(models.StringValue(in.FeedSummary))),
+		ExcludeFromFeed: in.ExcludeFromFeed,
+		CanonicalURL:    models.NullString(strings.TrimSpace(models.StringValue(in.CanonicalURL))),
+	}
+
+	if entry.Title == "" {
+		return nil, errors.New("title is required")
+	}
+	t, known := models.TypeByKey(entry.Type)
+	if !known {
+		return nil, fmt.Errorf("unknown type %q", entry.Type)
+	}
+	if t.Retired && entry.Type != storedType {
+		return nil, fmt.Errorf("type %q is retired", entry.Type)
+	}
+	if entry.Mood != nil && entry.MoodInfo() == nil {
+		return nil, fmt.Errorf("unknown mood %q", *entry.Mood)
+	}
+	if entry.CanonicalURL != nil && !validator.WebURL(*entry.CanonicalURL) {
+		return nil, fmt.Errorf("invalid canonical URL %q", *entry.CanonicalURL)
+	}
+	fields, err := in.Fields.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	entry.Fields = fields
+
+	return entry, nil
+}
+
+// writeStoredEntry answers with an entry as it now reads from the database.
+func (app *application) writeStoredEntry(w http.ResponseWriter, id int, status int) {
+	entry, err := app.entries.Get(id)
+	var res *entryResource
+	if err == nil {
+		res, err = app.loadEntryResource(entry)
+	}
+	if err != nil {
+		slog.Error("API entry error", "err", err)
+		writeAPIError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/entries/%d", id))
+	writeAPIJSON(w, status, res)
+}
+
+// loadEntryResource builds the JSON view of an entry as /entry/{slug} serves it,
+// thread replies included.
+func (app *application) loadEntryResource(entry *models.Entry) (*entryResource, error) {
+	page, err := app.loadEntryPage(entry, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := app.badgeEpochs(append([]*models.Entry{entry}, page.Thread...)); err != nil {
+		return nil, err
+	}
+	return app.newEntryResource(page), nil
+}
+
+// loadEntryPage gathers what hangs off an entry for its resource: attachments,
+// syndications, external IDs and, when thread is set and it is a thought, its thread.
+func (app *application) loadEntryPage(entry *models.Entry, thread bool) (entryPage, error) {
+	page := entryPage{Entry: entry}
+	var err error
+
+	if thread && models.IsThoughtType(entry.Type) {
+		t, err := app.entries.Thread(entry.ID)
+		if err != nil {
+			return page, err
+		}
+		page.Thread = models.NestThreads(t)
+	}
+	if page.Attachments, err = app.attachments.ForEntry(entry.ID); err != nil {
+		return page, err
+	}
+	if page.Syndications, err = app.syndication.ForEntry(entry.ID); err != nil {
+		return page, err
+	}
+	if page.ExternalIDs, err = app.externalIDs.ForEntry(entry.ID); err != nil {
+		return page, err
+	}
+	return page, nil
+}
+
+// parseAPITime reads an RFC 3339 timestamp or a bare date (midnight UTC); empty is the zero time.
+func parseAPITime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither an RFC 3339 time nor a date", s)
+	}
+	return t, nil
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, v any) {
+	body, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(append(body, '\n'))
+}
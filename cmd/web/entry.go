@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// entryPage is the data handed to entry.tmpl
+type entryPage struct {
+	Entry        *models.Entry
+	Thread       []*models.Entry // Nested thread roots, only set for thoughts
+	Syndications []*models.Syndication
+	Attachments  []*models.Attachment
+	ExternalIDs  []*models.ExternalID
+	Providers    []models.ExternalProvider // Catalogues the operator can link the entry to
+	ReplyContext *models.ReplyContext      // Snapshot of the post the entry's URL points at
+	Prev, Next   *models.Entry             // Neighbours in the same sector, nil at either end
+	IsAdmin      bool
+	Queued       bool       // Already in the reading queue
+	Meta         *entryMeta // Link preview tags for the page head
+}
+
+// entryHandler renders a single entry GET /entry/{slug}, with its whole thread for
+// thoughts. The Accept header picks HTML, JSON, Markdown or plain text; old numeric
+// links redirect to the slug so every entry keeps one canonical URL.
+func (app *application) entryHandler(w http.ResponseWriter, r *http.Request) {
+	slug := r.PathValue("slug")
+
+	var entry *models.Entry
+	var err error
+	if id, convErr := strconv.Atoi(slug); convErr == nil {
+		if id < 1 {
+			app.notFound(w, r)
+			return
+		}
+		entry, err = app.entries.Get(id)
+	} else {
+		entry, err = app.entries.GetBySlug(slug)
+	}
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	if slug != entry.Slug {
+		http.Redirect(w, r, entry.Permalink(), http.StatusMovedPermanently)
+		return
+	}
+
+	page := entryPage{Entry: entry, IsAdmin: app.isAdmin(r), Providers: models.ExternalProviders}
+
+	if models.IsThoughtType(entry.Type) {
+		thread, err := app.entries.Thread(entry.ID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		page.Thread = models.NestThreads(thread)
+	}
+
+	page.Syndications, err = app.syndication.ForEntry(entry.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	page.Attachments, err = app.attachments.ForEntry(entry.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	page.ExternalIDs, err = app.externalIDs.ForEntry(entry.ID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	page.ReplyContext, err = app.replyContexts.ForEntry(entry.ID)
+	if err != nil && !errors.Is(err, models.ErrNoRecord) {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if page.IsAdmin && entry.URL != nil {
+		page.Queued, err = app.queue.HasEntry(entry.ID)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	page.Prev, page.Next, err = app.entries.Adjacent(entry)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// Badge the entry itself and, for thoughts, everything in its thread
+	if err := app.badgeEpochs(append([]*models.Entry{entry}, page.Thread...)); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Add("Vary", "Accept")
+
+	switch negotiate(r, mediaHTML, mediaJSON, mediaMarkdown, mediaText) {
+	case mediaJSON:
+		writeEntryJSON(w, app.newEntryResource(page))
+	case mediaMarkdown:
+		writeEntryMarkdown(w, app.newEntryResource(page))
+	case mediaText:
+		writeEntryText(w, app.newEntryResource(page))
+	default:
+		page.Meta = app.newEntryMeta(app.siteURL(r), entry, page.Attachments)
+		app.render(w, r, page, "partials/thought.tmpl", "pages/entry.tmpl")
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// Media types /entry/{slug} can answer with, in order of preference.
+const (
+	mediaHTML     = "text/html"
+	mediaJSON     = "application/json"
+	mediaMarkdown = "text/markdown"
+	mediaText     = "text/plain"
+)
+
+// entryResource is the format-neutral view of an entry shared by the JSON,
+// Markdown and plain text representations.
+type entryResource struct {
+	ID          int                   `json:"id"`
+	Slug        string                `json:"slug"`
+	Permalink   string                `json:"permalink"`
+	Title       string                `json:"title"`
+	Type        string                `json:"type"`
+	Content     *string               `json:"content"`
+	URL         *string               `json:"url"`
+	Mood        *string               `json:"mood"`
+	Epoch       *string               `json:"epoch"`
+	ParentID    *int                  `json:"parent_id"`
+	CreatedAt   time.Time             `json:"created_at"`
+	UpdatedAt   *time.Time            `json:"updated_at"`
+	Checksum    string                `json:"checksum"` // See models.Entry.Checksum
+	Fields      models.Fields         `json:"fields"`
+	Attachments []attachmentResource  `json:"attachments"`
+	ExternalIDs []externalIDResource  `json:"external_ids"`
+	Syndicated  []syndicationResource `json:"syndicated"`
+	Replies     []*entryResource      `json:"replies,omitempty"`
+}
+
+type attachmentResource struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	URL         string `json:"url"`
+}
+
+type externalIDResource struct {
+	Provider string `json:"provider"`
+	ID       string `json:"id"`
+	URL      string `json:"url,omitempty"`
+}
+
+type syndicationResource struct {
+	Service string `json:"service"`
+	URL     string `json:"url"`
+}
+
+// newEntryResource builds the resource from the same page data entry.tmpl renders.
+func (app *application) newEntryResource(page entryPage) *entryResource {
+	res := app.entryResourceFor(page.Entry)
+
+	res.Attachments = []attachmentResource{}
+	for _, a := range page.Attachments {
+		res.Attachments = append(res.Attachments, attachmentResource{
+			Filename:    a.Filename,
+			ContentType: a.ContentType,
+			Size:        a.Size,
+			URL:         app.absoluteURL(fmt.Sprintf("/attachments/%d", a.ID)),
+		})
+	}
+
+	res.ExternalIDs = []externalIDResource{}
+	for _, x := range page.ExternalIDs {
+		res.ExternalIDs = append(res.ExternalIDs, externalIDResource{Provider: x.Provider, ID: x.ExternalID, URL: x.Link()})
+	}
+
+	res.Syndicated = []syndicationResource{}
+	for _, s := range page.Syndications {
+		res.Syndicated = append(res.Syndicated, syndicationResource{Service: s.Service, URL: s.URL})
+	}
+
+	// For thoughts, carry the replies below this entry in the thread
+	if node := findInThread(page.Thread, page.Entry.ID); node != nil {
+		res.Replies = app.replyResources(node.Replies)
+	}
+
+	return res
+}
+
+func (app *application) entryResourceFor(e *models.Entry) *entryResource {
+	res := &entryResource{
+		ID:        e.ID,
+		Slug:      e.Slug,
+		Permalink: app.absoluteURL(e.Permalink()),
+		Title:     e.Title,
+		Type:      e.Type,
+		Content:   e.Content,
+		URL:       e.URL,
+		Mood:      e.Mood,
+		ParentID:  e.ParentID,
+		CreatedAt: e.CreatedAt,
+		UpdatedAt: e.UpdatedAt,
+		Checksum:  e.Checksum(),
+		Fields:    e.Fields,
+	}
+	if res.Fields == nil {
+		res.Fields = models.Fields{}
+	}
+	if e.Epoch != nil {
+		res.Epoch = &e.Epoch.Name
+	}
+	return res
+}
+
+func (app *application) replyResources(replies []*models.Entry) []*entryResource {
+	var out []*entryResource
+	for _, r := range replies {
+		res := app.entryResourceFor(r)
+		res.Replies = app.replyResources(r.Replies)
+		out = append(out, res)
+	}
+	return out
+}
+
+// findInThread returns the node for id in a nested thread.
+func findInThread(roots []*models.Entry, id int) *models.Entry {
+	for _, e := range roots {
+		if e.ID == id {
+			return e
+		}
+		if found := findInThread(e.Replies, id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// absoluteURL prefixes a station path with SACRIF_BASE_URL, when it is set.
+func (app *application) absoluteURL(path string) string {
+	return strings.TrimRight(app.baseURL, "/") + path
+}
+
+// writeEntryJSON answers with the entry as a JSON document.
+func writeEntryJSON(w http.ResponseWriter, res *entryResource) {
+	body, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		http.Error(w, "Internal Server Error", 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(append(body, '\n'))
+}
+
+// writeEntryMarkdown answers with the entry as a Markdown document. Content is
+// already Markdown, so it goes out as written.
+func writeEntryMarkdown(w http.ResponseWriter, res *entryResource) {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", res.Title)
+	for _, line := range entryMetadata(res) {
+		fmt.Fprintf(&b, "- %s\n", line)
+	}
+	if res.URL != nil {
+		fmt.Fprintf(&b, "- Link: <%s>\n", *res.URL)
+	}
+	if res.Content != nil {
+		fmt.Fprintf(&b, "\n%s\n", strings.TrimSpace(*res.Content))
+	}
+	if len(res.Attachments) > 0 {
+		b.WriteString("\n## Attachments\n\n")
+		for _, a := range res.Attachments {
+			fmt.Fprintf(&b, "- [%s](%s) (%s)\n", a.Filename, a.URL, models.HumanBytes(a.Size))
+		}
+	}
+	writeMarkdownReplies(&b, res.Replies, 0)
+	fmt.Fprintf(&b, "\n[Permalink](%s)\n", res.Permalink)
+
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
+
+func writeMarkdownReplies(b *strings.Builder, replies []*entryResource, depth int) {
+	if len(replies) == 0 {
+		return
+	}
+	if depth == 0 {
+		b.WriteString("\n## Replies\n\n")
+	}
+	for _, r := range replies {
+		fmt.Fprintf(b, "%s- **%s** (%s)", strings.Repeat("  ", depth), r.Title, r.CreatedAt.Format(time.DateOnly))
+		if r.Content != nil {
+			fmt.Fprintf(b, ": %s", strings.Join(strings.Fields(*r.Content), " "))
+		}
+		b.WriteString("\n")
+		writeMarkdownReplies(b, r.Replies, depth+1)
+	}
+}
+
+// writeEntryText answers with the entry as plain text.
+func writeEntryText(w http.ResponseWriter, res *entryResource) {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s\n%s\n\n", res.Title, strings.Repeat("=", len([]rune(res.Title))))
+	for _, line := range entryMetadata(res) {
+		fmt.Fprintf(&b, "%s\n", line)
+	}
+	if res.URL != nil {
+		fmt.Fprintf(&b, "Link: %s\n", *res.URL)
+	}
+	if res.Content != nil {
+		fmt.Fprintf(&b, "\n%s\n", strings.TrimSpace(*res.Content))
+	}
+	for _, a := range res.Attachments {
+		fmt.Fprintf(&b, "\nAttachment: %s %s\n", a.Filename, a.URL)
+	}
+	writeTextReplies(&b, res.Replies, 0)
+	fmt.Fprintf(&b, "\nPermalink: %s\n", res.Permalink)
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
+
+func writeTextReplies(b *strings.Builder, replies []*entryResource, depth int) {
+	if len(replies) > 0 && depth == 0 {
+		b.WriteString("\nReplies:\n")
+	}
+	for _, r := range replies {
+		fmt.Fprintf(b, "%s> %s (%s)\n", strings.Repeat("  ", depth+1), r.Title, r.CreatedAt.Format(time.DateOnly))
+		writeTextReplies(b, r.Replies, depth+1)
+	}
+}
+
+// entryMetadata is the "Key: value" header shared by the Markdown and text forms.
+func entryMetadata(res *entryResource) []string {
+	lines := []string{
+		"Type: " + res.Type,
+		"Logged: " + res.CreatedAt.UTC().Format("2006-01-02 15:04 UTC"),
+	}
+	if res.Mood != nil {
+		lines = append(lines, "Mood: "+*res.Mood)
+	}
+	if res.Epoch != nil {
+		lines = append(lines, "Epoch: "+*res.Epoch)
+	}
+	for _, x := range res.ExternalIDs {
+		lines = append(lines, (&models.ExternalID{Provider: x.Provider}).ProviderLabel()+": "+x.ID)
+	}
+	for _, f := range res.Fields {
+		lines = append(lines, strings.ToUpper(f.Label()[:1])+f.Label()[1:]+": "+f.Value)
+	}
+	return lines
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// epochsPage is the data handed to epochs.tmpl
+type epochsPage struct {
+	Epochs  models.Epochs
+	IsAdmin bool
+}
+
+// epochPage is the data handed to epoch.tmpl
+type epochPage struct {
+	Epoch    *models.Epoch
+	Thoughts []*models.Entry // Nested thread roots
+	Media    []*models.Entry
+}
+
+// badgeEpochs tags each entry (and its replies) with the epoch it was created in.
+func (app *application) badgeEpochs(entries []*models.Entry) error {
+	epochs, err := app.epochs.All()
+	if err != nil {
+		return err
+	}
+	epochs.Badge(entries)
+	return nil
+}
+
+// epochsHandler lists the named chapters of the timeline GET /epochs
+func (app *application) epochsHandler(w http.ResponseWriter, r *http.Request) {
+	epochs, err := app.epochs.All()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	page := epochsPage{Epochs: epochs, IsAdmin: app.isAdmin(r)}
+
+	app.render(w, r, page, "pages/epochs.tmpl")
+}
+
+// epochHandler renders the archive of everything logged during one epoch GET /epochs/{id}
+func (app *application) epochHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w, r)
+		return
+	}
+
+	epoch, err := app.epochs.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	entries, err := app.entries.InEpoch(epoch)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// Overlapping epochs mean entries here can still be badged with a narrower one
+	if err := app.badgeEpochs(entries); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	page := epochPage{Epoch: epoch}
+
+	var thoughts []*models.Entry
+	for _, e := range entries {
+		if models.IsThoughtType(e.Type) {
+			thoughts = append(thoughts, e)
+		} else {
+			page.Media = append(page.Media, e)
+		}
+	}
+	page.Thoughts = models.NestThreads(thoughts)
+
+	app.render(w, r, page, "partials/thought.tmpl", "pages/epoch.tmpl")
+}
+
+// epochsPostHandler creates an epoch POST /admin/epochs
+func (app *application) epochsPostHandler(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	epoch := &models.Epoch{
+		Name:        strings.TrimSpace(r.PostForm.Get("name")),
+		Description: models.NullString(strings.TrimSpace(r.PostForm.Get("description"))),
+	}
+	if epoch.Name == "" {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	epoch.StartsOn, err = time.Parse(time.DateOnly, r.PostForm.Get("starts_on"))
+	if err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	// An empty end date leaves the epoch open, i.e. the current one
+	if v := r.PostForm.Get("ends_on"); v != "" {
+		endsOn, err := time.Parse(time.DateOnly, v)
+		if err != nil || endsOn.Before(epoch.StartsOn) {
+			http.Error(w, "Bad Request", 400)
+			return
+		}
+		epoch.EndsOn = &endsOn
+	}
+
+	_, err = app.epochs.Insert(epoch)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/epochs", http.StatusSeeOther)
+}
+
+// epochDeletePostHandler removes an epoch POST /admin/epochs/{id}/delete
+func (app *application) epochDeletePostHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w, r)
+		return
+	}
+
+	if err := app.epochs.Delete(id); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/epochs", http.StatusSeeOther)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// externalIDAddPostHandler links an entry to a catalogue ID POST /admin/entries/{id}/external-ids
+func (app *application) externalIDAddPostHandler(w http.ResponseWriter, r *http.Request) {
+	entry, ok := app.entryFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	provider := r.PostForm.Get("provider")
+	id, err := models.NormalizeExternalID(provider, r.PostForm.Get("external_id"))
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), 400)
+		return
+	}
+
+	err = app.externalIDs.Add(entry.ID, provider, id)
+	if errors.Is(err, models.ErrExternalIDTaken) {
+		// Say which entry already has it rather than linking a duplicate
+		owner, err := app.externalIDs.Owner(provider, id)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		other, err := app.entries.Get(owner)
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		http.Error(w, "Conflict: "+provider+" "+id+" is already linked to "+other.Permalink(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, entry.Permalink(), http.StatusSeeOther)
+}
+
+// externalIDRemovePostHandler unlinks a catalogue ID POST /admin/entries/{id}/external-ids/delete
+func (app *application) externalIDRemovePostHandler(w http.ResponseWriter, r *http.Request) {
+	entry, ok := app.entryFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	if err := app.externalIDs.Remove(entry.ID, r.PostForm.Get("provider"), r.PostForm.Get("external_id")); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, entry.Permalink(), http.StatusSeeOther)
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// feedLength is how many entries a feed carries.
+const feedLength = 30
+
+// rssFeed is an RSS 2.0 document, with the Atom self link feed validators ask for.
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Self          rssLink   `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Category    string  `xml:"category,omitempty"`
+	Description string  `xml:"description"` // Escaped HTML
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// atomFeed is an Atom (RFC 4287) document.
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle"`
+	ID       string      `xml:"id"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Author   atomAuthor  `xml:"author"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title     string       `xml:"title"`
+	ID        string       `xml:"id"`
+	Link      atomLink     `xml:"link"`
+	Published string       `xml:"published"`
+	Updated   string       `xml:"updated"`
+	Category  atomCategory `xml:"category"`
+	Summary   *atomText    `xml:"summary"` // Only for entries with a custom feed summary
+	Content   *atomText    `xml:"content"`
+}
+
+type atomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// mediaFeedHandler serves the latest media entries as Atom GET /media/feed.atom
+func (app *application) mediaFeedHandler(w http.ResponseWriter, r *http.Request) {
+	entries, err := app.entries.MediaEntries(feedLength * 2)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	entries = feedEntries(entries)
+
+	site := app.siteURL(r)
+	feed := atomFeed{
+		Title:    app.siteIdentity().Title + ": Media Compendium",
+		Subtitle: "Books, anime, games and tools, logged as they're consumed.",
+		ID:       site + "/media/feed.atom",
+		Links: []atomLink{
+			{Href: site + "/media/feed.atom", Rel: "self", Type: "application/atom+xml"},
+			{Href: site + "/media", Rel: "alternate", Type: "text/html"},
+		},
+		Author:  atomAuthor{Name: app.siteIdentity().Title},
+		Entries: []atomEntry{},
+	}
+
+	for _, e := range entries {
+		t := e.TypeInfo()
+		entry := atomEntry{
+			Title:     e.Title,
+			ID:        entryTagURI(site, e),
+			Link:      atomLink{Href: e.FeedLink(site + e.Permalink()), Rel: "alternate", Type: "text/html"},
+			Published: e.CreatedAt.UTC().Format(time.RFC3339),
+			Updated:   e.LastModified().UTC().Format(time.RFC3339),
+			Category:  atomCategory{Term: e.Type, Label: t.Label},
+		}
+		if e.FeedSummary != nil {
+			entry.Summary = &atomText{Type: "text", Body: *e.FeedSummary}
+		}
+		if e.Content != nil {
+			entry.Content = &atomText{Type: "html", Body: string(entryContent(e))}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	updated := feedModified(entries)
+	if updated.IsZero() {
+		feed.Updated = time.Now().UTC().Format(time.RFC3339)
+	} else {
+		feed.Updated = updated.UTC().Format(time.RFC3339)
+	}
+
+	body, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	writeConditional(w, r, "application/atom+xml; charset=utf-8", xmlDocument(body), updated)
+}
+
+// entryTagURI is an entry's permanent Atom ID (RFC 4151), e.g.
+// tag:station.example.com,2024-03-01:entry/42. It is built from the entry's number
+// and creation date rather than its permalink, so it never changes with a slug.
+func entryTagURI(site string, e *models.Entry) string {
+	host := site
+	if u, err := url.Parse(site); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return fmt.Sprintf("tag:%s,%s:entry/%d", host, e.CreatedAt.UTC().Format(time.DateOnly), e.ID)
+}
+
+// jsonFeed is a JSON Feed 1.1 document (https://jsonfeed.org/version/1.1).
+type jsonFeed struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageURL string           `json:"home_page_url"`
+	FeedURL     string           `json:"feed_url"`
+	Description string           `json:"description"`
+	Language    string           `json:"language"`
+	Authors     []jsonFeedAuthor `json:"authors"`
+	Items       []jsonFeedItem   `json:"items"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonFeedItem struct {
+	ID            string   `json:"id"`
+	URL           string   `json:"url"`
+	ExternalURL   string   `json:"external_url,omitempty"` // The canonical original of cross-posted pieces
+	Title         string   `json:"title"`
+	ContentHTML   string   `json:"content_html"`
+	Summary       string   `json:"summary,omitempty"`
+	DatePublished string   `json:"date_published"`
+	DateModified  string   `json:"date_modified,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+}
+
+// jsonFeedHandler serves the latest entries of every section as JSON Feed GET /feed.json
+func (app *application) jsonFeedHandler(w http.ResponseWriter, r *http.Request) {
+	entries, err := app.entries.Latest(feedLength * 2)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	entries = feedEntries(entries)
+
+	site := app.siteURL(r)
+	feed := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       app.siteIdentity().Title,
+		HomePageURL: site + "/",
+		FeedURL:     site + "/feed.json",
+		Description: "The Media Compendium and the Organic Thoughts sector in one feed.",
+		Language:    "en",
+		Authors:     []jsonFeedAuthor{{Name: app.siteIdentity().Title}},
+		Items:       []jsonFeedItem{},
+	}
+
+	for _, e := range entries {
+		item := jsonFeedItem{
+			ID:            entryTagURI(site, e),
+			URL:           site + e.Permalink(),
+			ExternalURL:   models.StringValue(e.CanonicalURL),
+			Title:         e.Title,
+			ContentHTML:   string(entryContent(e)),
+			Summary:       models.StringValue(e.FeedSummary),
+			DatePublished: e.CreatedAt.UTC().Format(time.RFC3339),
+			Tags:          []string{e.Type},
+		}
+		if e.UpdatedAt != nil {
+			item.DateModified = e.UpdatedAt.UTC().Format(time.RFC3339)
+		}
+		if e.Mood != nil {
+			item.Tags = append(item.Tags, *e.Mood)
+		}
+		feed.Items = append(feed.Items, item)
+	}
+
+	// content_html is HTML on purpose, so it goes out without \u003c escapes
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	writeConditional(w, r, "application/feed+json; charset=utf-8", body.Bytes(), feedModified(entries))
+}
+
+// thoughtsFeedHandler serves the latest thoughts as RSS GET /thoughts/feed.xml
+func (app *application) thoughtsFeedHandler(w http.ResponseWriter, r *http.Request) {
+	entries, err := app.entries.LatestThoughts(feedLength * 2)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	entries = feedEntries(entries)
+
+	site := app.siteURL(r)
+	feed := rssFeed{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:       app.siteIdentity().Title + ": Organic Thoughts",
+			Link:        site + "/thoughts",
+			Description: "Internal logs, raw text, and system notes.",
+			Language:    "en",
+			Self:        rssLink{Href: site + "/thoughts/feed.xml", Rel: "self", Type: "application/rss+xml"},
+			Items:       []rssItem{},
+		},
+	}
+	if len(entries) > 0 {
+		feed.Channel.LastBuildDate = entries[0].CreatedAt.UTC().Format(time.RFC1123Z)
+	}
+
+	for _, e := range entries {
+		permalink := site + e.Permalink()
+		item := rssItem{
+			Title:       e.Title,
+			Link:        e.FeedLink(permalink),
+			GUID:        rssGUID{IsPermaLink: true, Value: permalink},
+			PubDate:     e.CreatedAt.UTC().Format(time.RFC1123Z),
+			Description: string(feedHTML(e)),
+		}
+		if mood := e.MoodInfo(); mood != nil {
+			item.Category = mood.Label
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+
+	body, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	writeConditional(w, r, "application/rss+xml; charset=utf-8", xmlDocument(body), feedModified(entries))
+}
+
+// feedEntries keeps the entries that belong in feeds, newest first, up to feedLength.
+func feedEntries(entries []*models.Entry) []*models.Entry {
+	var out []*models.Entry
+	for _, e := range entries {
+		if e.InFeeds() && len(out) < feedLength {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// feedModified is when a feed last changed: when its most recently changed entry
+// did, or zero for an empty feed.
+func feedModified(entries []*models.Entry) time.Time {
+	var updated time.Time
+	for _, e := range entries {
+		if e.LastModified().After(updated) {
+			updated = e.LastModified()
+		}
+	}
+	return updated
+}
+
+// xmlDocument puts the XML declaration in front of a marshalled feed.
+func xmlDocument(body []byte) []byte {
+	return append(append([]byte(xml.Header), body...), '\n')
+}
+
+// feedHTML is an entry's feed description as HTML: the custom feed summary when it
+// has one, otherwise its content rendered as the entry page renders it.
+func feedHTML(e *models.Entry) template.HTML {
+	if e.FeedSummary != nil {
+		return template.HTML("<p>" + template.HTMLEscapeString(*e.FeedSummary) + "</p>")
+	}
+	return entryContent(e)
+}
+
+// siteURL is the station's public origin: SACRIF_BASE_URL when set, otherwise
+// worked out from the request, since feed readers need absolute links.
+func (app *application) siteURL(r *http.Request) string {
+	if app.baseURL != "" {
+		return strings.TrimRight(app.baseURL, "/")
+	}
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
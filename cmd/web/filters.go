@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/filter"
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// filtersPage is the data handed to filters.tmpl
+type filtersPage struct {
+	Rules     []*models.FilterRule
+	PerMinute int
+	Result    string
+}
+
+// defenseDay is one row of the refused-traffic table on the telemetry page
+type defenseDay struct {
+	Day      string
+	CIDR     int
+	Agent    int
+	Throttle int
+}
+
+// reloadFilters compiles the stored rules into the live request filter.
+func (app *application) reloadFilters() error {
+	rules, err := app.filters.Rules()
+	if err != nil {
+		return err
+	}
+
+	perMinute, err := app.filters.ThrottleLimit()
+	if err != nil {
+		return err
+	}
+
+	set, err := filter.Compile(rules, perMinute)
+	if err != nil {
+		return err
+	}
+
+	app.filter.Load(set)
+	return nil
+}
+
+// flushFilterHits persists the refusals counted in memory since the last flush.
+func (app *application) flushFilterHits() {
+	byReason, byRule := app.filter.Drain()
+	if len(byReason) == 0 {
+		return
+	}
+
+	if err := app.filters.RecordHits(time.Now(), byReason, byRule); err != nil {
+		slog.Error("Failed to record filter hits", "err", err)
+	}
+}
+
+// flushFilterHitsEvery flushes the refusal counters on a timer until ctx is
+// cancelled, so a flood of refused requests costs one write a minute, not one each.
+func (app *application) flushFilterHitsEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			app.flushFilterHits()
+			return
+		case <-ticker.C:
+			app.flushFilterHits()
+		}
+	}
+}
+
+// hasAdminSession reports whether the request carries a valid operator cookie. Unlike
+// isAdmin it is false for local requests to a development station without a
+// password, so filtering still applies there.
+func (app *application) hasAdminSession(r *http.Request) bool {
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return false
+	}
+
+	sess, err := app.cookies.Verify(c.Value)
+	return err == nil && sess.Subject == "admin"
+}
+
+// defenseReport sums the last week of refused traffic per day for the telemetry page.
+func (app *application) defenseReport() ([]defenseDay, error) {
+	app.flushFilterHits()
+
+	stats, err := app.filters.Stats(time.Now().AddDate(0, 0, -6))
+	if err != nil {
+		return nil, err
+	}
+
+	var days []defenseDay
+	for _, s := range stats {
+		if len(days) == 0 || days[len(days)-1].Day != s.Day {
+			days = append(days, defenseDay{Day: s.Day})
+		}
+		d := &days[len(days)-1]
+		switch s.Reason {
+		case models.FilterCIDR:
+			d.CIDR += s.Hits
+		case models.FilterAgent:
+			d.Agent += s.Hits
+		case filter.ReasonThrottle:
+			d.Throttle += s.Hits
+		}
+	}
+	return days, nil
+}
+
+// filtersHandler lists the request filter rules GET /admin/filters
+func (app *application) filtersHandler(w http.ResponseWriter, r *http.Request) {
+	app.flushFilterHits()
+
+	rules, err := app.filters.Rules()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	perMinute, err := app.filters.ThrottleLimit()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	page := filtersPage{Rules: rules, PerMinute: perMinute, Result: r.URL.Query().Get("result")}
+
+	app.render(w, r, page, "pages/filters.tmpl")
+}
+
+// filtersPostHandler adds a rule and applies it immediately POST /admin/filters
+func (app *application) filtersPostHandler(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	fr := &models.FilterRule{
+		Kind:    r.PostForm.Get("kind"),
+		Pattern: strings.TrimSpace(r.PostForm.Get("pattern")),
+		Note:    models.NullString(strings.TrimSpace(r.PostForm.Get("note"))),
+	}
+
+	switch fr.Kind {
+	case models.FilterCIDR:
+		n, err := filter.ParseCIDR(fr.Pattern)
+		if err != nil {
+			http.Error(w, "Bad Request: "+err.Error(), 400)
+			return
+		}
+		fr.Pattern = n.String()
+	case models.FilterAgent:
+		if fr.Pattern == "" {
+			http.Error(w, "Bad Request", 400)
+			return
+		}
+		if _, err := filter.CompileAgent(fr.Pattern); err != nil {
+			http.Error(w, "Bad Request: "+err.Error(), 400)
+			return
+		}
+	default:
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	if err := app.filters.InsertRule(fr); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if err := app.reloadFilters(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/filters?result="+url.QueryEscape("Blocking "+fr.Kind+" "+fr.Pattern), http.StatusSeeOther)
+}
+
+// filterDeletePostHandler removes a rule POST /admin/filters/{id}/delete
+func (app *application) filterDeletePostHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w, r)
+		return
+	}
+
+	if err := app.filters.DeleteRule(id); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if err := app.reloadFilters(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/filters?result="+url.QueryEscape("Rule removed"), http.StatusSeeOther)
+}
+
+// filterThrottlePostHandler sets the per-address request limit POST /admin/filters/throttle
+func (app *application) filterThrottlePostHandler(w http.ResponseWriter, r *http.Request) {
+	perMinute, err := strconv.Atoi(r.PostFormValue("per_minute"))
+	if err != nil || perMinute < 0 {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	if err := app.filters.SetThrottleLimit(perMinute); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if err := app.reloadFilters(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	result := "Throttling off"
+	if perMinute > 0 {
+		result = "Throttling addresses above " + strconv.Itoa(perMinute) + " requests a minute"
+	}
+	http.Redirect(w, r, "/admin/filters?result="+url.QueryEscape(result), http.StatusSeeOther)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/auth"
+)
+
+const (
+	flashCookie = "sacrif_flash"
+	flashTTL    = 5 * time.Minute // Long enough to survive a redirect, short enough not to resurface later
+)
+
+// setFlash leaves a message for the next page to show, in a signed session cookie
+// of its own so it can't be forged or mistaken for the admin session.
+func (app *application) setFlash(w http.ResponseWriter, r *http.Request, msg string) {
+	value, err := app.cookies.Sign(auth.Session{Subject: "flash", Flash: msg, Expires: time.Now().Add(flashTTL)})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Flash message not set", "err", err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     flashCookie,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(flashTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// popFlash returns the message setFlash left, if any, and clears it so it shows once.
+// A page showing one is personal to its visitor, so it is never cached.
+func (app *application) popFlash(w http.ResponseWriter, r *http.Request) string {
+	c, err := r.Cookie(flashCookie)
+	if err != nil {
+		return ""
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     flashCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	sess, err := app.cookies.Verify(c.Value)
+	if err != nil || sess.Subject != "flash" {
+		return ""
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	return sess.Flash
+}
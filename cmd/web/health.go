@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// healthReport is the body of GET /healthz.
+type healthReport struct {
+	Status    string                    `json:"status"` // "ok" or "fail"
+	Uptime    int64                     `json:"uptime_seconds"`
+	Databases map[string]databaseHealth `json:"databases"`
+	Scheduler schedulerHealth           `json:"scheduler"`
+	TLS       *tlsHealth                `json:"tls,omitempty"` // Only when serving certificate files
+}
+
+type databaseHealth struct {
+	OK        bool    `json:"ok"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type tlsHealth struct {
+	Expires time.Time `json:"expires"`         // Of the certificate being served
+	Error   string    `json:"error,omitempty"` // Why a renewal on disk didn't load
+}
+
+type schedulerHealth struct {
+	State      string     `json:"state"` // "running", "starting", "stalled" or "off"
+	LastTick   *time.Time `json:"last_tick"`
+	Hosts      int        `json:"hosts_scraping"`
+	WindowOpen *bool      `json:"window_open,omitempty"` // Left out when the scheduler is off
+}
+
+// healthHandler is the readiness check GET /healthz
+//
+// It answers 200 when every database returns a query, the scraper scheduler (if it
+// runs) is still ticking and the TLS certificate (if served from files) is in date,
+// and 503 otherwise, so Docker's HEALTHCHECK or an uptime monitor can go by the
+// status code alone.
+func (app *application) healthHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	report := healthReport{
+		Status:    "ok",
+		Uptime:    int64(time.Since(app.startedAt).Seconds()),
+		Databases: map[string]databaseHealth{},
+		Scheduler: schedulerHealth{State: "off"},
+	}
+
+	for _, name := range databaseNames {
+		db, ok := app.databases[name]
+		if !ok {
+			continue
+		}
+		start := time.Now()
+		var one int
+		err := db.DB.QueryRowContext(ctx, "SELECT 1").Scan(&one)
+		h := databaseHealth{OK: err == nil, LatencyMS: float64(time.Since(start).Microseconds()) / 1000}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Health check query failed", "database", name, "err", err)
+			h.Error = err.Error()
+			report.Status = "fail"
+		}
+		report.Databases[name] = h
+	}
+
+	if app.scheduler != nil {
+		st := app.scheduler.Status()
+		report.Scheduler = schedulerHealth{Hosts: st.Hosts, WindowOpen: &st.WindowOpen}
+		if !st.LastTick.IsZero() {
+			report.Scheduler.LastTick = &st.LastTick
+		}
+		switch {
+		case st.Stalled:
+			report.Scheduler.State = "stalled"
+			report.Status = "fail"
+		case st.Running:
+			report.Scheduler.State = "running"
+		default:
+			report.Scheduler.State = "starting"
+		}
+	}
+
+	// A renewal that won't load leaves the old certificate in service until it runs out
+	if app.certs != nil {
+		expires, err := app.certs.status()
+		report.TLS = &tlsHealth{Expires: expires}
+		if err != nil {
+			report.TLS.Error = err.Error()
+		}
+		if time.Now().After(expires) {
+			report.Status = "fail"
+		}
+	}
+
+	status := http.StatusOK
+	if report.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeAPIJSON(w, status, report)
+}
+
+// liveHandler is the liveness check GET /healthz/live
+//
+// It touches nothing but the process itself: a station stuck on its database is
+// unready, but restarting it wouldn't help.
+func (app *application) liveHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeAPIJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/backup"
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// maxImportBytes caps an uploaded archive or entries.json.
+const maxImportBytes = 256 << 20
+
+// importUploadPage is the data handed to import.tmpl
+type importUploadPage struct {
+	Result string // Outcome of the last applied import, if any
+}
+
+// importPage is the data handed to reconcile.tmpl
+type importPage struct {
+	Import    *models.Import
+	Rows      []*models.ImportRow
+	Conflicts int // Rows that collide with an existing entry
+	Altered   int // Rows whose checksum no longer matches
+}
+
+// importHandler shows the upload form GET /admin/import
+func (app *application) importHandler(w http.ResponseWriter, r *http.Request) {
+	app.render(w, r, importUploadPage{Result: r.URL.Query().Get("result")}, "pages/import.tmpl")
+}
+
+// importPostHandler stages an uploaded backup archive or entries.json POST /admin/import
+func (app *application) importPostHandler(w http.ResponseWriter, r *http.Request) {
+	// Backup archives run to hundreds of megabytes, more than the read timeout allows over a slow link
+	http.NewResponseController(w).SetReadDeadline(time.Now().Add(uploadTimeout))
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+	defer file.Close()
+
+	records, err := backup.ReadEntries(file)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), 400)
+		return
+	}
+
+	for i, rec := range records {
+		if err := rec.Validate(); err != nil {
+			http.Error(w, fmt.Sprintf("Bad Request: record %d: %v", i+1, err), 400)
+			return
+		}
+	}
+
+	id, err := app.imports.Stage(cleanFilename(header.Filename), records)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/import/%d", id), http.StatusSeeOther)
+}
+
+// importReconcileHandler lists each staged row with its conflicts GET /admin/import/{id}
+func (app *application) importReconcileHandler(w http.ResponseWriter, r *http.Request) {
+	imp, ok := app.importFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := app.imports.Rows(imp)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	page := importPage{Import: imp, Rows: rows}
+	for _, row := range rows {
+		if len(row.Conflicts) > 0 {
+			page.Conflicts++
+		}
+		if !row.Record.Intact() {
+			page.Altered++
+		}
+	}
+
+	app.render(w, r, page, "pages/reconcile.tmpl")
+}
+
+// importApplyPostHandler applies the chosen action for every row POST /admin/import/{id}
+func (app *application) importApplyPostHandler(w http.ResponseWriter, r *http.Request) {
+	imp, ok := app.importFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	// One radio group per row, named row-<index>
+	decisions := map[int]string{}
+	for key, values := range r.PostForm {
+		i, err := strconv.Atoi(strings.TrimPrefix(key, "row-"))
+		if err != nil || !strings.HasPrefix(key, "row-") || len(values) == 0 {
+			continue
+		}
+		decisions[i] = values[0]
+	}
+
+	summary, err := app.imports.Apply(imp, decisions)
+	if err != nil {
+		app.serverError(w, r, err, "import", imp.ID)
+		return
+	}
+
+	var parts []string
+	for _, action := range []string{models.ImportAdd, models.ImportKeepBoth, models.ImportOverwrite, models.ImportMerge, models.ImportSkip} {
+		if n := summary[action]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, importActionLabels[action]))
+		}
+	}
+	result := imp.Filename + ": " + strings.Join(parts, ", ")
+	if len(parts) == 0 {
+		result = imp.Filename + ": nothing to import"
+	}
+
+	http.Redirect(w, r, "/admin/import?result="+url.QueryEscape(result), http.StatusSeeOther)
+}
+
+// importDiscardPostHandler drops a staged import without touching any entry POST /admin/import/{id}/discard
+func (app *application) importDiscardPostHandler(w http.ResponseWriter, r *http.Request) {
+	imp, ok := app.importFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := app.imports.Delete(imp.ID); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/import?result="+url.QueryEscape(imp.Filename+": discarded"), http.StatusSeeOther)
+}
+
+// importActionLabels describe each action in the result message.
+var importActionLabels = map[string]string{
+	models.ImportAdd:       "added",
+	models.ImportKeepBoth:  "kept alongside",
+	models.ImportOverwrite: "overwritten",
+	models.ImportMerge:     "merged",
+	models.ImportSkip:      "skipped",
+}
+
+// importFromPath loads the staged import named by the {id} path segment, writing an error response if it can't.
+func (app *application) importFromPath(w http.ResponseWriter, r *http.Request) (*models.Import, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w, r)
+		return nil, false
+	}
+
+	imp, err := app.imports.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return nil, false
+	}
+
+	return imp, true
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+	"github.com/federicopalou/sacrif-station/internal/scraper"
+)
+
+// Limits on one ingest request.
+const (
+	maxIngestBytes = 4 << 20
+	maxIngestItems = 500
+)
+
+// ingestRequest is the body of POST /api/scraper/ingest
+type ingestRequest struct {
+	SourceID *int         `json:"source_id,omitempty"` // Existing source to credit the items to
+	Items    []ingestItem `json:"items"`
+}
+
+// ingestItem is one pushed item. Only title and value are required.
+type ingestItem struct {
+	Title     string     `json:"title"`
+	Value     string     `json:"value"`
+	URL       string     `json:"url,omitempty"`
+	Published *time.Time `json:"published,omitempty"` // RFC 3339
+	Excerpt   string     `json:"excerpt,omitempty"`
+	Image     string     `json:"image,omitempty"`
+}
+
+// ingestResponse is what the endpoint answers with
+type ingestResponse struct {
+	Received int    `json:"received"`
+	New      int    `json:"new"`
+	Dropped  int    `json:"dropped,omitempty"` // Rejected by the source's filters
+	Error    string `json:"error,omitempty"`
+}
+
+// ingestPostHandler stores items pushed by scrapers running elsewhere POST /api/scraper/ingest
+//
+// Callers authenticate with "Authorization: Bearer $SACRIF_INGEST_TOKEN", checked by
+// requireAPIToken; without a configured token the endpoint doesn't exist.
+func (app *application) ingestPostHandler(w http.ResponseWriter, r *http.Request) {
+	var req ingestRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxIngestBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeIngestJSON(w, http.StatusBadRequest, ingestResponse{Error: "malformed JSON: " + err.Error()})
+		return
+	}
+
+	if len(req.Items) == 0 || len(req.Items) > maxIngestItems {
+		writeIngestJSON(w, http.StatusBadRequest, ingestResponse{Error: fmt.Sprintf("send between 1 and %d items", maxIngestItems)})
+		return
+	}
+
+	if req.SourceID != nil {
+		if _, err := app.sources.Get(*req.SourceID); err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				writeIngestJSON(w, http.StatusBadRequest, ingestResponse{Error: fmt.Sprintf("unknown source_id %d", *req.SourceID)})
+			} else {
+				writeIngestJSON(w, http.StatusInternalServerError, ingestResponse{Error: "internal error"})
+			}
+			return
+		}
+	}
+
+	items := make([]scraper.Item, 0, len(req.Items))
+	for i, it := range req.Items {
+		item := scraper.Item{
+			Title:   strings.TrimSpace(it.Title),
+			Value:   strings.TrimSpace(it.Value),
+			URL:     strings.TrimSpace(it.URL),
+			Excerpt: strings.TrimSpace(it.Excerpt),
+			Image:   strings.TrimSpace(it.Image),
+		}
+		if it.Published != nil {
+			item.Published = *it.Published
+		}
+		if item.Title == "" || item.Value == "" {
+			writeIngestJSON(w, http.StatusBadRequest, ingestResponse{Error: fmt.Sprintf("item %d: title and value are required", i)})
+			return
+		}
+		items = append(items, item)
+	}
+
+	created, dropped, err := app.engine.Ingest(items, req.SourceID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Scraper ingest error", "err", err)
+		writeIngestJSON(w, http.StatusInternalServerError, ingestResponse{Received: len(items), New: created, Dropped: dropped, Error: "internal error"})
+		return
+	}
+
+	writeIngestJSON(w, http.StatusOK, ingestResponse{Received: len(items), New: created, Dropped: dropped})
+}
+
+func writeIngestJSON(w http.ResponseWriter, status int, res ingestResponse) {
+	body, err := json.Marshal(res)
+	if err != nil {
+		http.Error(w, "Internal Server Error", 500)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(append(body, '\n'))
+}
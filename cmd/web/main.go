@@ -1,29 +1,216 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
 	"html/template"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
 
+	"github.com/federicopalou/sacrif-station/internal/auth"
+	"github.com/federicopalou/sacrif-station/internal/backup"
+	"github.com/federicopalou/sacrif-station/internal/chaos"
+	"github.com/federicopalou/sacrif-station/internal/clientip"
+	"github.com/federicopalou/sacrif-station/internal/filter"
+	"github.com/federicopalou/sacrif-station/internal/indieauth"
+	"github.com/federicopalou/sacrif-station/internal/jobs"
+	"github.com/federicopalou/sacrif-station/internal/metrics"
 	"github.com/federicopalou/sacrif-station/internal/models"
+	"github.com/federicopalou/sacrif-station/internal/ratelimit"
+	"github.com/federicopalou/sacrif-station/internal/scraper"
+	"github.com/federicopalou/sacrif-station/internal/spam"
+	"github.com/federicopalou/sacrif-station/internal/storage"
+	"github.com/federicopalou/sacrif-station/internal/syndicate"
 	"github.com/federicopalou/sacrif-station/internal/utils"
-	"github.com/gomarkdown/markdown"
+	"github.com/federicopalou/sacrif-station/internal/validator"
+	"github.com/federicopalou/sacrif-station/internal/webhook"
 	"github.com/joho/godotenv"
 	_ "modernc.org/sqlite"
 )
 
 // application holds the dependencies for our HTTP handlers
 type application struct {
-	entries *models.EntryModel
-	scraper *models.ScraperModel
+	data           *storage.Root
+	blobs          *storage.Blobs
+	entries        *models.EntryModel
+	search         *models.SearchModel
+	typeMigrations *models.TypeMigrationModel
+	scraper        *models.ScraperModel
+	sources        *models.SourceModel
+	scrapeRuns     *models.ScrapeRunModel
+	snapshots      *models.SnapshotModel
+	prices         *models.PriceModel
+	alerts         *models.AlertModel
+	engine         *scraper.Engine
+	scheduler      *scraper.Scheduler // nil with SCRAPER_SCHEDULER=off
+	jobs           *models.JobModel
+	syndication    *models.SyndicationModel
+	replyContexts  *models.ReplyContextModel
+	epochs         *models.EpochModel
+	queue          *models.QueueModel
+	attachments    *models.AttachmentModel
+	externalIDs    *models.ExternalIDModel
+	imports        *models.ImportModel
+	transmissions  *models.TransmissionModel
+	reputation     *models.ReputationModel
+	filters        *models.FilterModel
+	apiUsage       *models.APIUsageModel
+	apiKeys        *models.APIKeyModel
+	filter         *filter.Filter                   // Live request filter, reloaded whenever the rules change
+	databases      map[string]*models.DatabaseModel // Keyed by databaseNames
+	adminPassword  string
+	localAdmin     bool   // No password in development: requests made on this machine are the operator
+	ingestToken    string // Bearer token for POST /api/scraper/ingest; empty disables the endpoint
+	apiToken       string // Bearer token for writes to /api/v1/entries; empty disables them
+	cookies        *auth.Signer
+	backupSigner   *backup.Signer // nil leaves backups unsigned
+	spamGuard      *spam.Guard
+	baseURL        string // Public origin, e.g. https://station.example, used for absolute links
+	syndicators    map[string]syndicate.Poster
+	webhooks       *models.WebhookModel
+	settings       *models.SettingsModel
+	site           atomic.Pointer[models.Site] // Identity every page is rendered with, reloaded when it is edited
+	webhookURLs    []string                    // Endpoints every entry event is sent to
+	webhookSender  *webhook.Sender             // Signs with SACRIF_WEBHOOK_SECRET; nil when no endpoints are set
+	federation     *federation                 // The ActivityPub actor; nil unless SACRIF_ACTIVITYPUB_USER is set
+	followers      *models.FollowerModel
+	micropub       *indieauth.Verifier // Checks Micropub clients' tokens; nil unless SACRIF_INDIEAUTH_TOKEN_ENDPOINT is set
+	authEndpoint   string              // Advertised for Micropub clients to sign in with; optional
+	templates      *templateCache
+	theme          theme // What visitors see until they pick another; SACRIF_THEME
+	cache          *cachePolicy
+	decay          *decayPolicy
+	startedAt      time.Time
+	certs          *certReloader // nil unless HTTPS is served from certificate files
+	metrics        *stationMetrics
+	metricsToken   string // Bearer token /metrics asks for; empty leaves it open
+}
+
+// developmentMode reads SACRIF_ENV: "production", the default, or "development".
+func developmentMode(env string) (bool, error) {
+	switch env {
+	case "", "production":
+		return false, nil
+	case "development":
+		return true, nil
+	}
+	return false, fmt.Errorf("SACRIF_ENV %q must be development or production", env)
 }
 
 func main() {
 	// Attempt to load .env.development file if it exists, but don't fail if missing (like in Production Unraid)
-	if err := godotenv.Load(".env.development"); err != nil {
-		log.Println("No .env.development file found. Relying on system environment variables.")
+	envFile := godotenv.Load(".env.development") == nil
+
+	// Logs go out as text or JSON (SACRIF_LOG_FORMAT=json), and the standard logger
+	// used by dependencies ends up in the same stream
+	logger, err := newLogger(os.Getenv("SACRIF_LOG_FORMAT"))
+	if err != nil {
+		fatal("Invalid SACRIF_LOG_FORMAT", "err", err)
+	}
+	slog.SetDefault(logger)
+	if !envFile {
+		slog.Info("No .env.development file found. Relying on system environment variables.")
+	}
+
+	// SACRIF_ENV=development, which .env.development sets, re-parses templates on
+	// every request and allows chaos testing
+	devMode, err := developmentMode(os.Getenv("SACRIF_ENV"))
+	if err != nil {
+		fatal("Invalid SACRIF_ENV", "err", err)
+	}
+	if devMode {
+		slog.Warn("Development mode: templates are parsed on every request")
+	}
+
+	// The listen address comes from -addr, then SACRIF_ADDR, e.g. 127.0.0.1:4001 to
+	// keep a second instance local
+	addr := os.Getenv("SACRIF_ADDR")
+	if addr == "" {
+		addr = ":4000"
+	}
+	flag.StringVar(&addr, "addr", addr, "HTTP listen address (overrides SACRIF_ADDR)")
+	flag.Parse()
+
+	// Bind the port first so we can still grab privileged ports before dropping root
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		fatal("Failed to bind listener", "err", err)
+	}
+
+	// HTTPS is served straight from certificate files when both are given (renewals are
+	// picked up without a restart), or from Let's Encrypt for the domains listed in
+	// SACRIF_ACME_DOMAINS, optionally with a plain-HTTP port that only redirects
+	var certs *certReloader
+	var redirectLn net.Listener
+	certFile, keyFile := os.Getenv("SACRIF_TLS_CERT"), os.Getenv("SACRIF_TLS_KEY")
+	if (certFile == "") != (keyFile == "") {
+		fatal("SACRIF_TLS_CERT and SACRIF_TLS_KEY must be set together")
+	}
+	var acmeDomains []string
+	for _, d := range strings.Split(os.Getenv("SACRIF_ACME_DOMAINS"), ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			acmeDomains = append(acmeDomains, d)
+		}
+	}
+	if certFile != "" && acmeDomains != nil {
+		fatal("Set either SACRIF_TLS_CERT and SACRIF_TLS_KEY or SACRIF_ACME_DOMAINS, not both")
+	}
+	if certFile != "" {
+		certs, err = newCertReloader(certFile, keyFile)
+		if err != nil {
+			fatal("Failed to load TLS certificate", "err", err)
+		}
+	}
+	if certFile != "" || acmeDomains != nil {
+		if v := os.Getenv("SACRIF_HTTP_REDIRECT_ADDR"); v != "" {
+			redirectLn, err = net.Listen("tcp", v)
+			if err != nil {
+				fatal("Failed to bind redirect listener", "err", err)
+			}
+		}
+	}
+
+	// Optionally give up root now that the socket is open (e.g. SACRIF_RUN_AS=nobody:users on Unraid)
+	if err := dropPrivileges(os.Getenv("SACRIF_RUN_AS")); err != nil {
+		fatal("Failed to drop privileges", "err", err)
+	}
+	if certs != nil {
+		certs.checkReadable()
+	}
+
+	// Every file the station writes is confined under the data root
+	dataDir := os.Getenv("SACRIF_DATA_DIR")
+	if dataDir == "" {
+		dataDir = "."
+	}
+
+	dataRoot, err := storage.Open(dataDir)
+	if err != nil {
+		fatal("Failed to open data directory", "err", err)
+	}
+	defer dataRoot.Close()
+
+	// Let's Encrypt accounts and certificates are kept with the rest of the station's data
+	var acme acmeCerts
+	if acmeDomains != nil {
+		cacheDir, err := dataRoot.Path("acme")
+		if err != nil {
+			fatal("Invalid ACME cache directory", "err", err)
+		}
+		acme = newACME(acmeDomains, os.Getenv("SACRIF_ACME_EMAIL"), cacheDir)
 	}
 
 	// Fetch paths from environment, fallback to defaults if strictly missing
@@ -37,151 +224,770 @@ func main() {
 		scraperPath = "scraper.db"
 	}
 
-	// Initialize the main SQLite database connection
-	db, err := sql.Open("sqlite", sacrifPath)
+	// Database files must live inside the data root too
+	sacrifPath, err = dataRoot.Path(sacrifPath)
 	if err != nil {
-		log.Fatal("Failed to open main database:", err)
+		fatal("Invalid SACRIF_DB_PATH", "err", err)
+	}
+
+	scraperPath, err = dataRoot.Path(scraperPath)
+	if err != nil {
+		fatal("Invalid SCRAPER_DB_PATH", "err", err)
+	}
+
+	// Pointing both paths at the same file keeps entries and scraper data in one
+	// database (their tables don't overlap), so there is a single file to back up
+	singleDB := sacrifPath == scraperPath
+
+	// Chaos testing sabotages requests, scraper runs and queries on purpose, so it only
+	// runs in development (e.g. SACRIF_CHAOS=10 hits 10% of them)
+	var monkey *chaos.Monkey
+	sqlDriver := "sqlite"
+	if v := os.Getenv("SACRIF_CHAOS"); v != "" {
+		if !devMode {
+			slog.Warn("SACRIF_CHAOS ignored: chaos mode only runs with SACRIF_ENV=development")
+		} else {
+			monkey, err = chaos.New(v, os.Getenv("SACRIF_CHAOS_LATENCY"))
+			if err != nil {
+				fatal("Invalid SACRIF_CHAOS", "err", err)
+			}
+			if sqlDriver, err = monkey.Driver(sqlDriver); err != nil {
+				fatal("Failed to wrap database driver for chaos", "err", err)
+			}
+			slog.Warn("Chaos mode: sabotaging requests, scraper runs and statements", "percent", monkey.Rate*100)
+		}
+	}
+
+	// Every statement is timed for /metrics, on top of whatever chaos does to it
+	stationMetrics := newStationMetrics()
+	mainDriver, err := metrics.Driver(sqlDriver, "main", stationMetrics.dbDuration)
+	if err != nil {
+		fatal("Failed to wrap database driver for metrics", "err", err)
+	}
+	scraperDriver, err := metrics.Driver(sqlDriver, "scraper", stationMetrics.dbDuration)
+	if err != nil {
+		fatal("Failed to wrap database driver for metrics", "err", err)
+	}
+
+	// Initialize the main SQLite database connection. Scraper runs write to it too when
+	// the databases are shared, which needs the same busy timeout as the scraper's own
+	mainDSN := sacrifPath
+	if singleDB {
+		mainDSN += "?_pragma=busy_timeout(5000)"
+	}
+	db, err := sql.Open(mainDriver, mainDSN)
+	if err != nil {
+		fatal("Failed to open main database", "err", err)
 	}
 	defer db.Close()
 
 	if err = db.Ping(); err != nil {
-		log.Fatal("Failed to ping main database:", err)
+		fatal("Failed to ping main database", "err", err)
+	}
+
+	// Initialize the custom Scraper SQLite database connection. Scheduled runs write to it
+	// from several workers at once, so writers wait their turn instead of failing busy
+	scraperDB := db
+	databases := map[string]*models.DatabaseModel{"main": {DB: db, Path: sacrifPath}}
+	if singleDB {
+		slog.Info("Single-database mode: scraper tables share the main database", "path", sacrifPath)
+	} else {
+		scraperDB, err = sql.Open(scraperDriver, scraperPath+"?_pragma=busy_timeout(5000)")
+		if err != nil {
+			fatal("Failed to open scraper database", "err", err)
+		}
+		defer scraperDB.Close()
+
+		if err = scraperDB.Ping(); err != nil {
+			fatal("Failed to ping scraper database", "err", err)
+		}
+		databases["scraper"] = &models.DatabaseModel{DB: scraperDB, Path: scraperPath}
+	}
+
+	// Admin auth is a stateless HMAC-signed cookie, so logins never write to SQLite
+	adminPassword := os.Getenv("SACRIF_ADMIN_PASSWORD")
+	localAdmin := adminPassword == "" && devMode
+	switch {
+	case localAdmin:
+		slog.Warn("SACRIF_ADMIN_PASSWORD is not set. Admin routes are open to requests made on this machine.")
+	case adminPassword == "":
+		slog.Error("SACRIF_ADMIN_PASSWORD is not set. Admin routes are locked until it is.")
+	}
+
+	cookies, err := auth.NewSigner(cookieKeys()...)
+	if err != nil {
+		fatal("Invalid SACRIF_COOKIE_KEYS", "err", err)
+	}
+
+	// Uploaded files are stored once per unique content under the data root
+	blobs, err := dataRoot.Blobs("blobs")
+	if err != nil {
+		fatal("Failed to open blob store", "err", err)
+	}
+
+	// Backups are signed when a key is configured; the manifest checksums are written either way
+	var backupSigner *backup.Signer
+	if key := os.Getenv("SACRIF_BACKUP_KEY"); key != "" {
+		backupSigner, err = backup.NewSigner(key)
+		if err != nil {
+			fatal("Invalid SACRIF_BACKUP_KEY", "err", err)
+		}
+	}
+
+	// Behind a reverse proxy, client addresses come from the X-Forwarded-For it sets
+	clients, err := clientip.New(os.Getenv("SACRIF_TRUSTED_PROXIES"))
+	if err != nil {
+		fatal("Invalid SACRIF_TRUSTED_PROXIES", "err", err)
+	}
+
+	// Senders are known by a keyed hash of their address, never the address itself
+	originKey, err := loadOriginKey(dataRoot)
+	if err != nil {
+		fatal("Failed to load origin key", "err", err)
+	}
+
+	// Public forms can demand a proof of work from the browser before they accept a submission
+	powBits := 0
+	if v := os.Getenv("SACRIF_SPAM_POW_BITS"); v != "" {
+		powBits, err = strconv.Atoi(v)
+		if err != nil || powBits < 0 || powBits > 32 {
+			fatal("Invalid SACRIF_SPAM_POW_BITS", "value", v)
+		}
+	}
+
+	// Writes and API calls are rate limited per address, so one runaway client can't queue
+	// up SQLite writes for everyone else; SACRIF_RATE_LIMIT=off lifts the limit
+	var limiter *ratelimit.Limiter
+	if v := os.Getenv("SACRIF_RATE_LIMIT"); v != "off" {
+		if v == "" {
+			v = "2/s"
+		}
+		burst := os.Getenv("SACRIF_RATE_BURST")
+		if burst == "" {
+			burst = "20"
+		}
+		limiter, err = ratelimit.New(v, burst)
+		if err != nil {
+			fatal("Invalid SACRIF_RATE_LIMIT", "err", err)
+		}
+	}
+
+	// Entry events are pushed to downstream automations, always signed so they can trust them
+	webhookURLs, err := webhookEndpoints()
+	if err != nil {
+		fatal("Invalid SACRIF_WEBHOOK_URLS", "err", err)
+	}
+	var webhookSender *webhook.Sender
+	if len(webhookURLs) > 0 {
+		secret := os.Getenv("SACRIF_WEBHOOK_SECRET")
+		if secret == "" {
+			fatal("SACRIF_WEBHOOK_URLS is set but SACRIF_WEBHOOK_SECRET is not; webhooks are never sent unsigned.")
+		}
+		webhookSender = webhook.NewSender(secret)
+	}
+
+	// Thoughts can be followed from Mastodon and the like through an ActivityPub actor
+	fed, err := newFederation(dataRoot, os.Getenv("SACRIF_BASE_URL"))
+	if err != nil {
+		fatal("Failed to set up ActivityPub", "err", err)
+	}
+	if fed != nil {
+		slog.Info("ActivityPub actor enabled", "handle", "@"+fed.username+"@"+fed.host)
+	}
+
+	// Micropub clients can publish thoughts with a token from the owner's IndieAuth server
+	micropub, err := newMicropub(os.Getenv("SACRIF_BASE_URL"))
+	if err != nil {
+		fatal("Failed to set up Micropub", "err", err)
+	}
+
+	// How long browsers and proxies may keep pages, feeds and static files
+	cache, err := newCachePolicy()
+	if err != nil {
+		fatal("Invalid cache configuration", "err", err)
+	}
+	decay, err := newDecayPolicy()
+	if err != nil {
+		fatal("Invalid decay configuration", "err", err)
 	}
 
-	// Initialize the custom Scraper SQLite database connection
-	scraperDB, err := sql.Open("sqlite", scraperPath)
+	theme, err := defaultTheme()
 	if err != nil {
-		log.Fatal("Failed to open scraper database:", err)
+		fatal("Invalid theme", "err", err)
 	}
-	defer scraperDB.Close()
 
-	if err = scraperDB.Ping(); err != nil {
-		log.Fatal("Failed to ping scraper database:", err)
+	// Templates are compiled in; SACRIF_TEMPLATE_DIR, or development mode, reads them
+	// from disk instead
+	templates, err := newTemplateCache(devMode)
+	if err != nil {
+		fatal("Invalid template configuration", "err", err)
+	}
+	if templates.live {
+		slog.Info("Serving templates from disk", "dir", templates.dir)
 	}
 
 	// Initialize our custom application struct
 	app := &application{
-		entries: &models.EntryModel{DB: db},
-		scraper: &models.ScraperModel{DB: scraperDB},
+		data:           dataRoot,
+		blobs:          blobs,
+		entries:        &models.EntryModel{DB: db},
+		search:         &models.SearchModel{DB: db},
+		typeMigrations: &models.TypeMigrationModel{DB: db},
+		scraper:        &models.ScraperModel{DB: scraperDB},
+		sources:        &models.SourceModel{DB: scraperDB},
+		scrapeRuns:     &models.ScrapeRunModel{DB: scraperDB},
+		snapshots:      &models.SnapshotModel{DB: scraperDB},
+		prices:         &models.PriceModel{DB: scraperDB},
+		alerts:         &models.AlertModel{DB: scraperDB},
+		jobs:           &models.JobModel{DB: db},
+		syndication:    &models.SyndicationModel{DB: db},
+		replyContexts:  &models.ReplyContextModel{DB: db},
+		epochs:         &models.EpochModel{DB: db},
+		queue:          &models.QueueModel{DB: db},
+		attachments:    &models.AttachmentModel{DB: db},
+		externalIDs:    &models.ExternalIDModel{DB: db},
+		imports:        &models.ImportModel{DB: db},
+		transmissions:  &models.TransmissionModel{DB: db},
+		reputation:     &models.ReputationModel{DB: db},
+		filters:        &models.FilterModel{DB: db},
+		apiUsage:       &models.APIUsageModel{DB: db},
+		apiKeys:        &models.APIKeyModel{DB: db},
+		webhooks:       &models.WebhookModel{DB: db},
+		settings:       &models.SettingsModel{DB: db},
+		filter:         &filter.Filter{},
+		databases:      databases,
+		adminPassword:  adminPassword,
+		localAdmin:     localAdmin,
+		ingestToken:    os.Getenv("SACRIF_INGEST_TOKEN"),
+		apiToken:       os.Getenv("SACRIF_API_TOKEN"),
+		cookies:        cookies,
+		backupSigner:   backupSigner,
+		spamGuard:      spam.NewGuard(cookies, originKey, powBits),
+		baseURL:        os.Getenv("SACRIF_BASE_URL"),
+		syndicators:    syndicationTargets(),
+		webhookURLs:    webhookURLs,
+		webhookSender:  webhookSender,
+		federation:     fed,
+		followers:      &models.FollowerModel{DB: db},
+		micropub:       micropub,
+		authEndpoint:   os.Getenv("SACRIF_INDIEAUTH_AUTH_ENDPOINT"),
+		templates:      templates,
+		theme:          theme,
+		cache:          cache,
+		decay:          decay,
+		startedAt:      time.Now(),
+		certs:          certs,
+		metrics:        stationMetrics,
+		metricsToken:   os.Getenv("SACRIF_METRICS_TOKEN"),
+	}
+	// Transient fetch failures are retried; SCRAPER_RETRIES=0 turns that off
+	fetcher := scraper.NewFetcher()
+	if v := os.Getenv("SCRAPER_RETRIES"); v != "" {
+		fetcher.Retries, err = strconv.Atoi(v)
+		if err != nil || fetcher.Retries < 0 {
+			fatal("Invalid SCRAPER_RETRIES", "value", v)
+		}
+	}
+	if v := os.Getenv("SCRAPER_RETRY_BACKOFF"); v != "" {
+		fetcher.Backoff, err = time.ParseDuration(v)
+		if err != nil || fetcher.Backoff < 0 {
+			fatal("Invalid SCRAPER_RETRY_BACKOFF", "value", v)
+		}
+	}
+	app.engine = &scraper.Engine{Sources: app.sources, Items: app.scraper, Fetcher: fetcher, Runs: app.scrapeRuns,
+		Snapshots: app.snapshots, Prices: app.prices, Alerts: app.alerts}
+
+	if monkey != nil {
+		app.engine.Chaos = monkey.Strike
+	}
+
+	// Sources built with JavaScript load in headless Chrome, which only -tags chromedp builds include
+	if renderer, err := scraper.NewBrowser(fetcher.UserAgent); err != nil {
+		slog.Warn("Headless rendering off, rendered sources fetch over plain HTTP", "err", err)
+	} else {
+		app.engine.Renderer = renderer
+		defer renderer.Close()
 	}
 
 	// Ensure the database tables exist
 	if err := app.entries.InitSchema(); err != nil {
-		log.Fatal("Failed to initialize entries schema:", err)
+		fatal("Failed to initialize entries schema", "err", err)
+	}
+
+	if err := app.search.InitSchema(); err != nil {
+		fatal("Failed to initialize search schema", "err", err)
+	}
+
+	if err := app.typeMigrations.InitSchema(); err != nil {
+		fatal("Failed to initialize type migrations schema", "err", err)
 	}
 
 	if err := app.scraper.InitSchema(); err != nil {
-		log.Fatal("Failed to initialize scraper schema:", err)
+		fatal("Failed to initialize scraper schema", "err", err)
+	}
+
+	if err := app.sources.InitSchema(); err != nil {
+		fatal("Failed to initialize sources schema", "err", err)
+	}
+
+	if err := app.scrapeRuns.InitSchema(); err != nil {
+		fatal("Failed to initialize scrape runs schema", "err", err)
+	}
+
+	if err := app.snapshots.InitSchema(); err != nil {
+		fatal("Failed to initialize page snapshots schema", "err", err)
+	}
+
+	if err := app.prices.InitSchema(); err != nil {
+		fatal("Failed to initialize price history schema", "err", err)
+	}
+
+	if err := app.alerts.InitSchema(); err != nil {
+		fatal("Failed to initialize alerts schema", "err", err)
+	}
+
+	if err := app.jobs.InitSchema(); err != nil {
+		fatal("Failed to initialize jobs schema", "err", err)
+	}
+
+	if err := app.syndication.InitSchema(); err != nil {
+		fatal("Failed to initialize syndication schema", "err", err)
+	}
+
+	if err := app.replyContexts.InitSchema(); err != nil {
+		fatal("Failed to initialize reply context schema", "err", err)
+	}
+
+	if err := app.epochs.InitSchema(); err != nil {
+		fatal("Failed to initialize epochs schema", "err", err)
+	}
+
+	if err := app.queue.InitSchema(); err != nil {
+		fatal("Failed to initialize queue schema", "err", err)
+	}
+
+	if err := app.attachments.InitSchema(); err != nil {
+		fatal("Failed to initialize attachments schema", "err", err)
+	}
+
+	if err := app.externalIDs.InitSchema(); err != nil {
+		fatal("Failed to initialize external IDs schema", "err", err)
+	}
+
+	if err := app.imports.InitSchema(); err != nil {
+		fatal("Failed to initialize imports schema", "err", err)
+	}
+
+	if err := app.transmissions.InitSchema(); err != nil {
+		fatal("Failed to initialize transmissions schema", "err", err)
+	}
+
+	if err := app.reputation.InitSchema(); err != nil {
+		fatal("Failed to initialize reputation schema", "err", err)
+	}
+
+	if err := app.filters.InitSchema(); err != nil {
+		fatal("Failed to initialize filters schema", "err", err)
+	}
+
+	if err := app.apiUsage.InitSchema(); err != nil {
+		fatal("Failed to initialize API usage schema", "err", err)
+	}
+
+	if err := app.apiKeys.InitSchema(); err != nil {
+		fatal("Failed to initialize API keys schema", "err", err)
+	}
+
+	if err := app.webhooks.InitSchema(); err != nil {
+		fatal("Failed to initialize webhook deliveries schema", "err", err)
+	}
+
+	if err := app.followers.InitSchema(); err != nil {
+		fatal("Failed to initialize ActivityPub followers schema", "err", err)
+	}
+
+	if err := app.settings.InitSchema(); err != nil {
+		fatal("Failed to initialize settings schema", "err", err)
+	}
+	if err := app.reloadSite(); err != nil {
+		fatal("Failed to load site settings", "err", err)
+	}
+
+	app.registerCollectors()
+
+	app.spamGuard.Clients = clients
+	app.filter.Clients = clients
+
+	// The signed-in operator is never filtered, so a bad rule can always be undone
+	app.filter.Exempt = app.hasAdminSession
+	if err := app.reloadFilters(); err != nil {
+		fatal("Failed to load request filters", "err", err)
 	}
 
-	// Check if DB is empty, if so, SEED initial testing data
+	// Check if DB is empty, if so, SEED initial testing data; SACRIF_SEED=off starts
+	// a new station blank instead
 	count, err := app.entries.Count()
-	if err == nil && count == 0 {
-		log.Println("Database is empty. Injecting seed data...")
-		app.entries.Insert("Hyperion", "book", "Dan Simmons. A structural masterpiece. The Priest's Tale is one of the most haunting things I've ever read.", "")
-		app.entries.Insert("The Expanse", "anime", "The most grounded sci-fi television currently in existence. The political tension between Earth, Mars, and the Belt is perfectly executed.", "")
-		app.entries.Insert("Inertia", "thought", "The concept of an organic compendium fits perfectly. Things don't need rigid boxes, just a type tag and a display heuristic. Building this feels like carving out a quiet corner of the internet.", "")
+	if err == nil && count == 0 && os.Getenv("SACRIF_SEED") != "off" {
+		slog.Info("Database is empty. Injecting seed data...")
+		app.entries.Insert(&models.Entry{Title: "Hyperion", Type: "book", Content: models.NullString("Dan Simmons. A structural masterpiece. The Priest's Tale is one of the most haunting things I've ever read.")})
+		app.entries.Insert(&models.Entry{Title: "The Expanse", Type: "anime", Content: models.NullString("The most grounded sci-fi television currently in existence. The political tension between Earth, Mars, and the Belt is perfectly executed.")})
+		app.entries.Insert(&models.Entry{Title: "Inertia", Type: "thought", Content: models.NullString("The concept of an organic compendium fits perfectly. Things don't need rigid boxes, just a type tag and a display heuristic. Building this feels like carving out a quiet corner of the internet."), Mood: models.NullString("focused")})
 	}
 
-	mux := http.NewServeMux()
+	mux := newRouter(app)
 
 	// Define the routes for our sectors
-	mux.HandleFunc("GET /", app.homeHandler)
+	mux.HandleFunc("GET /{$}", app.homeHandler)
 	mux.HandleFunc("GET /media", app.mediaHandler)
 	mux.HandleFunc("GET /thoughts", app.thoughtsHandler)
-	mux.HandleFunc("GET /admin/add", app.createEntryHandler)
-	mux.HandleFunc("POST /admin/add", app.createEntryPostHandler)
+	mux.HandleFunc("GET /media/fragment", app.mediaFragmentHandler)
+	mux.HandleFunc("GET /thoughts/fragment", app.thoughtsFragmentHandler)
+	mux.HandleFunc("GET /thoughts/feed.xml", app.thoughtsFeedHandler)
+	mux.HandleFunc("GET /media/feed.atom", app.mediaFeedHandler)
+	mux.HandleFunc("GET /feed.json", app.jsonFeedHandler)
+	mux.HandleFunc("GET /sitemap.xml", app.sitemapHandler)
+	mux.HandleFunc("GET /robots.txt", app.robotsHandler)
+	mux.HandleFunc("GET /static/{path...}", app.staticHandler)
+	mux.HandleFunc("GET /healthz", app.healthHandler)
+	mux.HandleFunc("GET /healthz/live", app.liveHandler)
+	mux.HandleFunc("GET /metrics", app.metricsHandler)
+	mux.HandleFunc("GET /screensaver", app.screensaverHandler)
+	mux.HandleFunc("GET /screensaver/stream", app.screensaverStreamHandler)
+	mux.HandleFunc("GET /stats", app.statsHandler)
+	mux.HandleFunc("GET /entry/{slug}", app.entryHandler)
+	mux.HandleFunc("GET /attachments/{id}", app.attachmentHandler)
+	mux.HandleFunc("GET /search", app.searchHandler)
+	mux.HandleFunc("GET /epochs", app.epochsHandler)
+	mux.HandleFunc("GET /epochs/{id}", app.epochHandler)
+	mux.HandleFunc("POST /theme", app.themePostHandler)
+	mux.HandleFunc("GET /transmit", app.transmitHandler)
+	mux.HandleFunc("POST /transmit", app.transmitPostHandler)
+	mux.HandleFunc("GET /admin/login", app.loginHandler)
+	mux.HandleFunc("POST /admin/login", app.loginPostHandler)
+	mux.HandleFunc("POST /admin/logout", app.logoutPostHandler)
+	mux.HandleFunc("GET /admin", app.requireAdmin(app.dashboardHandler))
+	mux.HandleFunc("GET /admin/add", app.requireAdmin(app.createEntryHandler))
+	mux.HandleFunc("POST /admin/add", app.requireAdmin(app.createEntryPostHandler))
+	mux.HandleFunc("GET /admin/edit/{id}", app.requireAdmin(app.editEntryHandler))
+	mux.HandleFunc("POST /admin/edit/{id}", app.requireAdmin(app.editEntryPostHandler))
+	mux.HandleFunc("GET /admin/entries", app.requireAdmin(app.adminEntriesHandler))
+	mux.HandleFunc("POST /admin/entries/bulk", app.requireAdmin(app.adminEntriesBulkPostHandler))
+	mux.HandleFunc("POST /admin/entries/{id}/delete", app.requireAdmin(app.entryDeletePostHandler))
+	mux.HandleFunc("POST /admin/entries/{id}/attachments", app.requireAdmin(app.attachmentUploadPostHandler))
+	mux.HandleFunc("POST /admin/entries/{id}/attachments/link", app.requireAdmin(app.attachmentLinkPostHandler))
+	mux.HandleFunc("POST /admin/entries/{id}/external-ids", app.requireAdmin(app.externalIDAddPostHandler))
+	mux.HandleFunc("POST /admin/entries/{id}/external-ids/delete", app.requireAdmin(app.externalIDRemovePostHandler))
+	mux.HandleFunc("POST /admin/epochs", app.requireAdmin(app.epochsPostHandler))
+	mux.HandleFunc("GET /admin/types", app.requireAdmin(app.typesHandler))
+	mux.HandleFunc("POST /admin/types/migrate", app.requireAdmin(app.typesMigratePostHandler))
+	mux.HandleFunc("GET /admin/storage", app.requireAdmin(app.storageHandler))
+	mux.HandleFunc("POST /admin/storage/{db}/vacuum", app.requireAdmin(app.storageVacuumPostHandler))
+	mux.HandleFunc("GET /admin/backups", app.requireAdmin(app.backupsHandler))
+	mux.HandleFunc("POST /admin/backups", app.requireAdmin(app.backupsPostHandler))
+	mux.HandleFunc("GET /admin/backups/minisign.pub", app.requireAdmin(app.backupPublicKeyHandler))
+	mux.HandleFunc("GET /admin/backups/{name}", app.requireAdmin(app.backupDownloadHandler))
+	mux.HandleFunc("GET /admin/import", app.requireAdmin(app.importHandler))
+	mux.HandleFunc("POST /admin/import", app.requireAdmin(app.importPostHandler))
+	mux.HandleFunc("GET /admin/import/{id}", app.requireAdmin(app.importReconcileHandler))
+	mux.HandleFunc("POST /admin/import/{id}", app.requireAdmin(app.importApplyPostHandler))
+	mux.HandleFunc("POST /admin/import/{id}/discard", app.requireAdmin(app.importDiscardPostHandler))
+	mux.HandleFunc("GET /admin/search", app.requireAdmin(app.adminSearchHandler))
+	mux.HandleFunc("GET /admin/transmissions", app.requireAdmin(app.transmissionsHandler))
+	mux.HandleFunc("POST /admin/transmissions/{id}/approve", app.requireAdmin(app.transmissionApprovePostHandler))
+	mux.HandleFunc("POST /admin/transmissions/{id}/reject", app.requireAdmin(app.transmissionRejectPostHandler))
+	mux.HandleFunc("GET /admin/spam", app.requireAdmin(app.spamHandler))
+	mux.HandleFunc("POST /admin/spam/block", app.requireAdmin(app.spamBlockPostHandler))
+	mux.HandleFunc("POST /admin/spam/unblock", app.requireAdmin(app.spamUnblockPostHandler))
+	mux.HandleFunc("GET /admin/settings", app.requireAdmin(app.settingsHandler))
+	mux.HandleFunc("POST /admin/settings", app.requireAdmin(app.settingsPostHandler))
+	mux.HandleFunc("GET /admin/filters", app.requireAdmin(app.filtersHandler))
+	mux.HandleFunc("POST /admin/filters", app.requireAdmin(app.filtersPostHandler))
+	mux.HandleFunc("POST /admin/filters/throttle", app.requireAdmin(app.filterThrottlePostHandler))
+	mux.HandleFunc("POST /admin/filters/{id}/delete", app.requireAdmin(app.filterDeletePostHandler))
+	mux.HandleFunc("GET /admin/api", app.requireAdmin(app.apiUsageHandler))
+	mux.HandleFunc("POST /admin/api/keys", app.requireAdmin(app.apiKeyCreatePostHandler))
+	mux.HandleFunc("POST /admin/api/keys/{id}/delete", app.requireAdmin(app.apiKeyDeletePostHandler))
+	mux.HandleFunc("POST /admin/api/{token}/limit", app.requireAdmin(app.apiLimitPostHandler))
+	mux.HandleFunc("POST /admin/api/{token}/revoke", app.requireAdmin(app.apiRevokePostHandler))
+	mux.HandleFunc("POST /admin/api/{token}/restore", app.requireAdmin(app.apiRestorePostHandler))
+	mux.HandleFunc("GET /admin/webhooks", app.requireAdmin(app.webhooksHandler))
+	mux.HandleFunc("POST /admin/webhooks/{id}/redeliver", app.requireAdmin(app.webhookRedeliverPostHandler))
+	mux.HandleFunc("POST /admin/epochs/{id}/delete", app.requireAdmin(app.epochDeletePostHandler))
 
-	// Define scraper route
+	// Define reading queue routes, it's a personal list so all of them need admin
+	mux.HandleFunc("GET /queue", app.requireAdmin(app.queueHandler))
+	mux.HandleFunc("POST /queue", app.requireAdmin(app.queuePostHandler))
+	mux.HandleFunc("POST /queue/{id}/move", app.requireAdmin(app.queueMovePostHandler))
+	mux.HandleFunc("POST /queue/{id}/snooze", app.requireAdmin(app.queueSnoozePostHandler))
+	mux.HandleFunc("POST /queue/{id}/done", app.requireAdmin(app.queueDonePostHandler))
+
+	// Define scraper routes
 	mux.HandleFunc("GET /scraper", app.scraperHandler)
+	mux.HandleFunc("POST /admin/scraper/{id}/promote", app.requireAdmin(app.scraperPromotePostHandler))
+	mux.HandleFunc("POST /admin/scraper/{id}/dismiss", app.requireAdmin(app.scraperDismissPostHandler))
+	mux.HandleFunc("POST /admin/scraper/{id}/delete", app.requireAdmin(app.scraperDeletePostHandler))
+	mux.HandleFunc("POST /admin/scraper/clear", app.requireAdmin(app.scraperClearPostHandler))
+	mux.HandleFunc("GET /admin/alerts", app.requireAdmin(app.alertsHandler))
+	mux.HandleFunc("POST /admin/alerts", app.requireAdmin(app.alertsPostHandler))
+	mux.HandleFunc("POST /admin/alerts/seen", app.requireAdmin(app.alertsSeenPostHandler))
+	mux.HandleFunc("POST /admin/alerts/{id}/seen", app.requireAdmin(app.alertSeenPostHandler))
+	mux.HandleFunc("POST /admin/alerts/rules/{id}/delete", app.requireAdmin(app.alertRuleDeletePostHandler))
+	mux.HandleFunc("GET /admin/sources", app.requireAdmin(app.sourcesHandler))
+	mux.HandleFunc("POST /admin/sources", app.requireAdmin(app.sourcesPostHandler))
+	mux.HandleFunc("GET /admin/sources/runs", app.requireAdmin(app.scrapeRunsHandler))
+	mux.HandleFunc("GET /admin/sources/opml", app.requireAdmin(app.sourcesOPMLHandler))
+	mux.HandleFunc("POST /admin/sources/opml", app.requireAdmin(app.sourcesOPMLPostHandler))
+	mux.HandleFunc("POST /admin/sources/{id}/run", app.requireAdmin(app.sourceRunPostHandler))
+	mux.HandleFunc("POST /admin/sources/{id}/toggle", app.requireAdmin(app.sourceTogglePostHandler))
+	mux.HandleFunc("POST /admin/sources/{id}/filters", app.requireAdmin(app.sourceFiltersPostHandler))
+	mux.HandleFunc("POST /admin/sources/{id}/delete", app.requireAdmin(app.sourceDeletePostHandler))
 
 	// Define intercept route
 	mux.HandleFunc("GET /intercept", app.interceptHandler)
 
-	log.Println("Starting server on :4000")
-	err = http.ListenAndServe(":4000", mux)
-	log.Fatal(err)
-}
+	// Machine endpoints authenticate with their own tokens rather than the admin session
+	mux.HandleFunc("POST /api/scraper/ingest", app.requireAPIToken(scopeIngest, app.ingestPostHandler))
 
-// homeHandler renders the Root Domain landing page
-func (app *application) homeHandler(w http.ResponseWriter, r *http.Request) {
-	if r.URL.Path != "/" {
-		http.NotFound(w, r)
-		return
+	// The snapshot and entry reads only show public content, so just writes need a token
+	mux.HandleFunc("GET /api/v1/snapshot", app.snapshotHandler)
+	mux.HandleFunc("GET /api/v1/entries", app.apiEntriesHandler)
+	mux.HandleFunc("GET /api/v1/entries/{id}", app.apiEntryHandler)
+	mux.HandleFunc("POST /api/v1/entries", app.requireAPIToken(scopeAPI, app.apiEntryCreateHandler))
+	mux.HandleFunc("PUT /api/v1/entries/{id}", app.requireAPIToken(scopeAPI, app.apiEntryUpdateHandler))
+	mux.HandleFunc("DELETE /api/v1/entries/{id}", app.requireAPIToken(scopeAPI, app.apiEntryDeleteHandler))
+
+	// ActivityPub federation of the thoughts; every route 404s while it is off
+	mux.HandleFunc("GET /.well-known/webfinger", app.webfingerHandler)
+	mux.HandleFunc("GET /ap/actor", app.actorHandler)
+	mux.HandleFunc("GET /ap/outbox", app.outboxHandler)
+	mux.HandleFunc("GET /ap/followers", app.followersHandler)
+	mux.HandleFunc("GET /ap/notes/{id}", app.noteHandler)
+	mux.HandleFunc("POST /ap/inbox", app.inboxPostHandler)
+
+	// Micropub publishing; clients bring IndieAuth tokens, and the routes 404 while it is off
+	mux.HandleFunc("GET /micropub", app.micropubQueryHandler)
+	mux.HandleFunc("POST /micropub", app.micropubPostHandler)
+
+	// Background work stops when the process is asked to shut down
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Background work can be held to quiet hours so it doesn't compete with whatever else shares the box
+	defaultWindow := jobWindow("SACRIF_WINDOW", nil)
+	runner := &jobs.Runner{Jobs: app.jobs, Windows: map[string]*jobs.Window{}}
+	for _, kind := range []string{jobSyndicate, jobReplyContext, jobWebhook, jobActivityPub} {
+		if w := jobWindow("SACRIF_WINDOW_"+strings.ToUpper(kind), defaultWindow); w != nil {
+			runner.Windows[kind] = w
+			slog.Info("Jobs run only within their window", "kind", kind, "window", w.String())
+		}
 	}
+	runner.Handle(jobSyndicate, app.runSyndicateJob)
+	runner.Handle(jobReplyContext, app.runReplyContextJob)
+	runner.Handle(jobWebhook, app.runWebhookJob)
+	runner.Handle(jobActivityPub, app.runActivityPubJob)
+	go runner.Run(ctx)
 
-	ts, err := template.ParseFiles("./ui/html/base.tmpl", "./ui/html/pages/home.tmpl")
-	if err != nil {
-		http.Error(w, "Internal Server Error", 500)
-		return
+	go app.flushFilterHitsEvery(ctx, time.Minute)
+
+	// Scraping happens in-process on each source's interval; SCRAPER_SCHEDULER=off leaves it to manual runs
+	if os.Getenv("SCRAPER_SCHEDULER") != "off" {
+		scheduler := &scraper.Scheduler{Engine: app.engine, Jitter: 0.1, Window: jobWindow("SACRIF_WINDOW_SCRAPER", defaultWindow)}
+		if scheduler.Window != nil {
+			slog.Info("Scheduled scrapes run only within their window", "window", scheduler.Window.String())
+		}
+		if v := os.Getenv("SCRAPER_WORKERS"); v != "" {
+			scheduler.Workers, err = strconv.Atoi(v)
+			if err != nil || scheduler.Workers < 1 {
+				fatal("Invalid SCRAPER_WORKERS", "value", v)
+			}
+		}
+		app.scheduler = scheduler
+		go scheduler.Run(ctx)
 	}
 
-	err = ts.ExecuteTemplate(w, "base", nil)
+	// The first visitor after a restart shouldn't wait on template parsing and cold queries
+	app.warmUp()
+
+	handler := app.cacheHeaders(mux)
+	if monkey != nil {
+		handler = monkey.Handler(handler)
+		monkey.Start()
+	}
+
+	if limiter != nil {
+		limiter.Applies = rateLimited
+		limiter.Exempt = app.hasAdminSession
+		limiter.Refuse = rateLimitRefused
+		limiter.Clients = clients
+		handler = limiter.Handler(handler)
+		slog.Info("Rate limiting writes and API calls", "limit", limiter.String())
+	}
+
+	// Other origins may call the API from the browser only when listed in SACRIF_CORS_ORIGINS
+	cors, err := newCORSPolicy()
 	if err != nil {
-		http.Error(w, "Internal Server Error", 500)
+		fatal("Invalid CORS configuration", "err", err)
+	}
+	if cors != nil {
+		handler = cors.Handler(handler)
+		slog.Info("CORS enabled for the API", "origins", strings.Join(cors.origins, ","))
+	}
+
+	srv := &http.Server{
+		Handler:           withRequestID(logRequests(app.instrument(app.recoverPanics(compressResponses(app.filter.Handler(handler)))))),
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+
+	var redirectSrv *http.Server
+	if certs != nil || acme != nil {
+		srv.TLSConfig = tlsConfig(certs, acme)
+		if redirectLn != nil {
+			redirect := redirectToHTTPS(ln.Addr().String())
+			if acme != nil {
+				// Let's Encrypt's HTTP-01 challenges arrive on this port, before any redirect
+				redirect = acme.HTTPHandler(redirect)
+			}
+			redirectSrv = &http.Server{Handler: redirect, ReadHeaderTimeout: readHeaderTimeout, IdleTimeout: idleTimeout}
+			go redirectSrv.Serve(redirectLn)
+			slog.Info("Redirecting plain HTTP to HTTPS", "addr", redirectLn.Addr().String())
+		}
+	}
+
+	go func() {
+		<-ctx.Done()
+		slog.Info("Shutting down...")
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if redirectSrv != nil {
+			redirectSrv.Shutdown(shutdownCtx)
+		}
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	switch {
+	case acme != nil:
+		slog.Info("Starting server with TLS from Let's Encrypt", "addr", ln.Addr().String(), "domains", strings.Join(acmeDomains, ","))
+		err = srv.ServeTLS(ln, "", "")
+	case certs != nil:
+		slog.Info("Starting server with TLS", "addr", ln.Addr().String(), "cert", certFile)
+		err = srv.ServeTLS(ln, "", "")
+	default:
+		slog.Info("Starting server", "addr", ln.Addr().String())
+		err = srv.Serve(ln)
+	}
+	if !errors.Is(err, http.ErrServerClosed) {
+		fatal("Server failed", "err", err)
 	}
 }
 
-// mediaHandler renders the Media Compendium (everything EXCEPT thoughts/logs)
-func (app *application) mediaHandler(w http.ResponseWriter, r *http.Request) {
-	// Let's fetch the latest 50 entries that are NOT "thought"
-	latestEntries, err := app.entries.MediaEntries(50)
+// How long a client may take over its side of a connection, so slow ones can't tie
+// up the server's by the thousand. There is no write timeout: streams and large
+// downloads run as long as they need.
+const (
+	readHeaderTimeout = 10 * time.Second
+	readTimeout       = time.Minute      // The whole request, body included
+	uploadTimeout     = 30 * time.Minute // What admin uploads get instead
+	idleTimeout       = 2 * time.Minute  // Between requests on a kept-alive connection
+)
+
+// jobWindow reads a scheduling window from the environment, falling back to def when
+// the variable is unset; "always" lifts the default for one class of work.
+func jobWindow(name string, def *jobs.Window) *jobs.Window {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return def
+	}
+	w, err := jobs.ParseWindow(v)
 	if err != nil {
-		http.Error(w, "Internal Server Error", 500)
-		return
+		fatal("Invalid "+name, "err", err)
 	}
+	return w
+}
 
-	ts, err := template.ParseFiles("./ui/html/base.tmpl", "./ui/html/pages/media.tmpl")
-	if err != nil {
-		http.Error(w, "Internal Server Error", 500)
-		return
+// cookieKeys reads the comma-separated signing keys (newest first) from SACRIF_COOKIE_KEYS.
+// Without any, a random key is generated, which simply logs everyone out on restart.
+func cookieKeys() [][]byte {
+	var keys [][]byte
+	for _, k := range strings.Split(os.Getenv("SACRIF_COOKIE_KEYS"), ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, []byte(k))
+		}
 	}
 
-	err = ts.ExecuteTemplate(w, "base", latestEntries)
-	if err != nil {
-		http.Error(w, "Internal Server Error", 500)
+	if len(keys) == 0 {
+		key := make([]byte, 32)
+		rand.Read(key)
+		keys = append(keys, key)
 	}
+
+	return keys
 }
 
-// thoughtsHandler renders the Organic Thoughts Sector (ONLY thoughts/logs)
-func (app *application) thoughtsHandler(w http.ResponseWriter, r *http.Request) {
-	// Fetch the latest 50 thought entries
-	latestEntries, err := app.entries.LatestThoughts(50)
+// homeHandler renders the Root Domain landing page
+func (app *application) homeHandler(w http.ResponseWriter, r *http.Request) {
+	app.micropubLinks(w)
+	app.render(w, r, nil, "pages/home.tmpl")
+}
+
+// mediaHandler renders the Media Compendium (everything EXCEPT thoughts/logs)
+func (app *application) mediaHandler(w http.ResponseWriter, r *http.Request) {
+	page, err := app.mediaSector(r)
 	if err != nil {
-		http.Error(w, "Internal Server Error", 500)
+		app.serverError(w, r, err)
 		return
 	}
 
-	funcs := template.FuncMap{
-		"renderMarkdown": func(text string) template.HTML {
-			return template.HTML(markdown.ToHTML([]byte(text), nil, nil))
-		},
-	}
+	app.renderNegotiated(w, r, page, mediaFiles...)
+}
 
-	ts, err := template.New("base.tmpl").Funcs(funcs).ParseFiles("./ui/html/base.tmpl", "./ui/html/pages/thoughts.tmpl")
+// thoughtsHandler renders the Organic Thoughts Sector (ONLY thoughts/logs)
+func (app *application) thoughtsHandler(w http.ResponseWriter, r *http.Request) {
+	page, err := app.thoughtsSector(r)
 	if err != nil {
-		http.Error(w, "Internal Server Error", 500)
+		app.serverError(w, r, err)
 		return
 	}
 
-	err = ts.ExecuteTemplate(w, "base", latestEntries)
-	if err != nil {
-		http.Error(w, "Internal Server Error", 500)
+	app.renderNegotiated(w, r, page, thoughtsFiles...)
+}
+
+// entryFormPage is the data handed to create.tmpl, shared by the add and edit forms
+type entryFormPage struct {
+	Action string
+	Entry  *models.Entry
+	Types  []models.EntryType
+	Moods  []models.Mood
+	Parent *models.Entry     // Set when continuing an existing thought
+	Fields string            // Custom fields as text, as typed when the form comes back
+	Next   string            // Where to go after saving, e.g. the dashboard's quick-add
+	Errors map[string]string // Problems with the last submission, keyed by field name
+}
+
+// renderEntryForm shows the add/edit form, with status other than 200 when it comes
+// back with errors.
+func (app *application) renderEntryForm(w http.ResponseWriter, r *http.Request, status int, page entryFormPage) {
+	if status != http.StatusOK {
+		w.WriteHeader(status)
 	}
+	app.render(w, r, page, "pages/create.tmpl")
 }
 
 // createEntryHandler renders the admin form GET /admin/add
 func (app *application) createEntryHandler(w http.ResponseWriter, r *http.Request) {
-	ts, err := template.ParseFiles("./ui/html/base.tmpl", "./ui/html/pages/create.tmpl")
-	if err != nil {
-		http.Error(w, "Internal Server Error", 500)
-		return
-	}
+	page := entryFormPage{Action: "/admin/add", Entry: &models.Entry{}, Types: models.SelectableTypes(), Moods: models.Moods}
 
-	err = ts.ExecuteTemplate(w, "base", nil)
-	if err != nil {
-		http.Error(w, "Internal Server Error", 500)
+	// ?parent= continues an existing thought as a reply
+	if id, err := strconv.Atoi(r.URL.Query().Get("parent")); err == nil {
+		parent, err := app.entries.Get(id)
+		if err == nil && models.IsThoughtType(parent.Type) {
+			page.Parent = parent
+		}
 	}
+
+	app.renderEntryForm(w, r, http.StatusOK, page)
 }
 
 // createEntryPostHandler processes the form submission POST /admin/add
@@ -192,43 +998,237 @@ func (app *application) createEntryPostHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	title := r.PostForm.Get("title")
-	entryType := r.PostForm.Get("type")
-	content := r.PostForm.Get("content")
-	url := r.PostForm.Get("url")
+	entry, v := entryFromForm(r.PostForm, "")
+
+	// Replies are only allowed between thoughts
+	var parent *models.Entry
+	if parentID, err := strconv.Atoi(r.PostForm.Get("parent_id")); err == nil {
+		parent, err = app.entries.Get(parentID)
+		if err != nil || !models.IsThoughtType(parent.Type) {
+			http.Error(w, "Bad Request", 400)
+			return
+		}
+		v.CheckField(models.IsThoughtType(entry.Type), "type", "A thread continues with a thought log.")
+		entry.ParentID = &parent.ID
+	}
+
+	if !v.Valid() {
+		page := entryFormPage{
+			Action: "/admin/add",
+			Entry:  entry,
+			Types:  models.SelectableTypes(),
+			Moods:  models.Moods,
+			Parent: parent,
+			Fields: r.PostForm.Get("fields"),
+			Next:   r.PostForm.Get("next"),
+			Errors: v.FieldErrors,
+		}
+		app.renderEntryForm(w, r, http.StatusUnprocessableEntity, page)
+		return
+	}
 
 	// Insert into SQLite database
-	_, err = app.entries.Insert(title, entryType, content, url)
+	id, err := app.entries.Insert(entry)
 	if err != nil {
-		log.Println("Database insert error:", err)
-		http.Error(w, "Internal Server Error", 500)
+		app.serverError(w, r, err)
+		return
+	}
+
+	entry.ID = id
+	app.entryCreated(entry)
+	app.setFlash(w, r, "Entry logged: "+entry.Title)
+
+	// Thread continuations land back on the thread, everything else drops to root
+	if entry.ParentID != nil {
+		http.Redirect(w, r, entry.Permalink(), http.StatusSeeOther)
 		return
 	}
 
-	// Redirect back to root to drop them into the appropriate sector automatically
-	http.Redirect(w, r, "/", http.StatusSeeOther)
+	// Redirect back to root to drop them into the appropriate sector automatically,
+	// unless the form came from somewhere to return to, like the dashboard's quick-add
+	http.Redirect(w, r, safeRedirect(r.PostForm.Get("next"), "/"), http.StatusSeeOther)
 }
 
-// scraperHandler renders the generic Scraper view
+// entryCreated sets off everything that follows a new entry: cross-posting, fetching
+// the context of what it replies to, webhooks and ActivityPub delivery.
+func (app *application) entryCreated(entry *models.Entry) {
+	app.enqueueSyndication(entry.ID)
+	app.enqueueReplyContext(entry, false)
+	app.emitEntryEvent(eventEntryCreated, entry.ID)
+	app.federateEntry(entry.ID)
+}
+
+// entriesDeleted unlinks the scraped items promoted to deleted entries. They live
+// in the scraper database, which can be a separate file out of reach of the
+// delete's transaction, so this runs after it; the entries are gone either way.
+func (app *application) entriesDeleted(ids ...int) {
+	if err := app.scraper.UnlinkEntries(ids); err != nil {
+		slog.Error("Failed to unlink scraped items from deleted entries", "entries", ids, "err", err)
+	}
+}
+
+// Longest title and content the admin form accepts, in characters
+const (
+	maxTitleRunes   = 200
+	maxContentRunes = 100_000
+)
+
+// entryFromForm reads the fields shared by the add and edit forms and checks them,
+// returning the entry as far as it could be read along with any problems found.
+// storedType is the entry's current type on edits, which may stay on a retired type.
+func entryFromForm(form url.Values, storedType string) (*models.Entry, validator.Validator) {
+	entry := &models.Entry{
+		Title:           strings.TrimSpace(form.Get("title")),
+		Type:            form.Get("type"),
+		Content:         models.NullString(form.Get("content")),
+		URL:             models.NullString(strings.TrimSpace(form.Get("url"))),
+		Mood:            models.NullString(form.Get("mood")),
+		FeedSummary:     models.NullString(strings.TrimSpace(form.Get("feed_summary"))),
+		ExcludeFromFeed: form.Get("exclude_from_feed") == "on",
+		CanonicalURL:    models.NullString(strings.TrimSpace(form.Get("canonical_url"))),
+	}
+
+	var v validator.Validator
+	v.CheckField(validator.NotBlank(entry.Title), "title", "A title is required.")
+	v.CheckField(validator.MaxChars(entry.Title, maxTitleRunes), "title", fmt.Sprintf("Keep the title under %d characters.", maxTitleRunes))
+
+	types := []string{storedType}
+	for _, t := range models.SelectableTypes() {
+		types = append(types, t.Key)
+	}
+	v.CheckField(entry.Type != "" && validator.PermittedValue(entry.Type, types...), "type", "Pick one of the listed payload types.")
+
+	v.CheckField(validator.MaxChars(models.StringValue(entry.Content), maxContentRunes), "content", fmt.Sprintf("Keep the content under %d characters.", maxContentRunes))
+	if entry.URL != nil {
+		v.CheckField(validator.WebURL(*entry.URL), "url", "The link must be a full http:// or https:// address.")
+	}
+	// A canonical link ends up in every feed reader, so it has to be real
+	if entry.CanonicalURL != nil {
+		v.CheckField(validator.WebURL(*entry.CanonicalURL), "canonical_url", "The canonical link must be a full http:// or https:// address.")
+	}
+
+	fields, err := models.ParseFields(form.Get("fields"))
+	if err != nil {
+		v.AddFieldError("fields", err.Error())
+	}
+	entry.Fields = fields
+
+	// Mood is optional; silently drop anything outside the known set
+	if entry.MoodInfo() == nil {
+		entry.Mood = nil
+	}
+
+	return entry, v
+}
+
+// scraperPage is the data handed to scraper.tmpl
+type scraperPage struct {
+	Items   []*models.ScraperItem
+	Starred map[int]bool // Items already in the reading queue
+	Types   []models.EntryType
+	IsAdmin bool
+	Result  string // Outcome of the last bulk clear, if any
+	Sources []*models.SourceTally
+	Source  *models.SourceTally // Set when the view is filtered with ?source=
+	Filter  int                 // ID of that source, 0 when showing everything
+	Prices  []*models.PriceSummary
+	History []*models.PricePoint // Recent prices of the filtered source, if it tracks one
+	Query   string               // Search typed into ?q=, which replaces the item list with matches
+	Matches []*models.ScraperSearchResult
+	Alerts  []*models.Alert // Unacknowledged alerts, newest first, shown to the operator above everything else
+	Unseen  int             // How many there are in all
+}
+
+// scraperHandler renders the generic Scraper view, optionally narrowed to one source with ?source=
 func (app *application) scraperHandler(w http.ResponseWriter, r *http.Request) {
-	// Let's fetch the latest 50 scraped items
-	latestItems, err := app.scraper.Latest(50)
+	tallies, err := app.scraper.SourceTallies()
 	if err != nil {
-		http.Error(w, "Internal Server Error", 500)
+		app.serverError(w, r, err)
 		return
 	}
 
-	// We'll create a simple scraper.tmpl page next
-	ts, err := template.ParseFiles("./ui/html/base.tmpl", "./ui/html/pages/scraper.tmpl")
+	page := scraperPage{Sources: tallies, Types: models.SelectableTypes(), IsAdmin: app.isAdmin(r), Result: r.URL.Query().Get("result")}
+
+	// Let's fetch the latest 50 scraped items, or the best 50 matches
+	if v := r.URL.Query().Get("source"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id < 1 {
+			http.Error(w, "Bad Request", 400)
+			return
+		}
+
+		for _, t := range tallies {
+			if t.SourceID == id {
+				page.Source = t
+			}
+		}
+		// A source that hasn't produced anything yet still gets an (empty) view
+		if page.Source == nil {
+			src, err := app.sources.Get(id)
+			if err != nil {
+				if errors.Is(err, models.ErrNoRecord) {
+					app.notFound(w, r)
+				} else {
+					app.serverError(w, r, err)
+				}
+				return
+			}
+			page.Source = &models.SourceTally{SourceID: src.ID, SourceName: &src.Name}
+		}
+		page.Filter = id
+	}
+
+	// Searches cover the whole archive, narrowed to the filtered source if there is one
+	page.Query = strings.TrimSpace(r.URL.Query().Get("q"))
+	switch {
+	case page.Query != "":
+		page.Matches, err = app.scraper.Search(page.Query, page.Filter, 50)
+	case page.Filter != 0:
+		page.Items, err = app.scraper.LatestFromSource(page.Filter, 50)
+	default:
+		page.Items, err = app.scraper.Latest(50)
+	}
 	if err != nil {
-		http.Error(w, "Internal Server Error", 500)
+		app.serverError(w, r, err)
 		return
 	}
 
-	err = ts.ExecuteTemplate(w, "base", latestItems)
+	prices, err := app.prices.Summaries()
 	if err != nil {
-		http.Error(w, "Internal Server Error", 500)
+		app.serverError(w, r, err)
+		return
+	}
+	for _, p := range prices {
+		if page.Filter == 0 || p.SourceID == page.Filter {
+			page.Prices = append(page.Prices, p)
+		}
 	}
+	if page.Filter != 0 && len(page.Prices) > 0 {
+		if page.History, err = app.prices.History(page.Filter, 20); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	if page.IsAdmin {
+		page.Starred, err = app.queue.StarredItems()
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+
+		if page.Unseen, err = app.alerts.CountUnseen(); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		if page.Alerts, err = app.alerts.Unseen(5); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	// We'll create a simple scraper.tmpl page next
+	app.render(w, r, page, "pages/scraper.tmpl")
 }
 
 // interceptHandler fetches a random entry, corrupts it, and returns the HTML partial
@@ -241,24 +1241,24 @@ func (app *application) interceptHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Corrupt the title directly
-	entry.Title = utils.CorruptText(entry.Title, 15) // Light corruption on title
-
-	// For the content, since it might be markdown, we first grab the raw text
-	rawContent := utils.CorruptText(entry.Content, 20) // Medium corruption on content
+	// Each type declares how badly its content degrades; titles get off a bit lighter
+	severity := entry.TypeInfo().Corruption
+	entry.Title = utils.CorruptText(entry.Title, severity*3/4)
 
-	// And then we render it as markdown so backticks/headers still attempt to format
-	entry.Content = string(markdown.ToHTML([]byte(rawContent), nil, nil))
+	// The raw text is corrupted before rendering, so backticks/headers still attempt to
+	// format for the types that use Markdown
+	corrupted := utils.CorruptText(models.StringValue(entry.Content), severity)
+	entry.Content = &corrupted
 
-	ts, err := template.ParseFiles("./ui/html/partials/intercept.tmpl")
+	ts, err := template.New("intercept.tmpl").Funcs(templateFuncs).ParseFS(app.templates.fsys, "partials/intercept.tmpl")
 	if err != nil {
-		http.Error(w, "Internal Server Error", 500)
+		app.serverError(w, r, err)
 		return
 	}
 
 	// We execute the intercept.tmpl partial directly, bypassing the "base" template
 	err = ts.Execute(w, entry)
 	if err != nil {
-		http.Error(w, "Internal Server Error", 500)
+		app.serverError(w, r, err)
 	}
 }
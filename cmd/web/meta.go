@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+	"golang.org/x/net/html"
+)
+
+// metaDescriptionLength is how much text a link preview shows before cutting off.
+const metaDescriptionLength = 200
+
+// entryMeta is what chat apps and social sites read off an entry page to unfurl a
+// link to it: OpenGraph tags, which Twitter cards fall back on when theirs are missing.
+type entryMeta struct {
+	SiteName    string
+	Title       string
+	Description string // Plain text, the feed summary or the start of the content
+	URL         string // Absolute permalink
+	Image       string // Absolute URL of the first picture attached, empty if there is none
+	Published   string // RFC 3339
+}
+
+// newEntryMeta describes the entry for link previews. Everything is absolute since
+// the page is read from outside the station.
+func (app *application) newEntryMeta(site string, e *models.Entry, attachments []*models.Attachment) *entryMeta {
+	meta := &entryMeta{
+		SiteName:    app.siteIdentity().Title,
+		Title:       e.Title,
+		Description: truncate(metaDescriptionLength, htmlText(string(feedHTML(e)))),
+		URL:         site + e.Permalink(),
+		Published:   e.CreatedAt.Format(time.RFC3339),
+	}
+	for _, a := range attachments {
+		if a.IsImage() {
+			meta.Image = site + "/attachments/" + strconv.Itoa(a.ID)
+			break
+		}
+	}
+	return meta
+}
+
+// htmlText is the text of an HTML fragment with the markup dropped and whitespace
+// collapsed, e.g. for a rendered Markdown body.
+func htmlText(fragment string) string {
+	var b strings.Builder
+	z := html.NewTokenizer(strings.NewReader(fragment))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return strings.Join(strings.Fields(b.String()), " ")
+		case html.TextToken:
+			b.Write(z.Text())
+		case html.StartTagToken, html.EndTagToken:
+			// Block boundaries would otherwise run words together
+			b.WriteByte(' ')
+		}
+	}
+}
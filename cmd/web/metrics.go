@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/metrics"
+)
+
+// dbBuckets are upper bounds for statement durations; SQLite on local disk answers
+// most of them well under a millisecond.
+var dbBuckets = []float64{.0001, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, 1}
+
+// stationMetrics are the metrics updated as requests and statements happen; the rest
+// are read from the database when /metrics is scraped.
+type stationMetrics struct {
+	registry        *metrics.Registry
+	requests        *metrics.CounterVec   // route, method, status
+	requestDuration *metrics.HistogramVec // route, method
+	dbDuration      *metrics.HistogramVec // database, op
+}
+
+func newStationMetrics() *stationMetrics {
+	reg := &metrics.Registry{}
+	return &stationMetrics{
+		registry: reg,
+		requests: reg.NewCounterVec("sacrif_http_requests_total",
+			"HTTP requests answered, by route pattern, method and status code.", "route", "method", "status"),
+		requestDuration: reg.NewHistogramVec("sacrif_http_request_duration_seconds",
+			"Time taken to answer HTTP requests, by route pattern and method.", metrics.DefaultBuckets, "route", "method"),
+		dbDuration: reg.NewHistogramVec("sacrif_db_statement_duration_seconds",
+			"Time SQLite took to run a statement, by database and kind (query or exec).", dbBuckets, "database", "op"),
+	}
+}
+
+// registerCollectors adds the metrics that are read from the database at scrape time.
+func (app *application) registerCollectors() {
+	reg := app.metrics.registry
+
+	reg.GaugeFunc("sacrif_entries", "Entries in the archive, by type.", []string{"type"}, func() ([]metrics.Sample, error) {
+		counts, err := app.typeMigrations.Counts()
+		if err != nil {
+			return nil, err
+		}
+		// Unregistered keys all fall back to one type, and a series may only appear once
+		totals := map[string]int{}
+		var order []string
+		for _, c := range counts {
+			if _, ok := totals[c.Type.Key]; !ok {
+				order = append(order, c.Type.Key)
+			}
+			totals[c.Type.Key] += c.Entries
+		}
+		var samples []metrics.Sample
+		for _, key := range order {
+			samples = append(samples, metrics.Sample{Labels: []string{key}, Value: float64(totals[key])})
+		}
+		return samples, nil
+	})
+
+	reg.CounterFunc("sacrif_scraper_runs_total", "Scraper runs recorded, by source and outcome (ok or failed).",
+		[]string{"source", "outcome"}, func() ([]metrics.Sample, error) {
+			outcomes, err := app.scrapeRuns.Outcomes()
+			if err != nil {
+				return nil, err
+			}
+			var samples []metrics.Sample
+			for _, o := range outcomes {
+				samples = append(samples, metrics.Sample{Labels: []string{runSourceLabel(o.SourceID, o.SourceName), runOutcome(o.Failed)}, Value: float64(o.Runs)})
+			}
+			return samples, nil
+		})
+
+	reg.CounterFunc("sacrif_scraper_items_new_total", "New items scraper runs stored, by source.",
+		[]string{"source"}, func() ([]metrics.Sample, error) {
+			outcomes, err := app.scrapeRuns.Outcomes()
+			if err != nil {
+				return nil, err
+			}
+			totals := map[string]int{}
+			var order []string
+			for _, o := range outcomes {
+				label := runSourceLabel(o.SourceID, o.SourceName)
+				if _, ok := totals[label]; !ok {
+					order = append(order, label)
+				}
+				totals[label] += o.ItemsNew
+			}
+			var samples []metrics.Sample
+			for _, label := range order {
+				samples = append(samples, metrics.Sample{Labels: []string{label}, Value: float64(totals[label])})
+			}
+			return samples, nil
+		})
+
+	reg.GaugeFunc("sacrif_start_time_seconds", "When the station started, as a Unix timestamp.", nil, func() ([]metrics.Sample, error) {
+		return []metrics.Sample{{Value: float64(app.startedAt.Unix())}}, nil
+	})
+}
+
+func runSourceLabel(id int, name string) string {
+	if name == "" {
+		return fmt.Sprintf("#%d", id) // Deleted since
+	}
+	return name
+}
+
+func runOutcome(failed bool) string {
+	if failed {
+		return "failed"
+	}
+	return "ok"
+}
+
+// instrument counts and times every request by the route pattern it matched, which
+// keeps the label set as small as the route table. It must sit between the mux and
+// any middleware that copies the request, or the pattern the mux records is lost.
+func (app *application) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		route := "unmatched"
+		if r.Pattern != "" {
+			// Patterns carry their method ("GET /entries/{id}"), which has a label of its own
+			_, path, ok := strings.Cut(r.Pattern, " ")
+			if !ok {
+				path = r.Pattern
+			}
+			route = path
+		}
+		app.metrics.requests.Inc(route, r.Method, strconv.Itoa(status))
+		app.metrics.requestDuration.Observe(time.Since(start).Seconds(), route, r.Method)
+	})
+}
+
+// metricsHandler serves every metric for Prometheus GET /metrics
+//
+// With SACRIF_METRICS_TOKEN set, scrapers must send it as a bearer token.
+func (app *application) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	if app.metricsToken != "" {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(app.metricsToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := app.metrics.registry.Write(&buf); err != nil {
+		// What could be read is still worth serving
+		slog.ErrorContext(r.Context(), "Metrics collection failed", "err", err)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	buf.WriteTo(w)
+}
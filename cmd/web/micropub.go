@@ -0,0 +1,346 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/federicopalou/sacrif-station/internal/indieauth"
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// micropubType is what a Micropub post becomes; clients only ever write as the owner.
+const micropubType = "thought_admin"
+
+// micropubTitleRunes is how much of the first line of a post without a name is
+// used as its title.
+const micropubTitleRunes = 60
+
+// newMicropub sets up token checks from SACRIF_INDIEAUTH_TOKEN_ENDPOINT. Tokens must
+// be issued for SACRIF_INDIEAUTH_ME, which defaults to SACRIF_BASE_URL.
+func newMicropub(baseURL string) (*indieauth.Verifier, error) {
+	endpoint := os.Getenv("SACRIF_INDIEAUTH_TOKEN_ENDPOINT")
+	if endpoint == "" {
+		return nil, nil
+	}
+	if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("SACRIF_INDIEAUTH_TOKEN_ENDPOINT %q must be an http(s) URL", endpoint)
+	}
+	me := os.Getenv("SACRIF_INDIEAUTH_ME")
+	if me == "" {
+		me = baseURL
+	}
+	if me == "" {
+		return nil, errors.New("SACRIF_INDIEAUTH_TOKEN_ENDPOINT needs SACRIF_INDIEAUTH_ME or SACRIF_BASE_URL, the site tokens are issued for")
+	}
+	return indieauth.NewVerifier(endpoint, me), nil
+}
+
+// micropubLinks advertises the endpoints on the home page, which is where Micropub
+// clients look once the owner signs in with the site's URL.
+func (app *application) micropubLinks(w http.ResponseWriter) {
+	if app.micropub == nil {
+		return
+	}
+	w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="micropub"`, app.absoluteURL("/micropub")))
+	w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="token_endpoint"`, app.micropub.Endpoint))
+	if app.authEndpoint != "" {
+		w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="authorization_endpoint"`, app.authEndpoint))
+	}
+}
+
+// micropubQueryHandler answers client queries GET /micropub?q=
+//
+// q=config and q=syndicate-to describe the endpoint; q=source&url= returns a
+// thought's properties. New entries cross-post to every configured service anyway,
+// so there are no targets to pick from.
+func (app *application) micropubQueryHandler(w http.ResponseWriter, r *http.Request) {
+	if app.micropub == nil {
+		app.notFound(w, r)
+		return
+	}
+	if _, ok := app.micropubToken(w, r, ""); !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	switch q.Get("q") {
+	case "config":
+		writeMicropubJSON(w, http.StatusOK, map[string]any{
+			"syndicate-to": []any{},
+			"post-types":   []map[string]string{{"type": "note", "name": "Admin Log"}},
+		})
+	case "syndicate-to":
+		writeMicropubJSON(w, http.StatusOK, map[string]any{"syndicate-to": []any{}})
+	case "source":
+		entry, err := app.localEntry(q.Get("url"))
+		if errors.Is(err, models.ErrNoRecord) {
+			writeMicropubError(w, http.StatusBadRequest, "invalid_request", "no entry at that URL")
+			return
+		} else if err != nil {
+			slog.ErrorContext(r.Context(), "Micropub source error", "err", err)
+			writeMicropubError(w, http.StatusInternalServerError, "server_error", "")
+			return
+		}
+		props := map[string][]any{
+			"name":      {entry.Title},
+			"published": {entry.CreatedAt.Format(time.RFC3339)},
+		}
+		if entry.Content != nil {
+			props["content"] = []any{*entry.Content}
+		}
+		if entry.Mood != nil {
+			props["category"] = []any{*entry.Mood}
+		}
+		writeMicropubJSON(w, http.StatusOK, map[string]any{"type": []string{"h-entry"}, "properties": props})
+	default:
+		writeMicropubError(w, http.StatusBadRequest, "invalid_request", "unsupported query")
+	}
+}
+
+// micropubPostHandler publishes an Admin Log thought POST /micropub
+//
+// Both form-encoded and JSON h-entry bodies are taken. name becomes the title (the
+// start of the content otherwise), a category naming a mood sets it, and
+// in-reply-to either continues a local thought's thread or becomes a reply to an
+// outside URL. Updates and deletes are left to the admin pages.
+func (app *application) micropubPostHandler(w http.ResponseWriter, r *http.Request) {
+	if app.micropub == nil {
+		app.notFound(w, r)
+		return
+	}
+
+	// Nothing is parsed for a client without a valid token. A token sent as an
+	// access_token form field still means reading the form, hence the cap first
+	r.Body = http.MaxBytesReader(w, r.Body, maxAPIEntryBytes)
+	if _, ok := app.micropubToken(w, r, "create"); !ok {
+		return
+	}
+	props, err := parseMicropub(r)
+	if err != nil {
+		writeMicropubError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	in := &entryInput{Type: micropubType, Title: first(props["name"])}
+	content := first(props["content"])
+	if content != "" {
+		in.Content = &content
+	}
+	if in.Title == "" {
+		in.Title = titleFrom(content)
+	}
+	for _, c := range props["category"] {
+		if _, ok := models.MoodByKey(c); ok {
+			in.Mood = &c
+			break
+		}
+	}
+
+	var parent *models.Entry
+	if reply := first(props["in-reply-to"]); reply != "" {
+		parent, err = app.localEntry(reply)
+		if errors.Is(err, models.ErrNoRecord) {
+			in.URL = &reply
+		} else if err != nil {
+			slog.ErrorContext(r.Context(), "Micropub reply lookup failed", "err", err)
+			writeMicropubError(w, http.StatusInternalServerError, "server_error", "")
+			return
+		} else if !models.IsThoughtType(parent.Type) {
+			writeMicropubError(w, http.StatusBadRequest, "invalid_request", "replies are only allowed between thoughts")
+			return
+		}
+	}
+
+	entry, err := entryFromInput(in, "")
+	if err != nil {
+		writeMicropubError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if parent != nil {
+		entry.ParentID = &parent.ID
+	}
+
+	id, err := app.entries.Insert(entry)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Database insert error", "err", err)
+		writeMicropubError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+	entry.ID = id
+	app.entryCreated(entry)
+
+	stored, err := app.entries.Get(id)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Micropub entry error", "err", err)
+		writeMicropubError(w, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+	w.Header().Set("Location", app.absoluteURL(stored.Permalink()))
+	w.WriteHeader(http.StatusCreated)
+}
+
+// micropubToken checks the request's IndieAuth token, from the Authorization header
+// or, as the spec also allows, an access_token form field. An empty scope only
+// asks for a valid token.
+func (app *application) micropubToken(w http.ResponseWriter, r *http.Request, scope string) (*indieauth.Token, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		token = r.FormValue("access_token")
+	}
+	if token == "" {
+		writeMicropubError(w, http.StatusUnauthorized, "unauthorized", "no access token")
+		return nil, false
+	}
+
+	t, err := app.micropub.Verify(r.Context(), token)
+	if errors.Is(err, indieauth.ErrInvalidToken) {
+		writeMicropubError(w, http.StatusUnauthorized, "unauthorized", "the token endpoint doesn't accept this token for "+app.micropub.Me)
+		return nil, false
+	} else if err != nil {
+		slog.ErrorContext(r.Context(), "IndieAuth token check failed", "err", err)
+		writeMicropubError(w, http.StatusServiceUnavailable, "temporarily_unavailable", "couldn't reach the token endpoint")
+		return nil, false
+	}
+	if scope != "" && !t.HasScope(scope) {
+		writeMicropubError(w, http.StatusForbidden, "insufficient_scope", "the token lacks the "+scope+" scope")
+		return nil, false
+	}
+	return t, true
+}
+
+// parseMicropub reads a create request's properties, flattening JSON values to
+// strings: {"html": ...} and {"value": ...} content and nested objects alike.
+func parseMicropub(r *http.Request) (map[string][]string, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	if mediaType == "application/json" {
+		var body struct {
+			Type       []string                     `json:"type"`
+			Action     string                       `json:"action"`
+			Properties map[string][]json.RawMessage `json:"properties"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return nil, errors.New("malformed JSON: " + err.Error())
+		}
+		if body.Action != "" {
+			return nil, errors.New("only create is supported")
+		}
+		if len(body.Type) != 1 || body.Type[0] != "h-entry" {
+			return nil, errors.New("only h-entry posts are supported")
+		}
+		props := map[string][]string{}
+		for name, values := range body.Properties {
+			for _, raw := range values {
+				if v := jsonValue(raw); v != "" {
+					props[name] = append(props[name], v)
+				}
+			}
+		}
+		return props, nil
+	}
+
+	if err := r.ParseMultipartForm(maxAPIEntryBytes); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return nil, errors.New("malformed form: " + err.Error())
+	}
+	if r.PostForm.Get("action") != "" {
+		return nil, errors.New("only create is supported")
+	}
+	if h := r.PostForm.Get("h"); h != "entry" {
+		return nil, errors.New("only h=entry posts are supported")
+	}
+	props := map[string][]string{}
+	for name, values := range r.PostForm {
+		if name == "h" || name == "access_token" {
+			continue
+		}
+		name = strings.TrimSuffix(name, "[]")
+		for _, v := range values {
+			if v = strings.TrimSpace(v); v != "" {
+				props[name] = append(props[name], v)
+			}
+		}
+	}
+	return props, nil
+}
+
+// jsonValue turns one JSON property value into text; objects carry it in their
+// html, value or url member.
+func jsonValue(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return strings.TrimSpace(s)
+	}
+	var obj struct {
+		HTML  string `json:"html"`
+		Value string `json:"value"`
+		URL   string `json:"url"`
+	}
+	if json.Unmarshal(raw, &obj) != nil {
+		return ""
+	}
+	for _, v := range []string{obj.HTML, obj.Value, obj.URL} {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// localEntry finds the entry a URL on this station points at, matching just the
+// /entry/{slug} path so links copied from another host name still resolve.
+func (app *application) localEntry(rawURL string) (*models.Entry, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, models.ErrNoRecord
+	}
+	if base, err := url.Parse(app.baseURL); err == nil && u.Host != "" && !strings.EqualFold(u.Host, base.Host) {
+		return nil, models.ErrNoRecord
+	}
+	slug, ok := strings.CutPrefix(u.Path, "/entry/")
+	if !ok || slug == "" || strings.Contains(slug, "/") {
+		return nil, models.ErrNoRecord
+	}
+	return app.entries.GetBySlug(slug)
+}
+
+// titleFrom makes a title out of the first line of a post that has none, as notes
+// from phone clients never do.
+func titleFrom(content string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(content), "\n")
+	line = strings.TrimSpace(line)
+	if utf8.RuneCountInString(line) <= micropubTitleRunes {
+		return line
+	}
+	runes := []rune(line)
+	return strings.TrimSpace(string(runes[:micropubTitleRunes])) + "…"
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// writeMicropubError answers in the shape the Micropub spec gives errors.
+func writeMicropubError(w http.ResponseWriter, status int, code, description string) {
+	body := map[string]string{"error": code}
+	if description != "" {
+		body["error_description"] = description
+	}
+	writeMicropubJSON(w, status, body)
+}
+
+func writeMicropubJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeAPIJSON(w, status, v)
+}
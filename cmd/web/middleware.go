@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"strings"
+)
+
+// recoverPanics turns a panicking handler into a logged stack trace and a 500 page,
+// instead of a connection dropped without a reply.
+func (app *application) recoverPanics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w}
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			// Handlers abort streams on purpose this way; net/http knows to stay quiet about it
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+
+			slog.ErrorContext(r.Context(), "Handler panicked", "method", r.Method, "path", r.URL.Path,
+				"panic", fmt.Sprint(p), "stack", string(debug.Stack()))
+
+			// Past the headers there is no status left to change, only the body to cut short
+			if rec.status != 0 {
+				return
+			}
+			w.Header().Set("Connection", "close")
+			if strings.HasPrefix(r.URL.Path, "/api/") {
+				writeAPIError(w, http.StatusInternalServerError, "internal error")
+				return
+			}
+			app.renderError(w, r, http.StatusInternalServerError)
+		}()
+
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// requireAdmin bounces anonymous visitors to the login form before reaching admin handlers
+func (app *application) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !app.isAdmin(r) {
+			// Form posts can't be replayed after login, so only remember where GETs were headed
+			target := "/admin/login"
+			if r.Method == http.MethodGet {
+				target += "?next=" + url.QueryEscape(r.URL.RequestURI())
+			}
+			http.Redirect(w, r, target, http.StatusSeeOther)
+			return
+		}
+
+		// Admin pages must never be served from a shared cache
+		w.Header().Set("Cache-Control", "no-store")
+		next(w, r)
+	}
+}
+
+// rateLimited picks the requests the rate limiter counts: anything that writes, and
+// every API call, since those are what bots and scripts hammer. Page views are left
+// to the filter's throttle.
+func rateLimited(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return strings.HasPrefix(r.URL.Path, "/api/")
+	}
+	return true
+}
+
+// rateLimitRefused answers a rate-limited request in the format its caller expects
+func rateLimitRefused(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		writeAPIError(w, http.StatusTooManyRequests, "rate limit exceeded, see Retry-After")
+		return
+	}
+	http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+}
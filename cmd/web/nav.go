@@ -0,0 +1,117 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// navItem is one destination in the header's navigation.
+type navItem struct {
+	Label  string
+	Path   string
+	Admin  bool // Drawn in the operator's colour
+	Active bool // The sector the current page belongs to
+}
+
+// navItems are the header links, in order. A page belongs to the one whose path is
+// the longest prefix of its own, unless its data says otherwise.
+var navItems = []navItem{
+	{Label: "root", Path: "/"},
+	{Label: "media_compendium", Path: "/media"},
+	{Label: "organic_thoughts", Path: "/thoughts"},
+	{Label: "data_scraper", Path: "/scraper"},
+	{Label: "epochs", Path: "/epochs"},
+	{Label: "telemetry", Path: "/stats"},
+	{Label: "deep_scan", Path: "/search"},
+	{Label: "open_frequency", Path: "/transmit"},
+	{Label: "transmission_protocol", Path: "/admin", Admin: true},
+}
+
+// crumb is one step of the breadcrumb trail; the last has no link, being where the
+// visitor is.
+type crumb struct {
+	Label string
+	Path  string
+}
+
+// sectioned is page data that belongs to a sector its URL doesn't name, e.g. an
+// entry page under /entry/ that is part of the Media Compendium.
+type sectioned interface {
+	section() string // A navItems path
+}
+
+// trailed is page data that knows the breadcrumbs below its sector.
+type trailed interface {
+	crumbs() []crumb
+}
+
+// navigation marks the active sector and works out the breadcrumb trail for the page
+// at r, whose data may refine either.
+func navigation(r *http.Request, data any) ([]navItem, []crumb) {
+	section := ""
+	if s, ok := data.(sectioned); ok {
+		section = s.section()
+	} else {
+		for _, item := range navItems {
+			if underPath(r.URL.Path, item.Path) && len(item.Path) > len(section) {
+				section = item.Path
+			}
+		}
+	}
+
+	nav := make([]navItem, len(navItems))
+	trail := []crumb{{Label: navItems[0].Label, Path: navItems[0].Path}}
+	for i, item := range navItems {
+		item.Active = item.Path == section
+		if item.Active && item.Path != "/" {
+			trail = append(trail, crumb{Label: item.Label, Path: item.Path})
+		}
+		nav[i] = item
+	}
+
+	if t, ok := data.(trailed); ok {
+		trail = append(trail, t.crumbs()...)
+	} else if rest := strings.Trim(strings.TrimPrefix(r.URL.Path, section), "/"); rest != "" && section != "" {
+		// Deeper pages without a trail of their own are named by the rest of their path
+		trail = append(trail, crumb{Label: rest})
+	}
+
+	// The page itself isn't linked to
+	last := &trail[len(trail)-1]
+	if last.Path == r.URL.Path {
+		last.Path = ""
+	}
+	return nav, trail
+}
+
+// underPath reports whether path is prefix or below it; "/" only matches itself.
+func underPath(path, prefix string) bool {
+	if prefix == "/" {
+		return path == "/"
+	}
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// section puts an entry under the sector that lists it.
+func (p entryPage) section() string {
+	if models.IsThoughtType(p.Entry.Type) {
+		return "/thoughts"
+	}
+	return "/media"
+}
+
+func (p entryPage) crumbs() []crumb {
+	return []crumb{{Label: p.Entry.Title}}
+}
+
+func (p epochPage) crumbs() []crumb {
+	return []crumb{{Label: p.Epoch.Name}}
+}
+
+// crumbs names an error page by its status rather than by the path that failed.
+func (p errorPage) crumbs() []crumb {
+	return []crumb{{Label: strconv.Itoa(p.Status)}}
+}
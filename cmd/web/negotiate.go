@@ -0,0 +1,71 @@
+package main
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// negotiate picks the offered media type the client prefers according to its
+// Accept header. Offers are listed in the server's order of preference, and the
+// first one wins when the header is missing or nothing matches, so a browser
+// sending */* always gets the first offer. Each offer takes the q-value of the
+// most specific range covering it, so "*/*, text/html;q=0" refuses HTML.
+func negotiate(r *http.Request, offers ...string) string {
+	header := r.Header.Get("Accept")
+	if header == "" {
+		return offers[0]
+	}
+
+	type acceptRange struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType, q})
+	}
+
+	best, bestQ, bestSpecificity := offers[0], 0.0, -1
+	for _, offer := range offers {
+		q, specificity := 0.0, -1
+		for _, ar := range ranges {
+			if s := acceptMatch(ar.mediaType, offer); s > specificity {
+				q, specificity = ar.q, s
+			}
+		}
+		// Higher q wins; on a tie the more specific range wins (text/markdown over
+		// text/*), then the server's order
+		if q > bestQ || (q == bestQ && q > 0 && specificity > bestSpecificity) {
+			best, bestQ, bestSpecificity = offer, q, specificity
+		}
+	}
+	return best
+}
+
+// acceptMatch reports how specifically a media range from an Accept header covers
+// offer: 2 for an exact match, 1 for type/*, 0 for */* and -1 for no match.
+func acceptMatch(mediaRange, offer string) int {
+	if mediaRange == offer {
+		return 2
+	}
+	if mediaRange == "*/*" {
+		return 0
+	}
+	if prefix, ok := strings.CutSuffix(mediaRange, "/*"); ok && strings.HasPrefix(offer, prefix+"/") {
+		return 1
+	}
+	return -1
+}
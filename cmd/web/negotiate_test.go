@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	entryOffers := []string{mediaHTML, mediaJSON, mediaMarkdown, mediaText}
+	tests := []struct {
+		name   string
+		accept string
+		offers []string
+		want   string
+	}{
+		{"no header", "", entryOffers, mediaHTML},
+		{"browser", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", entryOffers, mediaHTML},
+		{"anything", "*/*", entryOffers, mediaHTML},
+		{"exact", "application/json", entryOffers, mediaJSON},
+		{"parameters and spacing", " text/markdown ; charset=utf-8 ", entryOffers, mediaMarkdown},
+		{"case-insensitive", "Application/JSON", entryOffers, mediaJSON},
+		{"nothing offered matches", "image/png", entryOffers, mediaHTML},
+		{"garbage", "not a media type", entryOffers, mediaHTML},
+
+		// q-values
+		{"higher q wins", "text/html;q=0.5, application/json", entryOffers, mediaJSON},
+		{"higher q wins whatever the order", "application/json;q=0.4, text/plain;q=0.6", entryOffers, mediaText},
+		{"wildcard outranks a lower exact q", "text/markdown;q=0.5, */*", entryOffers, mediaHTML},
+		{"unparseable q skips the range", "application/json;q=high, text/plain;q=0.1", entryOffers, mediaText},
+
+		// Wildcards
+		{"type wildcard", "text/*", []string{mediaJSON, mediaMarkdown}, mediaMarkdown},
+		{"type wildcard takes the first text offer", "text/*", entryOffers, mediaHTML},
+		{"specific range sets its own q", "text/*;q=0.3, text/plain", entryOffers, mediaText},
+		{"wildcard doesn't cross types", "application/*", []string{mediaHTML, mediaJSON}, mediaJSON},
+
+		// Ties
+		{"tie goes to the more specific range", "text/*, application/json", entryOffers, mediaJSON},
+		{"tie between exact ranges goes to server order", "text/plain, text/markdown", entryOffers, mediaMarkdown},
+		{"tie between wildcards goes to server order", "text/*, application/*", entryOffers, mediaHTML},
+
+		// q=0 refuses
+		{"q=0 alone", "text/html;q=0", entryOffers, mediaHTML},
+		{"q=0 excludes from a wildcard", "*/*, text/html;q=0", entryOffers, mediaJSON},
+		{"q=0 excludes a whole type", "text/*;q=0, */*;q=0.1", entryOffers, mediaJSON},
+		{"q=0 wildcard keeps exact ranges", "*/*;q=0, text/markdown", entryOffers, mediaMarkdown},
+		{"q=0.0 is refusal too", "application/json;q=0.0, */*;q=0.5", []string{mediaJSON, mediaText}, mediaText},
+		{"everything refused", "*/*;q=0", entryOffers, mediaHTML},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/entry/test", nil)
+		if tt.accept != "" {
+			r.Header.Set("Accept", tt.accept)
+		}
+		if got := negotiate(r, tt.offers...); got != tt.want {
+			t.Errorf("%s: negotiate(%q) = %q, want %q", tt.name, tt.accept, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+	"github.com/federicopalou/sacrif-station/internal/scraper"
+)
+
+// maxOPMLBytes caps an uploaded subscription list; thousands of feeds fit in far less.
+const maxOPMLBytes = 4 << 20
+
+// sourcesOPMLHandler downloads the feed sources as an OPML subscription list GET /admin/sources/opml
+//
+// Only sources of type feed are listed; other types are pages a feed reader can't follow.
+func (app *application) sourcesOPMLHandler(w http.ResponseWriter, r *http.Request) {
+	sources, err := app.sources.All()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	var subs []scraper.Subscription
+	for _, src := range sources {
+		if src.Type == "feed" {
+			subs = append(subs, scraper.Subscription{Title: src.Name, URL: src.URL})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := scraper.WriteOPML(&buf, app.siteIdentity().Title+" scraper feeds", subs); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/x-opml; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "sacrif-feeds.opml"}))
+	w.Write(buf.Bytes())
+}
+
+// sourcesOPMLPostHandler creates a feed source for every feed in an uploaded OPML file POST /admin/sources/opml
+//
+// Feeds already registered under the same URL are skipped, so importing the same
+// export twice is harmless. New sources start with the chosen interval and no filters.
+func (app *application) sourcesOPMLPostHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxOPMLBytes)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+	defer file.Close()
+
+	interval, err := strconv.Atoi(r.PostFormValue("interval"))
+	if err != nil || interval < 1 {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	subs, err := scraper.ReadOPML(file)
+	if err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), 400)
+		return
+	}
+
+	sources, err := app.sources.All()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	known := map[string]bool{}
+	for _, src := range sources {
+		known[src.URL] = true
+	}
+
+	added, skipped := 0, 0
+	for _, sub := range subs {
+		if known[sub.URL] {
+			skipped++
+			continue
+		}
+		src := &models.Source{Name: sub.Title, URL: sub.URL, Type: "feed", Config: "{}", Interval: interval}
+		if _, err := app.sources.Insert(src); err != nil {
+			app.serverError(w, r, err, "feed", sub.URL)
+			return
+		}
+		known[sub.URL] = true
+		added++
+	}
+
+	result := fmt.Sprintf("OPML import: %d feed(s) added, %d already registered", added, skipped)
+	http.Redirect(w, r, "/admin/sources?result="+url.QueryEscape(result), http.StatusSeeOther)
+}
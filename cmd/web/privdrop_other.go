@@ -0,0 +1,15 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+)
+
+// dropPrivileges is only supported on Unix-like systems.
+func dropPrivileges(spec string) error {
+	if spec == "" {
+		return nil
+	}
+	return errors.New("SACRIF_RUN_AS is not supported on this platform")
+}
@@ -0,0 +1,94 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// dropPrivileges switches the process to an unprivileged account once the port is bound.
+// The spec is "user" or "user:group", either as names or numeric IDs (handy in containers
+// without a passwd entry for the target account).
+func dropPrivileges(spec string) error {
+	if spec == "" {
+		return nil
+	}
+
+	userPart, groupPart, _ := strings.Cut(spec, ":")
+
+	uid, gid, err := lookupUser(userPart)
+	if err != nil {
+		return err
+	}
+
+	if groupPart != "" {
+		gid, err = lookupGroup(groupPart)
+		if err != nil {
+			return err
+		}
+	}
+
+	if os.Geteuid() != 0 {
+		// Already unprivileged: only fine if we are already the requested account
+		if os.Geteuid() == uid && os.Getegid() == gid {
+			return nil
+		}
+		return fmt.Errorf("cannot switch to %q: process is not running as root", spec)
+	}
+
+	// Order matters: supplementary groups and gid must go before we give up root via setuid
+	if err := syscall.Setgroups([]int{gid}); err != nil {
+		return fmt.Errorf("setgroups: %w", err)
+	}
+	if err := syscall.Setgid(gid); err != nil {
+		return fmt.Errorf("setgid: %w", err)
+	}
+	if err := syscall.Setuid(uid); err != nil {
+		return fmt.Errorf("setuid: %w", err)
+	}
+
+	return nil
+}
+
+func lookupUser(name string) (uid, gid int, err error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		u, err = user.LookupId(name)
+	}
+	if err != nil {
+		// Bare numeric IDs are allowed even without a passwd entry; the group defaults to the same ID
+		if id, convErr := strconv.Atoi(name); convErr == nil {
+			return id, id, nil
+		}
+		return 0, 0, fmt.Errorf("unknown user %q", name)
+	}
+
+	uid, err = strconv.Atoi(u.Uid)
+	if err != nil {
+		return 0, 0, err
+	}
+	gid, err = strconv.Atoi(u.Gid)
+	if err != nil {
+		return 0, 0, err
+	}
+	return uid, gid, nil
+}
+
+func lookupGroup(name string) (int, error) {
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		g, err = user.LookupGroupId(name)
+	}
+	if err != nil {
+		if id, convErr := strconv.Atoi(name); convErr == nil {
+			return id, nil
+		}
+		return 0, fmt.Errorf("unknown group %q", name)
+	}
+	return strconv.Atoi(g.Gid)
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// queuePage is the data handed to queue.tmpl
+type queuePage struct {
+	Up      []*models.QueueItem // Ready to read, in order
+	Snoozed []*models.QueueItem // Hidden until their snooze date, soonest first
+}
+
+// queueHandler renders the reading queue GET /queue
+func (app *application) queueHandler(w http.ResponseWriter, r *http.Request) {
+	items, err := app.queue.All()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	var page queuePage
+	now := time.Now()
+	for _, q := range items {
+		if q.Snoozed(now) {
+			page.Snoozed = append(page.Snoozed, q)
+		} else {
+			page.Up = append(page.Up, q)
+		}
+	}
+
+	// Snoozed items resurface in wake-up order, not queue order
+	slices.SortStableFunc(page.Snoozed, func(a, b *models.QueueItem) int {
+		return a.SnoozedUntil.Compare(*b.SnoozedUntil)
+	})
+
+	app.render(w, r, page, "pages/queue.tmpl")
+}
+
+// queuePostHandler queues a link entry (entry_id) or stars a scraper item (scraped_item_id) POST /queue
+func (app *application) queuePostHandler(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	q := &models.QueueItem{}
+
+	if id, err := strconv.Atoi(r.PostForm.Get("entry_id")); err == nil {
+		entry, err := app.entries.Get(id)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				http.Error(w, "Bad Request", 400)
+			} else {
+				app.serverError(w, r, err)
+			}
+			return
+		}
+
+		// Only entries that point somewhere are worth reading later
+		if entry.URL == nil {
+			http.Error(w, "Bad Request: entry has no link", 400)
+			return
+		}
+		q.EntryID, q.Title, q.URL = &entry.ID, entry.Title, entry.URL
+	} else if id, err := strconv.Atoi(r.PostForm.Get("scraped_item_id")); err == nil {
+		item, err := app.scraper.Get(id)
+		if err != nil {
+			if errors.Is(err, models.ErrNoRecord) {
+				http.Error(w, "Bad Request", 400)
+			} else {
+				app.serverError(w, r, err)
+			}
+			return
+		}
+		q.ScrapedItemID, q.Title, q.URL = &item.ID, item.Title, item.URL
+	} else {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	if err := app.queue.Insert(q); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/queue", http.StatusSeeOther)
+}
+
+// queueMovePostHandler shifts an item one place up or down POST /queue/{id}/move
+func (app *application) queueMovePostHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := app.queueIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	err := app.queue.Move(id, r.PostFormValue("direction") == "up")
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	http.Redirect(w, r, "/queue", http.StatusSeeOther)
+}
+
+// queueSnoozePostHandler hides an item for a number of days, or wakes it with days=0 POST /queue/{id}/snooze
+func (app *application) queueSnoozePostHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := app.queueIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	days, err := strconv.Atoi(r.PostFormValue("days"))
+	if err != nil || days < 0 || days > 365 {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	var until *time.Time
+	if days > 0 {
+		t := time.Now().AddDate(0, 0, days)
+		until = &t
+	}
+
+	err = app.queue.Snooze(id, until)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	http.Redirect(w, r, "/queue", http.StatusSeeOther)
+}
+
+// queueDonePostHandler takes a read item off the queue POST /queue/{id}/done
+func (app *application) queueDonePostHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := app.queueIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := app.queue.Delete(id); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/queue", http.StatusSeeOther)
+}
+
+// queueIDFromPath parses the {id} path segment, answering 404 when it isn't a valid ID.
+func (app *application) queueIDFromPath(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w, r)
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+	"github.com/federicopalou/sacrif-station/internal/utils"
+	"github.com/federicopalou/sacrif-station/ui"
+	"github.com/gomarkdown/markdown"
+)
+
+// templateFuncs is available to every page template
+var templateFuncs = template.FuncMap{
+	"renderMarkdown": func(text string) template.HTML {
+		return template.HTML(markdown.ToHTML([]byte(text), nil, nil))
+	},
+	"bytes":        models.HumanBytes,
+	"entryContent": entryContent,
+	"static":       assets.url,
+	"humandate":    humanDate,
+	"truncate":     truncate,
+	"pluralize":    pluralize,
+	// decayTitle is the entry's title as worn down by age, a little lighter than its content
+	"decayTitle": func(e *models.Entry) string {
+		return utils.DecayText(e.Title, e.Decay*3/4, int64(e.ID))
+	},
+	// highlight escapes marked search text, then turns its match markers into <mark> tags
+	"highlight": func(marked string) template.HTML {
+		escaped := template.HTMLEscapeString(marked)
+		return template.HTML(matchMarkers.Replace(escaped))
+	},
+	// card renders the named partial, e.g. {{card .TypeInfo.Card .}}. It is bound to
+	// each parsed template set in parseTemplates; this stub only declares it for parsing.
+	"card": func(name string, data any) (template.HTML, error) {
+		return "", fmt.Errorf("card %q: template set not bound", name)
+	},
+}
+
+// entryContent renders an entry's content as its type asks: Markdown, or a plain escaped
+// paragraph. Listings that age their entries get the decayed text, seeded by the entry
+// so it reads the same on every reload.
+func entryContent(e *models.Entry) template.HTML {
+	text := utils.DecayText(models.StringValue(e.Content), e.Decay, int64(e.ID))
+	if e.TypeInfo().Markdown {
+		return template.HTML(markdown.ToHTML([]byte(text), nil, nil))
+	}
+	return template.HTML("<p>" + template.HTMLEscapeString(text) + "</p>")
+}
+
+// humanDate says how long ago t was for the past week, e.g. {{humandate .CreatedAt}}
+// gives "3 hours ago", and the date itself before that.
+func humanDate(t time.Time) string {
+	d := time.Since(t)
+	switch {
+	case d < 0:
+		return t.Format("Jan 02, 2006 at 15:04")
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return pluralize(int(d/time.Minute), "minute", "minutes") + " ago"
+	case d < 24*time.Hour:
+		return pluralize(int(d/time.Hour), "hour", "hours") + " ago"
+	case d < 7*24*time.Hour:
+		return pluralize(int(d/(24*time.Hour)), "day", "days") + " ago"
+	}
+	return t.Format("Jan 02, 2006")
+}
+
+// truncate shortens text to at most n characters, cutting at a word where it can and
+// marking the cut with an ellipsis: {{truncate 160 .Message}}.
+func truncate(n int, text string) string {
+	runes := []rune(strings.TrimSpace(text))
+	if len(runes) <= n {
+		return string(runes)
+	}
+	cut := string(runes[:n])
+	if i := strings.LastIndexFunc(cut, unicode.IsSpace); i > n/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimRightFunc(cut, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsPunct(r) }) + "…"
+}
+
+// pluralize puts a count in front of the right form of its noun:
+// {{pluralize (len .Hits) "record" "records"}}.
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return "1 " + singular
+	}
+	return strconv.Itoa(n) + " " + plural
+}
+
+// matchMarkers swaps search match markers for <mark> tags
+var matchMarkers = strings.NewReplacer(models.MatchStart, "<mark>", models.MatchEnd, "</mark>")
+
+// errorPage is the data handed to error.tmpl
+type errorPage struct {
+	Status    int
+	Message   string
+	RequestID string // Quoted on the page so a report can be matched to the logs
+}
+
+// pageData is what base.tmpl executes with: the layout's own data, and the page's,
+// which the page's "title" and "main" templates get as their dot.
+type pageData struct {
+	Layout layoutData
+	Page   any
+}
+
+// layoutData is what the chrome around every page needs from the request.
+type layoutData struct {
+	Site   *models.Site // The station's title, tagline and footer, from /admin/settings
+	Theme  theme
+	Themes []theme
+	Path   string // Path and query of the page, for forms that come back to it
+	Flash  string // Message left by the request that redirected here, shown once
+	Nav    []navItem
+	Trail  []crumb // Breadcrumbs from the root down to the page
+}
+
+func (app *application) layout(w http.ResponseWriter, r *http.Request, data any) layoutData {
+	nav, trail := navigation(r, data)
+	return layoutData{Site: app.siteIdentity(), Theme: app.themeFor(r), Themes: themes, Path: r.URL.RequestURI(), Flash: app.popFlash(w, r), Nav: nav, Trail: trail}
+}
+
+// render executes base.tmpl together with the given page and partial files (relative
+// to ui/html). Output is buffered, so a template that fails halfway through produces
+// the themed error page instead of a half-written HTML body.
+func (app *application) render(w http.ResponseWriter, r *http.Request, data any, files ...string) {
+	body, ok := app.renderPage(w, r, data, files...)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(body)
+}
+
+// renderPage executes a page into memory, answering with the error page itself when
+// that fails.
+func (app *application) renderPage(w http.ResponseWriter, r *http.Request, data any, files ...string) ([]byte, bool) {
+	ts, err := app.templates.get(files...)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Template failed to parse", "templates", files, "err", err)
+		app.renderError(w, r, http.StatusInternalServerError)
+		return nil, false
+	}
+
+	// The theme comes from a cookie, so caches must keep a copy per cookie
+	w.Header().Add("Vary", "Cookie")
+
+	var buf bytes.Buffer
+	if err := ts.ExecuteTemplate(&buf, "base", pageData{Layout: app.layout(w, r, data), Page: data}); err != nil {
+		slog.ErrorContext(r.Context(), "Template failed rendering", "template", files[len(files)-1], "data", fmt.Sprintf("%T", data), "method", r.Method, "path", r.URL.Path, "err", err)
+		app.renderError(w, r, http.StatusInternalServerError)
+		return nil, false
+	}
+	return buf.Bytes(), true
+}
+
+// resourcer is page data that also has a JSON form.
+type resourcer interface {
+	resource(app *application) (any, error)
+}
+
+// renderNegotiated renders a page as render does, or answers with its JSON form when
+// the client's Accept header prefers application/json, so one handler serves both.
+// Either way the answer carries an ETag and conditional requests get a 304.
+func (app *application) renderNegotiated(w http.ResponseWriter, r *http.Request, data resourcer, files ...string) {
+	w.Header().Add("Vary", "Accept")
+	if negotiate(r, mediaHTML, mediaJSON) == mediaJSON {
+		res, err := data.resource(app)
+		var body []byte
+		if err == nil {
+			body, err = json.MarshalIndent(res, "", "  ")
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Page JSON failed to load", "path", r.URL.Path, "err", err)
+			writeAPIError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+		writeConditional(w, r, "application/json; charset=utf-8", append(body, '\n'), time.Time{})
+		return
+	}
+
+	body, ok := app.renderPage(w, r, data, files...)
+	if !ok {
+		return
+	}
+	writeConditional(w, r, "text/html; charset=utf-8", body, time.Time{})
+}
+
+// renderFragment executes just the named template from the set, without base.tmpl's
+// page around it, for htmx to swap into a page that is already showing.
+func (app *application) renderFragment(w http.ResponseWriter, r *http.Request, name string, data any, files ...string) {
+	ts, err := app.templates.get(files...)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Template failed to parse", "templates", files, "err", err)
+		app.renderError(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := ts.ExecuteTemplate(&buf, name, data); err != nil {
+		slog.ErrorContext(r.Context(), "Fragment failed rendering", "template", name, "data", fmt.Sprintf("%T", data), "method", r.Method, "path", r.URL.Path, "err", err)
+		app.renderError(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	buf.WriteTo(w)
+}
+
+// errorTemplates are the pages with a look of their own; every other status gets the
+// generic error.tmpl.
+var errorTemplates = map[int]string{
+	http.StatusNotFound:            "pages/404.tmpl",
+	http.StatusInternalServerError: "pages/500.tmpl",
+}
+
+// notFound answers with the themed 404 page.
+func (app *application) notFound(w http.ResponseWriter, r *http.Request) {
+	app.renderError(w, r, http.StatusNotFound)
+}
+
+// serverError logs err, with any extra key/value pairs, and answers with the themed
+// 500 page, which quotes the request ID the log line carries.
+func (app *application) serverError(w http.ResponseWriter, r *http.Request, err error, args ...any) {
+	args = append([]any{"method", r.Method, "path", r.URL.Path}, args...)
+	slog.ErrorContext(r.Context(), "Request failed", append(args, "err", err)...)
+	app.renderError(w, r, http.StatusInternalServerError)
+}
+
+// renderError serves the themed page for status, falling back to plain text if even
+// that can't be rendered.
+func (app *application) renderError(w http.ResponseWriter, r *http.Request, status int) {
+	data := errorPage{Status: status, Message: http.StatusText(status), RequestID: requestID(r.Context())}
+
+	page, ok := errorTemplates[status]
+	if !ok {
+		page = "pages/error.tmpl"
+	}
+
+	var buf bytes.Buffer
+	ts, err := app.templates.get(page)
+	if err == nil {
+		err = ts.ExecuteTemplate(&buf, "base", pageData{Layout: app.layout(w, r, data), Page: data})
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error page failed to render", "template", page, "err", err)
+		http.Error(w, data.Message, status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
+
+func parseTemplates(fsys fs.FS, files ...string) (*template.Template, error) {
+	ts, err := template.New("base.tmpl").Funcs(templateFuncs).ParseFS(fsys, append([]string{"base.tmpl"}, files...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cards are looked up by name at run time, so the partials a type declares only
+	// need to be among the parsed files; a type whose card isn't gets the generic one
+	return ts.Funcs(template.FuncMap{
+		"card": func(name string, data any) (template.HTML, error) {
+			if name == "" || ts.Lookup(name) == nil {
+				name = models.GenericCard
+			}
+			var buf bytes.Buffer
+			if err := ts.ExecuteTemplate(&buf, name, data); err != nil {
+				return "", err
+			}
+			return template.HTML(buf.String()), nil
+		},
+	}), nil
+}
+
+// templateCache keeps parsed template sets, keyed by their file list, so pages aren't
+// parsed again on every request. The templates compiled into the binary are parsed
+// once; templates read from disk are parsed afresh when any of their files has
+// changed since, which keeps template edits live without a restart. In development
+// nothing is kept, so even a change the mod times miss shows up.
+type templateCache struct {
+	fsys    fs.FS  // Rooted at ui/html
+	live    bool   // fsys is a directory on disk, whose files can change
+	dir     string // That directory, when live
+	reparse bool   // Development: parse on every get
+
+	mu   sync.Mutex
+	sets map[string]*cachedTemplates
+}
+
+type cachedTemplates struct {
+	ts       *template.Template
+	modTimes []time.Time // Of base.tmpl and the files, in order
+}
+
+// newTemplateCache serves the embedded templates, or with SACRIF_TEMPLATE_DIR set, the
+// ones in that directory, for working on them without rebuilding. Development mode
+// reads ./ui/html unless told otherwise, and never caches.
+func newTemplateCache(dev bool) (*templateCache, error) {
+	dir := os.Getenv("SACRIF_TEMPLATE_DIR")
+	if dir == "" && dev {
+		dir = "./ui/html"
+	}
+	if dir == "" {
+		fsys, err := fs.Sub(ui.Files, "html")
+		if err != nil {
+			return nil, err
+		}
+		return &templateCache{fsys: fsys}, nil
+	}
+
+	fsys := os.DirFS(dir)
+	if _, err := fs.Stat(fsys, "base.tmpl"); err != nil {
+		return nil, fmt.Errorf("template directory %q holds no base.tmpl: %w", dir, err)
+	}
+	return &templateCache{fsys: fsys, live: true, dir: dir, reparse: dev}, nil
+}
+
+// get returns the parsed set for the files, parsing it if it isn't cached or is stale.
+func (c *templateCache) get(files ...string) (*template.Template, error) {
+	if c.reparse {
+		return parseTemplates(c.fsys, files...)
+	}
+	key := strings.Join(files, "|")
+
+	var modTimes []time.Time
+	if c.live {
+		var err error
+		if modTimes, err = c.modTimes(files); err != nil {
+			return nil, err
+		}
+	}
+
+	c.mu.Lock()
+	cached, ok := c.sets[key]
+	c.mu.Unlock()
+	if ok && slices.EqualFunc(cached.modTimes, modTimes, time.Time.Equal) {
+		return cached.ts, nil
+	}
+
+	ts, err := parseTemplates(c.fsys, files...)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sets == nil {
+		c.sets = map[string]*cachedTemplates{}
+	}
+	c.sets[key] = &cachedTemplates{ts: ts, modTimes: modTimes}
+	return ts, nil
+}
+
+func (c *templateCache) modTimes(files []string) ([]time.Time, error) {
+	times := make([]time.Time, 0, len(files)+1)
+	for _, f := range append([]string{"base.tmpl"}, files...) {
+		info, err := fs.Stat(c.fsys, f)
+		if err != nil {
+			return nil, err
+		}
+		times = append(times, info.ModTime())
+	}
+	return times, nil
+}
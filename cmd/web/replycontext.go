@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+	"github.com/federicopalou/sacrif-station/internal/replycontext"
+)
+
+const jobReplyContext = "reply_context"
+
+// replyContextJob is the payload of a "reply_context" job.
+type replyContextJob struct {
+	EntryID int `json:"entry_id"`
+}
+
+// enqueueReplyContext queues a snapshot fetch when an entry links to a post we can quote.
+// Entries whose URL stopped pointing at one still get a job, so the stale snapshot is dropped.
+func (app *application) enqueueReplyContext(entry *models.Entry, hadContext bool) {
+	if !hadContext && !replycontext.Supported(models.StringValue(entry.URL)) {
+		return
+	}
+
+	if _, err := app.jobs.Enqueue(jobReplyContext, replyContextJob{EntryID: entry.ID}); err != nil {
+		slog.Error("Failed to enqueue reply context job", "err", err)
+	}
+}
+
+// runReplyContextJob fetches and stores the post an entry's URL refers to.
+func (app *application) runReplyContextJob(ctx context.Context, payload []byte) error {
+	var job replyContextJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return err
+	}
+
+	entry, err := app.entries.Get(job.EntryID)
+	if errors.Is(err, models.ErrNoRecord) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	snap, err := replycontext.Fetch(ctx, models.StringValue(entry.URL))
+	if errors.Is(err, replycontext.ErrUnsupported) {
+		return app.replyContexts.Delete(entry.ID)
+	}
+	if err != nil {
+		return err
+	}
+
+	return app.replyContexts.Upsert(&models.ReplyContext{
+		EntryID:     entry.ID,
+		Service:     snap.Service,
+		URL:         snap.URL,
+		Author:      snap.Author,
+		AuthorURL:   models.NullString(snap.AuthorURL),
+		Content:     snap.Text,
+		PublishedAt: snap.PublishedAt,
+	})
+}
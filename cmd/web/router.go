@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// routeMethods are the methods probed for when a path is known but the request's
+// method isn't, to fill the Allow header.
+var routeMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// router is the station's ServeMux with its misses answered properly. A path that
+// only exists without its trailing slash redirects there, a path served under other
+// methods gets a 405 listing them in Allow, and anything else gets the themed 404.
+// Under /api/ the errors are JSON, as the rest of the API's are.
+type router struct {
+	app *application
+	mux *http.ServeMux
+}
+
+func newRouter(app *application) *router {
+	return &router{app: app, mux: http.NewServeMux()}
+}
+
+// HandleFunc registers a route, with the same patterns as http.ServeMux.
+func (rt *router) HandleFunc(pattern string, handler http.HandlerFunc) {
+	rt.mux.HandleFunc(pattern, handler)
+}
+
+func (rt *router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The mux itself serves every match, so r.Pattern is still set for the metrics
+	if rt.matches(r, r.Method, r.URL.Path) {
+		rt.mux.ServeHTTP(w, r)
+		return
+	}
+
+	if trimmed := strings.TrimRight(r.URL.Path, "/"); trimmed != r.URL.Path && trimmed != "" && rt.matches(r, r.Method, trimmed) {
+		target := trimmed
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		status := http.StatusPermanentRedirect // Keeps the method and body
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			status = http.StatusMovedPermanently
+		}
+		http.Redirect(w, r, target, status)
+		return
+	}
+
+	var allowed []string
+	for _, method := range routeMethods {
+		if rt.matches(r, method, r.URL.Path) {
+			allowed = append(allowed, method)
+		}
+	}
+	switch {
+	case len(allowed) > 0 && r.Method == http.MethodOptions:
+		w.Header().Set("Allow", strings.Join(append(allowed, http.MethodOptions), ", "))
+		w.WriteHeader(http.StatusNoContent)
+	case len(allowed) > 0:
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		rt.fail(w, r, http.StatusMethodNotAllowed)
+	default:
+		rt.fail(w, r, http.StatusNotFound)
+	}
+}
+
+// matches reports whether some route serves the path under the method.
+func (rt *router) matches(r *http.Request, method, path string) bool {
+	probe := r
+	if method != r.Method || path != r.URL.Path {
+		probe = r.Clone(r.Context())
+		probe.Method = method
+		probe.URL.Path = path
+		probe.URL.RawPath = ""
+	}
+	_, pattern := rt.mux.Handler(probe)
+	return pattern != ""
+}
+
+func (rt *router) fail(w http.ResponseWriter, r *http.Request, status int) {
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		msg := "no such endpoint"
+		if status == http.StatusMethodNotAllowed {
+			msg = "method not allowed"
+		}
+		writeAPIError(w, status, msg)
+		return
+	}
+	rt.app.renderError(w, r, status)
+}
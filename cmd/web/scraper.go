@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// scraperPromotePostHandler turns a scraped item into an entry of the chosen type POST /admin/scraper/{id}/promote
+func (app *application) scraperPromotePostHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w, r)
+		return
+	}
+
+	item, err := app.scraper.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// Promoting twice would just create a duplicate entry
+	if item.PromotedEntryID != nil {
+		http.Redirect(w, r, fmt.Sprintf("/entry/%d", *item.PromotedEntryID), http.StatusSeeOther)
+		return
+	}
+
+	entryType := r.PostFormValue("type")
+	if t, known := models.TypeByKey(entryType); !known || t.Retired {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	entry := item.ToEntry(entryType)
+	entry.ID, err = app.entries.Insert(entry)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// The entry exists either way; a failed mark only means the button stays visible
+	if err := app.scraper.MarkPromoted(item.ID, entry.ID); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to mark scraped item as promoted", "item", item.ID, "err", err)
+	}
+
+	app.entryCreated(entry)
+
+	http.Redirect(w, r, entry.Permalink(), http.StatusSeeOther)
+}
+
+// scraperDismissPostHandler hides a scraped item POST /admin/scraper/{id}/dismiss
+func (app *application) scraperDismissPostHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w, r)
+		return
+	}
+
+	if err := app.scraper.Dismiss(id); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/scraper", http.StatusSeeOther)
+}
+
+// scraperDeletePostHandler removes a scraped item POST /admin/scraper/{id}/delete
+func (app *application) scraperDeletePostHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w, r)
+		return
+	}
+
+	if err := app.scraper.Delete(id); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/scraper", http.StatusSeeOther)
+}
+
+// scraperClearPostHandler dismisses (or with mode=delete, removes) everything scraped
+// before a date POST /admin/scraper/clear
+func (app *application) scraperClearPostHandler(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	before, err := time.Parse(time.DateOnly, r.PostForm.Get("before"))
+	if err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	var n int64
+	verb := "dismissed"
+	if r.PostForm.Get("mode") == "delete" {
+		verb = "deleted"
+		n, err = app.scraper.DeleteBefore(before)
+	} else {
+		n, err = app.scraper.DismissBefore(before)
+	}
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	result := fmt.Sprintf("%d item(s) from before %s %s", n, before.Format("Jan 02, 2006"), verb)
+	http.Redirect(w, r, "/scraper?result="+url.QueryEscape(result), http.StatusSeeOther)
+}
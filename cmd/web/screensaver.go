@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+	"github.com/federicopalou/sacrif-station/internal/utils"
+)
+
+const (
+	screensaverInterval = 10 * time.Second // Between frames, unless ?interval= says otherwise
+	fragmentLength      = 280              // Characters of content a fragment shows at most
+)
+
+// screensaverFrame is one screen of the ambient display: a corrupted entry
+// fragment or a burst of scraper telemetry.
+type screensaverFrame struct {
+	Kind  string   `json:"kind"` // "fragment" or "telemetry"
+	Label string   `json:"label"`
+	Title string   `json:"title"`
+	Lines []string `json:"lines"`
+	At    string   `json:"at"`
+}
+
+// screensaverPage is the data handed to screensaver.tmpl
+type screensaverPage struct {
+	Site     *models.Site // Bypassing "base", the page gets no layout and carries its own
+	Frame    *screensaverFrame
+	Interval int // Seconds
+}
+
+// screensaverHandler renders the full-screen ambient display GET /screensaver
+//
+// The first frame is rendered on the server; the page then follows /screensaver/stream,
+// and without JavaScript it simply reloads every interval.
+func (app *application) screensaverHandler(w http.ResponseWriter, r *http.Request) {
+	interval := screensaverIntervalParam(r)
+	page := screensaverPage{Site: app.siteIdentity(), Frame: app.screensaverFrame(), Interval: int(interval / time.Second)}
+
+	ts, err := app.templates.get("pages/screensaver.tmpl")
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Template pages/screensaver.tmpl failed to parse", "err", err)
+		app.renderError(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	// No site chrome on a wall display, so the page bypasses "base"
+	var buf bytes.Buffer
+	if err := ts.ExecuteTemplate(&buf, "screensaver", page); err != nil {
+		slog.ErrorContext(r.Context(), "Template pages/screensaver.tmpl failed rendering", "err", err)
+		app.renderError(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	buf.WriteTo(w)
+}
+
+// screensaverStreamHandler sends a new frame every interval as server-sent events
+// GET /screensaver/stream
+func (app *application) screensaverStreamHandler(w http.ResponseWriter, r *http.Request) {
+	interval := screensaverIntervalParam(r)
+	rc := http.NewResponseController(w)
+
+	// The request was read long ago; the read timeout would otherwise end the stream
+	rc.SetReadDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Keep reverse proxies from holding frames back
+
+	// A dropped display reconnects on its own; tell it not to wait long
+	fmt.Fprintf(w, "retry: %d\n\n", (5 * time.Second).Milliseconds())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		data, err := json.Marshal(app.screensaverFrame())
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(w, "event: frame\ndata: %s\n\n", data)
+		if err := rc.Flush(); err != nil {
+			slog.ErrorContext(r.Context(), "Screensaver stream can't flush", "err", err)
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// screensaverIntervalParam reads ?interval= in seconds, kept between 3s and 5m.
+func screensaverIntervalParam(r *http.Request) time.Duration {
+	secs, err := strconv.Atoi(r.URL.Query().Get("interval"))
+	if err != nil {
+		return screensaverInterval
+	}
+	return time.Duration(min(max(secs, 3), 300)) * time.Second
+}
+
+// screensaverFrame picks the next frame: mostly entry fragments, with telemetry
+// every so often. Anything that fails to load turns into a frame about the failure.
+func (app *application) screensaverFrame() *screensaverFrame {
+	now := time.Now().Format("15:04:05")
+
+	if rand.Intn(4) == 0 {
+		frame, err := app.telemetryFrame()
+		if err == nil {
+			frame.At = now
+			return frame
+		}
+		slog.Error("Screensaver telemetry error", "err", err)
+	}
+
+	entry, err := app.entries.RandomEntry()
+	if err != nil {
+		return &screensaverFrame{
+			Kind:  "fragment",
+			Label: "[sys.void]",
+			Title: utils.CorruptText("NO SIGNAL", 60),
+			Lines: []string{utils.CorruptText("The archive is silent. Waiting for transmissions.", 70)},
+			At:    now,
+		}
+	}
+
+	// Heavier than the intercept: on a wall display the noise is the point
+	t := entry.TypeInfo()
+	severity := min(t.Corruption*2+30, 90)
+	frame := &screensaverFrame{
+		Kind:  "fragment",
+		Label: t.Icon,
+		Title: utils.CorruptText(entry.Title, severity*3/4),
+		At:    entry.CreatedAt.Format("2006-01-02 15:04"),
+	}
+	if text := fragment(models.StringValue(entry.Content)); text != "" {
+		frame.Lines = []string{utils.CorruptText(text, severity)}
+	}
+	return frame
+}
+
+// telemetryFrame reports what the scraper has been doing lately.
+func (app *application) telemetryFrame() (*screensaverFrame, error) {
+	runs, err := app.scrapeRuns.Latest(5)
+	if err != nil {
+		return nil, err
+	}
+	total, err := app.entries.Count()
+	if err != nil {
+		return nil, err
+	}
+
+	frame := &screensaverFrame{Kind: "telemetry", Label: "[sys.telemetry]", Title: "SCRAPER TELEMETRY"}
+	frame.Lines = append(frame.Lines, fmt.Sprintf("ARCHIVE......... %d entries", total))
+	if len(runs) == 0 {
+		frame.Lines = append(frame.Lines, "UPLINK.......... idle, no runs logged")
+	}
+	for _, run := range runs {
+		name := run.SourceName
+		if name == "" {
+			name = fmt.Sprintf("source #%d", run.SourceID)
+		}
+		status := fmt.Sprintf("+%d/%d", run.ItemsNew, run.ItemsFound)
+		if run.Error != nil {
+			status = "FAULT"
+		}
+		frame.Lines = append(frame.Lines, fmt.Sprintf("%s %-24.24s %-8s %s",
+			run.StartedAt.Local().Format("15:04"), strings.ToUpper(name), status, run.Duration.Round(time.Millisecond)))
+	}
+	return frame, nil
+}
+
+// fragment cuts a random window of whole words out of text, at most fragmentLength
+// characters long.
+func fragment(text string) string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return ""
+	}
+
+	// Start anywhere, but far enough from the end to fill the fragment when the text allows
+	start := rand.Intn(len(words))
+	for rest := len(strings.Join(words[start:], " ")); start > 0 && rest < fragmentLength; start-- {
+		rest += len(words[start-1]) + 1
+	}
+
+	var b strings.Builder
+	for _, word := range words[start:] {
+		if b.Len()+len(word)+1 > fragmentLength {
+			break
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(word)
+	}
+	return b.String()
+}
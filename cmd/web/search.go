@@ -0,0 +1,193 @@
+package main
+
+import (
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// searchLimit caps how many results one search shows.
+const searchLimit = 50
+
+// searchPage is the data handed to search.tmpl
+type searchPage struct {
+	Query   string
+	Results []*models.SearchResult
+	Sectors []searchSector // Results grouped by sector, leaving out empty ones
+	IsAdmin bool
+}
+
+// searchSector is the results from one sector, kept in rank order
+type searchSector struct {
+	Name    string
+	Link    string
+	Results []*models.SearchResult
+}
+
+// groupBySector splits ranked results into the Media Compendium and Organic Thoughts.
+func groupBySector(results []*models.SearchResult) []searchSector {
+	media := searchSector{Name: "Media Compendium", Link: "/media"}
+	thoughts := searchSector{Name: "Organic Thoughts", Link: "/thoughts"}
+	for _, res := range results {
+		if res.Entry.TypeInfo().Thought {
+			thoughts.Results = append(thoughts.Results, res)
+		} else {
+			media.Results = append(media.Results, res)
+		}
+	}
+
+	var sectors []searchSector
+	for _, s := range []searchSector{media, thoughts} {
+		if len(s.Results) > 0 {
+			sectors = append(sectors, s)
+		}
+	}
+	return sectors
+}
+
+// searchHandler runs a full-text search over the entries GET /search?q=
+func (app *application) searchHandler(w http.ResponseWriter, r *http.Request) {
+	page := searchPage{Query: strings.TrimSpace(r.URL.Query().Get("q")), IsAdmin: app.isAdmin(r)}
+
+	if page.Query != "" {
+		var err error
+		page.Results, err = app.search.Search(page.Query, searchLimit)
+		if err != nil {
+			app.serverError(w, r, err, "query", page.Query)
+			return
+		}
+		page.Sectors = groupBySector(page.Results)
+	}
+
+	app.render(w, r, page, "pages/search.tmpl")
+}
+
+// Limits on the admin search: candidates read from each store, and results shown.
+const (
+	adminSearchPerStore = 100
+	adminSearchLimit    = 100
+)
+
+// searchHit is one admin search result, from whichever store it came from
+type searchHit struct {
+	Store   string // "entry", "scraped", "transmission" or "source"
+	Title   string // Marked with models.MatchStart/MatchEnd
+	Snippet string // Marked excerpt, may be empty
+	Link    string
+	Note    string // Why the public can't see it, e.g. "dismissed" or "pending"
+	When    time.Time
+	score   int
+}
+
+// adminSearchPage is the data handed to admin-search.tmpl
+type adminSearchPage struct {
+	Query string
+	Hits  []*searchHit
+}
+
+// adminSearchHandler searches every store the station keeps, hidden records
+// included, ranking the lot together GET /admin/search?q=
+func (app *application) adminSearchHandler(w http.ResponseWriter, r *http.Request) {
+	page := adminSearchPage{Query: strings.TrimSpace(r.URL.Query().Get("q"))}
+
+	hits, err := app.searchEverything(models.SearchTerms(page.Query))
+	if err != nil {
+		app.serverError(w, r, err, "query", page.Query)
+		return
+	}
+	page.Hits = hits
+
+	app.render(w, r, page, "pages/admin-search.tmpl")
+}
+
+// searchEverything matches the terms against entries, scraped items, transmissions
+// and sources. A hit scores three points per term occurrence in its title and one
+// per occurrence in its text; ties go to the newer record.
+func (app *application) searchEverything(terms []string) ([]*searchHit, error) {
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	var hits []*searchHit
+	add := func(h *searchHit, title, text string) {
+		var inTitle, inText int
+		h.Title, inTitle = models.MarkTerms(title, terms, 0)
+		h.Snippet, inText = models.MarkTerms(text, terms, 200)
+		h.score = 3*inTitle + inText
+		hits = append(hits, h)
+	}
+
+	entries, err := app.entries.Find(terms, adminSearchPerStore)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		h := &searchHit{Store: "entry", Link: e.Permalink(), When: e.CreatedAt}
+		if e.ExcludeFromFeed {
+			h.Note = "kept out of feeds"
+		}
+		add(h, e.Title, models.StringValue(e.Content)+" "+models.StringValue(e.URL))
+	}
+
+	items, err := app.scraper.Find(terms, adminSearchPerStore)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range items {
+		h := &searchHit{Store: "scraped", Link: models.StringValue(i.URL), When: i.FetchedAt}
+		if i.SourceID != nil && h.Link == "" {
+			h.Link = "/scraper?source=" + strconv.Itoa(*i.SourceID)
+		}
+		if i.Dismissed {
+			h.Note = "dismissed"
+		}
+		add(h, i.Title, i.Value+" "+models.StringValue(i.URL))
+	}
+
+	transmissions, err := app.transmissions.Find(terms, adminSearchPerStore)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range transmissions {
+		h := &searchHit{Store: "transmission", Link: "/admin/transmissions", When: t.CreatedAt}
+		switch {
+		case t.EntryID != nil:
+			h.Link = "/admin/edit/" + strconv.Itoa(*t.EntryID)
+		case t.Status != models.TransmissionPending:
+			h.Link = ""
+		}
+		if t.Status != models.TransmissionApproved {
+			h.Note = t.Status
+		}
+		title := "transmission #" + strconv.Itoa(t.ID)
+		if t.Callsign != nil {
+			title = *t.Callsign
+		}
+		add(h, title, t.Message)
+	}
+
+	sources, err := app.sources.Find(terms, adminSearchPerStore)
+	if err != nil {
+		return nil, err
+	}
+	for _, src := range sources {
+		h := &searchHit{Store: "source", Link: "/scraper?source=" + strconv.Itoa(src.ID), When: src.CreatedAt}
+		add(h, src.Name, src.URL+" "+src.Config)
+	}
+
+	slices.SortStableFunc(hits, func(a, b *searchHit) int {
+		if c := cmp.Compare(b.score, a.score); c != 0 {
+			return c
+		}
+		return b.When.Compare(a.When)
+	})
+	if len(hits) > adminSearchLimit {
+		hits = hits[:adminSearchLimit]
+	}
+	return hits, nil
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// sectorPageSize is how many entries a sector lists at once; the load-more button
+// fetches the rest a page at a time as the visitor scrolls down.
+const sectorPageSize = 50
+
+// templateData is one page of a listing as its templates see it: the entries on it,
+// where it sits among the rest, and the filter that narrowed it, which the prev/next
+// links carry along. The pager partial renders it.
+type templateData struct {
+	Entries    []*models.Entry
+	Page       int
+	TotalPages int        // Never below 1, so an empty listing is still page 1 of 1
+	Total      int        // Matching entries across all pages
+	Filter     url.Values // e.g. type=book; empty when nothing narrows the listing
+	Prev       string     // Link to the page before, empty on the first
+	Next       string     // Link to the page after, empty on the last
+	Fragment   bool       // Rendered for htmx, so the pager is swapped in out of band
+}
+
+// listPage is page of the listing at path, size entries to a page, total across them all.
+func listPage(path string, filter url.Values, entries []*models.Entry, page, size, total int) templateData {
+	d := templateData{
+		Entries:    entries,
+		Page:       page,
+		TotalPages: max(1, (total+size-1)/size),
+		Total:      total,
+		Filter:     filter,
+	}
+	if page > 1 {
+		// Past the end, prev leads back to the last real page
+		d.Prev = d.pageURL(path, min(page-1, d.TotalPages))
+	}
+	if page < d.TotalPages {
+		d.Next = d.pageURL(path, page+1)
+	}
+	return d
+}
+
+// pageURL links to another page of the listing at path, keeping the filter. The first
+// page goes without ?page=, so it has one URL.
+func (d *templateData) pageURL(path string, page int) string {
+	q := url.Values{}
+	for k, v := range d.Filter {
+		q[k] = v
+	}
+	if page > 1 {
+		q.Set("page", strconv.Itoa(page))
+	}
+	if len(q) == 0 {
+		return path
+	}
+	return path + "?" + q.Encode()
+}
+
+// mediaPage is the data handed to media.tmpl and the media-list fragment
+type mediaPage struct {
+	templateData
+	Types   []models.EntryType // Offered by the type filter
+	Type    string             // Active type filter, empty for all
+	More    string             // Fragment URL of the following page, for the load-more button
+	Refresh bool               // The fragment replaces the whole list, so the filter is swapped in too
+}
+
+// thoughtsPage is the data handed to thoughts.tmpl and the thoughts-list fragment
+type thoughtsPage struct {
+	templateData
+	Moods   []models.Mood
+	Mood    string // Active mood filter, empty for all
+	More    string
+	Refresh bool
+}
+
+// sectorList is the JSON a sector answers with when asked for application/json.
+// Thoughts carry their replies nested, as on the page.
+type sectorList struct {
+	Entries []*entryResource `json:"entries"`
+	Filter  string           `json:"filter,omitempty"` // The type or mood narrowing the list
+	Page    int              `json:"page"`
+	Total   int              `json:"total"`
+	Next    string           `json:"next,omitempty"` // URL of the following page, when there is one
+}
+
+// mediaTypes are the types the Media Compendium can be filtered by.
+func mediaTypes() []models.EntryType {
+	var types []models.EntryType
+	for _, t := range models.EntryTypes {
+		if !t.Thought {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// sectorPageNumber reads ?page=, which starts at 1; anything unparseable is the first page.
+func sectorPageNumber(r *http.Request) int {
+	n, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || n < 1 {
+		return 1
+	}
+	return n
+}
+
+// moreFragment is the fragment URL the load-more button fetches, or "" on the last page.
+func (d *templateData) moreFragment(path string) string {
+	if d.Next == "" {
+		return ""
+	}
+	return d.pageURL(path, d.Page+1)
+}
+
+// mediaSector loads one page of the Media Compendium, optionally narrowed with ?type=.
+// Thought types and unknown keys are ignored rather than listing nothing.
+func (app *application) mediaSector(r *http.Request) (*mediaPage, error) {
+	page := &mediaPage{Types: mediaTypes()}
+	filter := url.Values{}
+	if t, ok := models.TypeByKey(r.URL.Query().Get("type")); ok && !t.Thought {
+		page.Type = t.Key
+		filter.Set("type", t.Key)
+	}
+
+	n := sectorPageNumber(r)
+	entries, total, err := app.entries.List(models.EntryFilter{
+		Section: "media",
+		Type:    page.Type,
+		Limit:   sectorPageSize,
+		Offset:  (n - 1) * sectorPageSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := app.badgeEpochs(entries); err != nil {
+		return nil, err
+	}
+	decay(entries, app.decay.media, time.Now())
+	page.templateData = listPage("/media", filter, entries, n, sectorPageSize, total)
+	page.More = page.moreFragment("/media/fragment")
+	return page, nil
+}
+
+// resource is the JSON form of the page; its next link stays on /media.
+func (p *mediaPage) resource(app *application) (any, error) {
+	return app.sectorResource(p.Entries, p.Type, p.Page, p.Total, p.Next)
+}
+
+// thoughtsSector loads one page of the Organic Thoughts Sector, optionally narrowed
+// with ?mood=; unknown moods fall back to everything.
+func (app *application) thoughtsSector(r *http.Request) (*thoughtsPage, error) {
+	page := &thoughtsPage{Moods: models.Moods}
+	filter := url.Values{}
+	if mood, ok := models.MoodByKey(r.URL.Query().Get("mood")); ok {
+		page.Mood = mood.Key
+		filter.Set("mood", mood.Key)
+	}
+
+	n := sectorPageNumber(r)
+	entries, total, err := app.entries.List(models.EntryFilter{
+		Section: "thoughts",
+		Mood:    page.Mood,
+		Limit:   sectorPageSize,
+		Offset:  (n - 1) * sectorPageSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Replies that made it into this page hang off their parent thought
+	entries = models.NestThreads(entries)
+
+	if err := app.badgeEpochs(entries); err != nil {
+		return nil, err
+	}
+	decay(entries, app.decay.thoughts, time.Now())
+	page.templateData = listPage("/thoughts", filter, entries, n, sectorPageSize, total)
+	page.More = page.moreFragment("/thoughts/fragment")
+	return page, nil
+}
+
+func (p *thoughtsPage) resource(app *application) (any, error) {
+	return app.sectorResource(p.Entries, p.Mood, p.Page, p.Total, p.Next)
+}
+
+// sectorResource builds a sector's JSON with each entry as /api/v1/entries lists
+// it, plus the replies nested under it on this page.
+func (app *application) sectorResource(entries []*models.Entry, filter string, page, total int, next string) (*sectorList, error) {
+	list := &sectorList{Entries: []*entryResource{}, Filter: filter, Page: page, Total: total}
+	for _, e := range entries {
+		p, err := app.loadEntryPage(e, false)
+		if err != nil {
+			return nil, err
+		}
+		res := app.newEntryResource(p)
+		res.Replies = app.replyResources(e.Replies)
+		list.Entries = append(list.Entries, res)
+	}
+	if next != "" {
+		list.Next = app.absoluteURL(next)
+	}
+	return list, nil
+}
+
+// mediaFiles and thoughtsFiles are the templates each sector is rendered from, whole
+// or as a fragment.
+var (
+	mediaFiles    = append(mediaCardFiles(), "partials/pager.tmpl", "partials/media-list.tmpl", "pages/media.tmpl")
+	thoughtsFiles = []string{"partials/thought.tmpl", "partials/pager.tmpl", "partials/thoughts-list.tmpl", "pages/thoughts.tmpl"}
+)
+
+// mediaCardFiles are the partials of the cards the registry names for media types,
+// the generic card first. Each card lives in partials/<card>.tmpl.
+func mediaCardFiles() []string {
+	files := []string{"partials/" + models.GenericCard + ".tmpl"}
+	for _, t := range models.EntryTypes {
+		if f := "partials/" + t.Card + ".tmpl"; !t.Thought && t.Card != "" && !slices.Contains(files, f) {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// mediaFragmentHandler renders just the media list for htmx GET /media/fragment?page=&type=
+//
+// A later page comes back as its cards plus the next load-more button, to replace
+// the button that asked for it as it scrolled into view; the first page replaces the
+// whole list when the filter changes.
+func (app *application) mediaFragmentHandler(w http.ResponseWriter, r *http.Request) {
+	page, err := app.mediaSector(r)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	page.Fragment, page.Refresh = true, page.Page == 1
+
+	app.renderFragment(w, r, "media-list", page, mediaFiles...)
+}
+
+// thoughtsFragmentHandler renders just the thoughts list for htmx GET /thoughts/fragment?page=&mood=
+func (app *application) thoughtsFragmentHandler(w http.ResponseWriter, r *http.Request) {
+	page, err := app.thoughtsSector(r)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	page.Fragment, page.Refresh = true, page.Page == 1
+
+	app.renderFragment(w, r, "thoughts-list", page, thoughtsFiles...)
+}
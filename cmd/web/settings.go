@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+	"github.com/federicopalou/sacrif-station/internal/validator"
+)
+
+const (
+	maxSiteTitleRunes   = 80
+	maxSiteTaglineRunes = 200
+	maxSiteFooterRunes  = 300
+)
+
+// settingsPage is the data handed to settings.tmpl
+type settingsPage struct {
+	Site   *models.Site
+	Errors map[string]string // Problems with the submitted form, by field name
+}
+
+// reloadSite reads the station's identity from the database into memory, where every
+// page picks it up.
+func (app *application) reloadSite() error {
+	site, err := app.settings.Site()
+	if err != nil {
+		return err
+	}
+	app.site.Store(site)
+	return nil
+}
+
+// siteIdentity is the identity the station currently goes by.
+func (app *application) siteIdentity() *models.Site {
+	return app.site.Load()
+}
+
+// settingsHandler renders the site identity form GET /admin/settings
+func (app *application) settingsHandler(w http.ResponseWriter, r *http.Request) {
+	app.render(w, r, settingsPage{Site: app.siteIdentity()}, "pages/settings.tmpl")
+}
+
+// settingsPostHandler saves the site identity and applies it immediately POST /admin/settings
+func (app *application) settingsPostHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	site := &models.Site{
+		Title:   strings.TrimSpace(r.PostForm.Get("title")),
+		Tagline: strings.TrimSpace(r.PostForm.Get("tagline")),
+		Footer:  strings.TrimSpace(r.PostForm.Get("footer")),
+	}
+
+	var v validator.Validator
+	v.CheckField(validator.NotBlank(site.Title), "title", "The station needs a name.")
+	v.CheckField(validator.MaxChars(site.Title, maxSiteTitleRunes), "title", "Keep the name under 80 characters.")
+	v.CheckField(validator.MaxChars(site.Tagline, maxSiteTaglineRunes), "tagline", "Keep the tagline under 200 characters.")
+	v.CheckField(validator.MaxChars(site.Footer, maxSiteFooterRunes), "footer", "Keep the footer under 300 characters.")
+	if !v.Valid() {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		app.render(w, r, settingsPage{Site: site, Errors: v.FieldErrors}, "pages/settings.tmpl")
+		return
+	}
+
+	if err := app.settings.SetSite(site); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.site.Store(site)
+
+	app.setFlash(w, r, "Station identity updated")
+	http.Redirect(w, r, "/admin/settings", http.StatusSeeOther)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// sitemapURLSet is a sitemaps.org urlset document.
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"` // W3C date
+}
+
+// sitemapHandler lists the public pages and every entry's permalink GET /sitemap.xml
+func (app *application) sitemapHandler(w http.ResponseWriter, r *http.Request) {
+	entries, err := app.entries.All()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	epochs, err := app.epochs.All()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// Sector pages changed when the newest entry they show did
+	var newest, newestMedia, newestThought time.Time
+	for _, e := range entries {
+		t := e.LastModified()
+		newest = later(newest, t)
+		if e.TypeInfo().Thought {
+			newestThought = later(newestThought, t)
+		} else {
+			newestMedia = later(newestMedia, t)
+		}
+	}
+
+	site := app.siteURL(r)
+	set := sitemapURLSet{URLs: []sitemapURL{
+		{Loc: site + "/", LastMod: sitemapDate(newest)},
+		{Loc: site + "/media", LastMod: sitemapDate(newestMedia)},
+		{Loc: site + "/thoughts", LastMod: sitemapDate(newestThought)},
+		{Loc: site + "/epochs"},
+		{Loc: site + "/stats", LastMod: sitemapDate(newest)},
+	}}
+
+	for _, ep := range epochs {
+		var lastMod time.Time
+		for _, e := range entries {
+			if ep.Contains(e.CreatedAt) {
+				lastMod = later(lastMod, e.LastModified())
+			}
+		}
+		set.URLs = append(set.URLs, sitemapURL{Loc: site + fmt.Sprintf("/epochs/%d", ep.ID), LastMod: sitemapDate(lastMod)})
+	}
+
+	for _, e := range entries {
+		set.URLs = append(set.URLs, sitemapURL{Loc: site + e.Permalink(), LastMod: sitemapDate(e.LastModified())})
+	}
+
+	body, err := xml.MarshalIndent(set, "", "  ")
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	w.Write(append(body, '\n'))
+}
+
+// robotsHandler points crawlers at the sitemap and away from the admin side GET /robots.txt
+func (app *application) robotsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "User-agent: *\nDisallow: /admin\nDisallow: /api/\nDisallow: /screensaver\nDisallow: /healthz\nDisallow: /metrics\nDisallow: /media/fragment\nDisallow: /thoughts/fragment\n\nSitemap: %s/sitemap.xml\n", app.siteURL(r))
+}
+
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// sitemapDate formats a lastmod value, leaving it out for pages with nothing in them yet.
+func sitemapDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
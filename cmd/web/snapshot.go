@@ -0,0 +1,192 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// stationSnapshot is the body of GET /api/v1/snapshot: every public piece of the
+// station in one document, for static mirrors and offline archive viewers. It holds
+// nothing that changes between requests, so equal content means an equal ETag.
+type stationSnapshot struct {
+	Station snapshotStation `json:"station"`
+	Types   []snapshotType  `json:"types"`
+	Epochs  []snapshotEpoch `json:"epochs"`
+	Entries []snapshotEntry `json:"entries"` // Oldest first, replies flattened in
+}
+
+type snapshotStation struct {
+	Title     string     `json:"title"`
+	URL       string     `json:"url,omitempty"` // SACRIF_BASE_URL, when set
+	UpdatedAt *time.Time `json:"updated_at"`    // Newest entry, nil on an empty station
+	Entries   int        `json:"entries"`
+}
+
+type snapshotType struct {
+	Key     string `json:"key"`
+	Label   string `json:"label"`
+	Section string `json:"section"` // "thoughts" or "media"
+	InFeeds bool   `json:"in_feeds"`
+	Entries int    `json:"entries"`
+}
+
+type snapshotEpoch struct {
+	ID          int     `json:"id"`
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+	StartsOn    string  `json:"starts_on"`
+	EndsOn      *string `json:"ends_on"` // nil while the epoch is still running
+	Permalink   string  `json:"permalink"`
+}
+
+// snapshotEntry is an entry as /entry/{slug} serves it, plus how feeds present it.
+type snapshotEntry struct {
+	*entryResource
+	Feed snapshotFeed `json:"feed"`
+}
+
+type snapshotFeed struct {
+	Included     bool    `json:"included"`
+	Summary      *string `json:"summary"`
+	CanonicalURL *string `json:"canonical_url"`
+}
+
+// snapshotHandler serves the whole station as one JSON document GET /api/v1/snapshot
+//
+// The body is gzipped for clients that accept it and carries a weak ETag, so a
+// mirror polling with If-None-Match only downloads it again after something changed.
+func (app *application) snapshotHandler(w http.ResponseWriter, r *http.Request) {
+	snap, err := app.buildSnapshot()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	body, err := json.Marshal(snap)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	etag := weakETag(body)
+
+	w.Header().Set("ETag", etag)
+	w.Header().Add("Vary", "Accept-Encoding")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if !acceptsEncoding(r, "gzip") {
+		w.Write(body)
+		return
+	}
+
+	w.Header().Set("Content-Encoding", "gzip")
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(body); err != nil {
+		slog.ErrorContext(r.Context(), "Snapshot write error", "err", err)
+		return
+	}
+	if err := gz.Close(); err != nil {
+		slog.ErrorContext(r.Context(), "Snapshot write error", "err", err)
+	}
+}
+
+// buildSnapshot gathers everything the snapshot carries with one query per table.
+func (app *application) buildSnapshot() (*stationSnapshot, error) {
+	entries, err := app.entries.All()
+	if err != nil {
+		return nil, err
+	}
+	epochs, err := app.epochs.All()
+	if err != nil {
+		return nil, err
+	}
+	counts, err := app.typeMigrations.Counts()
+	if err != nil {
+		return nil, err
+	}
+	attachments, err := app.attachments.All()
+	if err != nil {
+		return nil, err
+	}
+	externalIDs, err := app.externalIDs.All()
+	if err != nil {
+		return nil, err
+	}
+	syndications, err := app.syndication.All()
+	if err != nil {
+		return nil, err
+	}
+
+	pages := make(map[int]*entryPage, len(entries))
+	for _, e := range entries {
+		pages[e.ID] = &entryPage{Entry: e}
+	}
+	for _, a := range attachments {
+		if p, ok := pages[a.EntryID]; ok {
+			p.Attachments = append(p.Attachments, a)
+		}
+	}
+	for _, x := range externalIDs {
+		if p, ok := pages[x.EntryID]; ok {
+			p.ExternalIDs = append(p.ExternalIDs, x)
+		}
+	}
+	for _, s := range syndications {
+		if p, ok := pages[s.EntryID]; ok {
+			p.Syndications = append(p.Syndications, s)
+		}
+	}
+	epochs.Badge(entries)
+
+	snap := &stationSnapshot{
+		Station: snapshotStation{Title: app.siteIdentity().Title, URL: strings.TrimRight(app.baseURL, "/"), Entries: len(entries)},
+		Types:   []snapshotType{},
+		Epochs:  []snapshotEpoch{},
+		Entries: make([]snapshotEntry, 0, len(entries)),
+	}
+
+	for _, c := range counts {
+		section := "media"
+		if c.Type.Thought {
+			section = "thoughts"
+		}
+		snap.Types = append(snap.Types, snapshotType{
+			Key: c.Type.Key, Label: c.Type.Label, Section: section, InFeeds: c.Type.InFeeds, Entries: c.Entries,
+		})
+	}
+
+	for _, ep := range epochs {
+		se := snapshotEpoch{
+			ID:          ep.ID,
+			Name:        ep.Name,
+			Description: ep.Description,
+			StartsOn:    ep.StartsOn.Format(time.DateOnly),
+			Permalink:   app.absoluteURL(fmt.Sprintf("/epochs/%d", ep.ID)),
+		}
+		if ep.EndsOn != nil {
+			end := ep.EndsOn.Format(time.DateOnly)
+			se.EndsOn = &end
+		}
+		snap.Epochs = append(snap.Epochs, se)
+	}
+
+	for _, e := range entries {
+		snap.Entries = append(snap.Entries, snapshotEntry{
+			entryResource: app.newEntryResource(*pages[e.ID]),
+			Feed:          snapshotFeed{Included: e.InFeeds(), Summary: e.FeedSummary, CanonicalURL: e.CanonicalURL},
+		})
+		if snap.Station.UpdatedAt == nil || e.CreatedAt.After(*snap.Station.UpdatedAt) {
+			snap.Station.UpdatedAt = &e.CreatedAt
+		}
+	}
+
+	return snap, nil
+}
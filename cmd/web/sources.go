@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+	"github.com/federicopalou/sacrif-station/internal/scraper"
+)
+
+// Extraction quality of a source's latest runs is judged against the runs before them
+const (
+	qualityRecentRuns   = 5
+	qualityBaselineRuns = 20
+)
+
+// sourcesPage is the data handed to sources.tmpl
+type sourcesPage struct {
+	Sources   []*models.Source
+	Quality   map[int]*models.QualityTrend // By source ID; sources without measured runs are missing
+	Types     []string
+	CanRender bool   // Whether this build has a headless browser for rendered sources
+	Result    string // Outcome of the last manual run, if any
+}
+
+// sourcesHandler lists scraper sources with an add form GET /admin/sources
+func (app *application) sourcesHandler(w http.ResponseWriter, r *http.Request) {
+	sources, err := app.sources.All()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	quality, err := app.scrapeRuns.QualityTrends(qualityRecentRuns, qualityBaselineRuns)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	page := sourcesPage{
+		Sources:   sources,
+		Quality:   quality,
+		Types:     scraper.Types(),
+		CanRender: app.engine.Renderer != nil,
+		Result:    r.URL.Query().Get("result"),
+	}
+
+	app.render(w, r, page, "pages/sources.tmpl")
+}
+
+// scrapeRunsPage is the data handed to runs.tmpl
+type scrapeRunsPage struct {
+	Runs  []*models.ScrapeRun
+	Limit int
+}
+
+// scrapeRunsHandler shows the most recent scraper runs, errors included GET /admin/sources/runs
+func (app *application) scrapeRunsHandler(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 || limit > 1000 {
+		limit = 100
+	}
+
+	runs, err := app.scrapeRuns.Latest(limit)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.render(w, r, scrapeRunsPage{Runs: runs, Limit: limit}, "pages/runs.tmpl")
+}
+
+// sourcesPostHandler creates a scraper source POST /admin/sources
+func (app *application) sourcesPostHandler(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	src := &models.Source{
+		Name:    strings.TrimSpace(r.PostForm.Get("name")),
+		URL:     strings.TrimSpace(r.PostForm.Get("url")),
+		Type:    r.PostForm.Get("type"),
+		Config:  strings.TrimSpace(r.PostForm.Get("config")),
+		Render:  r.PostForm.Get("render") == "on",
+		Include: cleanFilterLines(r.PostForm.Get("include")),
+		Exclude: cleanFilterLines(r.PostForm.Get("exclude")),
+	}
+	if src.Config == "" {
+		src.Config = "{}"
+	}
+
+	src.Interval, err = strconv.Atoi(r.PostForm.Get("interval"))
+	if err != nil || src.Interval < 1 {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	u, err := url.Parse(src.URL)
+	if src.Name == "" || err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	if err := scraper.CheckConfig(src.Type, json.RawMessage(src.Config)); err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), 400)
+		return
+	}
+	if _, err := scraper.CompileItemFilter(src.Include, src.Exclude); err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), 400)
+		return
+	}
+
+	_, err = app.sources.Insert(src)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/sources", http.StatusSeeOther)
+}
+
+// sourceFiltersPostHandler replaces a source's include and exclude filters POST /admin/sources/{id}/filters
+//
+// They apply from the next run on; items already stored are left alone.
+func (app *application) sourceFiltersPostHandler(w http.ResponseWriter, r *http.Request) {
+	src, ok := app.sourceFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	include := cleanFilterLines(r.PostFormValue("include"))
+	exclude := cleanFilterLines(r.PostFormValue("exclude"))
+	if _, err := scraper.CompileItemFilter(include, exclude); err != nil {
+		http.Error(w, "Bad Request: "+err.Error(), 400)
+		return
+	}
+
+	if err := app.sources.SetFilters(src.ID, include, exclude); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/sources?result="+url.QueryEscape(src.Name+": filters saved"), http.StatusSeeOther)
+}
+
+// cleanFilterLines trims a filters textarea to one filter per line, blank lines removed.
+func cleanFilterLines(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sourceRunPostHandler scrapes a source immediately POST /admin/sources/{id}/run
+func (app *application) sourceRunPostHandler(w http.ResponseWriter, r *http.Request) {
+	src, ok := app.sourceFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	result := ""
+	n, err := app.engine.Run(r.Context(), src)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Scrape failed", "source", src.ID, "err", err)
+		result = src.Name + ": run failed (" + err.Error() + ")"
+	} else {
+		result = src.Name + ": " + strconv.Itoa(n) + " item(s) collected"
+	}
+
+	http.Redirect(w, r, "/admin/sources?result="+url.QueryEscape(result), http.StatusSeeOther)
+}
+
+// sourceTogglePostHandler pauses or resumes a source's scheduled runs POST /admin/sources/{id}/toggle
+func (app *application) sourceTogglePostHandler(w http.ResponseWriter, r *http.Request) {
+	src, ok := app.sourceFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := app.sources.SetEnabled(src.ID, !src.Enabled); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	result := src.Name + ": paused, scheduled runs skip it until resumed"
+	if !src.Enabled {
+		result = src.Name + ": resumed"
+	}
+	http.Redirect(w, r, "/admin/sources?result="+url.QueryEscape(result), http.StatusSeeOther)
+}
+
+// sourceDeletePostHandler removes a source POST /admin/sources/{id}/delete
+func (app *application) sourceDeletePostHandler(w http.ResponseWriter, r *http.Request) {
+	src, ok := app.sourceFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := app.sources.Delete(src.ID); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if err := app.snapshots.Delete(src.ID); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to drop the snapshot of a deleted source", "source", src.ID, "err", err)
+	}
+	if err := app.prices.Delete(src.ID); err != nil {
+		slog.ErrorContext(r.Context(), "Failed to drop the price history of a deleted source", "source", src.ID, "err", err)
+	}
+
+	http.Redirect(w, r, "/admin/sources", http.StatusSeeOther)
+}
+
+// sourceFromPath loads the source named by the {id} path segment, writing an error response if it can't.
+func (app *application) sourceFromPath(w http.ResponseWriter, r *http.Request) (*models.Source, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w, r)
+		return nil, false
+	}
+
+	src, err := app.sources.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return nil, false
+	}
+
+	return src, true
+}
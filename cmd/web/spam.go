@@ -0,0 +1,113 @@
+package main
+
+import (
+	"crypto/rand"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+	"github.com/federicopalou/sacrif-station/internal/storage"
+)
+
+// originKeyFile holds the key sender origins are hashed with, relative to the data
+// root. Reputation and blocks are kept by origin, so it has to outlive restarts.
+const originKeyFile = "origin.key"
+
+// loadOriginKey reads the origin key, generating it on first start.
+func loadOriginKey(root *storage.Root) ([]byte, error) {
+	key, err := root.ReadFile(originKeyFile)
+	if err == nil {
+		if len(key) < 32 {
+			return nil, errors.New(originKeyFile + " is too short, delete it to have a new one generated")
+		}
+		return key, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	key = make([]byte, 32)
+	rand.Read(key)
+	if err := root.WriteFile(originKeyFile, key, 0o600); err != nil {
+		return nil, err
+	}
+	slog.Info("Generated origin key", "file", originKeyFile)
+	return key, nil
+}
+
+// spamPage is the data handed to spam.tmpl
+type spamPage struct {
+	Flagged   []*models.Reputation
+	Threshold int
+	PoWBits   int
+	Result    string
+}
+
+// spamHandler lists blocked and badly behaved origins GET /admin/spam
+func (app *application) spamHandler(w http.ResponseWriter, r *http.Request) {
+	flagged, err := app.reputation.Flagged()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	page := spamPage{
+		Flagged:   flagged,
+		Threshold: models.BlockThreshold,
+		PoWBits:   app.spamGuard.PoWBits,
+		Result:    r.URL.Query().Get("result"),
+	}
+
+	app.render(w, r, page, "pages/spam.tmpl")
+}
+
+// spamBlockPostHandler blocks an origin, given directly or as an IP address POST /admin/spam/block
+func (app *application) spamBlockPostHandler(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	origin := r.PostForm.Get("origin")
+	if ip := strings.TrimSpace(r.PostForm.Get("ip")); ip != "" {
+		if net.ParseIP(ip) == nil {
+			http.Error(w, "Bad Request: not an IP address", 400)
+			return
+		}
+		origin = app.spamGuard.OriginOf(ip)
+	}
+	if origin == "" {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	if err := app.reputation.Block(origin, strings.TrimSpace(r.PostForm.Get("note"))); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/spam?result="+url.QueryEscape("Blocked #"+origin[:min(8, len(origin))]), http.StatusSeeOther)
+}
+
+// spamUnblockPostHandler lifts a block and forgets the origin's record POST /admin/spam/unblock
+func (app *application) spamUnblockPostHandler(w http.ResponseWriter, r *http.Request) {
+	origin := r.PostFormValue("origin")
+
+	err := app.reputation.Unblock(origin)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	http.Redirect(w, r, "/admin/spam?result="+url.QueryEscape("Record cleared for #"+origin[:min(8, len(origin))]), http.StatusSeeOther)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/ui"
+)
+
+// staticAssets are the files under ui/static, compiled into the binary. Each is also
+// served under a name carrying a hash of its content (css/main.3f2a9c1b.css), which
+// pages link to through the "static" template func: the name changes whenever the
+// file does, so browsers can keep a copy for good.
+type staticAssets struct {
+	hashed  map[string]string       // Plain name -> hashed name
+	files   map[string]*staticAsset // Hashed and plain names -> content
+	modTime time.Time               // Embedded files have none, so the binary's start stands in
+}
+
+type staticAsset struct {
+	body      []byte
+	etag      string
+	immutable bool // Requested by its hashed name
+}
+
+// assets is built once from the embedded files, which can't change while running.
+var assets = loadStaticAssets()
+
+func loadStaticAssets() *staticAssets {
+	root, err := fs.Sub(ui.Files, "static")
+	if err != nil {
+		panic(err)
+	}
+
+	a := &staticAssets{hashed: map[string]string{}, files: map[string]*staticAsset{}, modTime: time.Now()}
+	err = fs.WalkDir(root, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		body, err := fs.ReadFile(root, name)
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(body)
+		hash := hex.EncodeToString(sum[:4])
+		ext := path.Ext(name)
+		hashedName := strings.TrimSuffix(name, ext) + "." + hash + ext
+
+		etag := `"` + hash + `"`
+		a.hashed[name] = hashedName
+		a.files[name] = &staticAsset{body: body, etag: etag}
+		a.files[hashedName] = &staticAsset{body: body, etag: etag, immutable: true}
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// url is where a page should link to the named file, e.g. {{static "css/main.css"}}.
+// Unknown names are linked as they are, so a typo shows up as a 404 in the browser.
+func (a *staticAssets) url(name string) string {
+	if hashed, ok := a.hashed[name]; ok {
+		return "/static/" + hashed
+	}
+	return "/static/" + name
+}
+
+// staticHandler serves an embedded file GET /static/{path...}
+func (app *application) staticHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("path")
+	asset, ok := assets.files[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Hashed names never change content; plain ones are checked again each time
+	if asset.immutable {
+		w.Header().Set("Cache-Control", app.cache.static)
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	w.Header().Set("ETag", asset.etag)
+	http.ServeContent(w, r, name, assets.modTime, bytes.NewReader(asset.body))
+}
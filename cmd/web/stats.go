@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// moodSegment is one coloured slice of a month's bar in the mood chart.
+type moodSegment struct {
+	Mood  models.Mood
+	Count int
+}
+
+// moodMonth is a single row of the mood-over-time chart.
+type moodMonth struct {
+	Month    string
+	Total    int
+	Width    int // Bar width as a percentage of the busiest month
+	Segments []moodSegment
+}
+
+// statsPage is the data handed to stats.tmpl
+type statsPage struct {
+	Moods     []models.Mood
+	MoodChart []moodMonth
+	Defense   []defenseDay // Refused requests over the last week, newest day first
+}
+
+// statsHandler renders station statistics: the mood-over-time chart and refused traffic
+func (app *application) statsHandler(w http.ResponseWriter, r *http.Request) {
+	counts, err := app.entries.MoodTimeline()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	defense, err := app.defenseReport()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	page := statsPage{Moods: models.Moods, MoodChart: buildMoodChart(counts), Defense: defense}
+
+	app.render(w, r, page, "pages/stats.tmpl")
+}
+
+// buildMoodChart groups the month/mood counts (already sorted by month) into chart rows,
+// keeping segments in the canonical Moods order so colours line up month to month.
+func buildMoodChart(counts []*models.MoodCount) []moodMonth {
+	var chart []moodMonth
+	byMonth := map[string]map[string]int{}
+
+	for _, c := range counts {
+		if _, ok := byMonth[c.Month]; !ok {
+			byMonth[c.Month] = map[string]int{}
+			chart = append(chart, moodMonth{Month: c.Month})
+		}
+		byMonth[c.Month][c.Mood] += c.Count
+	}
+
+	busiest := 0
+	for i := range chart {
+		for _, mood := range models.Moods {
+			if n := byMonth[chart[i].Month][mood.Key]; n > 0 {
+				chart[i].Segments = append(chart[i].Segments, moodSegment{Mood: mood, Count: n})
+				chart[i].Total += n
+			}
+		}
+		busiest = max(busiest, chart[i].Total)
+	}
+
+	for i := range chart {
+		if busiest > 0 {
+			chart[i].Width = chart[i].Total * 100 / busiest
+		}
+	}
+
+	return chart
+}
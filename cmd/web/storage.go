@@ -0,0 +1,80 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// databaseStats pairs a database's name with its size report for storage.tmpl
+type databaseStats struct {
+	Name string
+	*models.DatabaseStats
+}
+
+// storagePage is the data handed to storage.tmpl
+type storagePage struct {
+	Databases []databaseStats
+	Result    string // Outcome of the last vacuum, if any
+}
+
+// databaseNames fixes the order the databases are listed in. In single-database
+// mode there is no "scraper" one.
+var databaseNames = []string{"main", "scraper"}
+
+// storageHandler reports what is taking space in each database file GET /admin/storage
+func (app *application) storageHandler(w http.ResponseWriter, r *http.Request) {
+	page := storagePage{Result: r.URL.Query().Get("result")}
+
+	for _, name := range databaseNames {
+		db, ok := app.databases[name]
+		if !ok {
+			continue
+		}
+		stats, err := db.Stats()
+		if err != nil {
+			app.serverError(w, r, err, "database", name)
+			return
+		}
+		page.Databases = append(page.Databases, databaseStats{Name: name, DatabaseStats: stats})
+	}
+
+	app.render(w, r, page, "pages/storage.tmpl")
+}
+
+// storageVacuumPostHandler reclaims free pages in one database POST /admin/storage/{db}/vacuum
+func (app *application) storageVacuumPostHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("db")
+	db, ok := app.databases[name]
+	if !ok {
+		app.notFound(w, r)
+		return
+	}
+
+	before, err := db.Stats()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// The first run converts the file, which takes a full rewrite; later runs are cheap
+	if before.AutoVacuum == "incremental" {
+		err = db.IncrementalVacuum()
+	} else {
+		err = db.EnableIncrementalVacuum(r.Context())
+	}
+
+	result := name + ": "
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Vacuum failed", "database", name, "err", err)
+		result += "vacuum failed (" + err.Error() + ")"
+	} else if after, err := db.Stats(); err == nil {
+		result += "reclaimed " + models.HumanBytes(max(before.FileBytes()-after.FileBytes(), 0))
+	} else {
+		result += "vacuum done"
+	}
+
+	http.Redirect(w, r, "/admin/storage?result="+url.QueryEscape(result), http.StatusSeeOther)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+	"github.com/federicopalou/sacrif-station/internal/syndicate"
+)
+
+const jobSyndicate = "syndicate"
+
+// syndicateJob is the payload of a "syndicate" job: one entry, one service.
+type syndicateJob struct {
+	EntryID int    `json:"entry_id"`
+	Service string `json:"service"`
+}
+
+// syndicationTargets builds the configured cross-posting accounts from the environment.
+func syndicationTargets() map[string]syndicate.Poster {
+	targets := map[string]syndicate.Poster{}
+
+	if instance, token := os.Getenv("MASTODON_INSTANCE"), os.Getenv("MASTODON_TOKEN"); instance != "" && token != "" {
+		targets["mastodon"] = &syndicate.Mastodon{Instance: instance, Token: token}
+	}
+
+	if handle, password := os.Getenv("BLUESKY_HANDLE"), os.Getenv("BLUESKY_APP_PASSWORD"); handle != "" && password != "" {
+		service := os.Getenv("BLUESKY_SERVICE")
+		if service == "" {
+			service = "https://bsky.social"
+		}
+		targets["bluesky"] = &syndicate.Bluesky{Service: service, Handle: handle, AppPassword: password}
+	}
+
+	return targets
+}
+
+// enqueueSyndication queues one cross-posting job per configured service for a new entry.
+// Failures are logged rather than surfaced: the entry itself is already safely stored.
+func (app *application) enqueueSyndication(entryID int) {
+	if len(app.syndicators) == 0 {
+		return
+	}
+	if app.baseURL == "" {
+		slog.Warn("Skipping syndication: SACRIF_BASE_URL is not set, so there is no permalink to share.")
+		return
+	}
+
+	for service := range app.syndicators {
+		if _, err := app.jobs.Enqueue(jobSyndicate, syndicateJob{EntryID: entryID, Service: service}); err != nil {
+			slog.Error("Failed to enqueue syndication job", "err", err)
+		}
+	}
+}
+
+// runSyndicateJob posts a link+excerpt of an entry to one service and records the copy's URL.
+func (app *application) runSyndicateJob(ctx context.Context, payload []byte) error {
+	var job syndicateJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return err
+	}
+
+	poster, ok := app.syndicators[job.Service]
+	if !ok {
+		return fmt.Errorf("syndication service %q is no longer configured", job.Service)
+	}
+
+	// Retried jobs must not double-post
+	done, err := app.syndication.Exists(job.EntryID, job.Service)
+	if err != nil || done {
+		return err
+	}
+
+	entry, err := app.entries.Get(job.EntryID)
+	if errors.Is(err, models.ErrNoRecord) {
+		// Deleted before we got to it, nothing left to share
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	url, err := poster.Publish(ctx, syndicate.Post{
+		Title:   entry.Title,
+		Excerpt: entry.FeedDescription(),
+		Link:    app.absoluteURL(entry.Permalink()),
+	})
+	if err != nil {
+		return err
+	}
+
+	return app.syndication.Insert(entry.ID, job.Service, url)
+}
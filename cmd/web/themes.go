@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	themeCookie = "sacrif_theme"
+	themeTTL    = 365 * 24 * time.Hour
+)
+
+// theme is one look for the station. Its stylesheet, under ui/static/css/themes/,
+// loads after main.css and overrides its colour variables.
+type theme struct {
+	Key        string
+	Label      string
+	Stylesheet string // Relative to ui/static; empty for the plain main.css look
+}
+
+// themes are the looks a visitor can pick from the footer, the default first.
+var themes = []theme{
+	{Key: "station", Label: "station"},
+	{Key: "green-phosphor", Label: "green_phosphor", Stylesheet: "css/themes/green-phosphor.css"},
+	{Key: "amber", Label: "amber", Stylesheet: "css/themes/amber.css"},
+	{Key: "paper", Label: "paper", Stylesheet: "css/themes/paper.css"},
+}
+
+func themeByKey(key string) (theme, bool) {
+	i := slices.IndexFunc(themes, func(t theme) bool { return t.Key == key })
+	if i < 0 {
+		return theme{}, false
+	}
+	return themes[i], true
+}
+
+// defaultTheme reads SACRIF_THEME, the look visitors get until they choose one.
+func defaultTheme() (theme, error) {
+	key := os.Getenv("SACRIF_THEME")
+	if key == "" {
+		return themes[0], nil
+	}
+	t, ok := themeByKey(key)
+	if !ok {
+		keys := make([]string, len(themes))
+		for i, t := range themes {
+			keys[i] = t.Key
+		}
+		return theme{}, fmt.Errorf("SACRIF_THEME %q is not one of %s", key, strings.Join(keys, ", "))
+	}
+	return t, nil
+}
+
+// themeFor is the visitor's chosen theme, or the station's default.
+func (app *application) themeFor(r *http.Request) theme {
+	if c, err := r.Cookie(themeCookie); err == nil {
+		if t, ok := themeByKey(c.Value); ok {
+			return t
+		}
+	}
+	return app.theme
+}
+
+// themePostHandler remembers the visitor's theme in a cookie and sends them back to
+// the page they were on POST /theme
+func (app *application) themePostHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	t, ok := themeByKey(r.PostForm.Get("theme"))
+	if !ok {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     themeCookie,
+		Value:    t.Key,
+		Path:     "/",
+		MaxAge:   int(themeTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, safeRedirect(r.PostForm.Get("next"), "/"), http.StatusSeeOther)
+}
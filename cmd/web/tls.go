@@ -0,0 +1,139 @@
+package main
+
+import (
+	"crypto/tls"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// certReloader serves a certificate from files on disk, picking up a renewed pair
+// (e.g. from certbot) on the first handshake after either file changes.
+//
+// Renewals are read by the station as whatever user it runs as by then, so with
+// SACRIF_RUN_AS both files have to stay readable by that user, not only by root.
+type certReloader struct {
+	certFile, keyFile string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	modTimes [2]time.Time // Of the certificate and key files when last loaded
+	lastErr  error        // Why the latest renewal didn't load; nil once one does
+}
+
+// newCertReloader loads the pair once up front, so a bad path fails at startup. That
+// first read may still happen as root; see checkReadable for the ones after it.
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	c := &certReloader{certFile: certFile, keyFile: keyFile}
+	modTimes, err := c.stat()
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	c.cert, c.modTimes = &cert, modTimes
+	return c, nil
+}
+
+func (c *certReloader) stat() ([2]time.Time, error) {
+	var times [2]time.Time
+	for i, name := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return times, err
+		}
+		times[i] = info.ModTime()
+	}
+	return times, nil
+}
+
+// getCertificate is the tls.Config hook. A renewal that can't be read keeps the
+// current certificate in service rather than failing handshakes.
+func (c *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	modTimes, err := c.stat()
+	if err != nil || modTimes == c.modTimes {
+		return c.cert, nil
+	}
+
+	// Mark the change as seen either way, so a broken renewal is logged once; a pair
+	// caught halfway through being replaced is tried again when the other file lands
+	c.modTimes = modTimes
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		slog.Error("Renewed TLS certificate failed to load, keeping the current one", "cert", c.certFile, "expires", c.cert.Leaf.NotAfter, "err", err)
+		c.lastErr = err
+		return c.cert, nil
+	}
+	slog.Info("Reloaded TLS certificate", "cert", c.certFile, "expires", cert.Leaf.NotAfter)
+	c.cert, c.lastErr = &cert, nil
+	return c.cert, nil
+}
+
+// checkReadable warns when the files can't be read any more, e.g. a key only root
+// may read once privileges are dropped: the certificate in use carries on, but no
+// renewal will replace it.
+func (c *certReloader) checkReadable() {
+	for _, name := range []string{c.certFile, c.keyFile} {
+		f, err := os.Open(name)
+		if err != nil {
+			slog.Error("TLS files are unreadable to the station, renewals won't be picked up", "file", name, "err", err)
+			continue
+		}
+		f.Close()
+	}
+}
+
+// status reports when the certificate being served expires, and why the latest
+// renewal failed to replace it, if it did.
+func (c *certReloader) status() (expires time.Time, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cert.Leaf.NotAfter, c.lastErr
+}
+
+// acmeCerts obtains certificates from Let's Encrypt and renews them before they
+// expire (an *autocert.Manager, see acme.go).
+type acmeCerts interface {
+	TLSConfig() *tls.Config
+	// HTTPHandler answers HTTP-01 challenges and hands everything else to fallback
+	HTTPHandler(fallback http.Handler) http.Handler
+}
+
+// tlsConfig is the server side of HTTPS: modern protocol versions only, with the
+// certificate from Let's Encrypt when acme is set, or else from the reloader.
+func tlsConfig(certs *certReloader, acme acmeCerts) *tls.Config {
+	if acme != nil {
+		// Keeps the TLS-ALPN-01 protocol, so certificates can be issued without port 80
+		cfg := acme.TLSConfig()
+		cfg.MinVersion = tls.VersionTLS12
+		return cfg
+	}
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certs.getCertificate,
+	}
+}
+
+// redirectToHTTPS answers plain-HTTP requests with a permanent redirect to the same
+// URL over HTTPS, on the port the TLS listener uses.
+func redirectToHTTPS(tlsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(tlsAddr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
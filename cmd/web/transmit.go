@@ -0,0 +1,282 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+	"github.com/federicopalou/sacrif-station/internal/spam"
+)
+
+// Limits on the public transmission form. They are deliberately tight: this is a
+// guestbook, not a chat.
+const (
+	maxCallsignRunes     = 40
+	maxTransmissionRunes = 1000
+	transmitPerHour      = 2
+	transmitPerDay       = 5
+	maxPendingQueue      = 50 // Beyond this the form closes until the operator catches up
+)
+
+// Reputation changes when the operator screens a transmission.
+const (
+	approvedReputation = 5
+	rejectedReputation = -3
+)
+
+// transmitPage is the data handed to transmit.tmpl
+type transmitPage struct {
+	Callsign  string
+	Message   string
+	Error     string
+	Sent      bool
+	MaxRunes  int
+	IsAdmin   bool
+	Pending   int            // Size of the moderation queue, shown to the operator
+	Challenge spam.Challenge // Issued fresh on every render
+}
+
+// transmissionsPage is the data handed to transmissions.tmpl
+type transmissionsPage struct {
+	Pending []*models.Transmission
+	Result  string
+}
+
+// transmitHandler renders the guest transmission form GET /transmit
+func (app *application) transmitHandler(w http.ResponseWriter, r *http.Request) {
+	page := transmitPage{Sent: r.URL.Query().Get("sent") == "1", MaxRunes: maxTransmissionRunes, IsAdmin: app.isAdmin(r)}
+
+	if page.IsAdmin {
+		var err error
+		page.Pending, err = app.transmissions.CountPending()
+		if err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+	}
+
+	app.renderTransmit(w, r, http.StatusOK, page)
+}
+
+// renderTransmit renders the form with a fresh spam challenge.
+func (app *application) renderTransmit(w http.ResponseWriter, r *http.Request, status int, page transmitPage) {
+	var err error
+	page.Challenge, err = app.spamGuard.Issue()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	// The challenge is good for one submission, so no copy of the form may be reused
+	w.Header().Set("Cache-Control", "no-store")
+	if status != http.StatusOK {
+		w.WriteHeader(status)
+	}
+	app.render(w, r, page, "pages/transmit.tmpl")
+}
+
+// transmitPostHandler queues a visitor's message for moderation POST /transmit
+func (app *application) transmitPostHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 16<<10)
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	page := transmitPage{
+		Callsign: strings.TrimSpace(r.PostForm.Get("callsign")),
+		Message:  strings.TrimSpace(r.PostForm.Get("message")),
+		MaxRunes: maxTransmissionRunes,
+	}
+
+	origin := app.spamGuard.Origin(r)
+	blocked, err := app.reputation.Blocked(origin)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if blocked {
+		page.Error = "Frequency closed to your station."
+		app.renderTransmit(w, r, http.StatusForbidden, page)
+		return
+	}
+
+	if rej := app.spamGuard.Check(r.PostForm); rej != nil {
+		if err := app.reputation.Strike(origin, rej.Check, rej.Penalty); err != nil {
+			app.serverError(w, r, err)
+			return
+		}
+		if rej.Silent {
+			http.Redirect(w, r, "/transmit?sent=1", http.StatusSeeOther)
+			return
+		}
+		page.Error = rej.Message
+		app.renderTransmit(w, r, http.StatusBadRequest, page)
+		return
+	}
+
+	switch {
+	case page.Message == "":
+		page.Error = "Empty transmission. Nothing to relay."
+	case utf8.RuneCountInString(page.Message) > maxTransmissionRunes:
+		page.Error = fmt.Sprintf("Transmission too long. Keep it under %d characters.", maxTransmissionRunes)
+	case utf8.RuneCountInString(page.Callsign) > maxCallsignRunes:
+		page.Error = fmt.Sprintf("Callsign too long. Keep it under %d characters.", maxCallsignRunes)
+	}
+	if page.Error != "" {
+		app.renderTransmit(w, r, http.StatusBadRequest, page)
+		return
+	}
+
+	if msg, err := app.transmitRefusal(origin); err != nil {
+		app.serverError(w, r, err)
+		return
+	} else if msg != "" {
+		page.Error = msg
+		w.Header().Set("Retry-After", "3600")
+		app.renderTransmit(w, r, http.StatusTooManyRequests, page)
+		return
+	}
+
+	t := &models.Transmission{
+		Callsign: models.NullString(page.Callsign),
+		Message:  page.Message,
+		Origin:   origin,
+	}
+	if _, err := app.transmissions.Insert(t); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/transmit?sent=1", http.StatusSeeOther)
+}
+
+// transmitRefusal explains why origin may not transmit right now, or returns "" if it may.
+func (app *application) transmitRefusal(origin string) (string, error) {
+	pending, err := app.transmissions.CountPending()
+	if err != nil {
+		return "", err
+	}
+	if pending >= maxPendingQueue {
+		return "Relay buffer full. The operator is behind on moderation; try again later.", nil
+	}
+
+	now := time.Now()
+	for _, limit := range []struct {
+		window time.Duration
+		max    int
+	}{
+		{time.Hour, transmitPerHour},
+		{24 * time.Hour, transmitPerDay},
+	} {
+		n, err := app.transmissions.CountFrom(origin, now.Add(-limit.window))
+		if err != nil {
+			return "", err
+		}
+		if n >= limit.max {
+			return "Signal saturated. Your station has transmitted enough for now; try again later.", nil
+		}
+	}
+
+	return "", nil
+}
+
+// transmissionsHandler lists the moderation queue GET /admin/transmissions
+func (app *application) transmissionsHandler(w http.ResponseWriter, r *http.Request) {
+	pending, err := app.transmissions.Pending()
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	page := transmissionsPage{Pending: pending, Result: r.URL.Query().Get("result")}
+
+	app.render(w, r, page, "pages/transmissions.tmpl")
+}
+
+// transmissionApprovePostHandler publishes a transmission as an entry POST /admin/transmissions/{id}/approve
+func (app *application) transmissionApprovePostHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w, r)
+		return
+	}
+
+	t, err := app.transmissions.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	entryID, err := app.transmissions.Approve(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	// The published entry goes out like any other new one
+	entry, err := app.entries.Get(entryID)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	app.entryCreated(entry)
+
+	if err := app.reputation.Adjust(t.Origin, approvedReputation); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/transmissions?result="+url.QueryEscape("Published as "+entry.Permalink()), http.StatusSeeOther)
+}
+
+// transmissionRejectPostHandler drops a transmission from the queue POST /admin/transmissions/{id}/reject
+func (app *application) transmissionRejectPostHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil || id < 1 {
+		app.notFound(w, r)
+		return
+	}
+
+	t, err := app.transmissions.Get(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	err = app.transmissions.Reject(id)
+	if err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.reputation.Adjust(t.Origin, rejectedReputation); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/transmissions?result="+url.QueryEscape("Transmission rejected"), http.StatusSeeOther)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// typesPage is the data handed to types.tmpl
+type typesPage struct {
+	Counts  []*models.TypeCount
+	Targets []models.EntryType
+	From    []string                  // Types picked for the preview
+	To      string                    // Target picked for the preview
+	Plan    *models.TypeMigrationPlan // Set once a valid migration has been previewed
+	Error   string                    // Why the previewed migration can't run
+	History []*models.TypeMigration
+	Result  string // Outcome of the last migration, if any
+}
+
+// Picked reports whether a type is among those selected for the preview.
+func (p typesPage) Picked(key string) bool {
+	return slices.Contains(p.From, key)
+}
+
+// typesHandler lists the stored entry types and previews a migration between them GET /admin/types
+func (app *application) typesHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	page := typesPage{Targets: models.SelectableTypes(), From: q["from"], To: q.Get("to"), Result: q.Get("result")}
+
+	var err error
+	if page.Counts, err = app.typeMigrations.Counts(); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+	if page.History, err = app.typeMigrations.History(20); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	if page.To != "" {
+		if page.Plan, err = app.typeMigrations.Plan(page.From, page.To); err != nil {
+			page.Error = err.Error()
+		}
+	}
+
+	app.render(w, r, page, "pages/types.tmpl")
+}
+
+// typesMigratePostHandler retypes every entry of the chosen types POST /admin/types/migrate
+func (app *application) typesMigratePostHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad Request", 400)
+		return
+	}
+
+	mig, err := app.typeMigrations.Migrate(r.PostForm["from"], r.PostForm.Get("to"))
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Type migration failed", "err", err)
+		http.Redirect(w, r, "/admin/types?result="+url.QueryEscape("Migration refused: "+err.Error()), http.StatusSeeOther)
+		return
+	}
+
+	result := fmt.Sprintf("Moved %d entries from %s to %s", mig.Entries, strings.Join(mig.From, ", "), mig.To)
+	if mig.Detached > 0 {
+		result += fmt.Sprintf(", detaching %d from their threads", mig.Detached)
+	}
+	http.Redirect(w, r, "/admin/types?result="+url.QueryEscape(result), http.StatusSeeOther)
+}
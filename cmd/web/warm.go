@@ -0,0 +1,48 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// warmTemplates are the template sets parsed at boot: the public pages a visitor is
+// most likely to land on, and the error pages. Others are parsed on first use.
+var warmTemplates = [][]string{
+	{"pages/home.tmpl"},
+	mediaFiles,
+	thoughtsFiles,
+	{"partials/thought.tmpl", "pages/entry.tmpl"},
+	{"pages/404.tmpl"},
+	{"pages/500.tmpl"},
+	{"pages/error.tmpl"},
+}
+
+// warmUp does at boot what the first requests after a restart would otherwise pay
+// for: parsing the busiest templates and running the media and thoughts queries, so
+// SQLite opens its connections and pulls those pages into its cache. Failures are
+// only logged; the request that needs the same thing will report it properly.
+func (app *application) warmUp() {
+	start := time.Now()
+
+	for _, files := range warmTemplates {
+		if _, err := app.templates.get(files...); err != nil {
+			slog.Error("Warm-up: template failed to parse", "templates", files, "err", err)
+		}
+	}
+
+	media, _, err := app.entries.List(models.EntryFilter{Section: "media", Limit: sectorPageSize})
+	if err != nil {
+		slog.Error("Warm-up: media query failed", "err", err)
+	}
+	thoughts, _, err := app.entries.List(models.EntryFilter{Section: "thoughts", Limit: sectorPageSize})
+	if err != nil {
+		slog.Error("Warm-up: thoughts query failed", "err", err)
+	}
+	if err := app.badgeEpochs(append(media, thoughts...)); err != nil {
+		slog.Error("Warm-up: epochs query failed", "err", err)
+	}
+
+	slog.Info("Warmed up", "took", time.Since(start).Round(time.Millisecond))
+}
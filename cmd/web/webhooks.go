@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+const jobWebhook = "webhook"
+
+// Entry lifecycle events sent to webhook endpoints.
+const (
+	eventEntryCreated = "entry.created"
+	eventEntryUpdated = "entry.updated"
+	eventEntryDeleted = "entry.deleted" // Carries the entry as it was just before deletion
+)
+
+// webhookJob is the payload of a "webhook" job: one logged delivery.
+type webhookJob struct {
+	DeliveryID string `json:"delivery_id"`
+}
+
+// webhookEvent is the JSON body of a delivery.
+type webhookEvent struct {
+	ID         string         `json:"id"` // Delivery ID, repeated in X-Sacrif-Delivery
+	Event      string         `json:"event"`
+	OccurredAt time.Time      `json:"occurred_at"`
+	Entry      *entryResource `json:"entry"`
+}
+
+// webhookEndpoints reads the comma-separated endpoint URLs from SACRIF_WEBHOOK_URLS.
+func webhookEndpoints() ([]string, error) {
+	var endpoints []string
+	for _, raw := range strings.Split(os.Getenv("SACRIF_WEBHOOK_URLS"), ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("%q is not an http(s) URL", raw)
+		}
+		endpoints = append(endpoints, raw)
+	}
+	return endpoints, nil
+}
+
+// emitEntryEvent logs one delivery per endpoint with a snapshot of the entry as it is
+// now, and queues them. Like syndication, failures are logged rather than surfaced:
+// the entry itself is already safely stored.
+func (app *application) emitEntryEvent(event string, entryID int) {
+	if len(app.webhookURLs) == 0 {
+		return
+	}
+
+	entry, err := app.entries.Get(entryID)
+	if err != nil {
+		slog.Error("Failed to load entry for webhooks", "entry", entryID, "event", event, "err", err)
+		return
+	}
+	snapshot, err := app.loadEntryResource(entry)
+	if err != nil {
+		slog.Error("Failed to load entry for webhooks", "entry", entryID, "event", event, "err", err)
+		return
+	}
+
+	app.emitEntrySnapshot(event, snapshot)
+}
+
+// emitEntrySnapshot queues deliveries of an entry snapshot taken earlier, for
+// events where the entry can't be loaded any more.
+func (app *application) emitEntrySnapshot(event string, snapshot *entryResource) {
+	if len(app.webhookURLs) == 0 {
+		return
+	}
+
+	now := time.Now().UTC()
+
+	for _, endpoint := range app.webhookURLs {
+		id := rand.Text()
+		payload, err := json.Marshal(webhookEvent{ID: id, Event: event, OccurredAt: now, Entry: snapshot})
+		if err != nil {
+			slog.Error("Failed to encode webhook payload", "err", err)
+			return
+		}
+
+		d := &models.WebhookDelivery{ID: id, Event: event, EntryID: snapshot.ID, Endpoint: endpoint, Payload: payload}
+		if err := app.webhooks.Insert(d); err != nil {
+			slog.Error("Failed to log webhook delivery", "err", err)
+			continue
+		}
+		if _, err := app.jobs.Enqueue(jobWebhook, webhookJob{DeliveryID: id}); err != nil {
+			slog.Error("Failed to enqueue webhook job", "err", err)
+		}
+	}
+}
+
+// runWebhookJob sends one logged delivery and records how it went. Returning the
+// error lets the job runner retry with backoff.
+func (app *application) runWebhookJob(ctx context.Context, payload []byte) error {
+	var job webhookJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return err
+	}
+
+	d, err := app.webhooks.Get(job.DeliveryID)
+	if errors.Is(err, models.ErrNoRecord) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if d.Status == models.DeliveryDelivered {
+		return nil
+	}
+
+	// An endpoint dropped from the configuration shouldn't keep receiving events
+	if !slices.Contains(app.webhookURLs, d.Endpoint) {
+		err = fmt.Errorf("webhook endpoint %s is no longer configured", d.Endpoint)
+		if recErr := app.webhooks.RecordAttempt(d.ID, 0, err); recErr != nil {
+			return recErr
+		}
+		return nil
+	}
+
+	status, sendErr := app.webhookSender.Send(ctx, d.Endpoint, d.Event, d.ID, d.Payload)
+	if err := app.webhooks.RecordAttempt(d.ID, status, sendErr); err != nil {
+		slog.Error("Failed to record webhook delivery", "delivery", d.ID, "err", err)
+	}
+	return sendErr
+}
+
+type webhooksPage struct {
+	Endpoints  []string
+	Deliveries []*models.WebhookDelivery
+	Result     string
+}
+
+// webhooksHandler shows the deliveries log GET /admin/webhooks
+func (app *application) webhooksHandler(w http.ResponseWriter, r *http.Request) {
+	deliveries, err := app.webhooks.Recent(200)
+	if err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	app.render(w, r, webhooksPage{
+		Endpoints:  app.webhookURLs,
+		Deliveries: deliveries,
+		Result:     r.URL.Query().Get("result"),
+	}, "pages/webhooks.tmpl")
+}
+
+// webhookRedeliverPostHandler sends a logged delivery again, same ID and payload, POST /admin/webhooks/{id}/redeliver
+func (app *application) webhookRedeliverPostHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	if err := app.webhooks.Requeue(id); err != nil {
+		if errors.Is(err, models.ErrNoRecord) {
+			app.notFound(w, r)
+		} else {
+			app.serverError(w, r, err)
+		}
+		return
+	}
+
+	if _, err := app.jobs.Enqueue(jobWebhook, webhookJob{DeliveryID: id}); err != nil {
+		app.serverError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/webhooks?result="+url.QueryEscape("Delivery "+id+" queued again"), http.StatusSeeOther)
+}
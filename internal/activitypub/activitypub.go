@@ -0,0 +1,146 @@
+// Package activitypub speaks the small part of ActivityPub a publish-only actor
+// needs: documents describing the actor and its posts, HTTP Signatures on every
+// request in both directions (as Mastodon requires them), and a client that fetches
+// remote actors and delivers activities to their inboxes.
+package activitypub
+
+import (
+	"encoding/json"
+	"mime"
+	"strings"
+	"time"
+)
+
+// ContentType is the media type of ActivityPub documents.
+const ContentType = "application/activity+json"
+
+// Public addresses an activity to everyone, which is what makes a post public.
+const Public = "https://www.w3.org/ns/activitystreams#Public"
+
+// Context is the JSON-LD context of every document the station serves: the
+// ActivityStreams vocabulary plus the security one publicKey comes from.
+var Context = []string{"https://www.w3.org/ns/activitystreams", "https://w3id.org/security/v1"}
+
+// IsActivityType reports whether a Content-Type or Accept entry names an
+// ActivityPub document, either as activity+json or as ld+json with the
+// ActivityStreams profile.
+func IsActivityType(value string) bool {
+	mediaType, params, err := mime.ParseMediaType(value)
+	if err != nil {
+		return false
+	}
+	switch mediaType {
+	case ContentType:
+		return true
+	case "application/ld+json":
+		return strings.Contains(params["profile"], "https://www.w3.org/ns/activitystreams")
+	}
+	return false
+}
+
+// Actor is a person, service or other account.
+type Actor struct {
+	Context                   any        `json:"@context,omitempty"`
+	ID                        string     `json:"id"`
+	Type                      string     `json:"type"`
+	PreferredUsername         string     `json:"preferredUsername,omitempty"`
+	Name                      string     `json:"name,omitempty"`
+	Summary                   string     `json:"summary,omitempty"`
+	URL                       string     `json:"url,omitempty"`
+	Inbox                     string     `json:"inbox"`
+	Outbox                    string     `json:"outbox,omitempty"`
+	Followers                 string     `json:"followers,omitempty"`
+	Endpoints                 *Endpoints `json:"endpoints,omitempty"`
+	PublicKey                 PublicKey  `json:"publicKey"`
+	ManuallyApprovesFollowers bool       `json:"manuallyApprovesFollowers"`
+	Discoverable              bool       `json:"discoverable,omitempty"`
+}
+
+// Endpoints lists an actor's optional extra endpoints.
+type Endpoints struct {
+	SharedInbox string `json:"sharedInbox,omitempty"` // One inbox for every account on a server
+}
+
+// PublicKey is the key an actor's requests are signed with.
+type PublicKey struct {
+	ID           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKeyPem string `json:"publicKeyPem"`
+}
+
+// DeliveryInbox is where activities for the actor should go: the server's shared
+// inbox when it has one, so a post reaches every follower there in one request.
+func (a *Actor) DeliveryInbox() string {
+	if a.Endpoints != nil && a.Endpoints.SharedInbox != "" {
+		return a.Endpoints.SharedInbox
+	}
+	return a.Inbox
+}
+
+// Note is a post.
+type Note struct {
+	Context      any        `json:"@context,omitempty"`
+	ID           string     `json:"id"`
+	Type         string     `json:"type"` // Always "Note"
+	AttributedTo string     `json:"attributedTo"`
+	Content      string     `json:"content"` // HTML
+	URL          string     `json:"url,omitempty"`
+	InReplyTo    string     `json:"inReplyTo,omitempty"`
+	Published    time.Time  `json:"published"`
+	Updated      *time.Time `json:"updated,omitempty"`
+	To           []string   `json:"to"`
+	Cc           []string   `json:"cc,omitempty"`
+}
+
+// Activity is an action taken by an actor on an object, e.g. Create on a Note or
+// Accept on a Follow.
+type Activity struct {
+	Context   any       `json:"@context,omitempty"`
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Actor     string    `json:"actor"`
+	Object    any       `json:"object"`
+	Published time.Time `json:"published,omitzero"`
+	To        []string  `json:"to,omitempty"`
+	Cc        []string  `json:"cc,omitempty"`
+}
+
+// OrderedCollection is a list such as an outbox or a followers collection.
+type OrderedCollection struct {
+	Context      any    `json:"@context,omitempty"`
+	ID           string `json:"id"`
+	Type         string `json:"type"` // Always "OrderedCollection"
+	TotalItems   int    `json:"totalItems"`
+	OrderedItems []any  `json:"orderedItems,omitempty"`
+}
+
+// Incoming is an activity as received in an inbox, where the object may be
+// embedded or referred to by its ID.
+type Incoming struct {
+	ID     string          `json:"id"`
+	Type   string          `json:"type"`
+	Actor  string          `json:"actor"`
+	Object json.RawMessage `json:"object"`
+}
+
+// ObjectID returns the ID of the activity's object, embedded or not.
+func (in *Incoming) ObjectID() string {
+	var id string
+	if json.Unmarshal(in.Object, &id) == nil {
+		return id
+	}
+	var obj struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(in.Object, &obj)
+	return obj.ID
+}
+
+// ObjectType returns the type of an embedded object, or "" when only its ID was sent.
+func (in *Incoming) ObjectType() string {
+	var obj struct {
+		Type string `json:"type"`
+	}
+	json.Unmarshal(in.Object, &obj)
+	return obj.Type
+}
@@ -0,0 +1,192 @@
+package activitypub
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// maxDocument caps how much of a remote document is read.
+const maxDocument = 1 << 20
+
+// ErrPrivateAddress means a remote server resolved to an address on the station's
+// own machine or network, which anyone can name as their actor or inbox to make
+// the station send requests there.
+var ErrPrivateAddress = errors.New("activitypub: refusing to connect to a non-public address")
+
+// Client signs its requests as the station's actor, which servers in authorized
+// fetch mode demand even for reading an actor document.
+type Client struct {
+	KeyID     string // e.g. "https://station.example/ap/actor#main-key"
+	Key       *rsa.PrivateKey
+	UserAgent string
+	HTTP      *http.Client
+}
+
+// NewClient returns a Client signing with key under keyID, which only connects to
+// public addresses over HTTPS.
+func NewClient(keyID string, key *rsa.PrivateKey) *Client {
+	// The address is checked as it is dialled, after DNS, so a name that resolves
+	// somewhere public at first and privately later is still caught. No proxy, since
+	// the dial would be to the proxy instead
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: publicOnly}
+	return &Client{
+		KeyID:     keyID,
+		Key:       key,
+		UserAgent: "SacrifStation-ActivityPub/1.0",
+		HTTP: &http.Client{
+			Timeout: 15 * time.Second,
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				ForceAttemptHTTP2:   true,
+				TLSHandshakeTimeout: 10 * time.Second,
+				MaxIdleConns:        100,
+				IdleConnTimeout:     90 * time.Second,
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if req.URL.Scheme != "https" {
+					return fmt.Errorf("redirected to non-HTTPS %s", req.URL)
+				}
+				if len(via) >= 5 {
+					return errors.New("too many redirects")
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// publicOnly is the dialer's Control hook, refusing addresses on this machine or
+// its networks.
+func publicOnly(network, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !publicAddr(ap.Addr()) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, address)
+	}
+	return nil
+}
+
+// publicAddr reports whether addr is reachable on the internet at large, rather
+// than loopback, private, link-local or unspecified.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() && !addr.IsLoopback() && !addr.IsPrivate() && !addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() && !addr.IsInterfaceLocalMulticast() && !addr.IsUnspecified()
+}
+
+// httpsURL parses raw, which has to be an absolute https URL.
+func httpsURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("activitypub: %q is not an https URL", raw)
+	}
+	return u, nil
+}
+
+// sameHost checks that an endpoint an actor names is served by the actor's own
+// server, so a document can't point deliveries at some other host.
+func sameHost(id *url.URL, endpoint string) error {
+	u, err := httpsURL(endpoint)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(u.Host, id.Host) {
+		return fmt.Errorf("activitypub: %s is not on %s", endpoint, id.Host)
+	}
+	return nil
+}
+
+// FetchActor reads a remote actor document. The ID in the document has to match
+// the one asked for, so a server can't answer for accounts it doesn't host, and its
+// inboxes have to be on the same server.
+func (c *Client) FetchActor(ctx context.Context, id string) (*Actor, error) {
+	idURL, err := httpsURL(id)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, id, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", ContentType)
+	req.Header.Set("User-Agent", c.UserAgent)
+	if err := Sign(req, c.KeyID, c.Key, nil); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", id, resp.Status)
+	}
+
+	var actor Actor
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxDocument)).Decode(&actor); err != nil {
+		return nil, fmt.Errorf("%s: %w", id, err)
+	}
+	if actor.ID != id {
+		return nil, fmt.Errorf("%s describes a different actor, %s", id, actor.ID)
+	}
+	if actor.Inbox == "" {
+		return nil, fmt.Errorf("%s has no inbox", id)
+	}
+	if err := sameHost(idURL, actor.Inbox); err != nil {
+		return nil, err
+	}
+	if actor.Endpoints != nil && actor.Endpoints.SharedInbox != "" {
+		if err := sameHost(idURL, actor.Endpoints.SharedInbox); err != nil {
+			return nil, err
+		}
+	}
+	return &actor, nil
+}
+
+// Deliver posts an activity to an inbox and returns the status code it answered
+// with. Anything but a 2xx answer is an error, but the status is still returned, so
+// callers can tell an inbox that is gone (410) from one that is down.
+func (c *Client) Deliver(ctx context.Context, inbox string, activity []byte) (int, error) {
+	if _, err := httpsURL(inbox); err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, inbox, bytes.NewReader(activity))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", ContentType)
+	req.Header.Set("User-Agent", c.UserAgent)
+	if err := Sign(req, c.KeyID, c.Key, activity); err != nil {
+		return 0, err
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return resp.StatusCode, fmt.Errorf("%s returned %s: %s", inbox, resp.Status, bytes.TrimSpace(msg))
+	}
+
+	// Drain so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,137 @@
+package activitypub
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var (
+	testKeyOnce sync.Once
+	testKey     *rsa.PrivateKey
+)
+
+// key is one actor key for the whole package's tests, as generating one is slow.
+func key(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	testKeyOnce.Do(func() {
+		var err error
+		if testKey, err = GenerateKey(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	return testKey
+}
+
+func TestPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr   string
+		public bool
+	}{
+		{"93.184.215.14", true},
+		{"2606:2800:21f:cb07:6820:80da:af6b:8b2c", true},
+		{"127.0.0.1", false},
+		{"127.8.8.8", false},
+		{"::1", false},
+		{"10.0.0.5", false},
+		{"172.16.3.4", false},
+		{"192.168.1.1", false},
+		{"fd00::1", false},
+		{"169.254.169.254", false}, // Cloud metadata services
+		{"fe80::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.1.2.3", false},
+	}
+	for _, tt := range tests {
+		if got := publicAddr(netip.MustParseAddr(tt.addr)); got != tt.public {
+			t.Errorf("publicAddr(%s) = %v, want %v", tt.addr, got, tt.public)
+		}
+	}
+}
+
+// The dial guard is what stops a name that resolves to a private address, so the
+// real client must refuse even a server it has been told to trust.
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	hit := false
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit = true
+	}))
+	defer srv.Close()
+
+	c := NewClient("https://station.example/ap/actor#main-key", key(t))
+	c.HTTP.Transport.(*http.Transport).TLSClientConfig = srv.Client().Transport.(*http.Transport).TLSClientConfig
+
+	if _, err := c.FetchActor(context.Background(), srv.URL+"/actor"); !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("FetchActor: got %v, want ErrPrivateAddress", err)
+	}
+	if _, err := c.Deliver(context.Background(), srv.URL+"/inbox", []byte("{}")); !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("Deliver: got %v, want ErrPrivateAddress", err)
+	}
+	if hit {
+		t.Error("a request reached the loopback server")
+	}
+}
+
+func TestClientRequiresHTTPS(t *testing.T) {
+	c := NewClient("https://station.example/ap/actor#main-key", key(t))
+	for _, u := range []string{"http://remote.example/users/a", "remote.example/users/a", "ftp://remote.example/a", ""} {
+		if _, err := c.FetchActor(context.Background(), u); err == nil || !strings.Contains(err.Error(), "https") {
+			t.Errorf("FetchActor(%q): got %v, want an https error", u, err)
+		}
+		if _, err := c.Deliver(context.Background(), u, []byte("{}")); err == nil || !strings.Contains(err.Error(), "https") {
+			t.Errorf("Deliver(%q): got %v, want an https error", u, err)
+		}
+	}
+}
+
+func TestFetchActorChecksInboxes(t *testing.T) {
+	var doc Actor
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(doc)
+	}))
+	defer srv.Close()
+
+	// The test server lives on loopback, so it is reached without the dial guard
+	c := NewClient("https://station.example/ap/actor#main-key", key(t))
+	c.HTTP = srv.Client()
+	id := srv.URL + "/users/a"
+
+	tests := []struct {
+		name    string
+		inbox   string
+		shared  string
+		wantErr bool
+	}{
+		{"own inbox", srv.URL + "/users/a/inbox", "", false},
+		{"own shared inbox", srv.URL + "/users/a/inbox", srv.URL + "/inbox", false},
+		{"inbox elsewhere", "https://victim.example/inbox", "", true},
+		{"shared inbox elsewhere", srv.URL + "/users/a/inbox", "https://victim.example/inbox", true},
+		{"plain http inbox", strings.Replace(srv.URL, "https", "http", 1) + "/inbox", "", true},
+		{"no inbox", "", "", true},
+	}
+	for _, tt := range tests {
+		doc = Actor{ID: id, Type: "Person", Inbox: tt.inbox}
+		if tt.shared != "" {
+			doc.Endpoints = &Endpoints{SharedInbox: tt.shared}
+		}
+		_, err := c.FetchActor(context.Background(), id)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: got %v, want error %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	// A server can't answer for an actor it doesn't host
+	doc = Actor{ID: "https://elsewhere.example/users/a", Inbox: srv.URL + "/inbox"}
+	if _, err := c.FetchActor(context.Background(), id); err == nil {
+		t.Error("accepted a document describing a different actor")
+	}
+}
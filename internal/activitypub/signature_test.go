@@ -0,0 +1,167 @@
+package activitypub
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testKeyID = "https://remote.example/users/a#main-key"
+
+// signed returns a request signed as testKeyID, with body if it isn't nil.
+func signed(t *testing.T, body []byte) *http.Request {
+	t.Helper()
+	method := http.MethodGet
+	if body != nil {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequest(method, "https://station.example/ap/inbox", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Sign(req, testKeyID, key(t), body); err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"type":"Follow"}`)
+	lookup := func(keyID string) (*rsa.PublicKey, error) {
+		if keyID != testKeyID {
+			return nil, errors.New("unknown key")
+		}
+		return &key(t).PublicKey, nil
+	}
+
+	tests := []struct {
+		name    string
+		body    []byte
+		tamper  func(r *http.Request) []byte // Returns the body the server sees
+		wantErr string
+	}{
+		{name: "signed GET"},
+		{name: "signed POST", body: body},
+		{
+			name: "unsigned",
+			tamper: func(r *http.Request) []byte {
+				r.Header.Del("Signature")
+				return nil
+			},
+			wantErr: "isn't signed",
+		},
+		{
+			name: "body changed after signing",
+			body: body,
+			tamper: func(r *http.Request) []byte {
+				return []byte(`{"type":"Undo"}`)
+			},
+			wantErr: "Digest",
+		},
+		{
+			name: "digest swapped to match a new body",
+			body: body,
+			tamper: func(r *http.Request) []byte {
+				forged := []byte(`{"type":"Undo"}`)
+				r.Header.Set("Digest", digest(forged))
+				return forged
+			},
+			wantErr: "doesn't verify",
+		},
+		{
+			name: "expired Date",
+			tamper: func(r *http.Request) []byte {
+				r.Header.Set("Date", time.Now().Add(-2*maxClockSkew).UTC().Format(http.TimeFormat))
+				return nil
+			},
+			wantErr: "Date",
+		},
+		{
+			name: "Date in the future",
+			tamper: func(r *http.Request) []byte {
+				r.Header.Set("Date", time.Now().Add(2*maxClockSkew).UTC().Format(http.TimeFormat))
+				return nil
+			},
+			wantErr: "Date",
+		},
+		{
+			name: "missing Date",
+			tamper: func(r *http.Request) []byte {
+				r.Header.Del("Date")
+				return nil
+			},
+			wantErr: "Date",
+		},
+		{
+			name: "Date not signed",
+			tamper: func(r *http.Request) []byte {
+				signedHeaders(r, "(request-target) host")
+				return nil
+			},
+			wantErr: "date is not signed",
+		},
+		{
+			name: "request target not signed",
+			tamper: func(r *http.Request) []byte {
+				signedHeaders(r, "host date")
+				return nil
+			},
+			wantErr: "(request-target) is not signed",
+		},
+		{
+			name: "digest not signed for a body",
+			body: body,
+			tamper: func(r *http.Request) []byte {
+				signedHeaders(r, "(request-target) host date")
+				return body
+			},
+			wantErr: "digest is not signed",
+		},
+		{
+			name: "unknown key",
+			tamper: func(r *http.Request) []byte {
+				r.Header.Set("Signature", strings.Replace(r.Header.Get("Signature"), testKeyID, "https://other.example/k", 1))
+				return nil
+			},
+			wantErr: "fetching key",
+		},
+		{
+			name: "signed for another path",
+			tamper: func(r *http.Request) []byte {
+				r.URL.Path = "/ap/elsewhere"
+				return nil
+			},
+			wantErr: "doesn't verify",
+		},
+	}
+
+	for _, tt := range tests {
+		r := signed(t, tt.body)
+		received := tt.body
+		if tt.tamper != nil {
+			received = tt.tamper(r)
+		}
+
+		keyID, err := Verify(r, received, lookup)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.wantErr == "" && keyID != testKeyID:
+			t.Errorf("%s: verified as %q, want %q", tt.name, keyID, testKeyID)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: got %v, want an error mentioning %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+// signedHeaders rewrites the list of headers a request's signature claims to cover.
+func signedHeaders(r *http.Request, headers string) {
+	sig := r.Header.Get("Signature")
+	start := strings.Index(sig, `headers="`) + len(`headers="`)
+	end := start + strings.Index(sig[start:], `"`)
+	r.Header.Set("Signature", sig[:start]+headers+sig[end:])
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidCookie is returned for malformed or tampered cookie values.
+	ErrInvalidCookie = errors.New("auth: invalid signed cookie")
+	// ErrExpiredCookie is returned when a correctly signed cookie is past its expiry.
+	ErrExpiredCookie = errors.New("auth: signed cookie expired")
+)
+
+// Session is the state carried inside a signed cookie. It lives entirely on the
+// client, so nothing is written to SQLite on login or on each request.
+type Session struct {
+	Subject string    `json:"sub"`
+	Expires time.Time `json:"exp"`
+	Flash   string    `json:"flash,omitempty"` // One-off message for the next page, e.g. "Entry logged"
+}
+
+// Signer signs and verifies cookie values with HMAC-SHA256.
+//
+// Keys are ordered newest first: the first key signs, every key verifies. To rotate,
+// prepend a new key and drop the oldest once existing cookies have expired.
+type Signer struct {
+	keys [][]byte
+}
+
+// NewSigner returns a Signer for the given keys (newest first).
+func NewSigner(keys ...[]byte) (*Signer, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("auth: at least one signing key is required")
+	}
+	for _, k := range keys {
+		if len(k) < 32 {
+			return nil, errors.New("auth: signing keys must be at least 32 bytes")
+		}
+	}
+	return &Signer{keys: keys}, nil
+}
+
+// Sign encodes the session as "payload.signature".
+func (s *Signer) Sign(sess Session) (string, error) {
+	payload, err := json.Marshal(sess)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(mac(s.keys[0], encoded)), nil
+}
+
+// Verify checks the signature against every known key and decodes the session.
+func (s *Signer) Verify(value string) (Session, error) {
+	var sess Session
+
+	encoded, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return sess, ErrInvalidCookie
+	}
+
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return sess, ErrInvalidCookie
+	}
+
+	valid := false
+	for _, k := range s.keys {
+		if hmac.Equal(got, mac(k, encoded)) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return sess, ErrInvalidCookie
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return sess, ErrInvalidCookie
+	}
+	if err := json.Unmarshal(payload, &sess); err != nil {
+		return sess, ErrInvalidCookie
+	}
+
+	if time.Now().After(sess.Expires) {
+		return sess, ErrExpiredCookie
+	}
+
+	return sess, nil
+}
+
+func mac(key []byte, msg string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(msg))
+	return h.Sum(nil)
+}
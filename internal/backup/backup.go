@@ -0,0 +1,214 @@
+// Package backup writes self-verifying archives of the compendium: every entry with
+// its content checksum, every attachment's bytes, and a SHA256SUMS manifest that can
+// optionally be signed with minisign.
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+	"github.com/federicopalou/sacrif-station/internal/storage"
+)
+
+// Snapshot is everything that goes into one archive.
+type Snapshot struct {
+	Name        string // Archive name, recorded in the signed trusted comment
+	CreatedAt   time.Time
+	Entries     []*models.Entry
+	Attachments []*models.Attachment
+	ExternalIDs []*models.ExternalID
+	Blobs       *storage.Blobs
+}
+
+// attachmentRecord is how an attachment is written to attachments.json.
+type attachmentRecord struct {
+	ID          int       `json:"id"`
+	EntryID     int       `json:"entry_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"` // Also the name of the file under blobs/
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+const readme = `Sacrif Station backup
+
+entries.json      Every entry. "checksum" is the SHA-256 (hex) of the fields below,
+                  each written as "name:length:value\n" in this order, where length
+                  is the value's size in bytes, NULLs are empty and created_at is
+                  RFC 3339 in UTC: title, type, content, url, mood, parent_id,
+                  created_at, feed_summary, exclude_from_feed, canonical_url.
+                  "external_ids" (catalogue IDs such as ISBNs) and "fields" (custom
+                  attributes) are not checksummed.
+attachments.json  Every attachment, pointing at its bytes in blobs/<sha256>.
+SHA256SUMS        Checksums of every other file. Check with: sha256sum -c SHA256SUMS
+SHA256SUMS.minisig
+                  Present when the station had a signing key. Check the manifest with:
+                  minisign -Vm SHA256SUMS -P <public key>
+`
+
+// Write streams snap to w as a gzipped tar archive. When signer is non-nil the
+// manifest is signed and SHA256SUMS.minisig is added.
+func Write(w io.Writer, snap *Snapshot, signer *Signer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	a := &archive{tw: tw, modTime: snap.CreatedAt.UTC(), sums: map[string]string{}}
+
+	externalIDs := map[int][]*models.ExternalID{}
+	for _, x := range snap.ExternalIDs {
+		externalIDs[x.EntryID] = append(externalIDs[x.EntryID], x)
+	}
+
+	entries := make([]*models.EntryRecord, 0, len(snap.Entries))
+	for _, e := range snap.Entries {
+		rec := e.Record()
+		rec.ExternalIDs = externalIDs[e.ID]
+		entries = append(entries, rec)
+	}
+
+	attachments := make([]attachmentRecord, 0, len(snap.Attachments))
+	for _, at := range snap.Attachments {
+		attachments = append(attachments, attachmentRecord{
+			ID:          at.ID,
+			EntryID:     at.EntryID,
+			Filename:    at.Filename,
+			ContentType: at.ContentType,
+			Size:        at.Size,
+			SHA256:      at.BlobHash,
+			CreatedAt:   at.CreatedAt.UTC(),
+		})
+	}
+
+	if err := a.addBytes("README.txt", []byte(readme)); err != nil {
+		return err
+	}
+	if err := a.addJSON("entries.json", entries); err != nil {
+		return err
+	}
+	if err := a.addJSON("attachments.json", attachments); err != nil {
+		return err
+	}
+
+	// Attachments can share a blob; each one is stored once
+	for _, at := range snap.Attachments {
+		name := "blobs/" + at.BlobHash
+		if _, done := a.sums[name]; done {
+			continue
+		}
+		if err := a.addBlob(snap.Blobs, at.BlobHash); err != nil {
+			return err
+		}
+	}
+
+	manifest := a.manifest()
+	if err := a.addFile("SHA256SUMS", manifest); err != nil {
+		return err
+	}
+	if signer != nil {
+		comment := fmt.Sprintf("%s created %s", snap.Name, a.modTime.Format(time.RFC3339))
+		if err := a.addFile("SHA256SUMS.minisig", signer.Sign(manifest, comment)); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// archive tracks the checksum of every file written so far.
+type archive struct {
+	tw      *tar.Writer
+	modTime time.Time
+	sums    map[string]string
+}
+
+func (a *archive) addJSON(name string, v any) error {
+	body, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return a.addBytes(name, append(body, '\n'))
+}
+
+// addBytes writes a file and records it in the manifest.
+func (a *archive) addBytes(name string, body []byte) error {
+	sum := sha256.Sum256(body)
+	a.sums[name] = hex.EncodeToString(sum[:])
+	return a.addFile(name, body)
+}
+
+// addFile writes a file without recording it, for the manifest and its signature.
+func (a *archive) addFile(name string, body []byte) error {
+	if err := a.header(name, int64(len(body))); err != nil {
+		return err
+	}
+	_, err := a.tw.Write(body)
+	return err
+}
+
+func (a *archive) addBlob(blobs *storage.Blobs, hash string) error {
+	f, err := blobs.Open(hash)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	name := "blobs/" + hash
+	if err := a.header(name, info.Size()); err != nil {
+		return err
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(a.tw, h), f); err != nil {
+		return err
+	}
+
+	// The blob store names files by their hash, so a mismatch means the copy on disk rotted
+	sum := hex.EncodeToString(h.Sum(nil))
+	if sum != hash {
+		return fmt.Errorf("backup: blob %s is corrupt on disk (hashes to %s)", hash, sum)
+	}
+	a.sums[name] = sum
+	return nil
+}
+
+func (a *archive) header(name string, size int64) error {
+	return a.tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    size,
+		ModTime: a.modTime,
+		Format:  tar.FormatPAX,
+	})
+}
+
+// manifest renders the recorded checksums in sha256sum(1) format, sorted by name.
+func (a *archive) manifest() []byte {
+	names := make([]string, 0, len(a.sums))
+	for name := range a.sums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s  %s\n", a.sums[name], name)
+	}
+	return b.Bytes()
+}
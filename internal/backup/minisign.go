@@ -0,0 +1,74 @@
+package backup
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Signer produces minisign-compatible signatures, so backups can be checked with
+// the stock tool: minisign -Vm SHA256SUMS -P <public key>.
+//
+// Signatures use minisign's original "Ed" algorithm (Ed25519 over the whole message),
+// which needs nothing beyond the standard library. That is why only the small
+// checksum manifest is signed; the manifest in turn pins every other file.
+type Signer struct {
+	key   ed25519.PrivateKey
+	keyID [8]byte
+}
+
+// NewSigner returns a Signer for a base64-encoded 32-byte Ed25519 seed, e.g. the
+// output of `openssl rand -base64 32`.
+func NewSigner(encodedSeed string) (*Signer, error) {
+	seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encodedSeed))
+	if err != nil {
+		return nil, fmt.Errorf("backup: signing key is not valid base64: %w", err)
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, errors.New("backup: signing key must decode to 32 bytes")
+	}
+
+	s := &Signer{key: ed25519.NewKeyFromSeed(seed)}
+
+	// minisign picks a random key ID; deriving it from the public key keeps it stable
+	sum := sha256.Sum256(s.key.Public().(ed25519.PublicKey))
+	copy(s.keyID[:], sum[:8])
+	return s, nil
+}
+
+// KeyID is the key ID as minisign prints it.
+func (s *Signer) KeyID() string {
+	return fmt.Sprintf("%016X", binary.LittleEndian.Uint64(s.keyID[:]))
+}
+
+// PublicKey is the base64 public key to pass to minisign -P.
+func (s *Signer) PublicKey() string {
+	blob := append([]byte("Ed"), s.keyID[:]...)
+	blob = append(blob, s.key.Public().(ed25519.PublicKey)...)
+	return base64.StdEncoding.EncodeToString(blob)
+}
+
+// PublicKeyFile is the contents of a minisign.pub file for the key.
+func (s *Signer) PublicKeyFile() string {
+	return fmt.Sprintf("untrusted comment: minisign public key %s\n%s\n", s.KeyID(), s.PublicKey())
+}
+
+// Sign returns a .minisig file for message. The trusted comment is covered by the
+// signature, so it is a safe place for the archive name and date.
+func (s *Signer) Sign(message []byte, trustedComment string) []byte {
+	sig := ed25519.Sign(s.key, message)
+	global := ed25519.Sign(s.key, append(append([]byte{}, sig...), trustedComment...))
+
+	blob := append([]byte("Ed"), s.keyID[:]...)
+	blob = append(blob, sig...)
+
+	return fmt.Appendf(nil, "untrusted comment: signature from sacrif-station key %s\n%s\ntrusted comment: %s\n%s\n",
+		s.KeyID(),
+		base64.StdEncoding.EncodeToString(blob),
+		trustedComment,
+		base64.StdEncoding.EncodeToString(global))
+}
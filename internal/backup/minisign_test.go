@@ -0,0 +1,108 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+// The seed bytes 0x00..0x1f, and the minisign public key and key ID they give
+const (
+	testSeed      = "AAECAwQFBgcICQoLDA0ODxAREhMUFRYXGBkaGxwdHh8="
+	testPublicKey = "RWRWR1qnVGNHTAOhB7/zzhC+HXDdGOdLwJln5NYwm6UNXx3chmQSVTG4"
+	testKeyID     = "4C476354A75A4756"
+)
+
+func TestSignerKey(t *testing.T) {
+	s, err := NewSigner(testSeed + "\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.PublicKey(); got != testPublicKey {
+		t.Errorf("PublicKey = %s, want %s", got, testPublicKey)
+	}
+	if got := s.KeyID(); got != testKeyID {
+		t.Errorf("KeyID = %s, want %s", got, testKeyID)
+	}
+	want := "untrusted comment: minisign public key " + testKeyID + "\n" + testPublicKey + "\n"
+	if got := s.PublicKeyFile(); got != want {
+		t.Errorf("PublicKeyFile = %q, want %q", got, want)
+	}
+
+	for _, seed := range []string{"not base64!", base64.StdEncoding.EncodeToString(make([]byte, 16))} {
+		if _, err := NewSigner(seed); err == nil {
+			t.Errorf("NewSigner(%q) accepted a bad seed", seed)
+		}
+	}
+}
+
+// Sign's output is checked the way minisign -V does it, against the known public
+// key rather than anything the Signer reports about itself.
+func TestSignVerifiesWithPublicKey(t *testing.T) {
+	s, err := NewSigner(testSeed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub, err := base64.StdEncoding.DecodeString(testPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pub) != 2+8+ed25519.PublicKeySize || string(pub[:2]) != "Ed" {
+		t.Fatalf("malformed test public key")
+	}
+	keyID, publicKey := pub[2:10], ed25519.PublicKey(pub[10:])
+
+	message := []byte("0123abcd  sacrif-backup-2026-10-14.tar.gz\n")
+	trusted := "timestamp:1792000000\tfile:SHA256SUMS"
+	out := string(s.Sign(message, trusted))
+
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("signature has %d lines, want 4:\n%s", len(lines), out)
+	}
+
+	if want := "untrusted comment: signature from sacrif-station key " + testKeyID; lines[0] != want {
+		t.Errorf("untrusted comment = %q, want %q", lines[0], want)
+	}
+
+	blob, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil {
+		t.Fatalf("signature line: %v", err)
+	}
+	if len(blob) != 2+8+ed25519.SignatureSize {
+		t.Fatalf("signature blob is %d bytes", len(blob))
+	}
+	if alg := string(blob[:2]); alg != "Ed" {
+		t.Errorf("algorithm = %q, want Ed", alg)
+	}
+	if !bytes.Equal(blob[2:10], keyID) {
+		t.Errorf("signature key ID %X, want %X", blob[2:10], keyID)
+	}
+	sig := blob[10:]
+	if !ed25519.Verify(publicKey, message, sig) {
+		t.Error("signature doesn't verify over the message")
+	}
+	if ed25519.Verify(publicKey, append(message, '!'), sig) {
+		t.Error("signature verifies over a changed message")
+	}
+
+	comment, ok := strings.CutPrefix(lines[2], "trusted comment: ")
+	if !ok || comment != trusted {
+		t.Errorf("trusted comment line = %q, want the comment %q", lines[2], trusted)
+	}
+
+	global, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil {
+		t.Fatalf("global signature line: %v", err)
+	}
+	signed := append(append([]byte{}, sig...), trusted...)
+	if !ed25519.Verify(publicKey, signed, global) {
+		t.Error("global signature doesn't verify over signature and trusted comment")
+	}
+	if ed25519.Verify(publicKey, append(append([]byte{}, sig...), "timestamp:0"...), global) {
+		t.Error("global signature verifies over a changed trusted comment")
+	}
+}
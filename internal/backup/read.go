@@ -0,0 +1,54 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// ErrNoEntries is returned for archives that don't contain an entries.json.
+var ErrNoEntries = errors.New("backup: archive has no entries.json")
+
+// ReadEntries reads the entry records from either a backup archive or a bare
+// entries.json, telling them apart by the gzip magic number.
+func ReadEntries(r io.Reader) ([]*models.EntryRecord, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(2)
+	if len(magic) < 2 || magic[0] != 0x1f || magic[1] != 0x8b {
+		return decodeEntries(br)
+	}
+
+	gz, err := gzip.NewReader(br)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, ErrNoEntries
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Name == "entries.json" {
+			return decodeEntries(tr)
+		}
+	}
+}
+
+func decodeEntries(r io.Reader) ([]*models.EntryRecord, error) {
+	var records []*models.EntryRecord
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, fmt.Errorf("backup: entries.json: %w", err)
+	}
+	return records, nil
+}
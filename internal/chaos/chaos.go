@@ -0,0 +1,106 @@
+// Package chaos injects faults on purpose: random latency, database errors and
+// panics, hitting a configurable share of requests, scraper runs and database
+// statements. It exists to check that error pages, retries and recovery behave as
+// designed when things go wrong, and is meant for development only.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ErrInjected is the error behind every fault the Monkey makes up.
+var ErrInjected = errors.New("chaos: injected fault")
+
+// Monkey decides which requests, runs and statements to sabotage. Nothing is hit
+// until Start is called, so the station can boot and migrate its schema cleanly.
+type Monkey struct {
+	Rate       float64       // Share hit, from 0 to 1
+	MaxLatency time.Duration // Upper bound on an injected delay
+
+	started atomic.Bool
+}
+
+// New parses a rate given as a percentage ("10" or "10%") and an optional maximum
+// latency ("2s", the default when empty).
+func New(rate, maxLatency string) (*Monkey, error) {
+	pct, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(rate), "%"), 64)
+	if err != nil || pct < 0 || pct > 100 {
+		return nil, fmt.Errorf("chaos rate %q: want a percentage between 0 and 100", rate)
+	}
+
+	m := &Monkey{Rate: pct / 100, MaxLatency: 2 * time.Second}
+	if maxLatency != "" {
+		if m.MaxLatency, err = time.ParseDuration(maxLatency); err != nil || m.MaxLatency <= 0 {
+			return nil, fmt.Errorf("chaos latency %q: want a positive duration", maxLatency)
+		}
+	}
+	return m, nil
+}
+
+// Start lets faults through from now on.
+func (m *Monkey) Start() {
+	m.started.Store(true)
+}
+
+func (m *Monkey) roll() bool {
+	return m.started.Load() && rand.Float64() < m.Rate
+}
+
+// sleep waits for a random delay up to MaxLatency, or until ctx is done.
+func (m *Monkey) sleep(ctx context.Context, what string) {
+	d := time.Duration(rand.Int63n(int64(m.MaxLatency)) + 1)
+	slog.WarnContext(ctx, "Chaos: delaying", "what", what, "delay", d.Round(time.Millisecond))
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
+
+// Handler delays or panics a share of the requests reaching next. Database errors
+// for requests come from the statements they run (see Driver).
+func (m *Monkey) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.roll() {
+			what := r.Method + " " + r.URL.Path
+			if rand.Intn(2) == 0 {
+				m.sleep(r.Context(), what)
+			} else {
+				slog.WarnContext(r.Context(), "Chaos: panicking", "what", what)
+				panic(fmt.Sprintf("chaos: injected panic in %s", what))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Strike is called at the start of a scraper run. For a share of runs it waits,
+// fails with ErrInjected or panics.
+func (m *Monkey) Strike(ctx context.Context, what string) error {
+	if !m.roll() {
+		return nil
+	}
+
+	switch rand.Intn(3) {
+	case 0:
+		m.sleep(ctx, what)
+	case 1:
+		slog.WarnContext(ctx, "Chaos: failing", "what", what)
+		return fmt.Errorf("%s: %w", what, ErrInjected)
+	default:
+		slog.WarnContext(ctx, "Chaos: panicking", "what", what)
+		panic(fmt.Sprintf("chaos: injected panic in %s", what))
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package chaos
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+var registered sync.Map // Wrapped driver names already passed to sql.Register
+
+// Driver registers a wrapper around the named database/sql driver that fails a share
+// of statements with ErrInjected, and returns the wrapper's name for sql.Open.
+func (m *Monkey) Driver(name string) (string, error) {
+	wrapped := name + "+chaos"
+	if _, ok := registered.Load(wrapped); ok {
+		return wrapped, nil
+	}
+
+	// Opening doesn't connect, it only looks the driver up
+	db, err := sql.Open(name, "")
+	if err != nil {
+		return "", err
+	}
+	inner := db.Driver()
+	db.Close()
+
+	if _, loaded := registered.LoadOrStore(wrapped, true); !loaded {
+		sql.Register(wrapped, &faultyDriver{Driver: inner, m: m})
+	}
+	return wrapped, nil
+}
+
+// statementError logs and returns an injected failure for a statement.
+func (m *Monkey) statementError(query string) error {
+	query = strings.Join(strings.Fields(query), " ")
+	if len(query) > 60 {
+		query = query[:60] + "…"
+	}
+	slog.Warn("Chaos: failing statement", "query", query)
+	return fmt.Errorf("database: %w", ErrInjected)
+}
+
+type faultyDriver struct {
+	driver.Driver
+	m *Monkey
+}
+
+func (d *faultyDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &faultyConn{Conn: conn, m: d.m}, nil
+}
+
+// faultyConn passes everything through to the real connection, except that a share
+// of statements fail before reaching it.
+type faultyConn struct {
+	driver.Conn
+	m *Monkey
+}
+
+func (c *faultyConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *faultyConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if c.m.roll() {
+		return nil, c.m.statementError(query)
+	}
+	if pc, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return pc.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *faultyConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	ec, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if c.m.roll() {
+		return nil, c.m.statementError(query)
+	}
+	return ec.ExecContext(ctx, query, args)
+}
+
+func (c *faultyConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	qc, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if c.m.roll() {
+		return nil, c.m.statementError(query)
+	}
+	return qc.QueryContext(ctx, query, args)
+}
+
+func (c *faultyConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if bc, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return bc.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *faultyConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
@@ -0,0 +1,88 @@
+// Package clientip works out which address a request came from. Behind a reverse
+// proxy every request connects from the proxy, so the client's own address has to
+// be read from X-Forwarded-For, but only as far as proxies the operator trusts
+// vouch for it: anyone can send the header, with whatever they like in it.
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Resolver finds the client address of requests. A nil Resolver trusts no proxy
+// and always answers with the connecting address.
+type Resolver struct {
+	trusted []netip.Prefix
+}
+
+// New returns a Resolver believing X-Forwarded-For from the proxies listed in
+// trusted: comma-separated addresses and CIDR ranges, e.g. "127.0.0.1,10.0.0.0/8".
+// An empty list trusts none, and gives a nil Resolver.
+func New(trusted string) (*Resolver, error) {
+	var prefixes []netip.Prefix
+	for _, item := range strings.Split(trusted, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		p, err := netip.ParsePrefix(item)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(item)
+			if addrErr != nil {
+				return nil, fmt.Errorf("trusted proxy %q: want an IP address or CIDR range", item)
+			}
+			p = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	if prefixes == nil {
+		return nil, nil
+	}
+	return &Resolver{trusted: prefixes}, nil
+}
+
+// IP returns the address r came from. When it connected from a trusted proxy, the
+// proxies' X-Forwarded-For entries are walked back from the nearest, and the first
+// address that isn't itself a trusted proxy is the client.
+func (res *Resolver) IP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !res.trusts(addr) {
+		return host
+	}
+
+	var hops []string
+	for _, v := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break // Nothing further along can be believed; the last proxy is as close as it gets
+		}
+		addr = hop.Unmap()
+		if !res.trusts(addr) {
+			break
+		}
+	}
+	return addr.String()
+}
+
+func (res *Resolver) trusts(addr netip.Addr) bool {
+	if res == nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range res.trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
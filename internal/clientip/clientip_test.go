@@ -0,0 +1,51 @@
+package clientip
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		trusted string
+		remote  string
+		xff     []string
+		want    string
+	}{
+		{"no proxy trusted", "", "203.0.113.7:4411", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"untrusted sender's header ignored", "127.0.0.1", "203.0.113.7:4411", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"trusted proxy", "127.0.0.1", "127.0.0.1:4411", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"trusted proxy without header", "127.0.0.1", "127.0.0.1:4411", nil, "127.0.0.1"},
+		{"spoofed entries left of the client", "10.0.0.0/8", "10.0.0.2:80", []string{"1.1.1.1, 198.51.100.1"}, "198.51.100.1"},
+		{"chain of trusted proxies", "10.0.0.0/8", "10.0.0.2:80", []string{"198.51.100.1, 10.0.0.9"}, "198.51.100.1"},
+		{"header split over lines", "10.0.0.0/8", "10.0.0.2:80", []string{"198.51.100.1", "10.0.0.9"}, "198.51.100.1"},
+		{"every hop trusted", "10.0.0.0/8", "10.0.0.2:80", []string{"10.0.0.5"}, "10.0.0.5"},
+		{"garbage hop", "10.0.0.0/8", "10.0.0.2:80", []string{"198.51.100.1, nonsense"}, "10.0.0.2"},
+		{"IPv6 proxy", "::1", "[::1]:80", []string{"2001:db8::5"}, "2001:db8::5"},
+		{"IPv4-mapped hop", "127.0.0.1", "127.0.0.1:80", []string{"::ffff:198.51.100.1"}, "198.51.100.1"},
+		{"no port", "", "203.0.113.7", nil, "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		res, err := New(tt.trusted)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remote
+		for _, v := range tt.xff {
+			r.Header.Add("X-Forwarded-For", v)
+		}
+		if got := res.IP(r); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNewRejectsGarbage(t *testing.T) {
+	for _, v := range []string{"localhost", "10.0.0.0/33", "10.0.0.1,,nope"} {
+		if _, err := New(v); err == nil {
+			t.Errorf("New(%q) accepted it", v)
+		}
+	}
+}
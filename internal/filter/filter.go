@@ -0,0 +1,196 @@
+// Package filter refuses unwanted traffic before it reaches the station's handlers:
+// addresses in blocked CIDR ranges, user agents matching blocked patterns, and
+// addresses making more requests per minute than the throttle allows. Rules can be
+// swapped at run time, and refusals are counted so they can be persisted.
+package filter
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/clientip"
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// ReasonThrottle counts requests refused by the throttle; rule refusals are counted
+// under the rule's kind.
+const ReasonThrottle = "throttle"
+
+// Set is a compiled collection of rules.
+type Set struct {
+	nets      []compiledNet
+	agents    []compiledAgent
+	perMinute int // 0 disables throttling
+}
+
+type compiledNet struct {
+	id  int
+	net *net.IPNet
+}
+
+type compiledAgent struct {
+	id int
+	re *regexp.Regexp
+}
+
+// Compile checks and compiles rules, together with the throttle limit.
+func Compile(rules []*models.FilterRule, perMinute int) (*Set, error) {
+	s := &Set{perMinute: perMinute}
+	for _, fr := range rules {
+		switch fr.Kind {
+		case models.FilterCIDR:
+			n, err := ParseCIDR(fr.Pattern)
+			if err != nil {
+				return nil, err
+			}
+			s.nets = append(s.nets, compiledNet{fr.ID, n})
+		case models.FilterAgent:
+			re, err := CompileAgent(fr.Pattern)
+			if err != nil {
+				return nil, err
+			}
+			s.agents = append(s.agents, compiledAgent{fr.ID, re})
+		default:
+			return nil, fmt.Errorf("filter: unknown rule kind %q", fr.Kind)
+		}
+	}
+	return s, nil
+}
+
+// ParseCIDR accepts a CIDR range or a single address.
+func ParseCIDR(pattern string) (*net.IPNet, error) {
+	if ip := net.ParseIP(pattern); ip != nil {
+		bits := 128
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 32
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+
+	_, n, err := net.ParseCIDR(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("filter: %q is neither an address nor a CIDR range", pattern)
+	}
+	return n, nil
+}
+
+// CompileAgent compiles a user-agent pattern, which matches case-insensitively.
+func CompileAgent(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, fmt.Errorf("filter: bad user-agent pattern: %w", err)
+	}
+	return re, nil
+}
+
+// match returns the reason and rule that refuse a request, if any.
+func (s *Set) match(ip net.IP, agent string) (reason string, ruleID int) {
+	if ip != nil {
+		for _, n := range s.nets {
+			if n.net.Contains(ip) {
+				return models.FilterCIDR, n.id
+			}
+		}
+	}
+	for _, a := range s.agents {
+		if a.re.MatchString(agent) {
+			return models.FilterAgent, a.id
+		}
+	}
+	return "", 0
+}
+
+// Filter is the middleware. The zero value lets everything through until Load is called.
+type Filter struct {
+	Exempt  func(*http.Request) bool // Requests it approves skip filtering, e.g. the signed-in operator
+	Clients *clientip.Resolver       // Finds addresses behind trusted proxies; nil trusts none
+
+	set atomic.Pointer[Set]
+
+	mu          sync.Mutex
+	windowStart time.Time
+	window      map[string]int // Requests per address in the current minute
+	byReason    map[string]int
+	byRule      map[int]int
+}
+
+// Load swaps in a new rule set; requests already in flight finish under the old one.
+func (f *Filter) Load(s *Set) {
+	f.set.Store(s)
+}
+
+// Handler wraps next, refusing filtered requests with 403 (rules) or 429 (throttle).
+func (f *Filter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := f.set.Load()
+		if s == nil || (f.Exempt != nil && f.Exempt(r)) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		host := f.Clients.IP(r)
+		if reason, id := s.match(net.ParseIP(host), r.UserAgent()); reason != "" {
+			f.count(reason, id)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		if s.perMinute > 0 {
+			if wait, ok := f.admit(host, s.perMinute); !ok {
+				f.count(ReasonThrottle, 0)
+				w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+				http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// admit counts a request from host against the current one-minute window, returning
+// how long until the window resets when the host is over its limit.
+func (f *Filter) admit(host string, perMinute int) (time.Duration, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	if f.window == nil || now.Sub(f.windowStart) >= time.Minute {
+		f.windowStart, f.window = now, map[string]int{}
+	}
+
+	f.window[host]++
+	if f.window[host] > perMinute {
+		return time.Minute - now.Sub(f.windowStart), false
+	}
+	return 0, true
+}
+
+func (f *Filter) count(reason string, ruleID int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.byReason == nil {
+		f.byReason, f.byRule = map[string]int{}, map[int]int{}
+	}
+	f.byReason[reason]++
+	if ruleID != 0 {
+		f.byRule[ruleID]++
+	}
+}
+
+// Drain returns the refusals counted since the last call and resets the counters.
+func (f *Filter) Drain() (byReason map[string]int, byRule map[int]int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	byReason, byRule = f.byReason, f.byRule
+	f.byReason, f.byRule = nil, nil
+	return byReason, byRule
+}
@@ -0,0 +1,140 @@
+// Package indieauth checks access tokens issued by an IndieAuth token endpoint,
+// which is how Micropub clients prove they post on the site owner's behalf. The
+// station issues no tokens itself; it asks the endpoint the owner signs in with.
+package indieauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidToken is returned for a token the endpoint doesn't vouch for, or that
+// was issued to somebody other than the site owner.
+var ErrInvalidToken = errors.New("indieauth: invalid token")
+
+// cacheTTL is how long a verified token is trusted before asking the endpoint again,
+// so a client posting a photo set doesn't mean a round trip per request. Revoking a
+// token takes at most this long to take effect.
+const cacheTTL = 2 * time.Minute
+
+// Token is what the endpoint says about a valid token.
+type Token struct {
+	Me       string   // The site the token was issued for
+	ClientID string   // The app holding it, e.g. https://quill.p3k.io/
+	Scopes   []string // e.g. "create", "update"
+}
+
+// HasScope reports whether the token was granted scope. The early Micropub "post"
+// scope counts as "create".
+func (t *Token) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope) || (scope == "create" && slices.Contains(t.Scopes, "post"))
+}
+
+// Verifier asks a token endpoint about bearer tokens.
+type Verifier struct {
+	Endpoint string // e.g. https://tokens.indieauth.com/token
+	Me       string // The site owner's URL; tokens for anyone else are refused
+	Client   *http.Client
+
+	mu    sync.Mutex
+	cache map[[32]byte]cachedToken
+}
+
+type cachedToken struct {
+	token   *Token
+	expires time.Time
+}
+
+// NewVerifier returns a Verifier accepting tokens the endpoint issued for me.
+func NewVerifier(endpoint, me string) *Verifier {
+	return &Verifier{Endpoint: endpoint, Me: me, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Verify asks the endpoint about a token, ErrInvalidToken meaning it said no or
+// that the token belongs to another site. Other errors mean the endpoint couldn't
+// be asked; they are worth a retry, not a rejection.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Token, error) {
+	key := sha256.Sum256([]byte(token))
+	v.mu.Lock()
+	if c, ok := v.cache[key]; ok && time.Now().Before(c.expires) {
+		v.mu.Unlock()
+		return c.token, nil
+	}
+	v.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.Endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, ErrInvalidToken
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("indieauth: %s returned %s", v.Endpoint, resp.Status)
+	}
+
+	var body struct {
+		Me       string `json:"me"`
+		ClientID string `json:"client_id"`
+		Scope    string `json:"scope"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("indieauth: %s: %w", v.Endpoint, err)
+	}
+	if body.Me == "" || !SameSite(body.Me, v.Me) {
+		return nil, ErrInvalidToken
+	}
+
+	t := &Token{Me: body.Me, ClientID: body.ClientID, Scopes: strings.Fields(body.Scope)}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.cache == nil {
+		v.cache = map[[32]byte]cachedToken{}
+	}
+	now := time.Now()
+	for k, c := range v.cache {
+		if now.After(c.expires) {
+			delete(v.cache, k)
+		}
+	}
+	v.cache[key] = cachedToken{token: t, expires: now.Add(cacheTTL)}
+	return t, nil
+}
+
+// SameSite compares two profile URLs the way IndieAuth canonicalizes them: the
+// scheme and host are case-insensitive and an empty path is "/".
+func SameSite(a, b string) bool {
+	ua, errA := url.Parse(a)
+	ub, errB := url.Parse(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	canon := func(u *url.URL) string {
+		p := u.Path
+		if p == "" {
+			p = "/"
+		}
+		return strings.ToLower(u.Scheme) + "://" + strings.ToLower(u.Host) + p
+	}
+	return canon(ua) == canon(ub)
+}
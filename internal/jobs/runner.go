@@ -0,0 +1,116 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// Handler performs one job, given its raw JSON payload.
+type Handler func(ctx context.Context, payload []byte) error
+
+// Runner polls the job queue and dispatches due jobs to the handler registered
+// for their kind. Failed jobs are retried with exponential backoff.
+type Runner struct {
+	Jobs         *models.JobModel
+	PollInterval time.Duration
+	Windows      map[string]*Window // Per kind; jobs outside their window wait in the queue
+
+	handlers map[string]Handler
+}
+
+// Handle registers the handler for a job kind.
+func (r *Runner) Handle(kind string, h Handler) {
+	if r.handlers == nil {
+		r.handlers = map[string]Handler{}
+	}
+	r.handlers[kind] = h
+}
+
+// Run processes jobs until ctx is cancelled.
+func (r *Runner) Run(ctx context.Context) {
+	interval := r.PollInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// Drain everything that's due before going back to sleep
+		for ctx.Err() == nil && r.runOne(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runOne claims and runs a single job, reporting whether there was one to run.
+func (r *Runner) runOne(ctx context.Context) bool {
+	job, err := r.Jobs.Claim(r.closedKinds(time.Now())...)
+	if err != nil {
+		if !errors.Is(err, models.ErrNoRecord) {
+			slog.Error("Job claim error", "err", err)
+		}
+		return false
+	}
+
+	err = r.dispatch(ctx, job)
+	if err == nil {
+		if err := r.Jobs.Complete(job.ID); err != nil {
+			slog.Error("Job complete error", "err", err)
+		}
+		return true
+	}
+
+	slog.Warn("Job attempt failed", "job", job.ID, "kind", job.Kind, "attempt", job.Attempts, "max_attempts", job.MaxAttempts, "err", err)
+	if err := r.Jobs.Fail(job.ID, err, time.Now().Add(Backoff(job.Attempts))); err != nil {
+		slog.Error("Job fail error", "err", err)
+	}
+	return true
+}
+
+// closedKinds lists the job kinds whose window is shut at t.
+func (r *Runner) closedKinds(t time.Time) []string {
+	var closed []string
+	for kind, w := range r.Windows {
+		if !w.Open(t) {
+			closed = append(closed, kind)
+		}
+	}
+	return closed
+}
+
+func (r *Runner) dispatch(ctx context.Context, job *models.Job) (err error) {
+	h, ok := r.handlers[job.Kind]
+	if !ok {
+		return fmt.Errorf("no handler registered for job kind %q", job.Kind)
+	}
+
+	// A panicking handler fails its job instead of taking the whole station down
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	return h(ctx, job.Payload)
+}
+
+// Backoff returns the delay before the next attempt: 30s, 1m, 2m, 4m... capped at 6h.
+func Backoff(attempts int) time.Duration {
+	d := 30 * time.Second
+	for i := 1; i < attempts && d < 6*time.Hour; i++ {
+		d *= 2
+	}
+	return min(d, 6*time.Hour)
+}
@@ -0,0 +1,86 @@
+package jobs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Window is the time of day background work is allowed to run, in local time, e.g.
+// "02:00-06:00" or "22:00-06:00,13:00-14:00". Ranges may wrap past midnight. A nil
+// Window is always open.
+type Window struct {
+	spans []span
+	text  string
+}
+
+// span is one range within a window, in minutes since local midnight
+type span struct {
+	start, end int
+}
+
+// ParseWindow parses a comma-separated list of HH:MM-HH:MM ranges. An empty string
+// or "always" returns nil, which never holds anything back.
+func ParseWindow(s string) (*Window, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "always") {
+		return nil, nil
+	}
+
+	w := &Window{text: s}
+	for _, part := range strings.Split(s, ",") {
+		from, to, ok := strings.Cut(strings.TrimSpace(part), "-")
+		if !ok {
+			return nil, fmt.Errorf("window %q: want HH:MM-HH:MM", part)
+		}
+		start, err := minuteOfDay(from)
+		if err != nil {
+			return nil, fmt.Errorf("window %q: %w", part, err)
+		}
+		end, err := minuteOfDay(to)
+		if err != nil {
+			return nil, fmt.Errorf("window %q: %w", part, err)
+		}
+		if start == end {
+			return nil, fmt.Errorf("window %q is empty", part)
+		}
+		w.spans = append(w.spans, span{start, end})
+	}
+	return w, nil
+}
+
+func minuteOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("bad time %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Open reports whether work may run at t.
+func (w *Window) Open(t time.Time) bool {
+	if w == nil {
+		return true
+	}
+
+	t = t.Local()
+	m := t.Hour()*60 + t.Minute()
+	for _, s := range w.spans {
+		if s.start < s.end && m >= s.start && m < s.end {
+			return true
+		}
+		// Wraps past midnight, e.g. 22:00-06:00
+		if s.start > s.end && (m >= s.start || m < s.end) {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the window as configured, or "always".
+func (w *Window) String() string {
+	if w == nil {
+		return "always"
+	}
+	return w.text
+}
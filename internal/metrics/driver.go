@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync"
+	"time"
+)
+
+var registered sync.Map // Wrapped driver names already passed to sql.Register
+
+// Driver registers a wrapper around the named database/sql driver that times every
+// statement into h, labelled with database and "query" or "exec", and returns the
+// wrapper's name for sql.Open. Timing stops when the driver hands back its result,
+// so reading a long result set isn't counted.
+func Driver(name, database string, h *HistogramVec) (string, error) {
+	wrapped := name + "+metrics:" + database
+	if _, ok := registered.Load(wrapped); ok {
+		return wrapped, nil
+	}
+
+	// Opening doesn't connect, it only looks the driver up
+	db, err := sql.Open(name, "")
+	if err != nil {
+		return "", err
+	}
+	inner := db.Driver()
+	db.Close()
+
+	if _, loaded := registered.LoadOrStore(wrapped, true); !loaded {
+		sql.Register(wrapped, &timedDriver{Driver: inner, database: database, h: h})
+	}
+	return wrapped, nil
+}
+
+type timedDriver struct {
+	driver.Driver
+	database string
+	h        *HistogramVec
+}
+
+func (d *timedDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &timedConn{Conn: conn, d: d}, nil
+}
+
+func (d *timedDriver) observe(op string, start time.Time) {
+	d.h.Observe(time.Since(start).Seconds(), d.database, op)
+}
+
+// timedConn passes everything through to the real connection, timing statements.
+type timedConn struct {
+	driver.Conn
+	d *timedDriver
+}
+
+func (c *timedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *timedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if pc, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return pc.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *timedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	ec, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	defer c.d.observe("exec", time.Now())
+	return ec.ExecContext(ctx, query, args)
+}
+
+func (c *timedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	qc, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	defer c.d.observe("query", time.Now())
+	return qc.QueryContext(ctx, query, args)
+}
+
+func (c *timedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if bc, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return bc.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *timedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
@@ -0,0 +1,265 @@
+// Package metrics keeps counters and histograms in memory and writes them out in the
+// Prometheus text exposition format, for scraping from /metrics. It covers the few
+// metric kinds the station needs rather than the whole client library: labelled
+// counters and histograms updated as things happen, and gauges or counters read from
+// the database at scrape time.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are histogram upper bounds in seconds, from 5ms to 10s.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Registry holds every metric the station exports.
+type Registry struct {
+	mu       sync.Mutex
+	families []family
+}
+
+type family interface {
+	name() string
+	write(w *bufio.Writer) error
+}
+
+func (r *Registry) register(f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.families = append(r.families, f)
+	sort.Slice(r.families, func(i, j int) bool { return r.families[i].name() < r.families[j].name() })
+}
+
+// Write writes every metric in the text exposition format. A collector that fails
+// leaves its metric out, so one broken query doesn't blank the whole scrape.
+func (r *Registry) Write(out io.Writer) error {
+	r.mu.Lock()
+	families := slices.Clone(r.families)
+	r.mu.Unlock()
+
+	w := bufio.NewWriter(out)
+	var errs []string
+	for _, f := range families {
+		if err := f.write(w); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", f.name(), err))
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("metrics: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// CounterVec is a counter per combination of label values.
+type CounterVec struct {
+	desc
+	mu     sync.Mutex
+	values map[string]*counterValue
+}
+
+type counterValue struct {
+	labels []string
+	n      float64
+}
+
+// NewCounterVec registers a counter with the given label names.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{desc: desc{metric: name, help: help, kind: "counter", labels: labels}, values: map[string]*counterValue{}}
+	r.register(c)
+	return c
+}
+
+// Add increases the counter for the label values, given in the order they were named.
+func (c *CounterVec) Add(v float64, labels ...string) {
+	key := strings.Join(labels, "\xff")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cv, ok := c.values[key]
+	if !ok {
+		cv = &counterValue{labels: labels}
+		c.values[key] = cv
+	}
+	cv.n += v
+}
+
+// Inc adds one.
+func (c *CounterVec) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+func (c *CounterVec) write(w *bufio.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.header(w)
+	for _, key := range sortedKeys(c.values) {
+		cv := c.values[key]
+		c.sample(w, "", cv.labels, nil, cv.n)
+	}
+	return nil
+}
+
+// HistogramVec counts observations into buckets per combination of label values.
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	values  map[string]*histogramValue
+}
+
+type histogramValue struct {
+	labels []string
+	counts []uint64 // Per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+// NewHistogramVec registers a histogram with the given bucket upper bounds and label names.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{
+		desc:    desc{metric: name, help: help, kind: "histogram", labels: labels},
+		buckets: buckets,
+		values:  map[string]*histogramValue{},
+	}
+	r.register(h)
+	return h
+}
+
+// Observe records one value, e.g. a duration in seconds.
+func (h *HistogramVec) Observe(v float64, labels ...string) {
+	key := strings.Join(labels, "\xff")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hv, ok := h.values[key]
+	if !ok {
+		hv = &histogramValue{labels: labels, counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hv
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		hv.counts[i]++
+	}
+	hv.sum += v
+	hv.count++
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.header(w)
+	for _, key := range sortedKeys(h.values) {
+		hv := h.values[key]
+		var cumulative uint64
+		for i, le := range h.buckets {
+			cumulative += hv.counts[i]
+			h.sample(w, "_bucket", hv.labels, []string{"le", formatFloat(le)}, float64(cumulative))
+		}
+		h.sample(w, "_bucket", hv.labels, []string{"le", "+Inf"}, float64(hv.count))
+		h.sample(w, "_sum", hv.labels, nil, hv.sum)
+		h.sample(w, "_count", hv.labels, nil, float64(hv.count))
+	}
+	return nil
+}
+
+// Sample is one value read by a collector, with its label values in order.
+type Sample struct {
+	Labels []string
+	Value  float64
+}
+
+// funcFamily is a gauge or counter whose values are read when scraped.
+type funcFamily struct {
+	desc
+	collect func() ([]Sample, error)
+}
+
+// GaugeFunc registers a gauge read by collect at every scrape, e.g. entries per type.
+func (r *Registry) GaugeFunc(name, help string, labels []string, collect func() ([]Sample, error)) {
+	r.register(&funcFamily{desc: desc{metric: name, help: help, kind: "gauge", labels: labels}, collect: collect})
+}
+
+// CounterFunc registers a counter kept somewhere else, such as a table of past runs,
+// and read by collect at every scrape.
+func (r *Registry) CounterFunc(name, help string, labels []string, collect func() ([]Sample, error)) {
+	r.register(&funcFamily{desc: desc{metric: name, help: help, kind: "counter", labels: labels}, collect: collect})
+}
+
+func (f *funcFamily) write(w *bufio.Writer) error {
+	samples, err := f.collect()
+	if err != nil {
+		return err
+	}
+	f.header(w)
+	for _, s := range samples {
+		f.sample(w, "", s.Labels, nil, s.Value)
+	}
+	return nil
+}
+
+// desc is what every metric family has in common.
+type desc struct {
+	metric, help, kind string
+	labels             []string
+}
+
+func (d *desc) name() string { return d.metric }
+
+func (d *desc) header(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.metric, escapeHelp(d.help), d.metric, d.kind)
+}
+
+// sample writes one line; extra is an additional name/value label pair (histogram "le").
+func (d *desc) sample(w *bufio.Writer, suffix string, values, extra []string, v float64) {
+	w.WriteString(d.metric + suffix)
+	var pairs []string
+	for i, name := range d.labels {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs = append(pairs, name+`="`+escapeLabel(value)+`"`)
+	}
+	if extra != nil {
+		pairs = append(pairs, extra[0]+`="`+extra[1]+`"`)
+	}
+	if len(pairs) > 0 {
+		w.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+	w.WriteString(" " + formatFloat(v) + "\n")
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,223 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Fields of a scraped item an alert rule can test.
+const (
+	AlertFieldTitle = "title"
+	AlertFieldValue = "value" // The item's text, or a price watch's current price
+	AlertFieldURL   = "url"
+)
+
+// Comparisons an alert rule can make. "<" and ">" compare the first number found
+// in the field, so "value < 20" works on "€19.99" as well as on a bare price.
+const (
+	AlertContains = "contains" // Case-insensitive substring
+	AlertMatches  = "matches"  // Case-insensitive regular expression
+	AlertBelow    = "<"
+	AlertAbove    = ">"
+)
+
+// AlertRule raises an alert whenever a newly scraped item matches it.
+type AlertRule struct {
+	ID        int
+	Field     string
+	Op        string
+	Operand   string
+	SourceID  *int    // Only items from this source; nil watches every source
+	Note      *string // Why the rule exists, shown next to its alerts
+	Hits      int     // Alerts raised so far
+	CreatedAt time.Time
+}
+
+// String renders the rule the way it reads on the page, e.g. `title contains "Hyperion"`.
+func (r *AlertRule) String() string {
+	if r.Op == AlertBelow || r.Op == AlertAbove {
+		return fmt.Sprintf("%s %s %s", r.Field, r.Op, r.Operand)
+	}
+	return fmt.Sprintf("%s %s %q", r.Field, r.Op, r.Operand)
+}
+
+// Alert records an item that matched a rule. It keeps its own copy of what matched,
+// so it still makes sense after the item or the rule is gone.
+type Alert struct {
+	ID        int
+	RuleID    int
+	Rule      string // The rule as it read when it fired
+	ItemID    *int   // nil for price watches, which don't store items
+	SourceID  *int
+	Title     string
+	Value     string
+	URL       *string
+	CreatedAt time.Time
+	SeenAt    *time.Time // nil until acknowledged
+}
+
+// AlertModel wraps a database connection pool for alert rules and the alerts they raise.
+type AlertModel struct {
+	DB *sql.DB
+}
+
+const alertColumns = `id, rule_id, rule, item_id, source_id, title, value, url, created_at, seen_at`
+
+// InitSchema creates the alert tables if they don't exist.
+func (m *AlertModel) InitSchema() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS alert_rules (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		field TEXT NOT NULL,
+		op TEXT NOT NULL,
+		operand TEXT NOT NULL,
+		source_id INTEGER,
+		note TEXT,
+		hits INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS alerts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		rule_id INTEGER NOT NULL,
+		rule TEXT NOT NULL,
+		item_id INTEGER,
+		source_id INTEGER,
+		title TEXT NOT NULL,
+		value TEXT NOT NULL,
+		url TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		seen_at DATETIME
+	);
+	CREATE INDEX IF NOT EXISTS alerts_unseen ON alerts(seen_at, created_at);
+	CREATE UNIQUE INDEX IF NOT EXISTS alerts_rule_item ON alerts(rule_id, item_id) WHERE item_id IS NOT NULL;
+	`
+	_, err := m.DB.Exec(stmt)
+	return err
+}
+
+// Rules returns every alert rule, oldest first.
+func (m *AlertModel) Rules() ([]*AlertRule, error) {
+	rows, err := m.DB.Query(`SELECT id, field, op, operand, source_id, note, hits, created_at FROM alert_rules ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []*AlertRule
+
+	for rows.Next() {
+		r := &AlertRule{}
+		if err := rows.Scan(&r.ID, &r.Field, &r.Op, &r.Operand, &r.SourceID, &r.Note, &r.Hits, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// InsertRule adds a rule.
+func (m *AlertModel) InsertRule(r *AlertRule) error {
+	stmt := `INSERT INTO alert_rules (field, op, operand, source_id, note, created_at)
+	VALUES(?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`
+	_, err := m.DB.Exec(stmt, r.Field, r.Op, r.Operand, r.SourceID, r.Note)
+	return err
+}
+
+// DeleteRule removes a rule. Alerts it already raised stay in the log.
+func (m *AlertModel) DeleteRule(id int) error {
+	_, err := m.DB.Exec(`DELETE FROM alert_rules WHERE id = ?`, id)
+	return err
+}
+
+// Raise records an alert and counts it against its rule. An item only ever raises
+// one alert per rule, however often it is scraped again; created reports whether
+// this one was new.
+func (m *AlertModel) Raise(a *Alert) (created bool, err error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	stmt := `INSERT INTO alerts (rule_id, rule, item_id, source_id, title, value, url, created_at)
+	VALUES(?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP) ON CONFLICT DO NOTHING`
+	result, err := tx.Exec(stmt, a.RuleID, a.Rule, a.ItemID, a.SourceID, a.Title, a.Value, a.URL)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil || n == 0 {
+		return false, err
+	}
+
+	if _, err := tx.Exec(`UPDATE alert_rules SET hits = hits + 1 WHERE id = ?`, a.RuleID); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// Recent returns the latest alerts, seen or not, newest first.
+func (m *AlertModel) Recent(limit int) ([]*Alert, error) {
+	stmt := `SELECT ` + alertColumns + ` FROM alerts ORDER BY created_at DESC, id DESC LIMIT ?`
+	return m.query(stmt, limit)
+}
+
+// Unseen returns the alerts nobody has acknowledged yet, newest first.
+func (m *AlertModel) Unseen(limit int) ([]*Alert, error) {
+	stmt := `SELECT ` + alertColumns + ` FROM alerts WHERE seen_at IS NULL ORDER BY created_at DESC, id DESC LIMIT ?`
+	return m.query(stmt, limit)
+}
+
+// CountUnseen returns how many alerts are waiting to be acknowledged.
+func (m *AlertModel) CountUnseen() (int, error) {
+	var n int
+	err := m.DB.QueryRow(`SELECT COUNT(*) FROM alerts WHERE seen_at IS NULL`).Scan(&n)
+	return n, err
+}
+
+// MarkSeen acknowledges one alert.
+func (m *AlertModel) MarkSeen(id int) error {
+	_, err := m.DB.Exec(`UPDATE alerts SET seen_at = CURRENT_TIMESTAMP WHERE id = ? AND seen_at IS NULL`, id)
+	return err
+}
+
+// MarkAllSeen acknowledges every waiting alert.
+func (m *AlertModel) MarkAllSeen() (int64, error) {
+	result, err := m.DB.Exec(`UPDATE alerts SET seen_at = CURRENT_TIMESTAMP WHERE seen_at IS NULL`)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (m *AlertModel) query(stmt string, args ...any) ([]*Alert, error) {
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*Alert
+
+	for rows.Next() {
+		a := &Alert{}
+		err := rows.Scan(&a.ID, &a.RuleID, &a.Rule, &a.ItemID, &a.SourceID, &a.Title, &a.Value, &a.URL, &a.CreatedAt, &a.SeenAt)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
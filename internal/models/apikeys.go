@@ -0,0 +1,96 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// APIKey is a bearer token issued from the admin page. Only its SHA-256 is kept;
+// the key itself is shown once, when it is created.
+type APIKey struct {
+	ID        int
+	Name      string // What the key is for, e.g. "laptop sync script"
+	Scope     string // Which endpoints it opens, as for the environment tokens: "api" or "ingest"
+	Hash      string // Hex SHA-256 of the key
+	CreatedAt time.Time
+}
+
+// APIKeyModel wraps a database connection pool for issued API keys.
+type APIKeyModel struct {
+	DB *sql.DB
+}
+
+// InitSchema creates the api_keys table if it doesn't exist.
+func (m *APIKeyModel) InitSchema() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		scope TEXT NOT NULL,
+		hash TEXT NOT NULL UNIQUE,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	_, err := m.DB.Exec(stmt)
+	return err
+}
+
+// Insert stores a new key by its hash.
+func (m *APIKeyModel) Insert(k *APIKey) (int, error) {
+	stmt := `INSERT INTO api_keys (name, scope, hash, created_at) VALUES(?, ?, ?, CURRENT_TIMESTAMP) RETURNING id`
+
+	var id int
+	err := m.DB.QueryRow(stmt, k.Name, k.Scope, k.Hash).Scan(&id)
+	return id, err
+}
+
+// All returns every key, oldest first.
+func (m *APIKeyModel) All() ([]*APIKey, error) {
+	rows, err := m.DB.Query(`SELECT id, name, scope, hash, created_at FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*APIKey
+
+	for rows.Next() {
+		k := &APIKey{}
+		if err := rows.Scan(&k.ID, &k.Name, &k.Scope, &k.Hash, &k.CreatedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Get returns a single key by ID.
+func (m *APIKeyModel) Get(id int) (*APIKey, error) {
+	k := &APIKey{}
+	err := m.DB.QueryRow(`SELECT id, name, scope, hash, created_at FROM api_keys WHERE id = ?`, id).
+		Scan(&k.ID, &k.Name, &k.Scope, &k.Hash, &k.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoRecord
+	}
+	return k, err
+}
+
+// Fingerprint is the start of the key's hash, as /admin/api shows every token.
+func (k *APIKey) Fingerprint() string {
+	if len(k.Hash) < 12 {
+		return k.Hash
+	}
+	return k.Hash[:12]
+}
+
+// Delete removes a key for good; it stops working immediately.
+func (m *APIKeyModel) Delete(id int) error {
+	_, err := m.DB.Exec(`DELETE FROM api_keys WHERE id = ?`, id)
+	return err
+}
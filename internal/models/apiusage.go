@@ -0,0 +1,173 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// APIUsage is what one API token has been up to, and the limits set on it. Tokens
+// are identified by a fingerprint, a hash prefix, so the table never holds a usable
+// secret.
+type APIUsage struct {
+	Fingerprint  string
+	Label        string     // Where the token comes from, e.g. "ingest"
+	Requests     int        // All time
+	Writes       int        // Requests with a method other than GET or HEAD
+	Errors       int        // Responses with a 4xx or 5xx status
+	Requests24h  int        // Filled in by All from the hourly tallies
+	Errors24h    int        //
+	LastUsedAt   *time.Time // nil until the token is first used
+	LastStatus   int
+	LimitPerHour int  // 0 leaves the token unthrottled
+	Revoked      bool // Revoked tokens are refused outright
+}
+
+// ErrorPercent is the share of the last day's requests that failed, as a percentage.
+func (u *APIUsage) ErrorPercent() float64 {
+	if u.Requests24h == 0 {
+		return 0
+	}
+	return float64(u.Errors24h) / float64(u.Requests24h) * 100
+}
+
+// APIUsageModel wraps a database connection pool for API usage tracking.
+type APIUsageModel struct {
+	DB *sql.DB
+}
+
+// InitSchema creates the usage tables if they don't exist.
+func (m *APIUsageModel) InitSchema() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS api_usage (
+		fingerprint TEXT PRIMARY KEY,
+		label TEXT NOT NULL,
+		requests INTEGER NOT NULL DEFAULT 0,
+		writes INTEGER NOT NULL DEFAULT 0,
+		errors INTEGER NOT NULL DEFAULT 0,
+		last_used_at DATETIME,
+		last_status INTEGER NOT NULL DEFAULT 0,
+		limit_per_hour INTEGER NOT NULL DEFAULT 0,
+		revoked BOOLEAN NOT NULL DEFAULT 0
+	);
+	CREATE TABLE IF NOT EXISTS api_usage_hourly (
+		fingerprint TEXT NOT NULL,
+		hour TEXT NOT NULL,
+		requests INTEGER NOT NULL DEFAULT 0,
+		errors INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (fingerprint, hour)
+	);
+	`
+	_, err := m.DB.Exec(stmt)
+	return err
+}
+
+// usageHour is the key of the hourly tally a moment falls in.
+func usageHour(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15")
+}
+
+// Record counts one request made with a token.
+func (m *APIUsageModel) Record(fingerprint, label string, write bool, status int, at time.Time) error {
+	failed := status >= 400
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := `INSERT INTO api_usage (fingerprint, label, requests, writes, errors, last_used_at, last_status)
+	VALUES(?1, ?2, 1, ?3, ?4, ?5, ?6)
+	ON CONFLICT(fingerprint) DO UPDATE SET
+		label = ?2,
+		requests = requests + 1,
+		writes = writes + ?3,
+		errors = errors + ?4,
+		last_used_at = ?5,
+		last_status = ?6`
+	if _, err := tx.Exec(stmt, fingerprint, label, write, failed, sqliteTime(at), status); err != nil {
+		return err
+	}
+
+	stmt = `INSERT INTO api_usage_hourly (fingerprint, hour, requests, errors) VALUES(?1, ?2, 1, ?3)
+	ON CONFLICT(fingerprint, hour) DO UPDATE SET requests = requests + 1, errors = errors + ?3`
+	if _, err := tx.Exec(stmt, fingerprint, usageHour(at), failed); err != nil {
+		return err
+	}
+
+	// Tallies only matter for the last day; old ones go as new hours are written
+	if _, err := tx.Exec(`DELETE FROM api_usage_hourly WHERE hour < ?`, usageHour(at.Add(-48*time.Hour))); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Policy returns whether a token is revoked and its hourly limit.
+func (m *APIUsageModel) Policy(fingerprint string) (revoked bool, limitPerHour int, err error) {
+	err = m.DB.QueryRow(`SELECT revoked, limit_per_hour FROM api_usage WHERE fingerprint = ?`, fingerprint).Scan(&revoked, &limitPerHour)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, 0, nil
+	}
+	return revoked, limitPerHour, err
+}
+
+// HourlyRequests returns how many requests a token has made in the clock hour containing t.
+func (m *APIUsageModel) HourlyRequests(fingerprint string, t time.Time) (int, error) {
+	var n int
+	err := m.DB.QueryRow(`SELECT requests FROM api_usage_hourly WHERE fingerprint = ? AND hour = ?`, fingerprint, usageHour(t)).Scan(&n)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return n, err
+}
+
+// All returns every token seen so far, most recently used first.
+func (m *APIUsageModel) All() ([]*APIUsage, error) {
+	stmt := `SELECT u.fingerprint, u.label, u.requests, u.writes, u.errors,
+		COALESCE(SUM(h.requests), 0), COALESCE(SUM(h.errors), 0),
+		u.last_used_at, u.last_status, u.limit_per_hour, u.revoked
+	FROM api_usage u LEFT JOIN api_usage_hourly h ON h.fingerprint = u.fingerprint AND h.hour > ?
+	GROUP BY u.fingerprint ORDER BY u.last_used_at DESC`
+
+	rows, err := m.DB.Query(stmt, usageHour(time.Now().Add(-24*time.Hour)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*APIUsage
+
+	for rows.Next() {
+		u := &APIUsage{}
+		err := rows.Scan(&u.Fingerprint, &u.Label, &u.Requests, &u.Writes, &u.Errors, &u.Requests24h, &u.Errors24h,
+			&u.LastUsedAt, &u.LastStatus, &u.LimitPerHour, &u.Revoked)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, u)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// SetLimit throttles a token to limitPerHour requests per clock hour; 0 lifts the limit.
+func (m *APIUsageModel) SetLimit(fingerprint, label string, limitPerHour int) error {
+	stmt := `INSERT INTO api_usage (fingerprint, label, limit_per_hour) VALUES(?, ?, ?)
+	ON CONFLICT(fingerprint) DO UPDATE SET limit_per_hour = excluded.limit_per_hour`
+	_, err := m.DB.Exec(stmt, fingerprint, label, limitPerHour)
+	return err
+}
+
+// SetRevoked revokes a token, or restores a revoked one.
+func (m *APIUsageModel) SetRevoked(fingerprint, label string, revoked bool) error {
+	stmt := `INSERT INTO api_usage (fingerprint, label, revoked) VALUES(?, ?, ?)
+	ON CONFLICT(fingerprint) DO UPDATE SET revoked = excluded.revoked`
+	_, err := m.DB.Exec(stmt, fingerprint, label, revoked)
+	return err
+}
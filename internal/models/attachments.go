@@ -0,0 +1,136 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Attachment is a file hung off an entry. The bytes live in the blob store under
+// BlobHash, so several attachments can share one stored file.
+type Attachment struct {
+	ID          int
+	EntryID     int
+	BlobHash    string
+	Filename    string
+	ContentType string
+	Size        int64
+	CreatedAt   time.Time
+}
+
+// IsImage reports whether the attachment can be shown inline as a picture.
+func (a *Attachment) IsImage() bool {
+	return strings.HasPrefix(a.ContentType, "image/")
+}
+
+// HumanSize formats the size for display, e.g. "1.2 MB".
+func (a *Attachment) HumanSize() string {
+	return HumanBytes(a.Size)
+}
+
+// AttachmentModel wraps a database connection pool for attachments.
+type AttachmentModel struct {
+	DB *sql.DB
+}
+
+const attachmentColumns = `id, entry_id, blob_hash, filename, content_type, size, created_at`
+
+// InitSchema creates the attachments table if it doesn't exist.
+func (m *AttachmentModel) InitSchema() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS attachments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		entry_id INTEGER NOT NULL REFERENCES entries(id) ON DELETE CASCADE,
+		blob_hash TEXT NOT NULL,
+		filename TEXT NOT NULL,
+		content_type TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS attachments_blob_hash ON attachments(blob_hash);
+	`
+	_, err := m.DB.Exec(stmt)
+	return err
+}
+
+// Insert records a new attachment.
+func (m *AttachmentModel) Insert(a *Attachment) (int, error) {
+	stmt := `INSERT INTO attachments (entry_id, blob_hash, filename, content_type, size, created_at)
+	VALUES(?, ?, ?, ?, ?, CURRENT_TIMESTAMP) RETURNING id`
+
+	var id int
+	err := m.DB.QueryRow(stmt, a.EntryID, a.BlobHash, a.Filename, a.ContentType, a.Size).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// Get returns a single attachment by ID.
+func (m *AttachmentModel) Get(id int) (*Attachment, error) {
+	stmt := `SELECT ` + attachmentColumns + ` FROM attachments WHERE id = ?`
+
+	a, err := scanAttachment(m.DB.QueryRow(stmt, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoRecord
+	}
+	return a, err
+}
+
+// ForEntry returns an entry's attachments in upload order.
+func (m *AttachmentModel) ForEntry(entryID int) ([]*Attachment, error) {
+	stmt := `SELECT ` + attachmentColumns + ` FROM attachments WHERE entry_id = ? ORDER BY id`
+	return m.query(stmt, entryID)
+}
+
+// All returns every attachment in upload order.
+func (m *AttachmentModel) All() ([]*Attachment, error) {
+	stmt := `SELECT ` + attachmentColumns + ` FROM attachments ORDER BY id`
+	return m.query(stmt)
+}
+
+// WithHash returns every attachment that shares the given blob, oldest first.
+func (m *AttachmentModel) WithHash(hash string) ([]*Attachment, error) {
+	stmt := `SELECT ` + attachmentColumns + ` FROM attachments WHERE blob_hash = ? ORDER BY id`
+	return m.query(stmt, hash)
+}
+
+// Delete removes an attachment. The blob stays, since other attachments may share it.
+func (m *AttachmentModel) Delete(id int) error {
+	_, err := m.DB.Exec(`DELETE FROM attachments WHERE id = ?`, id)
+	return err
+}
+
+func (m *AttachmentModel) query(stmt string, args ...any) ([]*Attachment, error) {
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*Attachment
+
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func scanAttachment(row rowScanner) (*Attachment, error) {
+	a := &Attachment{}
+	err := row.Scan(&a.ID, &a.EntryID, &a.BlobHash, &a.Filename, &a.ContentType, &a.Size, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
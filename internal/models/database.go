@@ -0,0 +1,138 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// DatabaseStats describes how a database file is using its space.
+type DatabaseStats struct {
+	Path        string
+	PageSize    int64
+	PageCount   int64
+	FreePages   int64  // Pages on the freelist, reclaimable by vacuuming
+	AutoVacuum  string // "none", "full" or "incremental"
+	JournalMode string
+	WALBytes    int64 // Size of the -wal file, 0 when there isn't one
+	Objects     []*ObjectSize
+}
+
+// FileBytes is the size of the main database file.
+func (s *DatabaseStats) FileBytes() int64 {
+	return s.PageSize * s.PageCount
+}
+
+// FreeBytes is the space held by free pages.
+func (s *DatabaseStats) FreeBytes() int64 {
+	return s.PageSize * s.FreePages
+}
+
+// ObjectSize is the space taken by one table or index.
+type ObjectSize struct {
+	Name  string
+	Type  string // "table" or "index"
+	Bytes int64
+	Pages int64
+}
+
+// DatabaseModel gives access to the storage internals of one SQLite file.
+type DatabaseModel struct {
+	DB   *sql.DB
+	Path string // On-disk location, for sizing the WAL file
+}
+
+// Stats reports page usage, per-object sizes (via the dbstat virtual table) and
+// the size of the write-ahead log.
+func (m *DatabaseModel) Stats() (*DatabaseStats, error) {
+	s := &DatabaseStats{Path: m.Path}
+
+	var autoVacuum int
+	for _, p := range []struct {
+		pragma string
+		dest   any
+	}{
+		{"page_size", &s.PageSize},
+		{"page_count", &s.PageCount},
+		{"freelist_count", &s.FreePages},
+		{"auto_vacuum", &autoVacuum},
+		{"journal_mode", &s.JournalMode},
+	} {
+		if err := m.DB.QueryRow("PRAGMA " + p.pragma).Scan(p.dest); err != nil {
+			return nil, err
+		}
+	}
+	s.AutoVacuum = [...]string{"none", "full", "incremental"}[autoVacuum%3]
+
+	stmt := `SELECT d.name, COALESCE(m.type, 'table'), SUM(d.pgsize), COUNT(*)
+	FROM dbstat d LEFT JOIN sqlite_master m ON m.name = d.name
+	GROUP BY d.name ORDER BY SUM(d.pgsize) DESC`
+
+	rows, err := m.DB.Query(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		o := &ObjectSize{}
+		if err := rows.Scan(&o.Name, &o.Type, &o.Bytes, &o.Pages); err != nil {
+			return nil, err
+		}
+		s.Objects = append(s.Objects, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(m.Path + "-wal")
+	if err == nil {
+		s.WALBytes = info.Size()
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// IncrementalVacuum returns free pages to the filesystem without rewriting the
+// whole file. It only works once auto_vacuum is "incremental".
+func (m *DatabaseModel) IncrementalVacuum() error {
+	_, err := m.DB.Exec("PRAGMA incremental_vacuum")
+	return err
+}
+
+// EnableIncrementalVacuum switches the file to incremental auto-vacuum. SQLite only
+// applies the change on a full VACUUM, so this rewrites the file once.
+func (m *DatabaseModel) EnableIncrementalVacuum(ctx context.Context) error {
+	// The pragma only sticks for the connection that runs the VACUUM
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "PRAGMA auto_vacuum = INCREMENTAL"); err != nil {
+		return err
+	}
+	_, err = conn.ExecContext(ctx, "VACUUM")
+	return err
+}
+
+// HumanBytes formats a byte count for display, e.g. "1.2 MB".
+func HumanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
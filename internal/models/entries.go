@@ -1,18 +1,143 @@
 package models
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
 // Entry defines the core flexible content unit of Sacrif Station.
 type Entry struct {
 	ID        int
+	Slug      string // Permalink segment, derived from the title when the entry is created
 	Title     string
-	Type      string // e.g., "thought", "book", "game", "link", "log", "anime"
-	Content   string
-	URL       string // Optional
+	Type      string  // e.g., "thought", "book", "game", "link", "log", "anime"
+	Content   *string // Optional
+	URL       *string // Optional
+	Mood      *string // Optional, only meaningful for thoughts (see Moods)
+	ParentID  *int    // Optional, the earlier thought this one continues
 	CreatedAt time.Time
+	UpdatedAt *time.Time // Last edit, nil for entries never edited (or edited before this was tracked)
+
+	// Per-entry overrides for feed rendering, nil when not set
+	FeedSummary     *string // Replaces the content as the feed item description
+	ExcludeFromFeed bool    // Keeps the entry out of every feed
+	CanonicalURL    *string // Points feed readers at the original for cross-posted pieces
+
+	Fields Fields // Custom attributes, stored in entry_fields
+
+	// Replies is filled in by NestThreads, Epoch by Epochs.Badge and Decay by the
+	// sector listing it; none of them is stored
+	Replies []*Entry
+	Epoch   *Epoch
+	Decay   int // Corruption severity (0-100) the entry has earned with age
+}
+
+// Permalink is the entry's canonical path on the station.
+func (e *Entry) Permalink() string {
+	return "/entry/" + e.Slug
+}
+
+// Checksum is a SHA-256 over the entry's stored content, hex encoded. Each field is
+// written as "name:length:value\n" in a fixed order (empty values for NULLs, times as
+// RFC 3339 UTC), so a copy can be re-hashed and compared long after it was exported.
+// The ID and the slug are left out: they only address the entry, they aren't its content.
+func (e *Entry) Checksum() string {
+	parent := ""
+	if e.ParentID != nil {
+		parent = strconv.Itoa(*e.ParentID)
+	}
+
+	h := sha256.New()
+	for _, f := range [][2]string{
+		{"title", e.Title},
+		{"type", e.Type},
+		{"content", StringValue(e.Content)},
+		{"url", StringValue(e.URL)},
+		{"mood", StringValue(e.Mood)},
+		{"parent_id", parent},
+		{"created_at", e.CreatedAt.UTC().Format(time.RFC3339)},
+		{"feed_summary", StringValue(e.FeedSummary)},
+		{"exclude_from_feed", strconv.FormatBool(e.ExcludeFromFeed)},
+		{"canonical_url", StringValue(e.CanonicalURL)},
+	} {
+		fmt.Fprintf(h, "%s:%d:%s\n", f[0], len(f[1]), f[1])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// LastModified is when the entry last changed: its last edit, or its creation.
+func (e *Entry) LastModified() time.Time {
+	if e.UpdatedAt != nil {
+		return *e.UpdatedAt
+	}
+	return e.CreatedAt
+}
+
+// MoodInfo returns the full mood definition for the entry, if it has a known one.
+func (e *Entry) MoodInfo() *Mood {
+	if m, ok := MoodByKey(StringValue(e.Mood)); ok {
+		return &m
+	}
+	return nil
+}
+
+// URLHost is the host the entry's URL points at, without "www.", or "" when it has
+// no usable URL.
+func (e *Entry) URLHost() string {
+	u, err := url.Parse(StringValue(e.URL))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
+
+// TypeInfo returns the registry definition for the entry's type.
+func (e *Entry) TypeInfo() EntryType {
+	t, _ := TypeByKey(e.Type)
+	return t
+}
+
+// InFeeds reports whether the entry belongs in feeds: its type has to be syndicated
+// and the entry must not have opted out.
+func (e *Entry) InFeeds() bool {
+	return !e.ExcludeFromFeed && e.TypeInfo().InFeeds
+}
+
+// HasMood reports whether the entry is tagged with the given mood key.
+func (e *Entry) HasMood(key string) bool {
+	return e.Mood != nil && *e.Mood == key
+}
+
+// FeedDescription returns the text feeds should use to describe the entry.
+func (e *Entry) FeedDescription() string {
+	if e.FeedSummary != nil {
+		return *e.FeedSummary
+	}
+	return StringValue(e.Content)
+}
+
+// FeedLink returns the link feeds should use for the entry, preferring the
+// canonical external URL over the station's own permalink when one is set.
+func (e *Entry) FeedLink(permalink string) string {
+	if e.CanonicalURL != nil {
+		return *e.CanonicalURL
+	}
+	return permalink
+}
+
+// MoodCount is the number of thoughts logged with a given mood in a given month.
+type MoodCount struct {
+	Month string // YYYY-MM
+	Mood  string
+	Count int
 }
 
 // EntryModel wraps a database connection pool.
@@ -20,6 +145,9 @@ type EntryModel struct {
 	DB *sql.DB
 }
 
+// entryColumns is the column list every entry query selects, in scanEntry order.
+const entryColumns = `id, slug, title, type, content, url, mood, parent_id, created_at, feed_summary, exclude_from_feed, canonical_url, updated_at, ` + fieldsColumn
+
 // InitSchema creates the entries table if it doesn't exist.
 func (m *EntryModel) InitSchema() error {
 	stmt := `
@@ -32,50 +160,602 @@ func (m *EntryModel) InitSchema() error {
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
 	`
+	if _, err := m.DB.Exec(stmt); err != nil {
+		return err
+	}
+
+	// Columns added after the first release
+	if err := addColumn(m.DB, "entries", "mood", "TEXT"); err != nil {
+		return err
+	}
+	if err := addColumn(m.DB, "entries", "parent_id", "INTEGER REFERENCES entries(id) ON DELETE SET NULL"); err != nil {
+		return err
+	}
+	if err := addColumn(m.DB, "entries", "feed_summary", "TEXT"); err != nil {
+		return err
+	}
+	if err := addColumn(m.DB, "entries", "exclude_from_feed", "BOOLEAN NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := addColumn(m.DB, "entries", "canonical_url", "TEXT"); err != nil {
+		return err
+	}
+
+	if err := m.migrateNullable(); err != nil {
+		return err
+	}
+
+	// Slugs came after the nullable rebuild, so older rows get theirs filled in here
+	if err := addColumn(m.DB, "entries", "slug", "TEXT"); err != nil {
+		return err
+	}
+	if err := m.backfillSlugs(); err != nil {
+		return err
+	}
+	if err := addColumn(m.DB, "entries", "updated_at", "DATETIME"); err != nil {
+		return err
+	}
+	if _, err := m.DB.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS entries_slug ON entries(slug)`); err != nil {
+		return err
+	}
+
+	// Every entry query reads the custom fields, so their table comes with the entries
+	stmt = `
+	CREATE TABLE IF NOT EXISTS entry_fields (
+		entry_id INTEGER NOT NULL REFERENCES entries(id) ON DELETE CASCADE,
+		key TEXT NOT NULL,
+		value TEXT NOT NULL,
+		position INTEGER NOT NULL,
+		PRIMARY KEY (entry_id, key)
+	);
+	`
 	_, err := m.DB.Exec(stmt)
 	return err
 }
 
-// Insert adds a new entry to the database.
-func (m *EntryModel) Insert(title, entryType, content, url string) (int, error) {
-	stmt := `INSERT INTO entries (title, type, content, url, created_at)
-	VALUES(?, ?, ?, ?, CURRENT_TIMESTAMP) RETURNING id`
+// backfillSlugs gives every entry without a slug one derived from its title.
+func (m *EntryModel) backfillSlugs() error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT id, title, type FROM entries WHERE slug IS NULL ORDER BY id`)
+	if err != nil {
+		return err
+	}
+
+	type pending struct {
+		id          int
+		title, kind string
+	}
+	var todo []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.title, &p.kind); err != nil {
+			rows.Close()
+			return err
+		}
+		todo = append(todo, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, p := range todo {
+		slug, err := uniqueSlug(tx, p.title, p.kind)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE entries SET slug = ? WHERE id = ?`, slug, p.id); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// migrateNullable (schema version 1) rebuilds the entries table so every optional
+// column is nullable, turning the empty strings older builds stored into real NULLs.
+// SQLite can't drop NOT NULL from a column in place, hence the copy-and-swap.
+func (m *EntryModel) migrateNullable() error {
+	version, err := schemaVersion(m.DB)
+	if err != nil || version >= 1 {
+		return err
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmts := []string{
+		`CREATE TABLE entries_nullable (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			title TEXT NOT NULL,
+			type TEXT NOT NULL,
+			content TEXT,
+			url TEXT,
+			mood TEXT,
+			parent_id INTEGER REFERENCES entries(id) ON DELETE SET NULL,
+			feed_summary TEXT,
+			exclude_from_feed BOOLEAN NOT NULL DEFAULT 0,
+			canonical_url TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`INSERT INTO entries_nullable (id, title, type, content, url, mood, parent_id, feed_summary, exclude_from_feed, canonical_url, created_at)
+		SELECT id, title, type, NULLIF(content, ''), NULLIF(url, ''), NULLIF(mood, ''), parent_id,
+			NULLIF(feed_summary, ''), exclude_from_feed, NULLIF(canonical_url, ''), created_at
+		FROM entries`,
+		`DROP TABLE entries`,
+		`ALTER TABLE entries_nullable RENAME TO entries`,
+		`PRAGMA user_version = 1`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Insert adds a new entry to the database, picking its slug (stored back on e).
+// Another insert can claim the same slug between the check and the write; the
+// loser starts over and takes the next free suffix instead of failing.
+func (m *EntryModel) Insert(e *Entry) (int, error) {
+	for attempt := 1; ; attempt++ {
+		id, err := m.insert(e)
+		if attempt < maxSlugAttempts && slugTaken(err) {
+			continue
+		}
+		return id, err
+	}
+}
+
+func (m *EntryModel) insert(e *Entry) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	slug, err := uniqueSlug(tx, e.Title, e.Type)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt := `INSERT INTO entries (slug, title, type, content, url, mood, parent_id, feed_summary, exclude_from_feed, canonical_url, created_at)
+	VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP) RETURNING id`
 
 	var id int
-	err := m.DB.QueryRow(stmt, title, entryType, content, url).Scan(&id)
+	err = tx.QueryRow(stmt, slug, e.Title, e.Type, e.Content, e.URL, e.Mood, e.ParentID,
+		e.FeedSummary, e.ExcludeFromFeed, e.CanonicalURL).Scan(&id)
 	if err != nil {
 		return 0, err
 	}
+	if err := setFields(tx, id, e.Fields); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	e.Slug = slug
 	return id, nil
 }
 
+// Update saves the editable fields of an existing entry, replacing its custom fields
+// with e.Fields. The thread position and creation time are left untouched.
+func (m *EntryModel) Update(e *Entry) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := `UPDATE entries SET title = ?, type = ?, content = ?, url = ?, mood = ?,
+	feed_summary = ?, exclude_from_feed = ?, canonical_url = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+
+	res, err := tx.Exec(stmt, e.Title, e.Type, e.Content, e.URL, e.Mood,
+		e.FeedSummary, e.ExcludeFromFeed, e.CanonicalURL, e.ID)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+	if err := setFields(tx, e.ID, e.Fields); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Get returns a single entry by ID.
+func (m *EntryModel) Get(id int) (*Entry, error) {
+	stmt := `SELECT ` + entryColumns + ` FROM entries WHERE id = ?`
+
+	e, err := scanEntry(m.DB.QueryRow(stmt, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoRecord
+	}
+	return e, err
+}
+
+// GetBySlug returns a single entry by its permalink slug.
+func (m *EntryModel) GetBySlug(slug string) (*Entry, error) {
+	stmt := `SELECT ` + entryColumns + ` FROM entries WHERE slug = ?`
+
+	e, err := scanEntry(m.DB.QueryRow(stmt, slug))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoRecord
+	}
+	return e, err
+}
+
+// All returns every entry, oldest first.
+func (m *EntryModel) All() ([]*Entry, error) {
+	stmt := `SELECT ` + entryColumns + ` FROM entries ORDER BY created_at, id`
+	return m.queryEntries(stmt)
+}
+
+// Thread returns every entry in the thread containing the given entry, from the
+// root thought down through all replies, oldest first.
+func (m *EntryModel) Thread(id int) ([]*Entry, error) {
+	stmt := `
+	WITH RECURSIVE
+		ancestors(id, parent_id) AS (
+			SELECT id, parent_id FROM entries WHERE id = ?
+			UNION
+			SELECT e.id, e.parent_id FROM entries e JOIN ancestors a ON e.id = a.parent_id
+		),
+		thread(id) AS (
+			SELECT id FROM ancestors WHERE parent_id IS NULL
+			UNION
+			SELECT e.id FROM entries e JOIN thread t ON e.parent_id = t.id
+		)
+	SELECT ` + entryColumns + ` FROM entries WHERE id IN (SELECT id FROM thread)
+	ORDER BY created_at ASC, id ASC`
+	return m.queryEntries(stmt, id)
+}
+
 // Latest returns the most recent entries of ALL types.
 func (m *EntryModel) Latest(limit int) ([]*Entry, error) {
-	stmt := `SELECT id, title, type, content, url, created_at FROM entries
+	stmt := `SELECT ` + entryColumns + ` FROM entries
 	ORDER BY created_at DESC LIMIT ?`
 	return m.queryEntries(stmt, limit)
 }
 
 // LatestThoughts returns the most recent thought-related entries.
 func (m *EntryModel) LatestThoughts(limit int) ([]*Entry, error) {
-	stmt := `SELECT id, title, type, content, url, created_at FROM entries
-	WHERE type IN ('thought', 'thought_admin', 'thought_stationai') ORDER BY created_at DESC LIMIT ?`
+	stmt := `SELECT ` + entryColumns + ` FROM entries
+	WHERE type IN ` + thoughtTypeList + ` ORDER BY created_at DESC LIMIT ?`
 	return m.queryEntries(stmt, limit)
 }
 
 // MediaEntries returns the most recent non-thought entries.
 func (m *EntryModel) MediaEntries(limit int) ([]*Entry, error) {
-	stmt := `SELECT id, title, type, content, url, created_at FROM entries
-	WHERE type NOT IN ('thought', 'thought_admin', 'thought_stationai') ORDER BY created_at DESC LIMIT ?`
+	stmt := `SELECT ` + entryColumns + ` FROM entries
+	WHERE type NOT IN ` + thoughtTypeList + ` ORDER BY created_at DESC LIMIT ?`
 	return m.queryEntries(stmt, limit)
 }
 
+// EntryFilter narrows a List. Zero fields don't filter.
+type EntryFilter struct {
+	Type    string
+	Section string // "thoughts" or "media"
+	Mood    string
+	Since   time.Time // Created at or after
+	Until   time.Time // Created before
+	Limit   int
+	Offset  int
+}
+
+// List returns a page of the entries matching the filter, newest first, and how
+// many match in all.
+func (m *EntryModel) List(f EntryFilter) ([]*Entry, int, error) {
+	var conds []string
+	var args []any
+	if f.Type != "" {
+		conds, args = append(conds, "type = ?"), append(args, f.Type)
+	}
+	switch f.Section {
+	case "thoughts":
+		conds = append(conds, "type IN "+thoughtTypeList)
+	case "media":
+		conds = append(conds, "type NOT IN "+thoughtTypeList)
+	}
+	if f.Mood != "" {
+		conds, args = append(conds, "mood = ?"), append(args, f.Mood)
+	}
+	if !f.Since.IsZero() {
+		conds, args = append(conds, "created_at >= ?"), append(args, sqliteTime(f.Since))
+	}
+	if !f.Until.IsZero() {
+		conds, args = append(conds, "created_at < ?"), append(args, sqliteTime(f.Until))
+	}
+
+	where := ""
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	var total int
+	if err := m.DB.QueryRow(`SELECT COUNT(*) FROM entries`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	stmt := `SELECT ` + entryColumns + ` FROM entries` + where + ` ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`
+	entries, err := m.queryEntries(stmt, append(args, f.Limit, f.Offset)...)
+	return entries, total, err
+}
+
+// Delete removes an entry along with what hangs off it: attachments (the files stay
+// in the blob store), syndication records, external IDs, its reply context and its
+// place in the reading queue. Its replies are reattached to its own parent so the
+// thread holds together, and a transmission it was approved from is unlinked. SQLite only enforces the
+// schema's ON DELETE clauses with foreign keys switched on, so it does the work itself.
+func (m *EntryModel) Delete(id int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteEntry(tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// BulkDelete removes several entries the way Delete removes one, all or none of
+// them: an ID that doesn't exist rolls the whole batch back with ErrNoRecord.
+func (m *EntryModel) BulkDelete(ids []int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, id := range slices.Compact(slices.Sorted(slices.Values(ids))) {
+		if err := deleteEntry(tx, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func deleteEntry(tx *sql.Tx, id int) error {
+	stmts := []string{
+		`DELETE FROM attachments WHERE entry_id = ?`,
+		`DELETE FROM entry_syndications WHERE entry_id = ?`,
+		`DELETE FROM entry_external_ids WHERE entry_id = ?`,
+		`DELETE FROM entry_fields WHERE entry_id = ?`,
+		`DELETE FROM reply_contexts WHERE entry_id = ?`,
+		`DELETE FROM queue_items WHERE entry_id = ?`,
+		`UPDATE transmissions SET entry_id = NULL WHERE entry_id = ?`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt, id); err != nil {
+			return err
+		}
+	}
+	stmt := `UPDATE entries SET parent_id = (SELECT parent_id FROM entries WHERE id = ?) WHERE parent_id = ?`
+	if _, err := tx.Exec(stmt, id, id); err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(`DELETE FROM entries WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// BulkUpdateType retypes the given entries in one transaction, to a type new entries
+// can have. Moving entries out of the thoughts cuts their thread links, since only
+// thoughts thread; the number of links cut is returned. An ID that doesn't exist
+// rolls everything back with ErrNoRecord.
+func (m *EntryModel) BulkUpdateType(ids []int, to string) (detached int, err error) {
+	target, known := TypeByKey(to)
+	if !known || target.Retired {
+		return 0, fmt.Errorf("%q is not a type new entries can have", to)
+	}
+	ids = slices.Compact(slices.Sorted(slices.Values(ids)))
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	in, args := intPlaceholders(ids)
+	if !target.Thought {
+		stmt := `UPDATE entries SET parent_id = NULL WHERE (id IN ` + in + ` AND parent_id IS NOT NULL)
+		OR parent_id IN ` + in
+		res, err := tx.Exec(stmt, append(args, args...)...)
+		if err != nil {
+			return 0, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		detached = int(n)
+	}
+
+	res, err := tx.Exec(`UPDATE entries SET type = ?, updated_at = CURRENT_TIMESTAMP WHERE id IN `+in, append([]any{to}, args...)...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if int(n) != len(ids) {
+		return 0, ErrNoRecord
+	}
+
+	return detached, tx.Commit()
+}
+
+// intPlaceholders is placeholders for IDs.
+func intPlaceholders(ids []int) (string, []any) {
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return "(" + strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ") + ")", args
+}
+
+// Find returns the entries whose title, content or URL contains every term, newest
+// first. It matches literally, unlike the stemmed full-text index behind SearchModel.
+func (m *EntryModel) Find(terms []string, limit int) ([]*Entry, error) {
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	cond, args := likeAll([]string{"title", "content", "url"}, terms)
+	stmt := `SELECT ` + entryColumns + ` FROM entries WHERE ` + cond + ` ORDER BY created_at DESC LIMIT ?`
+	return m.queryEntries(stmt, append(args, limit)...)
+}
+
+// Adjacent returns the entries just before (older) and after (newer) the given one
+// within its sector, either of which may be nil at the ends of the archive. Thoughts
+// step between thread roots, since a thread's replies are already shown together.
+// It is a keyset query on (created_at, id), so it stays cheap however deep the archive gets.
+func (m *EntryModel) Adjacent(e *Entry) (prev, next *Entry, err error) {
+	sector := `type NOT IN ` + thoughtTypeList
+	if IsThoughtType(e.Type) {
+		sector = `type IN ` + thoughtTypeList + ` AND parent_id IS NULL`
+	}
+
+	prevStmt := `SELECT ` + entryColumns + ` FROM entries WHERE ` + sector + `
+	AND (created_at, id) < (SELECT created_at, id FROM entries WHERE id = ?)
+	ORDER BY created_at DESC, id DESC LIMIT 1`
+
+	nextStmt := `SELECT ` + entryColumns + ` FROM entries WHERE ` + sector + `
+	AND (created_at, id) > (SELECT created_at, id FROM entries WHERE id = ?)
+	ORDER BY created_at ASC, id ASC LIMIT 1`
+
+	prev, err = scanEntry(m.DB.QueryRow(prevStmt, e.ID))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, err
+	}
+
+	next, err = scanEntry(m.DB.QueryRow(nextStmt, e.ID))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, err
+	}
+
+	return prev, next, nil
+}
+
+// InEpoch returns every entry created during the epoch, oldest first.
+func (m *EntryModel) InEpoch(ep *Epoch) ([]*Entry, error) {
+	if ep.EndsOn == nil {
+		stmt := `SELECT ` + entryColumns + ` FROM entries
+		WHERE created_at >= ? ORDER BY created_at ASC, id ASC`
+		return m.queryEntries(stmt, sqliteTime(ep.StartsOn))
+	}
+
+	stmt := `SELECT ` + entryColumns + ` FROM entries
+	WHERE created_at >= ? AND created_at < ? ORDER BY created_at ASC, id ASC`
+	return m.queryEntries(stmt, sqliteTime(ep.StartsOn), sqliteTime(ep.EndsOn.AddDate(0, 0, 1)))
+}
+
 // RandomEntry returns a single random entry from the database.
 func (m *EntryModel) RandomEntry() (*Entry, error) {
-	stmt := `SELECT id, title, type, content, url, created_at FROM entries ORDER BY RANDOM() LIMIT 1`
+	stmt := `SELECT ` + entryColumns + ` FROM entries ORDER BY RANDOM() LIMIT 1`
+	return scanEntry(m.DB.QueryRow(stmt))
+}
+
+// MoodTimeline returns per-month mood counts for tagged thoughts, oldest month first.
+func (m *EntryModel) MoodTimeline() ([]*MoodCount, error) {
+	stmt := `SELECT strftime('%Y-%m', created_at) AS month, mood, COUNT(*) FROM entries
+	WHERE type IN ` + thoughtTypeList + ` AND mood IS NOT NULL
+	GROUP BY month, mood ORDER BY month ASC`
+
+	rows, err := m.DB.Query(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []*MoodCount
+
+	for rows.Next() {
+		c := &MoodCount{}
+		err = rows.Scan(&c.Month, &c.Mood, &c.Count)
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// NestThreads attaches each entry to its parent's Replies when the parent is part of
+// the same slice, returning the remaining top-level entries in their original order.
+// Replies are kept oldest first so a thread reads top to bottom.
+func NestThreads(entries []*Entry) []*Entry {
+	byID := make(map[int]*Entry, len(entries))
+	for _, e := range entries {
+		e.Replies = nil
+		byID[e.ID] = e
+	}
+
+	var roots []*Entry
+	for _, e := range entries {
+		if e.ParentID != nil {
+			if parent, ok := byID[*e.ParentID]; ok {
+				parent.Replies = append(parent.Replies, e)
+				continue
+			}
+		}
+		roots = append(roots, e)
+	}
+
+	for _, e := range entries {
+		slices.SortStableFunc(e.Replies, func(a, b *Entry) int {
+			return a.CreatedAt.Compare(b.CreatedAt)
+		})
+	}
+
+	return roots
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
 
+// scanEntry reads a single entry selected with entryColumns.
+func scanEntry(row rowScanner) (*Entry, error) {
 	e := &Entry{}
-	err := m.DB.QueryRow(stmt).Scan(&e.ID, &e.Title, &e.Type, &e.Content, &e.URL, &e.CreatedAt)
+	err := row.Scan(&e.ID, &e.Slug, &e.Title, &e.Type, &e.Content, &e.URL, &e.Mood, &e.ParentID, &e.CreatedAt,
+		&e.FeedSummary, &e.ExcludeFromFeed, &e.CanonicalURL, &e.UpdatedAt, &e.Fields)
 	if err != nil {
 		return nil, err
 	}
@@ -93,8 +773,7 @@ func (m *EntryModel) queryEntries(stmt string, args ...any) ([]*Entry, error) {
 	var entries []*Entry
 
 	for rows.Next() {
-		e := &Entry{}
-		err = rows.Scan(&e.ID, &e.Title, &e.Type, &e.Content, &e.URL, &e.CreatedAt)
+		e, err := scanEntry(rows)
 		if err != nil {
 			return nil, err
 		}
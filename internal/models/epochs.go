@@ -0,0 +1,143 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Epoch is a named stretch of the station's timeline, e.g. "Construction" or
+// "First Light". Entries belong to whichever epoch their creation date falls into.
+type Epoch struct {
+	ID          int
+	Name        string
+	Description *string    // Optional
+	StartsOn    time.Time  // First day of the epoch (UTC)
+	EndsOn      *time.Time // Last day of the epoch, inclusive; nil while it is still running
+	CreatedAt   time.Time
+}
+
+// Contains reports whether t falls inside the epoch.
+func (ep *Epoch) Contains(t time.Time) bool {
+	if t.Before(ep.StartsOn) {
+		return false
+	}
+	return ep.EndsOn == nil || t.Before(ep.EndsOn.AddDate(0, 0, 1))
+}
+
+// Epochs is a list of epochs ordered by start date.
+type Epochs []*Epoch
+
+// For returns the epoch containing t. When epochs overlap the one that started
+// most recently wins, so a short chapter can sit inside a longer era.
+func (eps Epochs) For(t time.Time) *Epoch {
+	for i := len(eps) - 1; i >= 0; i-- {
+		if eps[i].Contains(t) {
+			return eps[i]
+		}
+	}
+	return nil
+}
+
+// Badge sets Epoch on every entry, including any nested replies.
+func (eps Epochs) Badge(entries []*Entry) {
+	for _, e := range entries {
+		e.Epoch = eps.For(e.CreatedAt)
+		eps.Badge(e.Replies)
+	}
+}
+
+// EpochModel wraps a database connection pool for epochs.
+type EpochModel struct {
+	DB *sql.DB
+}
+
+const epochColumns = `id, name, description, starts_on, ends_on, created_at`
+
+// InitSchema creates the epochs table if it doesn't exist.
+func (m *EpochModel) InitSchema() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS epochs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		description TEXT,
+		starts_on DATETIME NOT NULL,
+		ends_on DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	_, err := m.DB.Exec(stmt)
+	return err
+}
+
+// Insert adds a new epoch.
+func (m *EpochModel) Insert(ep *Epoch) (int, error) {
+	stmt := `INSERT INTO epochs (name, description, starts_on, ends_on, created_at)
+	VALUES(?, ?, ?, ?, CURRENT_TIMESTAMP) RETURNING id`
+
+	var endsOn *string
+	if ep.EndsOn != nil {
+		s := sqliteTime(*ep.EndsOn)
+		endsOn = &s
+	}
+
+	var id int
+	err := m.DB.QueryRow(stmt, ep.Name, ep.Description, sqliteTime(ep.StartsOn), endsOn).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// Get returns a single epoch by ID.
+func (m *EpochModel) Get(id int) (*Epoch, error) {
+	stmt := `SELECT ` + epochColumns + ` FROM epochs WHERE id = ?`
+
+	ep, err := scanEpoch(m.DB.QueryRow(stmt, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoRecord
+	}
+	return ep, err
+}
+
+// All returns every epoch, oldest first.
+func (m *EpochModel) All() (Epochs, error) {
+	stmt := `SELECT ` + epochColumns + ` FROM epochs ORDER BY starts_on ASC, id ASC`
+
+	rows, err := m.DB.Query(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var epochs Epochs
+
+	for rows.Next() {
+		ep, err := scanEpoch(rows)
+		if err != nil {
+			return nil, err
+		}
+		epochs = append(epochs, ep)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return epochs, nil
+}
+
+// Delete removes an epoch. Entries are untouched, they simply lose the badge.
+func (m *EpochModel) Delete(id int) error {
+	_, err := m.DB.Exec(`DELETE FROM epochs WHERE id = ?`, id)
+	return err
+}
+
+func scanEpoch(row rowScanner) (*Epoch, error) {
+	ep := &Epoch{}
+	err := row.Scan(&ep.ID, &ep.Name, &ep.Description, &ep.StartsOn, &ep.EndsOn, &ep.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return ep, nil
+}
@@ -0,0 +1,8 @@
+package models
+
+import (
+	"errors"
+)
+
+// ErrNoRecord is returned when a lookup matches no row.
+var ErrNoRecord = errors.New("models: no matching record found")
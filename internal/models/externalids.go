@@ -0,0 +1,261 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ExternalProvider is a catalogue an entry can be linked to.
+type ExternalProvider struct {
+	Key     string // Stored in entry_external_ids.provider
+	Label   string
+	Example string // Placeholder shown on the form
+	URL     string // Public page for an ID, with %s standing for it; empty when the provider has none
+}
+
+// ExternalProviders lists the catalogues entries can be linked to.
+var ExternalProviders = []ExternalProvider{
+	{Key: "isbn", Label: "ISBN", Example: "978-0-553-28368-5", URL: "https://openlibrary.org/isbn/%s"},
+	{Key: "anilist", Label: "AniList", Example: "21"},
+	{Key: "igdb", Label: "IGDB", Example: "1942"},
+	{Key: "tmdb", Label: "TMDB", Example: "movie/603 or tv/1399", URL: "https://www.themoviedb.org/%s"},
+	{Key: "steam", Label: "Steam", Example: "620", URL: "https://store.steampowered.com/app/%s"},
+}
+
+// ExternalProviderByKey looks a provider up by its key.
+func ExternalProviderByKey(key string) (ExternalProvider, bool) {
+	for _, p := range ExternalProviders {
+		if p.Key == key {
+			return p, true
+		}
+	}
+	return ExternalProvider{}, false
+}
+
+var (
+	reNumericID = regexp.MustCompile(`^[1-9][0-9]{0,11}$`)
+	reTMDBID    = regexp.MustCompile(`^(movie|tv)/([1-9][0-9]{0,11})$`)
+)
+
+// NormalizeExternalID checks an identifier for a provider and returns it in the one
+// form it is stored in, so the same book or game can't be linked twice under
+// different spellings: ISBNs lose their hyphens and become ISBN-13, TMDB IDs carry
+// their media type.
+func NormalizeExternalID(provider, id string) (string, error) {
+	id = strings.TrimSpace(id)
+	switch provider {
+	case "isbn":
+		return normalizeISBN(id)
+	case "anilist", "igdb", "steam":
+		if !reNumericID.MatchString(id) {
+			return "", fmt.Errorf("%s IDs are numbers, not %q", provider, id)
+		}
+		return id, nil
+	case "tmdb":
+		id = strings.ToLower(strings.Trim(id, "/"))
+		if !reTMDBID.MatchString(id) {
+			return "", fmt.Errorf("TMDB IDs look like movie/603 or tv/1399, not %q", id)
+		}
+		return id, nil
+	}
+	return "", fmt.Errorf("unknown provider %q", provider)
+}
+
+// normalizeISBN validates an ISBN-10 or ISBN-13 and returns it as ISBN-13 digits.
+func normalizeISBN(s string) (string, error) {
+	digits := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(s))
+
+	switch len(digits) {
+	case 10:
+		sum := 0
+		for i, c := range digits {
+			d := int(c - '0')
+			if c == 'X' && i == 9 {
+				d = 10
+			} else if c < '0' || c > '9' {
+				return "", fmt.Errorf("%q is not an ISBN", s)
+			}
+			sum += d * (10 - i)
+		}
+		if sum%11 != 0 {
+			return "", fmt.Errorf("%q fails the ISBN-10 check digit", s)
+		}
+		return isbn13("978" + digits[:9]), nil
+	case 13:
+		for _, c := range digits {
+			if c < '0' || c > '9' {
+				return "", fmt.Errorf("%q is not an ISBN", s)
+			}
+		}
+		if isbn13(digits[:12]) != digits {
+			return "", fmt.Errorf("%q fails the ISBN-13 check digit", s)
+		}
+		return digits, nil
+	}
+	return "", fmt.Errorf("%q is not an ISBN", s)
+}
+
+// isbn13 appends the check digit to the first twelve digits of an ISBN-13.
+func isbn13(first12 string) string {
+	sum := 0
+	for i, c := range first12 {
+		d := int(c - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return first12 + string(rune('0'+(10-sum%10)%10))
+}
+
+// ExternalID links an entry to its record in an outside catalogue, which is what
+// metadata refreshes look it up by and what imports recognise it by.
+type ExternalID struct {
+	EntryID    int        `json:"-"`
+	Provider   string     `json:"provider"`
+	ExternalID string     `json:"id"`
+	SyncedAt   *time.Time `json:"synced_at,omitempty"` // Last metadata refresh from the provider, nil if never
+	CreatedAt  time.Time  `json:"-"`
+}
+
+// ProviderLabel is the provider's display name.
+func (x *ExternalID) ProviderLabel() string {
+	if p, ok := ExternalProviderByKey(x.Provider); ok {
+		return p.Label
+	}
+	return x.Provider
+}
+
+// Link is the provider's public page for the ID, or "" when it has none.
+func (x *ExternalID) Link() string {
+	p, ok := ExternalProviderByKey(x.Provider)
+	if !ok || p.URL == "" {
+		return ""
+	}
+	return fmt.Sprintf(p.URL, x.ExternalID)
+}
+
+// ErrExternalIDTaken is returned when an identifier is already linked to another entry.
+var ErrExternalIDTaken = errors.New("models: external ID already linked to another entry")
+
+// ExternalIDModel wraps a database connection pool for entry external IDs.
+type ExternalIDModel struct {
+	DB *sql.DB
+}
+
+const externalIDColumns = `entry_id, provider, external_id, synced_at, created_at`
+
+// InitSchema creates the entry_external_ids table if it doesn't exist. Each
+// identifier belongs to one entry at most; an entry can carry several, e.g. the
+// ISBNs of two editions.
+func (m *ExternalIDModel) InitSchema() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS entry_external_ids (
+		entry_id INTEGER NOT NULL REFERENCES entries(id) ON DELETE CASCADE,
+		provider TEXT NOT NULL,
+		external_id TEXT NOT NULL,
+		synced_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (provider, external_id)
+	);
+	CREATE INDEX IF NOT EXISTS entry_external_ids_entry ON entry_external_ids(entry_id);
+	`
+	_, err := m.DB.Exec(stmt)
+	return err
+}
+
+// Add links an entry to an identifier, which must already be normalized. Adding
+// a link the entry already has is a no-op; one held by another entry fails with
+// ErrExternalIDTaken.
+func (m *ExternalIDModel) Add(entryID int, provider, externalID string) error {
+	return addExternalID(m.DB, entryID, provider, externalID)
+}
+
+func addExternalID(q importQuerier, entryID int, provider, externalID string) error {
+	var owner int
+	err := q.QueryRow(`INSERT INTO entry_external_ids (entry_id, provider, external_id, created_at)
+	VALUES(?, ?, ?, CURRENT_TIMESTAMP)
+	ON CONFLICT(provider, external_id) DO UPDATE SET entry_id = entry_id
+	RETURNING entry_id`, entryID, provider, externalID).Scan(&owner)
+	if err != nil {
+		return err
+	}
+	if owner != entryID {
+		return ErrExternalIDTaken
+	}
+	return nil
+}
+
+// Remove unlinks an identifier from an entry.
+func (m *ExternalIDModel) Remove(entryID int, provider, externalID string) error {
+	stmt := `DELETE FROM entry_external_ids WHERE entry_id = ? AND provider = ? AND external_id = ?`
+	_, err := m.DB.Exec(stmt, entryID, provider, externalID)
+	return err
+}
+
+// ForEntry returns an entry's identifiers, by provider.
+func (m *ExternalIDModel) ForEntry(entryID int) ([]*ExternalID, error) {
+	stmt := `SELECT ` + externalIDColumns + ` FROM entry_external_ids WHERE entry_id = ? ORDER BY provider, external_id`
+	return m.query(stmt, entryID)
+}
+
+// All returns every identifier, for backups.
+func (m *ExternalIDModel) All() ([]*ExternalID, error) {
+	stmt := `SELECT ` + externalIDColumns + ` FROM entry_external_ids ORDER BY entry_id, provider, external_id`
+	return m.query(stmt)
+}
+
+// Owner returns the ID of the entry linked to an identifier.
+func (m *ExternalIDModel) Owner(provider, externalID string) (int, error) {
+	var id int
+	err := m.DB.QueryRow(`SELECT entry_id FROM entry_external_ids WHERE provider = ? AND external_id = ?`,
+		provider, externalID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNoRecord
+	}
+	return id, err
+}
+
+// Stale returns a provider's identifiers not refreshed since the given time, least
+// recently synced (never first), for metadata refresh jobs to work through.
+func (m *ExternalIDModel) Stale(provider string, before time.Time, limit int) ([]*ExternalID, error) {
+	stmt := `SELECT ` + externalIDColumns + ` FROM entry_external_ids
+	WHERE provider = ? AND (synced_at IS NULL OR synced_at < ?)
+	ORDER BY synced_at IS NOT NULL, synced_at, created_at LIMIT ?`
+	return m.query(stmt, provider, sqliteTime(before), limit)
+}
+
+// MarkSynced records a metadata refresh of an identifier.
+func (m *ExternalIDModel) MarkSynced(provider, externalID string, at time.Time) error {
+	stmt := `UPDATE entry_external_ids SET synced_at = ? WHERE provider = ? AND external_id = ?`
+	_, err := m.DB.Exec(stmt, sqliteTime(at), provider, externalID)
+	return err
+}
+
+func (m *ExternalIDModel) query(stmt string, args ...any) ([]*ExternalID, error) {
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*ExternalID
+
+	for rows.Next() {
+		x := &ExternalID{}
+		if err := rows.Scan(&x.EntryID, &x.Provider, &x.ExternalID, &x.SyncedAt, &x.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, x)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
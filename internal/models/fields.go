@@ -0,0 +1,146 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Field is one custom attribute of an entry, e.g. a game's platform or a book's
+// translator, for details too niche to deserve a column of their own.
+type Field struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Fields are an entry's custom attributes in the order they were entered. They are
+// stored one row per field in entry_fields and read back with every entry as a JSON
+// column (see entryColumns), which Scan decodes.
+type Fields []Field
+
+const (
+	maxFields          = 30
+	maxFieldKeyLength  = 40
+	maxFieldValueBytes = 500
+)
+
+var reFieldKey = regexp.MustCompile(`^[a-z0-9][a-z0-9_]*$`)
+
+// fieldsColumn selects an entry's fields as a JSON array of {"key", "value"} objects.
+const fieldsColumn = `(SELECT json_group_array(json_object('key', key, 'value', value) ORDER BY position)
+	FROM entry_fields WHERE entry_fields.entry_id = entries.id)`
+
+// Scan reads the JSON column built by fieldsColumn.
+func (f *Fields) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*f = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("fields: cannot scan %T", src)
+	}
+
+	var fields Fields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if len(fields) == 0 {
+		fields = nil
+	}
+	*f = fields
+	return nil
+}
+
+// Label is the key as it is shown on the station, e.g. "page count" for page_count.
+func (f Field) Label() string {
+	return strings.ReplaceAll(f.Key, "_", " ")
+}
+
+// Get returns the value of a field, or "" when the entry doesn't have it.
+func (f Fields) Get(key string) string {
+	for _, field := range f {
+		if field.Key == key {
+			return field.Value
+		}
+	}
+	return ""
+}
+
+// Text writes the fields one "key: value" per line, as the admin form edits them.
+func (f Fields) Text() string {
+	var b strings.Builder
+	for _, field := range f {
+		b.WriteString(field.Key + ": " + field.Value + "\n")
+	}
+	return b.String()
+}
+
+// Normalize checks the fields and returns them in their stored form: keys are
+// lowercased, with spaces and dashes turned into underscores, values are trimmed,
+// and no key may appear twice.
+func (f Fields) Normalize() (Fields, error) {
+	if len(f) > maxFields {
+		return nil, fmt.Errorf("at most %d custom fields per entry", maxFields)
+	}
+
+	var out Fields
+	seen := map[string]bool{}
+	for _, field := range f {
+		key := strings.ToLower(strings.TrimSpace(field.Key))
+		key = strings.NewReplacer(" ", "_", "-", "_").Replace(key)
+		value := strings.TrimSpace(field.Value)
+
+		if !reFieldKey.MatchString(key) || len(key) > maxFieldKeyLength {
+			return nil, fmt.Errorf("field name %q: use up to %d letters, digits and underscores", field.Key, maxFieldKeyLength)
+		}
+		if value == "" {
+			return nil, fmt.Errorf("field %s has no value", key)
+		}
+		if len(value) > maxFieldValueBytes {
+			return nil, fmt.Errorf("field %s is longer than %d bytes", key, maxFieldValueBytes)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("field %s is set twice", key)
+		}
+		seen[key] = true
+		out = append(out, Field{Key: key, Value: value})
+	}
+	return out, nil
+}
+
+// ParseFields reads the admin form's "key: value" lines, skipping blank ones.
+func ParseFields(text string) (Fields, error) {
+	var fields Fields
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("custom field %q: write it as name: value", strings.TrimSpace(line))
+		}
+		fields = append(fields, Field{Key: key, Value: value})
+	}
+	return fields.Normalize()
+}
+
+// setFields replaces an entry's custom fields with already normalized ones.
+func setFields(q importQuerier, entryID int, fields Fields) error {
+	if _, err := q.Exec(`DELETE FROM entry_fields WHERE entry_id = ?`, entryID); err != nil {
+		return err
+	}
+	for i, field := range fields {
+		_, err := q.Exec(`INSERT INTO entry_fields (entry_id, key, value, position) VALUES(?, ?, ?, ?)`,
+			entryID, field.Key, field.Value, i)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// Kinds of request filter rule.
+const (
+	FilterCIDR  = "cidr"  // Pattern is an address or CIDR range, e.g. 203.0.113.0/24
+	FilterAgent = "agent" // Pattern is a case-insensitive regular expression matched against the User-Agent
+)
+
+// FilterRule blocks requests matching a pattern before they reach any handler.
+type FilterRule struct {
+	ID        int
+	Kind      string
+	Pattern   string
+	Note      *string
+	Hits      int // Requests refused by this rule so far
+	CreatedAt time.Time
+}
+
+// FilterStat is how many requests were refused for one reason on one day.
+type FilterStat struct {
+	Day    string // YYYY-MM-DD (UTC)
+	Reason string // A rule kind, or "throttle"
+	Hits   int
+}
+
+// FilterModel wraps a database connection pool for request filtering.
+type FilterModel struct {
+	DB *sql.DB
+}
+
+// InitSchema creates the filter tables if they don't exist.
+func (m *FilterModel) InitSchema() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS filter_rules (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		kind TEXT NOT NULL,
+		pattern TEXT NOT NULL,
+		note TEXT,
+		hits INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(kind, pattern)
+	);
+	CREATE TABLE IF NOT EXISTS filter_stats (
+		day TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		hits INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (day, reason)
+	);
+	CREATE TABLE IF NOT EXISTS filter_settings (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);
+	`
+	_, err := m.DB.Exec(stmt)
+	return err
+}
+
+// Rules returns every filter rule, oldest first.
+func (m *FilterModel) Rules() ([]*FilterRule, error) {
+	rows, err := m.DB.Query(`SELECT id, kind, pattern, note, hits, created_at FROM filter_rules ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []*FilterRule
+
+	for rows.Next() {
+		fr := &FilterRule{}
+		if err := rows.Scan(&fr.ID, &fr.Kind, &fr.Pattern, &fr.Note, &fr.Hits, &fr.CreatedAt); err != nil {
+			return nil, err
+		}
+		rules = append(rules, fr)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// InsertRule adds a rule. Adding the same pattern twice is a no-op.
+func (m *FilterModel) InsertRule(fr *FilterRule) error {
+	stmt := `INSERT INTO filter_rules (kind, pattern, note, created_at)
+	VALUES(?, ?, ?, CURRENT_TIMESTAMP) ON CONFLICT DO NOTHING`
+	_, err := m.DB.Exec(stmt, fr.Kind, fr.Pattern, fr.Note)
+	return err
+}
+
+// DeleteRule removes a rule.
+func (m *FilterModel) DeleteRule(id int) error {
+	_, err := m.DB.Exec(`DELETE FROM filter_rules WHERE id = ?`, id)
+	return err
+}
+
+// RecordHits adds refused-request counts: per rule, and per reason for the given day.
+func (m *FilterModel) RecordHits(day time.Time, byReason map[string]int, byRule map[int]int) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for id, n := range byRule {
+		if _, err := tx.Exec(`UPDATE filter_rules SET hits = hits + ? WHERE id = ?`, n, id); err != nil {
+			return err
+		}
+	}
+
+	stmt := `INSERT INTO filter_stats (day, reason, hits) VALUES(?, ?, ?)
+	ON CONFLICT(day, reason) DO UPDATE SET hits = hits + excluded.hits`
+	for reason, n := range byReason {
+		if _, err := tx.Exec(stmt, day.UTC().Format(time.DateOnly), reason, n); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Stats returns the refused-request counts since the given day, newest first.
+func (m *FilterModel) Stats(since time.Time) ([]*FilterStat, error) {
+	stmt := `SELECT day, reason, hits FROM filter_stats WHERE day >= ? ORDER BY day DESC, reason`
+
+	rows, err := m.DB.Query(stmt, since.UTC().Format(time.DateOnly))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []*FilterStat
+
+	for rows.Next() {
+		s := &FilterStat{}
+		if err := rows.Scan(&s.Day, &s.Reason, &s.Hits); err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// ThrottleLimit returns how many requests per minute one address may make before
+// it is throttled; 0 means no throttling.
+func (m *FilterModel) ThrottleLimit() (int, error) {
+	var v string
+	err := m.DB.QueryRow(`SELECT value FROM filter_settings WHERE key = 'throttle_per_minute'`).Scan(&v)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(v)
+}
+
+// SetThrottleLimit changes the per-address request limit; 0 turns throttling off.
+func (m *FilterModel) SetThrottleLimit(perMinute int) error {
+	stmt := `INSERT INTO filter_settings (key, value) VALUES('throttle_per_minute', ?)
+	ON CONFLICT(key) DO UPDATE SET value = excluded.value`
+	_, err := m.DB.Exec(stmt, strconv.Itoa(perMinute))
+	return err
+}
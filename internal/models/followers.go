@@ -0,0 +1,83 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Follower is a remote ActivityPub account following the station's thoughts.
+type Follower struct {
+	Actor     string // The account's actor ID, e.g. https://mastodon.example/users/someone
+	Inbox     string // Where its server wants activities delivered, preferably the shared inbox
+	CreatedAt time.Time
+}
+
+// FollowerModel wraps a database connection pool for ActivityPub followers.
+type FollowerModel struct {
+	DB *sql.DB
+}
+
+// InitSchema creates the ap_followers table if it doesn't exist.
+func (m *FollowerModel) InitSchema() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS ap_followers (
+		actor TEXT PRIMARY KEY,
+		inbox TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	_, err := m.DB.Exec(stmt)
+	return err
+}
+
+// Add records a follower. Following again just refreshes its inbox.
+func (m *FollowerModel) Add(actor, inbox string) error {
+	stmt := `INSERT INTO ap_followers (actor, inbox, created_at) VALUES(?, ?, CURRENT_TIMESTAMP)
+	ON CONFLICT(actor) DO UPDATE SET inbox = excluded.inbox`
+	_, err := m.DB.Exec(stmt, actor, inbox)
+	return err
+}
+
+// Remove forgets a follower; unknown actors are not an error.
+func (m *FollowerModel) Remove(actor string) error {
+	_, err := m.DB.Exec(`DELETE FROM ap_followers WHERE actor = ?`, actor)
+	return err
+}
+
+// RemoveInbox forgets every follower delivered to an inbox, once its server says
+// it is gone for good.
+func (m *FollowerModel) RemoveInbox(inbox string) (int, error) {
+	res, err := m.DB.Exec(`DELETE FROM ap_followers WHERE inbox = ?`, inbox)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// Count returns how many accounts follow the station.
+func (m *FollowerModel) Count() (int, error) {
+	var n int
+	err := m.DB.QueryRow(`SELECT COUNT(*) FROM ap_followers`).Scan(&n)
+	return n, err
+}
+
+// Inboxes returns each distinct inbox once, so followers sharing a server's shared
+// inbox get a post in one delivery.
+func (m *FollowerModel) Inboxes() ([]string, error) {
+	rows, err := m.DB.Query(`SELECT DISTINCT inbox FROM ap_followers ORDER BY inbox`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var inboxes []string
+	for rows.Next() {
+		var inbox string
+		if err := rows.Scan(&inbox); err != nil {
+			return nil, err
+		}
+		inboxes = append(inboxes, inbox)
+	}
+	return inboxes, rows.Err()
+}
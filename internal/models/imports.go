@@ -0,0 +1,342 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Import actions, chosen per row on the reconciliation screen.
+const (
+	ImportAdd       = "add"       // No conflict: insert the row
+	ImportSkip      = "skip"      // Leave the database as it is
+	ImportOverwrite = "overwrite" // Replace the existing entry's content with the row's
+	ImportMerge     = "merge"     // Fill the existing entry's empty fields from the row
+	ImportKeepBoth  = "keep"      // Insert the row as a separate entry
+)
+
+// Import is a staged upload waiting for its conflicts to be reconciled.
+type Import struct {
+	ID        int
+	Filename  string
+	Records   []*EntryRecord
+	CreatedAt time.Time
+}
+
+// ImportConflict is an existing entry that an imported row collides with.
+type ImportConflict struct {
+	Entry  *Entry
+	Reason string // "external_id", "slug", "url" or "title"
+}
+
+// ImportRow is one imported record with whatever it collides with.
+type ImportRow struct {
+	Index     int // Position in Import.Records, used to key decisions
+	Record    *EntryRecord
+	Conflicts []ImportConflict
+}
+
+// Target is the entry that overwrite and merge act on: the strongest conflict,
+// external ID over slug over URL over title.
+func (r *ImportRow) Target() *Entry {
+	if len(r.Conflicts) == 0 {
+		return nil
+	}
+	return r.Conflicts[0].Entry
+}
+
+// Actions lists the choices available for the row, the default first.
+func (r *ImportRow) Actions() []string {
+	if len(r.Conflicts) == 0 {
+		return []string{ImportAdd, ImportSkip}
+	}
+	return []string{ImportSkip, ImportOverwrite, ImportMerge, ImportKeepBoth}
+}
+
+// ImportModel wraps a database connection pool for staged imports.
+type ImportModel struct {
+	DB *sql.DB
+}
+
+// InitSchema creates the imports table if it doesn't exist.
+func (m *ImportModel) InitSchema() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS imports (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		filename TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	_, err := m.DB.Exec(stmt)
+	return err
+}
+
+// Stage stores uploaded (already validated) records until the import is applied or discarded.
+func (m *ImportModel) Stage(filename string, records []*EntryRecord) (int, error) {
+	payload, err := json.Marshal(records)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = m.DB.QueryRow(`INSERT INTO imports (filename, payload, created_at) VALUES(?, ?, CURRENT_TIMESTAMP) RETURNING id`,
+		filename, string(payload)).Scan(&id)
+	return id, err
+}
+
+// Get returns a staged import by ID.
+func (m *ImportModel) Get(id int) (*Import, error) {
+	imp := &Import{}
+	var payload string
+
+	err := m.DB.QueryRow(`SELECT id, filename, payload, created_at FROM imports WHERE id = ?`, id).
+		Scan(&imp.ID, &imp.Filename, &payload, &imp.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoRecord
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(payload), &imp.Records); err != nil {
+		return nil, err
+	}
+	return imp, nil
+}
+
+// Delete discards a staged import.
+func (m *ImportModel) Delete(id int) error {
+	_, err := m.DB.Exec(`DELETE FROM imports WHERE id = ?`, id)
+	return err
+}
+
+// importQuerier is what conflict detection and apply need, from either the pool or a transaction.
+type importQuerier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// Rows pairs each record of the import with the existing entries it collides with.
+func (m *ImportModel) Rows(imp *Import) ([]*ImportRow, error) {
+	return importRows(m.DB, imp)
+}
+
+func importRows(q importQuerier, imp *Import) ([]*ImportRow, error) {
+	stmt := `SELECT ` + entryColumns + ` FROM entries
+	WHERE slug = ? OR (url IS NOT NULL AND url = ?) OR (lower(title) = lower(?) AND type = ?)
+	ORDER BY id`
+
+	rows := make([]*ImportRow, 0, len(imp.Records))
+	for i, rec := range imp.Records {
+		row := &ImportRow{Index: i, Record: rec}
+
+		matches, err := q.Query(stmt, rec.Slug, StringValue(rec.URL), rec.Title, rec.Type)
+		if err != nil {
+			return nil, err
+		}
+		for matches.Next() {
+			e, err := scanEntry(matches)
+			if err != nil {
+				matches.Close()
+				return nil, err
+			}
+			row.Conflicts = append(row.Conflicts, ImportConflict{Entry: e, Reason: conflictReason(rec, e)})
+		}
+		matches.Close()
+		if err := matches.Err(); err != nil {
+			return nil, err
+		}
+
+		// The same book or game linked under a shared catalogue ID is a duplicate
+		// whatever it is titled, even when it came from another integration
+		for _, x := range rec.ExternalIDs {
+			e, err := scanEntry(q.QueryRow(`SELECT `+entryColumns+` FROM entries WHERE id =
+				(SELECT entry_id FROM entry_external_ids WHERE provider = ? AND external_id = ?)`, x.Provider, x.ExternalID))
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			i := slices.IndexFunc(row.Conflicts, func(c ImportConflict) bool { return c.Entry.ID == e.ID })
+			if i < 0 {
+				row.Conflicts = append(row.Conflicts, ImportConflict{Entry: e})
+				i = len(row.Conflicts) - 1
+			}
+			row.Conflicts[i].Reason = "external_id"
+		}
+
+		rank := map[string]int{"external_id": 0, "slug": 1, "url": 2, "title": 3}
+		slices.SortStableFunc(row.Conflicts, func(a, b ImportConflict) int {
+			return rank[a.Reason] - rank[b.Reason]
+		})
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func conflictReason(rec *EntryRecord, e *Entry) string {
+	switch {
+	case rec.Slug != "" && rec.Slug == e.Slug:
+		return "slug"
+	case rec.URL != nil && e.URL != nil && *rec.URL == *e.URL:
+		return "url"
+	default:
+		return "title"
+	}
+}
+
+// Apply carries out the decisions (keyed by ImportRow.Index) in one transaction and
+// discards the staged import, returning how many rows each action handled. Rows
+// without a valid decision get their default action. Conflicts are re-checked inside
+// the transaction, so entries changed since the screen was drawn are still respected.
+func (m *ImportModel) Apply(imp *Import, decisions map[int]string) (map[string]int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := importRows(tx, imp)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := map[string]int{}
+	localIDs := map[int]int{} // Exported ID -> ID in this database, for re-threading replies
+
+	// Rows whose parent should follow the mapping, by local ID
+	type pendingParent struct{ id, exportedParent int }
+	var reparent []pendingParent
+
+	for _, row := range rows {
+		action := decisions[row.Index]
+		if !slices.Contains(row.Actions(), action) {
+			action = row.Actions()[0]
+		}
+		rec, target := row.Record, row.Target()
+
+		local := 0
+
+		switch action {
+		case ImportAdd, ImportKeepBoth:
+			if local, err = insertRecord(tx, rec); err != nil {
+				return nil, err
+			}
+		case ImportOverwrite:
+			stmt := `UPDATE entries SET title = ?, type = ?, content = ?, url = ?, mood = ?, feed_summary = ?,
+			exclude_from_feed = ?, canonical_url = ?, created_at = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+			_, err := tx.Exec(stmt, rec.Title, rec.Type, rec.Content, rec.URL, rec.Mood, rec.FeedSummary,
+				rec.ExcludeFromFeed, rec.CanonicalURL, sqliteTime(rec.CreatedAt), target.ID)
+			if err != nil {
+				return nil, err
+			}
+			local = target.ID
+		case ImportMerge:
+			stmt := `UPDATE entries SET content = COALESCE(content, ?), url = COALESCE(url, ?),
+			mood = COALESCE(mood, ?), feed_summary = COALESCE(feed_summary, ?),
+			canonical_url = COALESCE(canonical_url, ?), updated_at = CURRENT_TIMESTAMP WHERE id = ?`
+			_, err := tx.Exec(stmt, rec.Content, rec.URL, rec.Mood, rec.FeedSummary, rec.CanonicalURL, target.ID)
+			if err != nil {
+				return nil, err
+			}
+			local = target.ID
+		case ImportSkip:
+			// Replies to a skipped duplicate thread onto the copy that's already here
+			if target != nil && rec.ID != 0 {
+				localIDs[rec.ID] = target.ID
+			}
+		}
+		summary[action]++
+
+		if local == 0 {
+			continue
+		}
+		if err := importFields(tx, action, local, target, rec.Fields); err != nil {
+			return nil, err
+		}
+		// An identifier another entry already holds stays with that entry
+		for _, x := range rec.ExternalIDs {
+			err := addExternalID(tx, local, x.Provider, x.ExternalID)
+			if err != nil && !errors.Is(err, ErrExternalIDTaken) {
+				return nil, err
+			}
+		}
+		if rec.ID != 0 {
+			localIDs[rec.ID] = local
+		}
+		// Merging never moves an entry that already sits in a thread
+		if rec.ParentID != nil && (action != ImportMerge || target.ParentID == nil) {
+			reparent = append(reparent, pendingParent{local, *rec.ParentID})
+		}
+	}
+
+	// Parents may come later in the file than their replies, so threads are wired up last
+	for _, p := range reparent {
+		parent, ok := localIDs[p.exportedParent]
+		if !ok || parent == p.id {
+			continue
+		}
+		if _, err := tx.Exec(`UPDATE entries SET parent_id = ? WHERE id = ?`, parent, p.id); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM imports WHERE id = ?`, imp.ID); err != nil {
+		return nil, err
+	}
+
+	return summary, tx.Commit()
+}
+
+// importFields stores an imported record's custom fields. Overwriting replaces the
+// entry's fields with the record's; merging only adds the ones it doesn't have yet.
+func importFields(q importQuerier, action string, entryID int, target *Entry, fields Fields) error {
+	switch action {
+	case ImportOverwrite:
+		return setFields(q, entryID, fields)
+	case ImportMerge:
+		merged := slices.Clone(target.Fields)
+		for _, f := range fields {
+			if !slices.ContainsFunc(merged, func(have Field) bool { return have.Key == f.Key }) {
+				merged = append(merged, f)
+			}
+		}
+		return setFields(q, entryID, merged)
+	}
+	return setFields(q, entryID, fields)
+}
+
+// insertRecord adds a record as a new entry, keeping its exported slug when that's free.
+func insertRecord(q importQuerier, rec *EntryRecord) (int, error) {
+	slug := strings.TrimSpace(rec.Slug)
+
+	var taken bool
+	if err := q.QueryRow(`SELECT EXISTS(SELECT 1 FROM entries WHERE slug = ?)`, slug).Scan(&taken); err != nil {
+		return 0, err
+	}
+	if slug == "" || taken || slug != Slugify(slug) {
+		var err error
+		if slug, err = uniqueSlug(q, rec.Title, rec.Type); err != nil {
+			return 0, err
+		}
+	}
+
+	createdAt := rec.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	stmt := `INSERT INTO entries (slug, title, type, content, url, mood, feed_summary, exclude_from_feed, canonical_url, created_at)
+	VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`
+
+	var id int
+	err := q.QueryRow(stmt, slug, rec.Title, rec.Type, rec.Content, rec.URL, rec.Mood, rec.FeedSummary,
+		rec.ExcludeFromFeed, rec.CanonicalURL, sqliteTime(createdAt)).Scan(&id)
+	return id, err
+}
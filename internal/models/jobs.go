@@ -0,0 +1,128 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Job is a unit of background work persisted in the main database, so queued
+// work survives restarts of the container.
+type Job struct {
+	ID          int
+	Kind        string
+	Payload     []byte // JSON, decoded by the handler registered for Kind
+	Status      string // "pending", "running", "done" or "failed"
+	Attempts    int
+	MaxAttempts int
+	RunAt       time.Time
+	LastError   *string
+	CreatedAt   time.Time
+}
+
+// JobModel wraps a database connection pool for the job queue.
+type JobModel struct {
+	DB *sql.DB
+}
+
+// InitSchema creates the jobs table if it doesn't exist.
+func (m *JobModel) InitSchema() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS jobs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		kind TEXT NOT NULL,
+		payload TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		max_attempts INTEGER NOT NULL DEFAULT 5,
+		run_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		last_error TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS jobs_due ON jobs (status, run_at);
+	`
+	if _, err := m.DB.Exec(stmt); err != nil {
+		return err
+	}
+
+	// Anything left "running" belonged to a process that died mid-job; give it another go
+	_, err := m.DB.Exec(`UPDATE jobs SET status = 'pending' WHERE status = 'running'`)
+	return err
+}
+
+// Enqueue stores a new pending job with a JSON-encoded payload.
+func (m *JobModel) Enqueue(kind string, payload any) (int, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	stmt := `INSERT INTO jobs (kind, payload, run_at, created_at)
+	VALUES(?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP) RETURNING id`
+
+	var id int
+	err = m.DB.QueryRow(stmt, kind, string(data)).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// Claim marks the oldest due job as running and returns it, or ErrNoRecord when
+// nothing is due. Jobs of the skipped kinds stay pending. The single UPDATE keeps two
+// workers from grabbing the same job.
+func (m *JobModel) Claim(skip ...string) (*Job, error) {
+	args := []any{sqliteTime(time.Now())}
+	skipped := ""
+	if len(skip) > 0 {
+		skipped = " AND kind NOT IN (?" + strings.Repeat(", ?", len(skip)-1) + ")"
+		for _, kind := range skip {
+			args = append(args, kind)
+		}
+	}
+
+	stmt := `UPDATE jobs SET status = 'running', attempts = attempts + 1
+	WHERE id = (
+		SELECT id FROM jobs WHERE status = 'pending' AND run_at <= ?` + skipped + ` ORDER BY run_at, id LIMIT 1
+	)
+	RETURNING id, kind, payload, status, attempts, max_attempts, run_at, last_error, created_at`
+
+	j := &Job{}
+	var payload string
+	err := m.DB.QueryRow(stmt, args...).Scan(&j.ID, &j.Kind, &payload, &j.Status,
+		&j.Attempts, &j.MaxAttempts, &j.RunAt, &j.LastError, &j.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoRecord
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	j.Payload = []byte(payload)
+	return j, nil
+}
+
+// Complete marks a job as successfully finished.
+func (m *JobModel) Complete(id int) error {
+	_, err := m.DB.Exec(`UPDATE jobs SET status = 'done', last_error = NULL WHERE id = ?`, id)
+	return err
+}
+
+// Fail records a failed attempt. The job is rescheduled for retryAt unless it
+// has used up its attempts, in which case it is parked as failed.
+func (m *JobModel) Fail(id int, jobErr error, retryAt time.Time) error {
+	stmt := `UPDATE jobs SET
+		status = CASE WHEN attempts >= max_attempts THEN 'failed' ELSE 'pending' END,
+		run_at = ?, last_error = ?
+	WHERE id = ?`
+	_, err := m.DB.Exec(stmt, sqliteTime(retryAt), jobErr.Error(), id)
+	return err
+}
+
+// sqliteTime formats a time the way CURRENT_TIMESTAMP does, so stored values
+// compare correctly as text.
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
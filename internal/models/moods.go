@@ -0,0 +1,29 @@
+package models
+
+// Mood is an optional emotional tag a thought can carry.
+type Mood struct {
+	Key   string
+	Emoji string
+	Label string
+	Color string // Used by the stats chart
+}
+
+// Moods is the fixed set of moods a thought can be tagged with, in display order.
+var Moods = []Mood{
+	{Key: "calm", Emoji: "🌊", Label: "Calm", Color: "#3498db"},
+	{Key: "focused", Emoji: "🎯", Label: "Focused", Color: "#4CAF50"},
+	{Key: "elated", Emoji: "✨", Label: "Elated", Color: "#f1c40f"},
+	{Key: "restless", Emoji: "⚡", Label: "Restless", Color: "#e67e22"},
+	{Key: "melancholy", Emoji: "🌧", Label: "Melancholy", Color: "#8e44ad"},
+	{Key: "drained", Emoji: "🌙", Label: "Drained", Color: "#7f8c8d"},
+}
+
+// MoodByKey looks up a mood by its key, reporting whether it exists.
+func MoodByKey(key string) (Mood, bool) {
+	for _, m := range Moods {
+		if m.Key == key {
+			return m, true
+		}
+	}
+	return Mood{}, false
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"strings"
+)
+
+// NullString maps an empty (or whitespace-only) string to nil, for optional columns.
+func NullString(s string) *string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	return &s
+}
+
+// StringValue dereferences an optional string, treating nil as empty.
+func StringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,142 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// PricePoint is one price a "price" source read from its page.
+type PricePoint struct {
+	ID         int
+	SourceID   int
+	Price      float64
+	Currency   *string // ISO code or symbol, when the page or the config says
+	ObservedAt time.Time
+}
+
+// PriceSummary is where one tracked price stands now, for the scraper page.
+type PriceSummary struct {
+	SourceID   int
+	SourceName *string // nil once the source is deleted
+	Current    float64
+	Currency   *string
+	ObservedAt time.Time
+	Previous   *float64 // The last different price, nil while it has never changed
+	Low, High  float64
+	Points     int
+}
+
+// Delta is the move from the previous price to the current one, 0 when unchanged.
+func (s *PriceSummary) Delta() float64 {
+	if s.Previous == nil {
+		return 0
+	}
+	return s.Current - *s.Previous
+}
+
+// DeltaPercent is Delta as a percentage of the previous price.
+func (s *PriceSummary) DeltaPercent() float64 {
+	if s.Previous == nil || *s.Previous == 0 {
+		return 0
+	}
+	return s.Delta() / *s.Previous * 100
+}
+
+// PriceModel wraps a database connection pool for price history.
+type PriceModel struct {
+	DB *sql.DB
+}
+
+// InitSchema creates the price_points table if it doesn't exist. It lives in
+// scraper.db beside the sources it tracks.
+func (m *PriceModel) InitSchema() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS price_points (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		source_id INTEGER NOT NULL,
+		price REAL NOT NULL,
+		currency TEXT,
+		observed_at DATETIME NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS price_points_source ON price_points(source_id, observed_at);
+	`
+	_, err := m.DB.Exec(stmt)
+	return err
+}
+
+// Insert records an observed price.
+func (m *PriceModel) Insert(p *PricePoint) error {
+	stmt := `INSERT INTO price_points (source_id, price, currency, observed_at) VALUES(?, ?, ?, ?)`
+	_, err := m.DB.Exec(stmt, p.SourceID, p.Price, p.Currency, sqliteTime(p.ObservedAt))
+	return err
+}
+
+// History returns a source's most recent price points, newest first.
+func (m *PriceModel) History(sourceID, limit int) ([]*PricePoint, error) {
+	stmt := `SELECT id, source_id, price, currency, observed_at FROM price_points
+	WHERE source_id = ? ORDER BY observed_at DESC, id DESC LIMIT ?`
+
+	rows, err := m.DB.Query(stmt, sourceID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []*PricePoint
+
+	for rows.Next() {
+		p := &PricePoint{}
+		if err := rows.Scan(&p.ID, &p.SourceID, &p.Price, &p.Currency, &p.ObservedAt); err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return points, nil
+}
+
+// Summaries returns where every tracked price stands, by source name.
+func (m *PriceModel) Summaries() ([]*PriceSummary, error) {
+	stmt := `SELECT c.source_id, s.name, c.price, c.currency, c.observed_at,
+		(SELECT price FROM price_points q WHERE q.source_id = c.source_id AND q.price != c.price
+			ORDER BY q.observed_at DESC, q.id DESC LIMIT 1),
+		(SELECT MIN(price) FROM price_points q WHERE q.source_id = c.source_id),
+		(SELECT MAX(price) FROM price_points q WHERE q.source_id = c.source_id),
+		(SELECT COUNT(*) FROM price_points q WHERE q.source_id = c.source_id)
+	FROM price_points c LEFT JOIN sources s ON s.id = c.source_id
+	WHERE c.id = (SELECT id FROM price_points l WHERE l.source_id = c.source_id ORDER BY l.observed_at DESC, l.id DESC LIMIT 1)
+	ORDER BY s.name COLLATE NOCASE`
+
+	rows, err := m.DB.Query(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*PriceSummary
+
+	for rows.Next() {
+		s := &PriceSummary{}
+		err := rows.Scan(&s.SourceID, &s.SourceName, &s.Current, &s.Currency, &s.ObservedAt, &s.Previous, &s.Low, &s.High, &s.Points)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// Delete forgets a source's price history.
+func (m *PriceModel) Delete(sourceID int) error {
+	_, err := m.DB.Exec(`DELETE FROM price_points WHERE source_id = ?`, sourceID)
+	return err
+}
@@ -0,0 +1,184 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// QueueItem is a link saved for later reading. It points either at a link entry or
+// at a starred scraper item; scraper items live in the other database, so their
+// title and URL are copied in when they are starred.
+type QueueItem struct {
+	ID            int
+	EntryID       *int // Set for entries
+	ScrapedItemID *int // Set for starred scraper items
+	Title         string
+	URL           *string
+	Position      int
+	SnoozedUntil  *time.Time // Hidden from the queue until then
+	CreatedAt     time.Time
+}
+
+// Snoozed reports whether the item is still hidden at t.
+func (q *QueueItem) Snoozed(t time.Time) bool {
+	return q.SnoozedUntil != nil && q.SnoozedUntil.After(t)
+}
+
+// QueueModel wraps a database connection pool for the reading queue.
+type QueueModel struct {
+	DB *sql.DB
+}
+
+const queueColumns = `id, entry_id, scraped_item_id, title, url, position, snoozed_until, created_at`
+
+// InitSchema creates the queue_items table if it doesn't exist.
+func (m *QueueModel) InitSchema() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS queue_items (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		entry_id INTEGER UNIQUE REFERENCES entries(id) ON DELETE CASCADE,
+		scraped_item_id INTEGER UNIQUE,
+		title TEXT NOT NULL,
+		url TEXT,
+		position INTEGER NOT NULL,
+		snoozed_until DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	_, err := m.DB.Exec(stmt)
+	return err
+}
+
+// Insert appends an item to the end of the queue. Queueing something twice is a no-op.
+func (m *QueueModel) Insert(q *QueueItem) error {
+	stmt := `INSERT INTO queue_items (entry_id, scraped_item_id, title, url, position, created_at)
+	VALUES(?, ?, ?, ?, (SELECT COALESCE(MAX(position), 0) + 1 FROM queue_items), CURRENT_TIMESTAMP)
+	ON CONFLICT DO NOTHING`
+	_, err := m.DB.Exec(stmt, q.EntryID, q.ScrapedItemID, q.Title, q.URL)
+	return err
+}
+
+// All returns the whole queue in reading order, snoozed items included.
+func (m *QueueModel) All() ([]*QueueItem, error) {
+	stmt := `SELECT ` + queueColumns + ` FROM queue_items ORDER BY position ASC`
+
+	rows, err := m.DB.Query(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []*QueueItem
+
+	for rows.Next() {
+		q := &QueueItem{}
+		err = rows.Scan(&q.ID, &q.EntryID, &q.ScrapedItemID, &q.Title, &q.URL, &q.Position, &q.SnoozedUntil, &q.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, q)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// HasEntry reports whether an entry is already queued.
+func (m *QueueModel) HasEntry(entryID int) (bool, error) {
+	var exists bool
+	err := m.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM queue_items WHERE entry_id = ?)`, entryID).Scan(&exists)
+	return exists, err
+}
+
+// StarredItems returns the IDs of every scraper item in the queue.
+func (m *QueueModel) StarredItems() (map[int]bool, error) {
+	rows, err := m.DB.Query(`SELECT scraped_item_id FROM queue_items WHERE scraped_item_id IS NOT NULL`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	starred := map[int]bool{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		starred[id] = true
+	}
+
+	return starred, rows.Err()
+}
+
+// Move swaps an item with its neighbour, up (towards the front) when up is true.
+// Moving past either end does nothing.
+func (m *QueueModel) Move(id int, up bool) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var position int
+	err = tx.QueryRow(`SELECT position FROM queue_items WHERE id = ?`, id).Scan(&position)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNoRecord
+	} else if err != nil {
+		return err
+	}
+
+	stmt := `SELECT id, position FROM queue_items WHERE position > ? ORDER BY position ASC LIMIT 1`
+	if up {
+		stmt = `SELECT id, position FROM queue_items WHERE position < ? ORDER BY position DESC LIMIT 1`
+	}
+
+	var otherID, otherPosition int
+	err = tx.QueryRow(stmt, position).Scan(&otherID, &otherPosition)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE queue_items SET position = ? WHERE id = ?`, otherPosition, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE queue_items SET position = ? WHERE id = ?`, position, otherID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Snooze hides an item until the given time; a nil time wakes it up again.
+func (m *QueueModel) Snooze(id int, until *time.Time) error {
+	var value *string
+	if until != nil {
+		s := sqliteTime(*until)
+		value = &s
+	}
+
+	res, err := m.DB.Exec(`UPDATE queue_items SET snoozed_until = ? WHERE id = ?`, value, id)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// Delete takes an item off the queue, e.g. once it has been read.
+func (m *QueueModel) Delete(id int) error {
+	_, err := m.DB.Exec(`DELETE FROM queue_items WHERE id = ?`, id)
+	return err
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ReplyContext is a stored snapshot of the external post an entry's URL points at,
+// so the quoted context survives the original being edited or deleted.
+type ReplyContext struct {
+	EntryID     int
+	Service     string // "mastodon", "bluesky" or "hackernews"
+	URL         string
+	Author      string
+	AuthorURL   *string
+	Content     string
+	PublishedAt time.Time
+	FetchedAt   time.Time
+}
+
+// ReplyContextModel wraps a database connection pool for reply context snapshots.
+type ReplyContextModel struct {
+	DB *sql.DB
+}
+
+// InitSchema creates the reply_contexts table if it doesn't exist.
+func (m *ReplyContextModel) InitSchema() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS reply_contexts (
+		entry_id INTEGER PRIMARY KEY REFERENCES entries(id) ON DELETE CASCADE,
+		service TEXT NOT NULL,
+		url TEXT NOT NULL,
+		author TEXT NOT NULL,
+		author_url TEXT,
+		content TEXT NOT NULL,
+		published_at DATETIME NOT NULL,
+		fetched_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	_, err := m.DB.Exec(stmt)
+	return err
+}
+
+// Upsert stores the snapshot for an entry, replacing any previous one.
+func (m *ReplyContextModel) Upsert(c *ReplyContext) error {
+	stmt := `INSERT INTO reply_contexts (entry_id, service, url, author, author_url, content, published_at, fetched_at)
+	VALUES(?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+	ON CONFLICT(entry_id) DO UPDATE SET
+		service = excluded.service, url = excluded.url, author = excluded.author,
+		author_url = excluded.author_url, content = excluded.content,
+		published_at = excluded.published_at, fetched_at = excluded.fetched_at`
+	_, err := m.DB.Exec(stmt, c.EntryID, c.Service, c.URL, c.Author, c.AuthorURL, c.Content, sqliteTime(c.PublishedAt))
+	return err
+}
+
+// Delete drops the snapshot for an entry, e.g. when its URL changes to something unsupported.
+func (m *ReplyContextModel) Delete(entryID int) error {
+	_, err := m.DB.Exec(`DELETE FROM reply_contexts WHERE entry_id = ?`, entryID)
+	return err
+}
+
+// ForEntry returns the snapshot for an entry, or ErrNoRecord if it has none.
+func (m *ReplyContextModel) ForEntry(entryID int) (*ReplyContext, error) {
+	stmt := `SELECT entry_id, service, url, author, author_url, content, published_at, fetched_at
+	FROM reply_contexts WHERE entry_id = ?`
+
+	c := &ReplyContext{}
+	err := m.DB.QueryRow(stmt, entryID).Scan(&c.EntryID, &c.Service, &c.URL, &c.Author, &c.AuthorURL,
+		&c.Content, &c.PublishedAt, &c.FetchedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoRecord
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
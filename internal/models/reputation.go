@@ -0,0 +1,145 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// BlockThreshold is the score at which an origin is blocked automatically.
+const BlockThreshold = -10
+
+// Reputation is what the station has learned about one sender origin (a hashed IP)
+// from its submissions: failed spam checks and rejected messages cost points,
+// approved ones earn them back.
+type Reputation struct {
+	Origin     string
+	Score      int
+	Strikes    int     // Failed spam checks
+	LastReason *string // Most recent strike or block reason
+	Blocked    bool
+	Note       *string // Operator's note, e.g. the address a manual block was for
+	UpdatedAt  time.Time
+}
+
+// ReputationModel wraps a database connection pool for sender reputation.
+type ReputationModel struct {
+	DB *sql.DB
+}
+
+const reputationColumns = `origin, score, strikes, last_reason, blocked, note, updated_at`
+
+// InitSchema creates the reputation table if it doesn't exist.
+func (m *ReputationModel) InitSchema() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS reputation (
+		origin TEXT PRIMARY KEY,
+		score INTEGER NOT NULL DEFAULT 0,
+		strikes INTEGER NOT NULL DEFAULT 0,
+		last_reason TEXT,
+		blocked BOOLEAN NOT NULL DEFAULT 0,
+		note TEXT,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	_, err := m.DB.Exec(stmt)
+	return err
+}
+
+// Blocked reports whether submissions from an origin are refused outright.
+func (m *ReputationModel) Blocked(origin string) (bool, error) {
+	var blocked bool
+	err := m.DB.QueryRow(`SELECT blocked FROM reputation WHERE origin = ?`, origin).Scan(&blocked)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return blocked, err
+}
+
+// Strike records a failed spam check, blocking the origin once its score falls to
+// BlockThreshold.
+func (m *ReputationModel) Strike(origin, reason string, penalty int) error {
+	stmt := `INSERT INTO reputation (origin, score, strikes, last_reason, blocked, updated_at)
+	VALUES(?1, -?2, 1, ?3, -?2 <= ?4, CURRENT_TIMESTAMP)
+	ON CONFLICT(origin) DO UPDATE SET
+		score = score - ?2,
+		strikes = strikes + 1,
+		last_reason = ?3,
+		blocked = blocked OR score - ?2 <= ?4,
+		updated_at = CURRENT_TIMESTAMP`
+	_, err := m.DB.Exec(stmt, origin, penalty, reason, BlockThreshold)
+	return err
+}
+
+// Adjust moves an origin's score without counting a strike, e.g. when the operator
+// approves (positive delta) or rejects (negative) one of its messages. It also
+// blocks the origin if the score falls to BlockThreshold.
+func (m *ReputationModel) Adjust(origin string, delta int) error {
+	stmt := `INSERT INTO reputation (origin, score, blocked, updated_at)
+	VALUES(?1, ?2, ?2 <= ?3, CURRENT_TIMESTAMP)
+	ON CONFLICT(origin) DO UPDATE SET
+		score = score + ?2,
+		blocked = blocked OR score + ?2 <= ?3,
+		updated_at = CURRENT_TIMESTAMP`
+	_, err := m.DB.Exec(stmt, origin, delta, BlockThreshold)
+	return err
+}
+
+// Block refuses an origin's submissions from now on.
+func (m *ReputationModel) Block(origin, note string) error {
+	stmt := `INSERT INTO reputation (origin, blocked, last_reason, note, updated_at)
+	VALUES(?1, 1, 'blocked by operator', ?2, CURRENT_TIMESTAMP)
+	ON CONFLICT(origin) DO UPDATE SET
+		blocked = 1,
+		last_reason = 'blocked by operator',
+		note = COALESCE(?2, note),
+		updated_at = CURRENT_TIMESTAMP`
+	_, err := m.DB.Exec(stmt, origin, NullString(note))
+	return err
+}
+
+// Unblock lifts a block and clears the origin's record, giving it a fresh start.
+func (m *ReputationModel) Unblock(origin string) error {
+	res, err := m.DB.Exec(`DELETE FROM reputation WHERE origin = ?`, origin)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+// Flagged returns blocked origins and those with a negative score, worst first.
+func (m *ReputationModel) Flagged() ([]*Reputation, error) {
+	stmt := `SELECT ` + reputationColumns + ` FROM reputation
+	WHERE blocked OR score < 0 ORDER BY blocked DESC, score ASC, updated_at DESC`
+
+	rows, err := m.DB.Query(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*Reputation
+
+	for rows.Next() {
+		rep := &Reputation{}
+		err := rows.Scan(&rep.Origin, &rep.Score, &rep.Strikes, &rep.LastReason, &rep.Blocked, &rep.Note, &rep.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rep)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
@@ -1,14 +1,82 @@
 package models
 
 import (
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
 )
 
-// ScraperItem is a placeholder representation of what the scraper might gather.
+// ScraperItem is a single piece of data the scraper gathered from a source.
 type ScraperItem struct {
-	ID    int
-	Title string
-	Value string
+	ID          int
+	Title       string
+	Value       string
+	URL         *string    // Optional, the item's own link (e.g. a feed entry)
+	PublishedAt *time.Time // Optional, when the source says the item was published
+	SourceURL   *string    // Optional, the page the item was read from
+	Excerpt     *string    // Optional, a short plain-text summary for listings
+	ImageURL    *string    // Optional, a picture the source associates with the item
+	FetchedAt   time.Time  // When the item was last seen on its source
+	SourceID    *int       // Source that produced the item; nil for items stored before sources were tracked
+	SourceName  *string    // Filled in from the sources table when read back; nil once the source is deleted
+	Diff        *string    // Set on change records from "monitor" sources: a unified diff of the page text
+
+	PromotedEntryID *int // Set once the item has been turned into an entry
+	Dismissed       bool // Hidden from the scraper view, but kept for deduplication
+}
+
+// Abridged reports whether the excerpt leaves out part of the item's text.
+func (i *ScraperItem) Abridged() bool {
+	return i.Excerpt != nil && *i.Excerpt != strings.TrimSpace(i.Value)
+}
+
+// DiffLine is one line of a change record's diff.
+type DiffLine struct {
+	Op   string // "+" added, "-" removed, " " context, "@" hunk header
+	Text string
+}
+
+// DiffLines splits the item's diff into lines for display.
+func (i *ScraperItem) DiffLines() []DiffLine {
+	if i.Diff == nil {
+		return nil
+	}
+
+	var lines []DiffLine
+	for _, l := range strings.Split(*i.Diff, "\n") {
+		switch {
+		case l == "":
+			continue
+		case strings.HasPrefix(l, "@@"):
+			lines = append(lines, DiffLine{Op: "@", Text: l})
+		default:
+			lines = append(lines, DiffLine{Op: l[:1], Text: l[1:]})
+		}
+	}
+	return lines
+}
+
+// ToEntry drafts an entry of the given type from the item, carrying over its title,
+// link and text.
+func (i *ScraperItem) ToEntry(entryType string) *Entry {
+	return &Entry{
+		Title:   i.Title,
+		Type:    entryType,
+		Content: NullString(strings.TrimSpace(i.Value)),
+		URL:     i.URL,
+	}
+}
+
+// SourceTally counts the visible items one source has produced, for the scraper's
+// source index.
+type SourceTally struct {
+	SourceID   int     // 0 groups items with no recorded source
+	SourceName *string // nil when the source has since been deleted
+	Items      int
+	LatestAt   time.Time // When the most recent item was scraped
 }
 
 // ScraperModel wraps a database connection pool for the scraper specifically.
@@ -26,29 +94,306 @@ func (m *ScraperModel) InitSchema() error {
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
 	`
-	_, err := m.DB.Exec(stmt)
+	if _, err := m.DB.Exec(stmt); err != nil {
+		return err
+	}
+
+	// Columns added for feed sources
+	if err := addColumn(m.DB, "scraped_items", "url", "TEXT"); err != nil {
+		return err
+	}
+	if err := addColumn(m.DB, "scraped_items", "published_at", "DATETIME"); err != nil {
+		return err
+	}
+
+	// Dedup: every item is keyed by a hash of its URL (or content when it has none)
+	if err := addColumn(m.DB, "scraped_items", "hash", "TEXT"); err != nil {
+		return err
+	}
+	if err := addColumn(m.DB, "scraped_items", "updated_at", "DATETIME"); err != nil {
+		return err
+	}
+	// Entries live in the main database, so this is a plain ID rather than a foreign key
+	if err := addColumn(m.DB, "scraped_items", "promoted_entry_id", "INTEGER"); err != nil {
+		return err
+	}
+	// Dismissed items are hidden but kept, so their hash stops the scraper bringing them back
+	if err := addColumn(m.DB, "scraped_items", "dismissed_at", "DATETIME"); err != nil {
+		return err
+	}
+	// Sources live in the same file but deleting one keeps its items, so this isn't enforced either
+	if err := addColumn(m.DB, "scraped_items", "source_id", "INTEGER"); err != nil {
+		return err
+	}
+	// Change records from monitor sources carry their diff
+	if err := addColumn(m.DB, "scraped_items", "diff", "TEXT"); err != nil {
+		return err
+	}
+	// Presentation columns; items from before them have no excerpt or image, and were
+	// last fetched when they were last stored
+	for _, col := range []struct{ name, def string }{
+		{"source_url", "TEXT"},
+		{"excerpt", "TEXT"},
+		{"image_url", "TEXT"},
+		{"fetched_at", "DATETIME"},
+	} {
+		if err := addColumn(m.DB, "scraped_items", col.name, col.def); err != nil {
+			return err
+		}
+	}
+	if _, err := m.DB.Exec(`UPDATE scraped_items SET fetched_at = COALESCE(updated_at, created_at) WHERE fetched_at IS NULL`); err != nil {
+		return err
+	}
+	if err := m.backfillHashes(); err != nil {
+		return err
+	}
+
+	_, err := m.DB.Exec(`
+	CREATE UNIQUE INDEX IF NOT EXISTS scraped_items_hash ON scraped_items(hash);
+	CREATE INDEX IF NOT EXISTS scraped_items_source ON scraped_items(source_id, created_at);
+	`)
+	if err != nil {
+		return err
+	}
+
+	return m.initSearch()
+}
+
+// backfillHashes fills in the hash of items stored before deduplication existed,
+// dropping the later copies of any duplicates so the unique index can be built.
+// Items are visited oldest first, so the copy that stays is always the first stored.
+func (m *ScraperModel) backfillHashes() error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT id, title, value, url, diff FROM scraped_items WHERE hash IS NULL ORDER BY id`)
+	if err != nil {
+		return err
+	}
+
+	type legacyItem struct {
+		id   int
+		hash string
+	}
+	var legacy []legacyItem
+	for rows.Next() {
+		var (
+			item  ScraperItem
+			value sql.NullString
+		)
+		if err := rows.Scan(&item.ID, &item.Title, &value, &item.URL, &item.Diff); err != nil {
+			rows.Close()
+			return err
+		}
+		item.Value = value.String
+		legacy = append(legacy, legacyItem{id: item.ID, hash: itemHash(&item)})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, li := range legacy {
+		// Any row already holding the hash is an earlier copy, or one stored since
+		// deduplication, which the index can't have twice either
+		var exists bool
+		err := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM scraped_items WHERE hash = ?)`, li.hash).Scan(&exists)
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			_, err = tx.Exec(`DELETE FROM scraped_items WHERE id = ?`, li.id)
+		} else {
+			_, err = tx.Exec(`UPDATE scraped_items SET hash = ? WHERE id = ?`, li.hash, li.id)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// itemHash identifies an item across runs: by its link when it has one, otherwise
+// by its title and value. Change records share their page's link, so they are told
+// apart by their diff.
+func itemHash(item *ScraperItem) string {
+	key := "url:" + strings.TrimSpace(StringValue(item.URL))
+	switch {
+	case item.Diff != nil:
+		key = "change:" + StringValue(item.URL) + "\x00" + *item.Diff
+	case item.URL == nil:
+		key = "content:" + item.Title + "\x00" + item.Value
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Insert stores an item, or refreshes the existing copy when one with the same hash
+// is already there. created reports whether a new row was added.
+func (m *ScraperModel) Insert(item *ScraperItem) (id int, created bool, err error) {
+	stmt := `INSERT INTO scraped_items (title, value, url, published_at, source_id, source_url, excerpt, image_url,
+		diff, fetched_at, hash, created_at)
+	VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+	ON CONFLICT(hash) DO UPDATE SET title = excluded.title, value = excluded.value,
+		published_at = COALESCE(excluded.published_at, published_at),
+		source_id = COALESCE(source_id, excluded.source_id), source_url = excluded.source_url,
+		excerpt = excluded.excerpt, image_url = COALESCE(excluded.image_url, image_url),
+		fetched_at = excluded.fetched_at, updated_at = CURRENT_TIMESTAMP
+	RETURNING id, updated_at IS NULL`
+
+	var publishedAt *string
+	if item.PublishedAt != nil {
+		s := sqliteTime(*item.PublishedAt)
+		publishedAt = &s
+	}
+
+	fetchedAt := item.FetchedAt
+	if fetchedAt.IsZero() {
+		fetchedAt = time.Now()
+	}
+
+	err = m.DB.QueryRow(stmt, item.Title, item.Value, item.URL, publishedAt, item.SourceID, item.SourceURL,
+		item.Excerpt, item.ImageURL, item.Diff, sqliteTime(fetchedAt), itemHash(item)).Scan(&id, &created)
+	if err != nil {
+		return 0, false, err
+	}
+	return id, created, nil
+}
+
+// Get returns a single scraped item by ID.
+func (m *ScraperModel) Get(id int) (*ScraperItem, error) {
+	stmt := `SELECT ` + scraperItemColumns + ` FROM scraped_items i LEFT JOIN sources s ON s.id = i.source_id
+	WHERE i.id = ?`
+
+	e, err := scanScraperItem(m.DB.QueryRow(stmt, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoRecord
+	}
+	return e, err
+}
+
+// MarkPromoted records the entry an item was turned into.
+func (m *ScraperModel) MarkPromoted(id, entryID int) error {
+	_, err := m.DB.Exec(`UPDATE scraped_items SET promoted_entry_id = ? WHERE id = ?`, entryID, id)
 	return err
 }
 
-// Insert adds a new item to the scraper DB.
-func (m *ScraperModel) Insert(title, value string) (int, error) {
-	stmt := `INSERT INTO scraped_items (title, value, created_at)
-	VALUES(?, ?, CURRENT_TIMESTAMP) RETURNING id`
+// UnlinkEntries forgets the entries items were promoted to once those entries are
+// deleted, so the items can be promoted again.
+func (m *ScraperModel) UnlinkEntries(entryIDs []int) error {
+	if len(entryIDs) == 0 {
+		return nil
+	}
+	in, args := intPlaceholders(entryIDs)
+	_, err := m.DB.Exec(`UPDATE scraped_items SET promoted_entry_id = NULL WHERE promoted_entry_id IN `+in, args...)
+	return err
+}
+
+// Dismiss hides an item from the scraper view. It stays in the table, so later runs
+// recognise it and don't store it again.
+func (m *ScraperModel) Dismiss(id int) error {
+	_, err := m.DB.Exec(`UPDATE scraped_items SET dismissed_at = CURRENT_TIMESTAMP WHERE id = ? AND dismissed_at IS NULL`, id)
+	return err
+}
+
+// Delete removes an item outright. If a source still lists it, the next run stores it again.
+func (m *ScraperModel) Delete(id int) error {
+	_, err := m.DB.Exec(`DELETE FROM scraped_items WHERE id = ?`, id)
+	return err
+}
 
-	var id int
-	err := m.DB.QueryRow(stmt, title, value).Scan(&id)
+// DismissBefore hides every item scraped before t, returning how many were hidden.
+func (m *ScraperModel) DismissBefore(t time.Time) (int64, error) {
+	res, err := m.DB.Exec(`UPDATE scraped_items SET dismissed_at = CURRENT_TIMESTAMP
+	WHERE created_at < ? AND dismissed_at IS NULL`, sqliteTime(t))
 	if err != nil {
 		return 0, err
 	}
-	return id, nil
+	return res.RowsAffected()
 }
 
-// Latest returns the most recent scraped items.
+// DeleteBefore removes every item scraped before t, returning how many were removed.
+func (m *ScraperModel) DeleteBefore(t time.Time) (int64, error) {
+	res, err := m.DB.Exec(`DELETE FROM scraped_items WHERE created_at < ?`, sqliteTime(t))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Latest returns the most recent scraped items that haven't been dismissed.
 func (m *ScraperModel) Latest(limit int) ([]*ScraperItem, error) {
-	stmt := `SELECT id, title, value FROM scraped_items
-	ORDER BY created_at DESC LIMIT ?`
+	stmt := `SELECT ` + scraperItemColumns + ` FROM scraped_items i LEFT JOIN sources s ON s.id = i.source_id
+	WHERE i.dismissed_at IS NULL ORDER BY i.created_at DESC LIMIT ?`
+	return m.query(stmt, limit)
+}
 
-	rows, err := m.DB.Query(stmt, limit)
+// LatestFromSource is Latest restricted to the items one source produced.
+func (m *ScraperModel) LatestFromSource(sourceID, limit int) ([]*ScraperItem, error) {
+	stmt := `SELECT ` + scraperItemColumns + ` FROM scraped_items i LEFT JOIN sources s ON s.id = i.source_id
+	WHERE i.source_id = ? AND i.dismissed_at IS NULL ORDER BY i.created_at DESC LIMIT ?`
+	return m.query(stmt, sourceID, limit)
+}
+
+// Find returns items whose title, text or link contains every term, newest first.
+// Unlike the scraper view it includes dismissed items.
+func (m *ScraperModel) Find(terms []string, limit int) ([]*ScraperItem, error) {
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	cond, args := likeAll([]string{"i.title", "i.value", "i.url"}, terms)
+	stmt := `SELECT ` + scraperItemColumns + ` FROM scraped_items i LEFT JOIN sources s ON s.id = i.source_id
+	WHERE ` + cond + ` ORDER BY i.created_at DESC LIMIT ?`
+	return m.query(stmt, append(args, limit)...)
+}
+
+// SourceTallies counts the visible items per source, busiest first.
+func (m *ScraperModel) SourceTallies() ([]*SourceTally, error) {
+	stmt := `SELECT COALESCE(i.source_id, 0), s.name, COUNT(*), MAX(i.created_at)
+	FROM scraped_items i LEFT JOIN sources s ON s.id = i.source_id
+	WHERE i.dismissed_at IS NULL
+	GROUP BY i.source_id ORDER BY COUNT(*) DESC, s.name`
+
+	rows, err := m.DB.Query(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tallies []*SourceTally
+
+	for rows.Next() {
+		t := &SourceTally{}
+		var latest string
+		if err := rows.Scan(&t.SourceID, &t.SourceName, &t.Items, &latest); err != nil {
+			return nil, err
+		}
+		// MAX() loses the column type, so the timestamp comes back as text
+		if t.LatestAt, err = time.Parse("2006-01-02 15:04:05", latest); err != nil {
+			return nil, err
+		}
+		tallies = append(tallies, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tallies, nil
+}
+
+const scraperItemColumns = `i.id, i.title, i.value, i.url, i.published_at, i.source_id, s.name, i.source_url,
+	i.excerpt, i.image_url, i.diff, i.fetched_at, i.promoted_entry_id,
+	i.dismissed_at IS NOT NULL`
+
+func (m *ScraperModel) query(stmt string, args ...any) ([]*ScraperItem, error) {
+	rows, err := m.DB.Query(stmt, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -57,8 +402,7 @@ func (m *ScraperModel) Latest(limit int) ([]*ScraperItem, error) {
 	var items []*ScraperItem
 
 	for rows.Next() {
-		e := &ScraperItem{}
-		err = rows.Scan(&e.ID, &e.Title, &e.Value)
+		e, err := scanScraperItem(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -71,3 +415,14 @@ func (m *ScraperModel) Latest(limit int) ([]*ScraperItem, error) {
 
 	return items, nil
 }
+
+func scanScraperItem(row rowScanner) (*ScraperItem, error) {
+	e := &ScraperItem{}
+	err := row.Scan(&e.ID, &e.Title, &e.Value, &e.URL, &e.PublishedAt, &e.SourceID, &e.SourceName, &e.SourceURL,
+		&e.Excerpt, &e.ImageURL, &e.Diff, &e.FetchedAt, &e.PromotedEntryID,
+		&e.Dismissed)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+// A scraper.db from before deduplication can hold the same item many times over;
+// migrating it must keep the first copy of each and still build the unique index.
+func TestInitSchemaDropsLegacyDuplicates(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "scraper.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// The table as it was first created, before its url and hash columns
+	_, err = db.Exec(`CREATE TABLE scraped_items (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT NOT NULL,
+		value TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Enough interleaved copies that any order but oldest first trips the index
+	titles := []string{"alpha", "beta", "alpha", "gamma", "beta", "alpha", "gamma", "beta", "delta", "alpha"}
+	for _, title := range titles {
+		if _, err := db.Exec(`INSERT INTO scraped_items (title, value) VALUES (?, 'same')`, title); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := &ScraperModel{DB: db}
+	if err := m.InitSchema(); err != nil {
+		t.Fatalf("InitSchema: %v", err)
+	}
+
+	rows, err := db.Query(`SELECT id, title FROM scraped_items ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	kept := map[string]int{}
+	for rows.Next() {
+		var (
+			id    int
+			title string
+		)
+		if err := rows.Scan(&id, &title); err != nil {
+			t.Fatal(err)
+		}
+		if _, dup := kept[title]; dup {
+			t.Errorf("%q kept more than once", title)
+		}
+		kept[title] = id
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]int{"alpha": 1, "beta": 2, "gamma": 4, "delta": 9}
+	for title, id := range want {
+		if kept[title] != id {
+			t.Errorf("%q: kept id %d, want the first copy, id %d", title, kept[title], id)
+		}
+	}
+	if len(kept) != len(want) {
+		t.Errorf("kept %v, want %v", kept, want)
+	}
+
+	// Migrating again finds nothing left to do
+	if err := m.InitSchema(); err != nil {
+		t.Fatalf("second InitSchema: %v", err)
+	}
+}
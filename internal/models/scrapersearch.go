@@ -0,0 +1,98 @@
+package models
+
+import "strings"
+
+// ScraperSearchResult is one scraped item matching a search, with the matched terms
+// marked like SearchResult's.
+type ScraperSearchResult struct {
+	Item    *ScraperItem
+	Title   string // The item's title with MatchStart/MatchEnd around matched terms
+	Snippet string // A marked excerpt of the item's text around the best match; empty if only the title matched
+}
+
+// initSearch creates the scraped_items_fts index if it doesn't exist, with triggers
+// that keep it in step with scraped_items, and fills it from the stored items the
+// first time.
+func (m *ScraperModel) initSearch() error {
+	var exists bool
+	err := m.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE name = 'scraped_items_fts')`).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	stmt := `
+	CREATE VIRTUAL TABLE IF NOT EXISTS scraped_items_fts USING fts5(
+		title, value,
+		content = 'scraped_items', content_rowid = 'id',
+		tokenize = 'porter unicode61 remove_diacritics 2'
+	);
+	CREATE TRIGGER IF NOT EXISTS scraped_items_fts_insert AFTER INSERT ON scraped_items BEGIN
+		INSERT INTO scraped_items_fts (rowid, title, value) VALUES (new.id, new.title, new.value);
+	END;
+	CREATE TRIGGER IF NOT EXISTS scraped_items_fts_delete AFTER DELETE ON scraped_items BEGIN
+		INSERT INTO scraped_items_fts (scraped_items_fts, rowid, title, value) VALUES ('delete', old.id, old.title, old.value);
+	END;
+	CREATE TRIGGER IF NOT EXISTS scraped_items_fts_update AFTER UPDATE OF title, value ON scraped_items BEGIN
+		INSERT INTO scraped_items_fts (scraped_items_fts, rowid, title, value) VALUES ('delete', old.id, old.title, old.value);
+		INSERT INTO scraped_items_fts (rowid, title, value) VALUES (new.id, new.title, new.value);
+	END;
+	`
+	if _, err := m.DB.Exec(stmt); err != nil {
+		return err
+	}
+
+	if !exists {
+		_, err = m.DB.Exec(`INSERT INTO scraped_items_fts (scraped_items_fts) VALUES ('rebuild')`)
+	}
+	return err
+}
+
+// Search returns the scraped items matching q, best first, optionally only those
+// from one source (sourceID 0 searches them all). Dismissed items are included, so
+// the whole archive can be searched rather than just what the scraper view shows.
+func (m *ScraperModel) Search(q string, sourceID, limit int) ([]*ScraperSearchResult, error) {
+	match := MatchQuery(q)
+	if match == "" {
+		return nil, nil
+	}
+
+	stmt := `SELECT ` + scraperItemColumns + `, marked_title, snippet
+	FROM scraped_items i LEFT JOIN sources s ON s.id = i.source_id JOIN (
+		SELECT rowid,
+			highlight(scraped_items_fts, 0, char(2), char(3)) AS marked_title,
+			COALESCE(snippet(scraped_items_fts, 1, char(2), char(3), '…', 24), '') AS snippet,
+			bm25(scraped_items_fts, 5.0, 1.0) AS rank
+		FROM scraped_items_fts WHERE scraped_items_fts MATCH ?
+	) hits ON hits.rowid = i.id
+	WHERE ? = 0 OR i.source_id = ?
+	ORDER BY hits.rank LIMIT ?`
+
+	rows, err := m.DB.Query(stmt, match, sourceID, sourceID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*ScraperSearchResult
+
+	for rows.Next() {
+		e := &ScraperItem{}
+		r := &ScraperSearchResult{Item: e}
+		err := rows.Scan(&e.ID, &e.Title, &e.Value, &e.URL, &e.PublishedAt, &e.SourceID, &e.SourceName, &e.SourceURL,
+			&e.Excerpt, &e.ImageURL, &e.Diff, &e.FetchedAt, &e.PromotedEntryID, &e.Dismissed, &r.Title, &r.Snippet)
+		if err != nil {
+			return nil, err
+		}
+		// A snippet without a marked term is just the start of the text
+		if !strings.Contains(r.Snippet, MatchStart) {
+			r.Snippet = ""
+		}
+		results = append(results, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
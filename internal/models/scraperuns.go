@@ -0,0 +1,252 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ScrapeRun records one attempt at scraping a source, successful or not.
+type ScrapeRun struct {
+	ID         int
+	SourceID   int
+	SourceName string // From the sources table, empty once the source is deleted
+	StartedAt  time.Time
+	Duration   time.Duration
+	ItemsFound int                // Items the extractor produced
+	ItemsNew   int                // Of those, how many weren't stored already
+	Dropped    int                // Of those, how many the source's filters threw away
+	Quality    *ExtractionQuality // nil for runs that don't extract items (monitors, price watches) and old runs
+	Attempts   int                // Fetches it took, more than 1 when transient failures were retried
+	Error      *string            // Nil for a successful run, otherwise the final attempt's error
+}
+
+// ExtractionQuality counts the signs of a decaying extractor among a run's items.
+// Selectors that stop matching tend to produce blank or mangled rows long before
+// they produce errors.
+type ExtractionQuality struct {
+	EmptyTitles int // Title is blank
+	Truncated   int // Value is blank or ends in an ellipsis
+	Garbled     int // Text has invalid UTF-8, replacement characters, mojibake or undecoded entities
+	Flagged     int // Items with at least one of the above
+}
+
+// ScrapeRunModel wraps a database connection pool for scraper run history.
+type ScrapeRunModel struct {
+	DB *sql.DB
+}
+
+// InitSchema creates the scrape_runs table if it doesn't exist.
+func (m *ScrapeRunModel) InitSchema() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS scrape_runs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		source_id INTEGER NOT NULL,
+		started_at DATETIME NOT NULL,
+		duration_ms INTEGER NOT NULL,
+		items_found INTEGER NOT NULL DEFAULT 0,
+		items_new INTEGER NOT NULL DEFAULT 0,
+		error TEXT
+	);
+	CREATE INDEX IF NOT EXISTS scrape_runs_source ON scrape_runs(source_id, started_at);
+	`
+	if _, err := m.DB.Exec(stmt); err != nil {
+		return err
+	}
+
+	if err := addColumn(m.DB, "scrape_runs", "attempts", "INTEGER NOT NULL DEFAULT 1"); err != nil {
+		return err
+	}
+	if err := addColumn(m.DB, "scrape_runs", "items_dropped", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	// Quality counts stay NULL for runs that weren't measured, so they can't pass for clean ones
+	for _, col := range []string{"empty_titles", "truncated_values", "garbled_values", "items_flagged"} {
+		if err := addColumn(m.DB, "scrape_runs", col, "INTEGER"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Insert records a finished run.
+func (m *ScrapeRunModel) Insert(run *ScrapeRun) error {
+	stmt := `INSERT INTO scrape_runs (source_id, started_at, duration_ms, items_found, items_new, items_dropped,
+		empty_titles, truncated_values, garbled_values, items_flagged, attempts, error)
+	VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	var quality [4]*int
+	if q := run.Quality; q != nil {
+		quality = [4]*int{&q.EmptyTitles, &q.Truncated, &q.Garbled, &q.Flagged}
+	}
+	_, err := m.DB.Exec(stmt, run.SourceID, sqliteTime(run.StartedAt), run.Duration.Milliseconds(),
+		run.ItemsFound, run.ItemsNew, run.Dropped,
+		quality[0], quality[1], quality[2], quality[3], max(run.Attempts, 1), run.Error)
+	return err
+}
+
+// Latest returns the most recent runs across every source, newest first.
+func (m *ScrapeRunModel) Latest(limit int) ([]*ScrapeRun, error) {
+	stmt := `SELECT r.id, r.source_id, COALESCE(s.name, ''), r.started_at, r.duration_ms,
+		r.items_found, r.items_new, r.items_dropped,
+		r.empty_titles, r.truncated_values, r.garbled_values, r.items_flagged, r.attempts, r.error
+	FROM scrape_runs r LEFT JOIN sources s ON s.id = r.source_id
+	ORDER BY r.started_at DESC, r.id DESC LIMIT ?`
+
+	rows, err := m.DB.Query(stmt, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []*ScrapeRun
+
+	for rows.Next() {
+		run := &ScrapeRun{}
+		var ms int64
+		var emptyTitles, truncated, garbled, flagged *int
+		err = rows.Scan(&run.ID, &run.SourceID, &run.SourceName, &run.StartedAt, &ms,
+			&run.ItemsFound, &run.ItemsNew, &run.Dropped,
+			&emptyTitles, &truncated, &garbled, &flagged, &run.Attempts, &run.Error)
+		if err != nil {
+			return nil, err
+		}
+		if flagged != nil {
+			run.Quality = &ExtractionQuality{Flagged: *flagged}
+			if emptyTitles != nil && truncated != nil && garbled != nil {
+				run.Quality.EmptyTitles, run.Quality.Truncated, run.Quality.Garbled = *emptyTitles, *truncated, *garbled
+			}
+		}
+		run.Duration = time.Duration(ms) * time.Millisecond
+		runs = append(runs, run)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return runs, nil
+}
+
+// RunOutcome totals one source's runs that succeeded, or that failed.
+type RunOutcome struct {
+	SourceID   int
+	SourceName string // Empty once the source is deleted
+	Failed     bool
+	Runs       int
+	ItemsNew   int
+}
+
+// Outcomes totals every recorded run by source and by whether it failed.
+func (m *ScrapeRunModel) Outcomes() ([]*RunOutcome, error) {
+	stmt := `SELECT r.source_id, COALESCE(s.name, ''), r.error IS NOT NULL, COUNT(*), SUM(r.items_new)
+	FROM scrape_runs r LEFT JOIN sources s ON s.id = r.source_id
+	GROUP BY r.source_id, r.error IS NOT NULL
+	ORDER BY r.source_id`
+
+	rows, err := m.DB.Query(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var outcomes []*RunOutcome
+
+	for rows.Next() {
+		o := &RunOutcome{}
+		if err := rows.Scan(&o.SourceID, &o.SourceName, &o.Failed, &o.Runs, &o.ItemsNew); err != nil {
+			return nil, err
+		}
+		outcomes = append(outcomes, o)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return outcomes, nil
+}
+
+// QualityTrend compares the share of flagged items in a source's latest runs with
+// the runs before them.
+type QualityTrend struct {
+	SourceID     int
+	RecentRuns   int
+	RecentRate   float64 // Flagged items per item found, 0-1
+	BaselineRuns int
+	BaselineRate float64
+}
+
+// Thresholds for Degraded: a source is flagged once at least this share of its
+// recent items look broken and that is this much worse than it used to be.
+const (
+	degradedRate = 0.25
+	degradedRise = 0.15
+)
+
+// Degraded reports whether the source's extraction has got noticeably worse. A
+// source that has always produced, say, clipped summaries isn't flagged for it;
+// one with no history yet is flagged only when most of what it finds looks broken.
+func (t *QualityTrend) Degraded() bool {
+	if t.RecentRate < degradedRate {
+		return false
+	}
+	if t.BaselineRuns == 0 {
+		return t.RecentRate >= 0.5
+	}
+	return t.RecentRate-t.BaselineRate >= degradedRise
+}
+
+// Summary describes the trend for a tooltip.
+func (t *QualityTrend) Summary() string {
+	s := fmt.Sprintf("%.0f%% of items from the last %d run(s) look broken", t.RecentRate*100, t.RecentRuns)
+	if t.BaselineRuns > 0 {
+		s += fmt.Sprintf(", against %.0f%% in the %d before", t.BaselineRate*100, t.BaselineRuns)
+	}
+	return s
+}
+
+// QualityTrends returns, per source, extraction quality over its last recent
+// successful runs that found anything against the baseline runs before those.
+func (m *ScrapeRunModel) QualityTrends(recent, baseline int) (map[int]*QualityTrend, error) {
+	stmt := `
+	WITH ranked AS (
+		SELECT source_id, items_found, items_flagged,
+			ROW_NUMBER() OVER (PARTITION BY source_id ORDER BY started_at DESC, id DESC) AS n
+		FROM scrape_runs WHERE error IS NULL AND items_found > 0 AND items_flagged IS NOT NULL
+	)
+	SELECT source_id, n <= ? AS recent, COUNT(*), SUM(items_flagged), SUM(items_found)
+	FROM ranked WHERE n <= ? GROUP BY source_id, recent`
+
+	rows, err := m.DB.Query(stmt, recent, recent+baseline)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trends := make(map[int]*QualityTrend)
+
+	for rows.Next() {
+		var sourceID, runs, flagged, found int
+		var isRecent bool
+		if err := rows.Scan(&sourceID, &isRecent, &runs, &flagged, &found); err != nil {
+			return nil, err
+		}
+		t := trends[sourceID]
+		if t == nil {
+			t = &QualityTrend{SourceID: sourceID}
+			trends[sourceID] = t
+		}
+		rate := float64(flagged) / float64(found)
+		if isRecent {
+			t.RecentRuns, t.RecentRate = runs, rate
+		} else {
+			t.BaselineRuns, t.BaselineRate = runs, rate
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return trends, nil
+}
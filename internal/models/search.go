@@ -0,0 +1,242 @@
+package models
+
+import (
+	"database/sql"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Markers around matched terms in search titles and snippets. They can't occur in
+// entry text, so the text can be HTML-escaped first and the markers swapped for
+// <mark> tags afterwards.
+const (
+	MatchStart = "\x02"
+	MatchEnd   = "\x03"
+)
+
+// SearchResult is one entry matching a search, with the matched terms marked.
+type SearchResult struct {
+	Entry   *Entry
+	Title   string // The entry's title with MatchStart/MatchEnd around matched terms
+	Snippet string // A marked excerpt of the content around the best match; empty if only the title matched
+}
+
+// Anchor returns a link to the entry that scrolls to and highlights the first
+// matched term in its content, using a text fragment (#:~:text=). Browsers without
+// text fragment support just open the entry.
+func (r *SearchResult) Anchor() string {
+	link := r.Entry.Permalink()
+
+	_, rest, found := strings.Cut(r.Snippet, MatchStart)
+	if !found {
+		return link
+	}
+	term, _, _ := strings.Cut(rest, MatchEnd)
+	if term == "" {
+		return link
+	}
+	return link + "#:~:text=" + textFragmentEscaper.Replace(url.PathEscape(term))
+}
+
+// textFragmentEscaper encodes what PathEscape leaves alone but text fragments treat
+// as syntax.
+var textFragmentEscaper = strings.NewReplacer("-", "%2D", ",", "%2C", "&", "%26")
+
+// SearchModel wraps a database connection pool for full-text search of entries.
+type SearchModel struct {
+	DB *sql.DB
+}
+
+// InitSchema creates the entries_fts index if it doesn't exist, with triggers that
+// keep it in step with the entries table, and fills it from the existing entries the
+// first time. Run it after EntryModel.InitSchema.
+func (m *SearchModel) InitSchema() error {
+	var exists bool
+	err := m.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM sqlite_master WHERE name = 'entries_fts')`).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	stmt := `
+	CREATE VIRTUAL TABLE IF NOT EXISTS entries_fts USING fts5(
+		title, content,
+		content = 'entries', content_rowid = 'id',
+		tokenize = 'porter unicode61 remove_diacritics 2'
+	);
+	CREATE TRIGGER IF NOT EXISTS entries_fts_insert AFTER INSERT ON entries BEGIN
+		INSERT INTO entries_fts (rowid, title, content) VALUES (new.id, new.title, new.content);
+	END;
+	CREATE TRIGGER IF NOT EXISTS entries_fts_delete AFTER DELETE ON entries BEGIN
+		INSERT INTO entries_fts (entries_fts, rowid, title, content) VALUES ('delete', old.id, old.title, old.content);
+	END;
+	CREATE TRIGGER IF NOT EXISTS entries_fts_update AFTER UPDATE OF title, content ON entries BEGIN
+		INSERT INTO entries_fts (entries_fts, rowid, title, content) VALUES ('delete', old.id, old.title, old.content);
+		INSERT INTO entries_fts (rowid, title, content) VALUES (new.id, new.title, new.content);
+	END;
+	`
+	if _, err := m.DB.Exec(stmt); err != nil {
+		return err
+	}
+
+	if !exists {
+		_, err = m.DB.Exec(`INSERT INTO entries_fts (entries_fts) VALUES ('rebuild')`)
+	}
+	return err
+}
+
+// MatchQuery turns what a visitor typed into an FTS5 query: every word has to
+// appear, as a prefix, and operators or quotes in the input are taken literally.
+// It returns "" when there is nothing to search for.
+func MatchQuery(q string) string {
+	var terms []string
+	for _, word := range strings.Fields(q) {
+		word = strings.ReplaceAll(word, `"`, "")
+		if word != "" {
+			terms = append(terms, `"`+word+`"*`)
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+// Search returns the entries matching q, best first. Title matches count for more
+// than content matches.
+func (m *SearchModel) Search(q string, limit int) ([]*SearchResult, error) {
+	match := MatchQuery(q)
+	if match == "" {
+		return nil, nil
+	}
+
+	stmt := `SELECT ` + entryColumns + `, marked_title, snippet FROM entries JOIN (
+		SELECT rowid,
+			highlight(entries_fts, 0, char(2), char(3)) AS marked_title,
+			COALESCE(snippet(entries_fts, 1, char(2), char(3), '…', 24), '') AS snippet,
+			bm25(entries_fts, 5.0, 1.0) AS rank
+		FROM entries_fts WHERE entries_fts MATCH ?
+	) hits ON hits.rowid = entries.id
+	ORDER BY hits.rank LIMIT ?`
+
+	rows, err := m.DB.Query(stmt, match, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*SearchResult
+
+	for rows.Next() {
+		e := &Entry{}
+		r := &SearchResult{Entry: e}
+		err := rows.Scan(&e.ID, &e.Slug, &e.Title, &e.Type, &e.Content, &e.URL, &e.Mood, &e.ParentID, &e.CreatedAt,
+			&e.FeedSummary, &e.ExcludeFromFeed, &e.CanonicalURL, &e.UpdatedAt, &e.Fields, &r.Title, &r.Snippet)
+		if err != nil {
+			return nil, err
+		}
+		// A snippet without a marked term is just the start of the content
+		if !strings.Contains(r.Snippet, MatchStart) {
+			r.Snippet = ""
+		}
+		results = append(results, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// SearchTerms splits a query into lower-cased words, each listed once.
+func SearchTerms(q string) []string {
+	var terms []string
+	for _, word := range strings.Fields(strings.ToLower(q)) {
+		if !slices.Contains(terms, word) {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// likeAll builds a WHERE condition requiring every term to appear, case-insensitively,
+// in at least one of the columns, for stores without a full-text index.
+func likeAll(columns []string, terms []string) (string, []any) {
+	var (
+		conds []string
+		args  []any
+	)
+	for _, t := range terms {
+		pattern := "%" + likeEscaper.Replace(t) + "%"
+
+		var either []string
+		for _, c := range columns {
+			either = append(either, c+` LIKE ? ESCAPE '\'`)
+			args = append(args, pattern)
+		}
+		conds = append(conds, "("+strings.Join(either, " OR ")+")")
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// MarkTerms wraps every case-insensitive occurrence of the terms in text with
+// MatchStart/MatchEnd, returning the marked text and how many occurrences it found.
+// When width is positive the result is cut down to about that many characters
+// around the first occurrence, with ellipses where text was left out.
+func MarkTerms(text string, terms []string, width int) (string, int) {
+	lower := []rune(strings.ToLower(text))
+	runes := []rune(text)
+	if len(lower) != len(runes) {
+		// Lower-casing changed the length (rare scripts); match on the original instead
+		lower = runes
+	}
+
+	// Find where each occurrence starts and ends, longest term first at any position
+	type span struct{ start, end int }
+	var spans []span
+	for i := 0; i < len(lower); {
+		matched := 0
+		for _, t := range terms {
+			tr := []rune(t)
+			if len(tr) > matched && i+len(tr) <= len(lower) && string(lower[i:i+len(tr)]) == t {
+				matched = len(tr)
+			}
+		}
+		if matched == 0 {
+			i++
+			continue
+		}
+		spans = append(spans, span{i, i + matched})
+		i += matched
+	}
+
+	from, to := 0, len(runes)
+	if width > 0 && len(runes) > width {
+		if len(spans) > 0 {
+			from = max(spans[0].start-width/4, 0)
+		}
+		to = min(from+width, len(runes))
+		from = max(to-width, 0)
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, s := range spans {
+		if s.end <= from || s.start >= to {
+			continue
+		}
+		start, end := max(s.start, from), min(s.end, to)
+		b.WriteString(string(runes[pos:start]))
+		b.WriteString(MatchStart + string(runes[start:end]) + MatchEnd)
+		pos = end
+	}
+	b.WriteString(string(runes[pos:to]))
+	if to < len(runes) {
+		b.WriteString("…")
+	}
+
+	return strings.Join(strings.Fields(b.String()), " "), len(spans)
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"database/sql"
+)
+
+// Site is the station's identity: what it calls itself in the header, page titles,
+// feeds and the footer.
+type Site struct {
+	Title   string
+	Tagline string // Shown under the title; empty shows none
+	Footer  string
+}
+
+// DefaultSite is the identity a station has until its operator changes it.
+func DefaultSite() *Site {
+	return &Site{
+		Title:   "Sacrif Station",
+		Tagline: "An organic compendium of media, thoughts and intercepted signals.",
+		Footer:  "Connection Established. Operator: Leo/Sacrif. Powered by Go + HTMX.",
+	}
+}
+
+// SettingsModel wraps a database connection pool for the station's settings, kept as
+// key/value pairs so a new setting needs no migration.
+type SettingsModel struct {
+	DB *sql.DB
+}
+
+// InitSchema creates the settings table if it doesn't exist.
+func (m *SettingsModel) InitSchema() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS site_settings (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);
+	`
+	_, err := m.DB.Exec(stmt)
+	return err
+}
+
+// Site returns the stored identity, with the defaults for anything never saved.
+func (m *SettingsModel) Site() (*Site, error) {
+	site := DefaultSite()
+	fields := site.fields()
+
+	rows, err := m.DB.Query(`SELECT key, value FROM site_settings`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		if f, ok := fields[key]; ok {
+			*f = value
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return site, nil
+}
+
+// SetSite saves every part of the identity at once.
+func (m *SettingsModel) SetSite(site *Site) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt := `INSERT INTO site_settings (key, value) VALUES(?, ?)
+	ON CONFLICT(key) DO UPDATE SET value = excluded.value`
+	for key, f := range site.fields() {
+		if _, err := tx.Exec(stmt, key, *f); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// fields maps each setting's key to the field it fills.
+func (s *Site) fields() map[string]*string {
+	return map[string]*string{
+		"site_title":   &s.Title,
+		"site_tagline": &s.Tagline,
+		"site_footer":  &s.Footer,
+	}
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxSlugLength keeps permalinks readable; longer titles are cut at a word boundary.
+const maxSlugLength = 60
+
+// maxSlugAttempts bounds how often an insert starts over after losing its slug
+// to a concurrent one.
+const maxSlugAttempts = 5
+
+// Slugify turns a title into a URL path segment, e.g. "The Expanse (S1)" -> "the-expanse-s1".
+func Slugify(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+
+	slug := b.String()
+	if len(slug) > maxSlugLength {
+		slug = slug[:maxSlugLength]
+		if i := strings.LastIndexByte(slug, '-'); i > 0 {
+			slug = slug[:i]
+		}
+		slug = strings.ToValidUTF8(slug, "")
+	}
+	return slug
+}
+
+// uniqueSlug picks a free slug for a new entry. Purely numeric slugs are prefixed
+// with the type so they can never be mistaken for an entry ID in /entry/{slug}.
+func uniqueSlug(q interface {
+	QueryRow(query string, args ...any) *sql.Row
+}, title, entryType string) (string, error) {
+	base := Slugify(title)
+	if _, err := strconv.Atoi(base); err == nil || base == "" {
+		base = strings.Trim(Slugify(entryType)+"-"+base, "-")
+	}
+
+	slug := base
+	for n := 2; ; n++ {
+		var taken bool
+		err := q.QueryRow(`SELECT EXISTS(SELECT 1 FROM entries WHERE slug = ?)`, slug).Scan(&taken)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return slug, nil
+		}
+		slug = base + "-" + strconv.Itoa(n)
+	}
+}
+
+// slugTaken reports whether err is the unique index on entries.slug refusing a
+// slug that was free when uniqueSlug checked it.
+func slugTaken(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed: entries.slug")
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+// Insert relies on recognising the slug index's error to start over, so a slug
+// claimed behind uniqueSlug's back has to read as taken and nothing else may.
+func TestSlugTaken(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "sacrif.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	m := &EntryModel{DB: db}
+	if err := m.InitSchema(); err != nil {
+		t.Fatalf("InitSchema: %v", err)
+	}
+	if _, err := m.Insert(&Entry{Title: "Same Title", Type: "thought"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// What a concurrent insert ends up doing after both checked the slug was free
+	_, err = db.Exec(`INSERT INTO entries (slug, title, type) VALUES ('same-title', 'Same Title', 'thought')`)
+	if !slugTaken(err) {
+		t.Errorf("duplicate slug: slugTaken(%v) = false", err)
+	}
+
+	_, err = db.Exec(`INSERT INTO entries (slug, type) VALUES ('other', 'thought')`)
+	if err == nil || slugTaken(err) {
+		t.Errorf("missing title: slugTaken(%v) = true", err)
+	}
+	if slugTaken(nil) || slugTaken(errors.New("database is locked")) {
+		t.Error("slugTaken matched an unrelated error")
+	}
+
+	e := &Entry{Title: "Same Title", Type: "thought"}
+	if _, err := m.Insert(e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Slug != "same-title-2" {
+		t.Errorf("second insert got slug %q, want same-title-2", e.Slug)
+	}
+}
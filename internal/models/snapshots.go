@@ -0,0 +1,72 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Snapshot is the last text a "monitor" source's page normalized to, kept so the
+// next run can tell whether it changed and by how much.
+type Snapshot struct {
+	SourceID  int
+	Hash      string // sha256 of Content
+	Content   string
+	ChangedAt time.Time // When Content was last different from the time before
+	CheckedAt time.Time // When the page was last fetched
+}
+
+// SnapshotModel wraps a database connection pool for page snapshots.
+type SnapshotModel struct {
+	DB *sql.DB
+}
+
+// InitSchema creates the page_snapshots table if it doesn't exist. It sits beside
+// the sources in scraper.db; snapshots of deleted sources are removed with them.
+func (m *SnapshotModel) InitSchema() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS page_snapshots (
+		source_id INTEGER PRIMARY KEY,
+		hash TEXT NOT NULL,
+		content TEXT NOT NULL,
+		changed_at DATETIME NOT NULL,
+		checked_at DATETIME NOT NULL
+	);
+	`
+	_, err := m.DB.Exec(stmt)
+	return err
+}
+
+// Get returns a source's snapshot, or ErrNoRecord before its first run.
+func (m *SnapshotModel) Get(sourceID int) (*Snapshot, error) {
+	stmt := `SELECT source_id, hash, content, changed_at, checked_at FROM page_snapshots WHERE source_id = ?`
+
+	s := &Snapshot{}
+	err := m.DB.QueryRow(stmt, sourceID).Scan(&s.SourceID, &s.Hash, &s.Content, &s.ChangedAt, &s.CheckedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoRecord
+	}
+	return s, err
+}
+
+// Save replaces a source's snapshot.
+func (m *SnapshotModel) Save(s *Snapshot) error {
+	stmt := `INSERT INTO page_snapshots (source_id, hash, content, changed_at, checked_at)
+	VALUES(?, ?, ?, ?, ?)
+	ON CONFLICT(source_id) DO UPDATE SET hash = excluded.hash, content = excluded.content,
+		changed_at = excluded.changed_at, checked_at = excluded.checked_at`
+	_, err := m.DB.Exec(stmt, s.SourceID, s.Hash, s.Content, sqliteTime(s.ChangedAt), sqliteTime(s.CheckedAt))
+	return err
+}
+
+// Checked records that a source's page was fetched and found unchanged.
+func (m *SnapshotModel) Checked(sourceID int, at time.Time) error {
+	_, err := m.DB.Exec(`UPDATE page_snapshots SET checked_at = ? WHERE source_id = ?`, sqliteTime(at), sourceID)
+	return err
+}
+
+// Delete forgets a source's snapshot, so its next run starts a fresh baseline.
+func (m *SnapshotModel) Delete(sourceID int) error {
+	_, err := m.DB.Exec(`DELETE FROM page_snapshots WHERE source_id = ?`, sourceID)
+	return err
+}
@@ -0,0 +1,157 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Source is a site the scraper pulls items from.
+type Source struct {
+	ID        int
+	Name      string
+	URL       string
+	Type      string // Extraction strategy, e.g. "html"
+	Config    string // JSON, interpreted by the extractor for Type
+	Interval  int    // Minutes between runs
+	LastRunAt *time.Time
+	Enabled   bool   // Paused sources keep their config and history but the scheduler skips them
+	Render    bool   // Load the page in a headless browser, for sites that build their content with JavaScript
+	Include   string // Keyword filters, one per line: when set, only items matching one of them are kept
+	Exclude   string // Keyword filters, one per line: items matching any of them are dropped
+	CreatedAt time.Time
+}
+
+// SourceModel wraps a database connection pool for scraper sources.
+type SourceModel struct {
+	DB *sql.DB
+}
+
+const sourceColumns = `id, name, url, type, config, interval_minutes, last_run_at, enabled, render, include_filter, exclude_filter, created_at`
+
+// InitSchema creates the sources table if it doesn't exist.
+func (m *SourceModel) InitSchema() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS sources (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		url TEXT NOT NULL,
+		type TEXT NOT NULL DEFAULT 'html',
+		config TEXT NOT NULL DEFAULT '{}',
+		interval_minutes INTEGER NOT NULL DEFAULT 60,
+		last_run_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	if _, err := m.DB.Exec(stmt); err != nil {
+		return err
+	}
+
+	if err := addColumn(m.DB, "sources", "enabled", "BOOLEAN NOT NULL DEFAULT 1"); err != nil {
+		return err
+	}
+	if err := addColumn(m.DB, "sources", "render", "BOOLEAN NOT NULL DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := addColumn(m.DB, "sources", "include_filter", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	return addColumn(m.DB, "sources", "exclude_filter", "TEXT NOT NULL DEFAULT ''")
+}
+
+// Insert adds a new source.
+func (m *SourceModel) Insert(s *Source) (int, error) {
+	stmt := `INSERT INTO sources (name, url, type, config, interval_minutes, render, include_filter, exclude_filter, created_at)
+	VALUES(?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP) RETURNING id`
+
+	var id int
+	err := m.DB.QueryRow(stmt, s.Name, s.URL, s.Type, s.Config, s.Interval, s.Render, s.Include, s.Exclude).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// Get returns a single source by ID.
+func (m *SourceModel) Get(id int) (*Source, error) {
+	stmt := `SELECT ` + sourceColumns + ` FROM sources WHERE id = ?`
+
+	s, err := scanSource(m.DB.QueryRow(stmt, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoRecord
+	}
+	return s, err
+}
+
+// All returns every source, alphabetically.
+func (m *SourceModel) All() ([]*Source, error) {
+	stmt := `SELECT ` + sourceColumns + ` FROM sources ORDER BY name COLLATE NOCASE`
+	return m.query(stmt)
+}
+
+// Find returns the sources whose name, URL or config contains every term.
+func (m *SourceModel) Find(terms []string, limit int) ([]*Source, error) {
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	cond, args := likeAll([]string{"name", "url", "config"}, terms)
+	stmt := `SELECT ` + sourceColumns + ` FROM sources WHERE ` + cond + ` ORDER BY name COLLATE NOCASE LIMIT ?`
+	return m.query(stmt, append(args, limit)...)
+}
+
+func (m *SourceModel) query(stmt string, args ...any) ([]*Source, error) {
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sources []*Source
+
+	for rows.Next() {
+		s, err := scanSource(rows)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sources, nil
+}
+
+// Delete removes a source. Items it already produced are kept.
+func (m *SourceModel) Delete(id int) error {
+	_, err := m.DB.Exec(`DELETE FROM sources WHERE id = ?`, id)
+	return err
+}
+
+// SetEnabled pauses (false) or resumes (true) a source's scheduled runs.
+func (m *SourceModel) SetEnabled(id int, enabled bool) error {
+	_, err := m.DB.Exec(`UPDATE sources SET enabled = ? WHERE id = ?`, enabled, id)
+	return err
+}
+
+// SetFilters replaces a source's include and exclude filters.
+func (m *SourceModel) SetFilters(id int, include, exclude string) error {
+	_, err := m.DB.Exec(`UPDATE sources SET include_filter = ?, exclude_filter = ? WHERE id = ?`, include, exclude, id)
+	return err
+}
+
+// MarkRun records when a source was last scraped.
+func (m *SourceModel) MarkRun(id int, at time.Time) error {
+	_, err := m.DB.Exec(`UPDATE sources SET last_run_at = ? WHERE id = ?`, sqliteTime(at), id)
+	return err
+}
+
+func scanSource(row rowScanner) (*Source, error) {
+	s := &Source{}
+	err := row.Scan(&s.ID, &s.Name, &s.URL, &s.Type, &s.Config, &s.Interval, &s.LastRunAt, &s.Enabled, &s.Render, &s.Include, &s.Exclude, &s.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
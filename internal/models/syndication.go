@@ -0,0 +1,89 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Syndication records where an entry was cross-posted (POSSE: publish on the
+// station, syndicate elsewhere).
+type Syndication struct {
+	EntryID   int
+	Service   string // e.g. "mastodon", "bluesky"
+	URL       string
+	CreatedAt time.Time
+}
+
+// SyndicationModel wraps a database connection pool for syndication records.
+type SyndicationModel struct {
+	DB *sql.DB
+}
+
+// InitSchema creates the entry_syndications table if it doesn't exist.
+func (m *SyndicationModel) InitSchema() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS entry_syndications (
+		entry_id INTEGER NOT NULL REFERENCES entries(id) ON DELETE CASCADE,
+		service TEXT NOT NULL,
+		url TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (entry_id, service)
+	);
+	`
+	_, err := m.DB.Exec(stmt)
+	return err
+}
+
+// Insert records the syndicated copy of an entry on a service.
+func (m *SyndicationModel) Insert(entryID int, service, url string) error {
+	stmt := `INSERT INTO entry_syndications (entry_id, service, url, created_at)
+	VALUES(?, ?, ?, CURRENT_TIMESTAMP)`
+	_, err := m.DB.Exec(stmt, entryID, service, url)
+	return err
+}
+
+// Exists reports whether the entry has already been syndicated to the service.
+func (m *SyndicationModel) Exists(entryID int, service string) (bool, error) {
+	var exists bool
+	stmt := `SELECT EXISTS(SELECT 1 FROM entry_syndications WHERE entry_id = ? AND service = ?)`
+	err := m.DB.QueryRow(stmt, entryID, service).Scan(&exists)
+	return exists, err
+}
+
+// ForEntry returns every syndicated copy of an entry.
+func (m *SyndicationModel) ForEntry(entryID int) ([]*Syndication, error) {
+	stmt := `SELECT entry_id, service, url, created_at FROM entry_syndications
+	WHERE entry_id = ? ORDER BY service`
+	return m.query(stmt, entryID)
+}
+
+// All returns every syndicated copy of every entry.
+func (m *SyndicationModel) All() ([]*Syndication, error) {
+	stmt := `SELECT entry_id, service, url, created_at FROM entry_syndications ORDER BY entry_id, service`
+	return m.query(stmt)
+}
+
+func (m *SyndicationModel) query(stmt string, args ...any) ([]*Syndication, error) {
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*Syndication
+
+	for rows.Next() {
+		s := &Syndication{}
+		err = rows.Scan(&s.EntryID, &s.Service, &s.URL, &s.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
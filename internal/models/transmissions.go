@@ -0,0 +1,202 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Moderation states of a guest transmission.
+const (
+	TransmissionPending  = "pending"
+	TransmissionApproved = "approved"
+	TransmissionRejected = "rejected"
+)
+
+// Transmission is a short message a visitor sent through /transmit. It waits in
+// the moderation queue until the operator approves it, which publishes it as a
+// "transmission" entry, or rejects it.
+type Transmission struct {
+	ID        int
+	Callsign  *string // Name the sender signed with, optional
+	Message   string
+	Origin    string // Hash of the sender's address, for rate limiting
+	Status    string
+	EntryID   *int // Published entry, once approved
+	CreatedAt time.Time
+}
+
+// Entry builds the entry an approved transmission is published as.
+func (t *Transmission) Entry() *Entry {
+	title := "Incoming transmission"
+	if t.Callsign != nil {
+		title += " from " + *t.Callsign
+	}
+	return &Entry{Title: title, Type: "transmission", Content: &t.Message}
+}
+
+// TransmissionModel wraps a database connection pool for guest transmissions.
+type TransmissionModel struct {
+	DB *sql.DB
+}
+
+const transmissionColumns = `id, callsign, message, origin, status, entry_id, created_at`
+
+// InitSchema creates the transmissions table if it doesn't exist.
+func (m *TransmissionModel) InitSchema() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS transmissions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		callsign TEXT,
+		message TEXT NOT NULL,
+		origin TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		entry_id INTEGER REFERENCES entries(id) ON DELETE SET NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS transmissions_origin ON transmissions(origin, created_at);
+	CREATE INDEX IF NOT EXISTS transmissions_status ON transmissions(status);
+	`
+	_, err := m.DB.Exec(stmt)
+	return err
+}
+
+// Insert queues a new transmission for moderation.
+func (m *TransmissionModel) Insert(t *Transmission) (int, error) {
+	stmt := `INSERT INTO transmissions (callsign, message, origin, status, created_at)
+	VALUES(?, ?, ?, 'pending', CURRENT_TIMESTAMP) RETURNING id`
+
+	var id int
+	err := m.DB.QueryRow(stmt, t.Callsign, t.Message, t.Origin).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// Get returns a single transmission by ID.
+func (m *TransmissionModel) Get(id int) (*Transmission, error) {
+	stmt := `SELECT ` + transmissionColumns + ` FROM transmissions WHERE id = ?`
+
+	t, err := scanTransmission(m.DB.QueryRow(stmt, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoRecord
+	}
+	return t, err
+}
+
+// Pending returns the moderation queue, oldest first.
+func (m *TransmissionModel) Pending() ([]*Transmission, error) {
+	stmt := `SELECT ` + transmissionColumns + ` FROM transmissions WHERE status = 'pending' ORDER BY created_at, id`
+	return m.query(stmt)
+}
+
+// Find returns transmissions of any status whose callsign or message contains every
+// term, newest first.
+func (m *TransmissionModel) Find(terms []string, limit int) ([]*Transmission, error) {
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	cond, args := likeAll([]string{"callsign", "message"}, terms)
+	stmt := `SELECT ` + transmissionColumns + ` FROM transmissions WHERE ` + cond + ` ORDER BY created_at DESC LIMIT ?`
+	return m.query(stmt, append(args, limit)...)
+}
+
+func (m *TransmissionModel) query(stmt string, args ...any) ([]*Transmission, error) {
+	rows, err := m.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*Transmission
+
+	for rows.Next() {
+		t, err := scanTransmission(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// CountPending returns how many transmissions are waiting for moderation.
+func (m *TransmissionModel) CountPending() (int, error) {
+	var n int
+	err := m.DB.QueryRow(`SELECT COUNT(*) FROM transmissions WHERE status = 'pending'`).Scan(&n)
+	return n, err
+}
+
+// CountFrom returns how many transmissions an origin has sent since the given time,
+// whatever became of them.
+func (m *TransmissionModel) CountFrom(origin string, since time.Time) (int, error) {
+	stmt := `SELECT COUNT(*) FROM transmissions WHERE origin = ? AND created_at >= ?`
+
+	var n int
+	err := m.DB.QueryRow(stmt, origin, sqliteTime(since)).Scan(&n)
+	return n, err
+}
+
+// Approve publishes a pending transmission as an entry, in one transaction so a
+// transmission can't be published twice. It returns the new entry's ID; the entry
+// keeps the time the transmission was received.
+func (m *TransmissionModel) Approve(id int) (int, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	t, err := scanTransmission(tx.QueryRow(`SELECT `+transmissionColumns+` FROM transmissions WHERE id = ? AND status = 'pending'`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNoRecord
+	} else if err != nil {
+		return 0, err
+	}
+
+	e := t.Entry()
+	e.CreatedAt = t.CreatedAt
+	entryID, err := insertRecord(tx, e.Record())
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(`UPDATE transmissions SET status = 'approved', entry_id = ? WHERE id = ?`, entryID, id); err != nil {
+		return 0, err
+	}
+
+	return entryID, tx.Commit()
+}
+
+// Reject takes a pending transmission out of the queue. The row is kept so it still
+// counts against its sender's rate limit.
+func (m *TransmissionModel) Reject(id int) error {
+	res, err := m.DB.Exec(`UPDATE transmissions SET status = 'rejected' WHERE id = ? AND status = 'pending'`, id)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+func scanTransmission(row rowScanner) (*Transmission, error) {
+	t := &Transmission{}
+	err := row.Scan(&t.ID, &t.Callsign, &t.Message, &t.Origin, &t.Status, &t.EntryID, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
@@ -0,0 +1,208 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// TypeCount is how many entries are stored under one type key.
+type TypeCount struct {
+	Type    EntryType // From the registry; the fallback type for unregistered keys
+	Known   bool
+	Entries int
+}
+
+// TypeMigration records one bulk retyping of entries.
+type TypeMigration struct {
+	ID         int
+	From       []string
+	To         string
+	Entries    int // Entries moved
+	Detached   int // Thread links cut because the target type isn't a thought
+	MigratedAt time.Time
+}
+
+// TypeMigrationPlan is what a migration would change, worked out before running it.
+type TypeMigrationPlan struct {
+	From     []string
+	To       EntryType
+	Entries  int
+	Detached int
+}
+
+// TypeMigrationModel wraps a database connection pool for retyping entries.
+type TypeMigrationModel struct {
+	DB *sql.DB
+}
+
+// InitSchema creates the type_migrations log if it doesn't exist.
+func (m *TypeMigrationModel) InitSchema() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS type_migrations (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		from_types TEXT NOT NULL,
+		to_type TEXT NOT NULL,
+		entries INTEGER NOT NULL,
+		detached INTEGER NOT NULL DEFAULT 0,
+		migrated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	_, err := m.DB.Exec(stmt)
+	return err
+}
+
+// Counts returns how many entries each stored type key has, registered or not,
+// busiest first.
+func (m *TypeMigrationModel) Counts() ([]*TypeCount, error) {
+	rows, err := m.DB.Query(`SELECT type, COUNT(*) FROM entries GROUP BY type ORDER BY COUNT(*) DESC, type`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []*TypeCount
+
+	for rows.Next() {
+		var key string
+		c := &TypeCount{}
+		if err := rows.Scan(&key, &c.Entries); err != nil {
+			return nil, err
+		}
+		c.Type, c.Known = TypeByKey(key)
+		counts = append(counts, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// Plan checks a migration of every entry typed as one of from into to, and counts
+// what it would change. The target has to be a registered type that is still
+// offered for new entries.
+func (m *TypeMigrationModel) Plan(from []string, to string) (*TypeMigrationPlan, error) {
+	return m.plan(m.DB, from, to)
+}
+
+func (m *TypeMigrationModel) plan(q interface {
+	QueryRow(query string, args ...any) *sql.Row
+}, from []string, to string) (*TypeMigrationPlan, error) {
+	target, known := TypeByKey(to)
+	if !known || target.Retired {
+		return nil, fmt.Errorf("%q is not a type new entries can have", to)
+	}
+
+	var sources []string
+	for _, f := range from {
+		if f = strings.TrimSpace(f); f != "" && f != to && !slices.Contains(sources, f) {
+			sources = append(sources, f)
+		}
+	}
+	if len(sources) == 0 {
+		return nil, errors.New("pick at least one type to migrate other than the target")
+	}
+
+	plan := &TypeMigrationPlan{From: sources, To: target}
+
+	in, args := placeholders(sources)
+	if err := q.QueryRow(`SELECT COUNT(*) FROM entries WHERE type IN `+in, args...).Scan(&plan.Entries); err != nil {
+		return nil, err
+	}
+
+	// Only thoughts thread, so moving entries out of the thoughts cuts their links
+	// to parents and replies
+	if !target.Thought {
+		stmt := `SELECT COUNT(*) FROM entries WHERE (type IN ` + in + ` AND parent_id IS NOT NULL)
+		OR parent_id IN (SELECT id FROM entries WHERE type IN ` + in + `)`
+		if err := q.QueryRow(stmt, append(args, args...)...).Scan(&plan.Detached); err != nil {
+			return nil, err
+		}
+	}
+
+	return plan, nil
+}
+
+// Migrate retypes every entry typed as one of from to to, in one transaction with
+// its log record, and returns what it did.
+func (m *TypeMigrationModel) Migrate(from []string, to string) (*TypeMigration, error) {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	plan, err := m.plan(tx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	if plan.Entries == 0 {
+		return nil, errors.New("no entries have those types")
+	}
+
+	in, args := placeholders(plan.From)
+	if !plan.To.Thought {
+		stmt := `UPDATE entries SET parent_id = NULL WHERE (type IN ` + in + ` AND parent_id IS NOT NULL)
+		OR parent_id IN (SELECT id FROM entries WHERE type IN ` + in + `)`
+		if _, err := tx.Exec(stmt, append(args, args...)...); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := tx.Exec(`UPDATE entries SET type = ? WHERE type IN `+in, append([]any{to}, args...)...); err != nil {
+		return nil, err
+	}
+
+	mig := &TypeMigration{From: plan.From, To: to, Entries: plan.Entries, Detached: plan.Detached}
+	stmt := `INSERT INTO type_migrations (from_types, to_type, entries, detached, migrated_at)
+	VALUES(?, ?, ?, ?, CURRENT_TIMESTAMP) RETURNING id, migrated_at`
+	err = tx.QueryRow(stmt, strings.Join(plan.From, ","), to, mig.Entries, mig.Detached).Scan(&mig.ID, &mig.MigratedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return mig, tx.Commit()
+}
+
+// History returns past migrations, newest first.
+func (m *TypeMigrationModel) History(limit int) ([]*TypeMigration, error) {
+	stmt := `SELECT id, from_types, to_type, entries, detached, migrated_at FROM type_migrations
+	ORDER BY migrated_at DESC, id DESC LIMIT ?`
+
+	rows, err := m.DB.Query(stmt, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*TypeMigration
+
+	for rows.Next() {
+		mig := &TypeMigration{}
+		var from string
+		if err := rows.Scan(&mig.ID, &from, &mig.To, &mig.Entries, &mig.Detached, &mig.MigratedAt); err != nil {
+			return nil, err
+		}
+		mig.From = strings.Split(from, ",")
+		out = append(out, mig)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// placeholders returns "(?, ?, ...)" for the values, along with them as arguments.
+func placeholders(values []string) (string, []any) {
+	args := make([]any, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return "(" + strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ") + ")", args
+}
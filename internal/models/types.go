@@ -0,0 +1,80 @@
+package models
+
+import "strings"
+
+// EntryType declares how entries of one type behave across the station, so
+// templates and handlers ask the registry instead of checking type names.
+type EntryType struct {
+	Key        string
+	Label      string   // Shown in the admin form
+	Icon       string   // Short tag on cards, e.g. "[b_ok]"
+	Thought    bool     // Belongs to Organic Thoughts rather than the Media Compendium
+	Corruption int      // Default severity (0-100) when the intercept garbles an entry's content
+	Markdown   bool     // Content is rendered as Markdown rather than plain text
+	Card       string   // Partial that renders the entry in listings; the generic GenericCard when empty or not parsed
+	InFeeds    bool     // Entries of this type appear in feeds (each entry can still opt out)
+	Retired    bool     // Still rendered, but no longer offered for new entries
+	Fields     []string // Custom fields the admin form suggests for this type; any others are allowed too
+}
+
+// EntryTypes is the registry of entry types, in the order the admin form offers them.
+var EntryTypes = []EntryType{
+	{Key: "thought_admin", Label: "Admin Log [sys.admin]", Icon: "[sys.admin]", Thought: true, Corruption: 10, Markdown: true, Card: "thought", InFeeds: true},
+	{Key: "thought_stationai", Label: "Station AI Log [sys.ai]", Icon: "[sys.ai]", Thought: true, Corruption: 35, Markdown: true, Card: "thought", InFeeds: true},
+	{Key: "book", Label: "Book [b_ok]", Icon: "[b_ok]", Corruption: 20, Card: "book-card", InFeeds: true, Fields: []string{"author", "translator", "edition"}},
+	{Key: "anime", Label: "Anime / TV [anim]", Icon: "[anim]", Corruption: 20, Card: "media-card", InFeeds: true, Fields: []string{"studio", "episodes"}},
+	{Key: "tool", Label: "Software Tool [exec]", Icon: "[exec]", Corruption: 20, Card: "media-card", InFeeds: true, Fields: []string{"version", "license"}},
+	{Key: "log", Label: "System Log [data]", Icon: "[sys.]", Corruption: 30, Card: "media-card"},
+	{Key: "game", Label: "Video Game [game]", Icon: "[game]", Corruption: 20, Card: "game-card", InFeeds: true, Fields: []string{"platform", "hours"}},
+	{Key: "link", Label: "Link [href]", Icon: "[href]", Corruption: 20, Card: "link-card", InFeeds: true},
+	{Key: "transmission", Label: "Incoming Transmission [rx.in]", Icon: "[rx.in]", Thought: true, Corruption: 40, Card: "thought"},
+	{Key: "thought", Label: "Organic Log [sys.log]", Icon: "[sys.log]", Thought: true, Corruption: 20, Markdown: true, Card: "thought", InFeeds: true, Retired: true},
+}
+
+// GenericCard is the partial for media entries whose type has no card of its own.
+const GenericCard = "media-card"
+
+// fallbackType covers entries whose type isn't in the registry, e.g. imported ones.
+var fallbackType = EntryType{Label: "Unknown", Icon: "[data]", Corruption: 20, Card: GenericCard, InFeeds: true}
+
+// TypeByKey looks up an entry type, reporting whether it is registered. Unknown
+// keys get a generic media type so they still render.
+func TypeByKey(key string) (EntryType, bool) {
+	for _, t := range EntryTypes {
+		if t.Key == key {
+			return t, true
+		}
+	}
+	t := fallbackType
+	t.Key = key
+	return t, false
+}
+
+// SelectableTypes returns the types offered when creating an entry.
+func SelectableTypes() []EntryType {
+	var types []EntryType
+	for _, t := range EntryTypes {
+		if !t.Retired {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// IsThoughtType reports whether entries of the given type are thoughts.
+func IsThoughtType(entryType string) bool {
+	t, _ := TypeByKey(entryType)
+	return t.Thought
+}
+
+// thoughtTypeList is the registered thought types as an SQL list, e.g. "('thought', 'thought_admin')".
+// The keys are constants from the registry, so they are inlined rather than bound.
+var thoughtTypeList = func() string {
+	var quoted []string
+	for _, t := range EntryTypes {
+		if t.Thought {
+			quoted = append(quoted, "'"+strings.ReplaceAll(t.Key, "'", "''")+"'")
+		}
+	}
+	return "(" + strings.Join(quoted, ", ") + ")"
+}()
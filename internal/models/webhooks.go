@@ -0,0 +1,150 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Delivery states of a webhook event.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one event sent (or to be sent) to one endpoint. The payload
+// is frozen when the event happens, so a redelivery replays exactly what the first
+// attempt carried, under the same ID.
+type WebhookDelivery struct {
+	ID             string
+	Event          string // e.g. "entry.created"
+	EntryID        int
+	Endpoint       string
+	Payload        []byte
+	Status         string
+	Attempts       int
+	ResponseStatus int     // Status of the last answer, 0 if none came back
+	LastError      *string // Why the last attempt failed
+	CreatedAt      time.Time
+	DeliveredAt    *time.Time
+}
+
+// WebhookModel wraps a database connection pool for the webhook deliveries log.
+type WebhookModel struct {
+	DB *sql.DB
+}
+
+const webhookColumns = `id, event, entry_id, endpoint, payload, status, attempts, response_status, last_error, created_at, delivered_at`
+
+// InitSchema creates the webhook_deliveries table if it doesn't exist.
+func (m *WebhookModel) InitSchema() error {
+	stmt := `
+	CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		id TEXT PRIMARY KEY,
+		event TEXT NOT NULL,
+		entry_id INTEGER NOT NULL,
+		endpoint TEXT NOT NULL,
+		payload BLOB NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		response_status INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		delivered_at DATETIME
+	);
+	CREATE INDEX IF NOT EXISTS webhook_deliveries_created ON webhook_deliveries(created_at);
+	`
+	_, err := m.DB.Exec(stmt)
+	return err
+}
+
+// Insert logs a new pending delivery.
+func (m *WebhookModel) Insert(d *WebhookDelivery) error {
+	stmt := `INSERT INTO webhook_deliveries (id, event, entry_id, endpoint, payload, status, created_at)
+	VALUES(?, ?, ?, ?, ?, 'pending', CURRENT_TIMESTAMP)`
+	_, err := m.DB.Exec(stmt, d.ID, d.Event, d.EntryID, d.Endpoint, d.Payload)
+	return err
+}
+
+// Get returns a single delivery by ID.
+func (m *WebhookModel) Get(id string) (*WebhookDelivery, error) {
+	stmt := `SELECT ` + webhookColumns + ` FROM webhook_deliveries WHERE id = ?`
+
+	d, err := scanWebhookDelivery(m.DB.QueryRow(stmt, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoRecord
+	}
+	return d, err
+}
+
+// Recent returns the latest deliveries, newest first.
+func (m *WebhookModel) Recent(limit int) ([]*WebhookDelivery, error) {
+	stmt := `SELECT ` + webhookColumns + ` FROM webhook_deliveries ORDER BY created_at DESC, rowid DESC LIMIT ?`
+
+	rows, err := m.DB.Query(stmt, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*WebhookDelivery
+
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// RecordAttempt counts one attempt at a delivery: a nil error marks it delivered,
+// anything else failed until a later attempt gets through.
+func (m *WebhookModel) RecordAttempt(id string, responseStatus int, attemptErr error) error {
+	if attemptErr == nil {
+		stmt := `UPDATE webhook_deliveries SET status = 'delivered', attempts = attempts + 1,
+		response_status = ?, last_error = NULL, delivered_at = CURRENT_TIMESTAMP WHERE id = ?`
+		_, err := m.DB.Exec(stmt, responseStatus, id)
+		return err
+	}
+
+	stmt := `UPDATE webhook_deliveries SET status = 'failed', attempts = attempts + 1,
+	response_status = ?, last_error = ? WHERE id = ?`
+	_, err := m.DB.Exec(stmt, responseStatus, attemptErr.Error(), id)
+	return err
+}
+
+// Requeue puts a delivery back to pending for another round of attempts. The
+// attempt count keeps running so the log shows the whole history.
+func (m *WebhookModel) Requeue(id string) error {
+	result, err := m.DB.Exec(`UPDATE webhook_deliveries SET status = 'pending' WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNoRecord
+	}
+	return nil
+}
+
+func scanWebhookDelivery(row rowScanner) (*WebhookDelivery, error) {
+	d := &WebhookDelivery{}
+	err := row.Scan(&d.ID, &d.Event, &d.EntryID, &d.Endpoint, &d.Payload, &d.Status, &d.Attempts,
+		&d.ResponseStatus, &d.LastError, &d.CreatedAt, &d.DeliveredAt)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
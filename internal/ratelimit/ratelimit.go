@@ -0,0 +1,139 @@
+// Package ratelimit keeps any one address from writing faster than the station can
+// take it: each address gets a token bucket that refills at a steady rate, requests
+// spend a token each, and a request finding the bucket empty is refused with 429.
+// Unlike the filter's throttle, which counts every request in one-minute windows,
+// it only guards the requests that cost something, such as form posts and the API.
+package ratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/clientip"
+)
+
+// sweepEvery is how often buckets that have refilled completely are forgotten.
+const sweepEvery = time.Minute
+
+// Limiter is the middleware. The zero value is not usable; build one with New.
+type Limiter struct {
+	Rate  float64 // Tokens added per second
+	Burst int     // Bucket size: how many requests an idle address may make at once
+
+	Applies func(*http.Request) bool                 // Requests it says no to pass straight through; nil limits everything
+	Exempt  func(*http.Request) bool                 // e.g. the signed-in operator
+	Refuse  func(http.ResponseWriter, *http.Request) // Writes the 429; nil sends plain text
+	Clients *clientip.Resolver                       // Finds addresses behind trusted proxies; nil trusts none
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	at     time.Time // When tokens was last brought up to date
+}
+
+// New parses a rate ("5" or "5/s", "120/m", "1000/h") and a burst size. An empty
+// burst defaults to one second's worth of requests, but never less than 1.
+func New(rate, burst string) (*Limiter, error) {
+	count, unit, _ := strings.Cut(strings.TrimSpace(rate), "/")
+	n, err := strconv.ParseFloat(count, 64)
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("rate %q: want a positive number of requests, e.g. 5/s or 120/m", rate)
+	}
+
+	per := time.Second
+	switch strings.TrimSpace(unit) {
+	case "", "s":
+	case "m", "min":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	default:
+		return nil, fmt.Errorf("rate %q: unit must be s, m or h", rate)
+	}
+
+	l := &Limiter{Rate: n / per.Seconds()}
+	l.Burst = max(int(l.Rate), 1)
+	if burst = strings.TrimSpace(burst); burst != "" {
+		l.Burst, err = strconv.Atoi(burst)
+		if err != nil || l.Burst < 1 {
+			return nil, fmt.Errorf("burst %q: want a whole number of at least 1", burst)
+		}
+	}
+	return l, nil
+}
+
+// String describes the limit for the startup log, e.g. "2 requests/s, bursts of 20".
+func (l *Limiter) String() string {
+	return fmt.Sprintf("%s requests/s, bursts of %d", strconv.FormatFloat(l.Rate, 'g', 4, 64), l.Burst)
+}
+
+// Allow spends a token from key's bucket, returning how long until the next one is
+// available when the bucket is empty.
+func (l *Limiter) Allow(key string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if l.buckets == nil {
+		l.buckets, l.lastSweep = map[string]*bucket{}, now
+	}
+	if now.Sub(l.lastSweep) >= sweepEvery {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.Burst), at: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(b.tokens+now.Sub(b.at).Seconds()*l.Rate, float64(l.Burst))
+	b.at = now
+
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / l.Rate * float64(time.Second)), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+// sweep drops the buckets that would be full by now, since a fresh bucket behaves
+// the same; without it every address ever seen would stay in memory.
+func (l *Limiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.at).Seconds()*l.Rate >= float64(l.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// Handler wraps next, refusing requests from addresses whose bucket is empty.
+func (l *Limiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if (l.Applies != nil && !l.Applies(r)) || (l.Exempt != nil && l.Exempt(r)) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if wait, ok := l.Allow(l.Clients.IP(r)); !ok {
+			// Retry-After only takes whole seconds, so round up rather than invite an early retry
+			w.Header().Set("Retry-After", strconv.Itoa(int(wait/time.Second)+1))
+			if l.Refuse != nil {
+				l.Refuse(w, r)
+				return
+			}
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,237 @@
+package replycontext
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ErrUnsupported is returned for URLs that don't point at a post we know how to fetch.
+var ErrUnsupported = errors.New("replycontext: unsupported URL")
+
+// Snapshot is the referenced post, flattened to plain text.
+type Snapshot struct {
+	Service     string
+	URL         string
+	Author      string
+	AuthorURL   string
+	Text        string
+	PublishedAt time.Time
+}
+
+var client = &http.Client{Timeout: 20 * time.Second}
+
+var (
+	mastodonPath = regexp.MustCompile(`^/(?:@[^/]+|users/[^/]+/statuses)/(\d+)/?$`)
+	blueskyPath  = regexp.MustCompile(`^/profile/([^/]+)/post/([^/]+)/?$`)
+)
+
+// Supported reports whether Fetch would attempt the URL.
+func Supported(raw string) bool {
+	_, _, err := classify(raw)
+	return err == nil
+}
+
+// Fetch retrieves a snapshot of the post at the given URL.
+func Fetch(ctx context.Context, raw string) (*Snapshot, error) {
+	service, u, err := classify(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	switch service {
+	case "hackernews":
+		return fetchHN(ctx, u)
+	case "bluesky":
+		return fetchBluesky(ctx, u)
+	default:
+		return fetchMastodon(ctx, u)
+	}
+}
+
+// classify works out which service a URL belongs to from its shape alone. Mastodon
+// runs on any host, so its status path pattern is the only signal we have.
+func classify(raw string) (string, *url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", nil, ErrUnsupported
+	}
+
+	switch {
+	case u.Host == "news.ycombinator.com" && u.Path == "/item" && u.Query().Get("id") != "":
+		return "hackernews", u, nil
+	case u.Host == "bsky.app" && blueskyPath.MatchString(u.Path):
+		return "bluesky", u, nil
+	case mastodonPath.MatchString(u.Path):
+		return "mastodon", u, nil
+	}
+	return "", nil, ErrUnsupported
+}
+
+func fetchHN(ctx context.Context, u *url.URL) (*Snapshot, error) {
+	id, err := strconv.Atoi(u.Query().Get("id"))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+
+	var item struct {
+		By    string `json:"by"`
+		Time  int64  `json:"time"`
+		Title string `json:"title"`
+		Text  string `json:"text"`
+		URL   string `json:"url"`
+	}
+	if err := getJSON(ctx, fmt.Sprintf("https://hacker-news.firebaseio.com/v0/item/%d.json", id), &item); err != nil {
+		return nil, err
+	}
+	if item.By == "" {
+		return nil, fmt.Errorf("hacker news item %d not found", id)
+	}
+
+	// Stories carry a title and maybe a link; comments only have text
+	text := htmlToText(item.Text)
+	if item.Title != "" {
+		text = strings.TrimSpace(item.Title + "\n\n" + text + "\n\n" + item.URL)
+	}
+
+	return &Snapshot{
+		Service:     "hackernews",
+		URL:         u.String(),
+		Author:      item.By,
+		AuthorURL:   "https://news.ycombinator.com/user?id=" + url.QueryEscape(item.By),
+		Text:        text,
+		PublishedAt: time.Unix(item.Time, 0),
+	}, nil
+}
+
+func fetchBluesky(ctx context.Context, u *url.URL) (*Snapshot, error) {
+	m := blueskyPath.FindStringSubmatch(u.Path)
+	atURI := "at://" + m[1] + "/app.bsky.feed.post/" + m[2]
+
+	var resp struct {
+		Thread struct {
+			Post struct {
+				Author struct {
+					Handle      string `json:"handle"`
+					DisplayName string `json:"displayName"`
+				} `json:"author"`
+				Record struct {
+					Text      string    `json:"text"`
+					CreatedAt time.Time `json:"createdAt"`
+				} `json:"record"`
+			} `json:"post"`
+		} `json:"thread"`
+	}
+	endpoint := "https://public.api.bsky.app/xrpc/app.bsky.feed.getPostThread?depth=0&parentHeight=0&uri=" + url.QueryEscape(atURI)
+	if err := getJSON(ctx, endpoint, &resp); err != nil {
+		return nil, err
+	}
+
+	post := resp.Thread.Post
+	author := post.Author.DisplayName
+	if author == "" {
+		author = "@" + post.Author.Handle
+	}
+
+	return &Snapshot{
+		Service:     "bluesky",
+		URL:         u.String(),
+		Author:      author,
+		AuthorURL:   "https://bsky.app/profile/" + post.Author.Handle,
+		Text:        post.Record.Text,
+		PublishedAt: post.Record.CreatedAt,
+	}, nil
+}
+
+func fetchMastodon(ctx context.Context, u *url.URL) (*Snapshot, error) {
+	id := mastodonPath.FindStringSubmatch(u.Path)[1]
+
+	var status struct {
+		Content   string    `json:"content"`
+		CreatedAt time.Time `json:"created_at"`
+		Account   struct {
+			Acct        string `json:"acct"`
+			DisplayName string `json:"display_name"`
+			URL         string `json:"url"`
+		} `json:"account"`
+	}
+	if err := getJSON(ctx, u.Scheme+"://"+u.Host+"/api/v1/statuses/"+id, &status); err != nil {
+		return nil, err
+	}
+
+	author := status.Account.DisplayName
+	if author == "" {
+		author = "@" + status.Account.Acct
+	}
+
+	return &Snapshot{
+		Service:     "mastodon",
+		URL:         u.String(),
+		Author:      author,
+		AuthorURL:   status.Account.URL,
+		Text:        htmlToText(status.Content),
+		PublishedAt: status.CreatedAt,
+	}, nil
+}
+
+func getJSON(ctx context.Context, endpoint string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %s", endpoint, resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// htmlToText flattens post HTML into plain text, keeping paragraph and line breaks.
+// We never store foreign markup, so the snapshot is always safe to render.
+func htmlToText(fragment string) string {
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div})
+	if err != nil {
+		return fragment
+	}
+
+	var b strings.Builder
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(n.Data)
+		case n.Type == html.ElementNode && n.Data == "br":
+			b.WriteString("\n")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if n.Type == html.ElementNode && n.Data == "p" {
+			b.WriteString("\n\n")
+		}
+	}
+	for _, n := range nodes {
+		walk(n)
+	}
+
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,131 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// AlertRule is a stored alert rule made ready to test items against.
+type AlertRule struct {
+	*models.AlertRule
+
+	re    *regexp.Regexp // For AlertMatches
+	limit float64        // For AlertBelow and AlertAbove
+}
+
+// CompileAlertRule checks a rule and prepares it for matching.
+func CompileAlertRule(r *models.AlertRule) (*AlertRule, error) {
+	switch r.Field {
+	case models.AlertFieldTitle, models.AlertFieldValue, models.AlertFieldURL:
+	default:
+		return nil, fmt.Errorf("unknown field %q", r.Field)
+	}
+
+	c := &AlertRule{AlertRule: r}
+	switch r.Op {
+	case models.AlertContains:
+		if strings.TrimSpace(r.Operand) == "" {
+			return nil, errors.New("contains needs some text to look for")
+		}
+	case models.AlertMatches:
+		re, err := regexp.Compile("(?i)" + r.Operand)
+		if err != nil {
+			return nil, err
+		}
+		c.re = re
+	case models.AlertBelow, models.AlertAbove:
+		limit, err := strconv.ParseFloat(strings.TrimSpace(r.Operand), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s needs a number, not %q", r.Op, r.Operand)
+		}
+		c.limit = limit
+	default:
+		return nil, fmt.Errorf("unknown comparison %q", r.Op)
+	}
+	return c, nil
+}
+
+// Match reports whether an item's title, value and URL satisfy the rule.
+func (c *AlertRule) Match(title, value, url string) bool {
+	text := title
+	switch c.Field {
+	case models.AlertFieldValue:
+		text = value
+	case models.AlertFieldURL:
+		text = url
+	}
+
+	switch c.Op {
+	case models.AlertContains:
+		return strings.Contains(strings.ToLower(text), strings.ToLower(c.Operand))
+	case models.AlertMatches:
+		return c.re.MatchString(text)
+	}
+
+	n, _, err := parsePrice(text)
+	if err != nil {
+		return false
+	}
+	if c.Op == models.AlertBelow {
+		return n < c.limit
+	}
+	return n > c.limit
+}
+
+// loadAlertRules returns the compiled rules that watch a source (nil when the
+// source is pushed without one). Broken rules are logged and left out rather than
+// stopping the scrape.
+func (e *Engine) loadAlertRules(sourceID *int) []*AlertRule {
+	if e.Alerts == nil {
+		return nil
+	}
+
+	rules, err := e.Alerts.Rules()
+	if err != nil {
+		slog.Error("Failed to load alert rules", "err", err)
+		return nil
+	}
+
+	var out []*AlertRule
+	for _, r := range rules {
+		if r.SourceID != nil && (sourceID == nil || *r.SourceID != *sourceID) {
+			continue
+		}
+		c, err := CompileAlertRule(r)
+		if err != nil {
+			slog.Warn("Skipping alert rule", "rule", r.ID, "match", r.String(), "err", err)
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// alert raises an alert for every rule a newly stored item matches. itemID is 0 for
+// price readings, which aren't stored as items. Failing to record an alert doesn't
+// fail the scrape; the item itself is safely stored.
+func (e *Engine) alert(rules []*AlertRule, itemID int, row *models.ScraperItem) {
+	url := models.StringValue(row.URL)
+	for _, r := range rules {
+		if !r.Match(row.Title, row.Value, url) {
+			continue
+		}
+
+		a := &models.Alert{RuleID: r.ID, Rule: r.String(), SourceID: row.SourceID, Title: row.Title, Value: row.Value, URL: row.URL}
+		if itemID != 0 {
+			a.ItemID = &itemID
+		}
+		created, err := e.Alerts.Raise(a)
+		if err != nil {
+			slog.Error("Failed to raise alert", "rule", r.ID, "err", err)
+		} else if created {
+			slog.Info("Alert raised", "title", row.Title, "rule", r.ID, "match", r.String())
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package scraper
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is how many unchanged lines surround each change in a diff.
+const diffContext = 2
+
+// maxDiffCells bounds the line-matching table; pages whose changed middle is bigger
+// than this diff as a wholesale replacement instead.
+const maxDiffCells = 4_000_000
+
+// edit is one line of a line diff.
+type edit struct {
+	op   byte // '+', '-' or ' '
+	text string
+}
+
+// lineDiff compares two texts line by line, returning a unified diff without file
+// headers, and the lines that were added and removed.
+func lineDiff(before, after string) (diff string, added, removed []string) {
+	edits := diffLines(splitLines(before), splitLines(after))
+	for _, e := range edits {
+		switch e.op {
+		case '+':
+			added = append(added, e.text)
+		case '-':
+			removed = append(removed, e.text)
+		}
+	}
+	return unified(edits), added, removed
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// diffLines matches a against b by their longest common subsequence, after setting
+// aside the common prefix and suffix, which is all most page edits leave changed.
+func diffLines(a, b []string) []edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var edits []edit
+	for _, l := range a[:prefix] {
+		edits = append(edits, edit{' ', l})
+	}
+	edits = append(edits, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, l := range a[len(a)-suffix:] {
+		edits = append(edits, edit{' ', l})
+	}
+	return edits
+}
+
+func diffMiddle(a, b []string) []edit {
+	var edits []edit
+
+	if len(a)*len(b) > maxDiffCells {
+		for _, l := range a {
+			edits = append(edits, edit{'-', l})
+		}
+		for _, l := range b {
+			edits = append(edits, edit{'+', l})
+		}
+		return edits
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i]})
+			i, j = i+1, j+1
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, edit{'-', a[i]})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		edits = append(edits, edit{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		edits = append(edits, edit{'+', b[j]})
+	}
+	return edits
+}
+
+// unified renders edits as hunks of changes with diffContext lines around them.
+func unified(edits []edit) string {
+	var b strings.Builder
+
+	for start := 0; start < len(edits); {
+		// Find the next change, then extend the hunk while changes stay close together
+		first := start
+		for first < len(edits) && edits[first].op == ' ' {
+			first++
+		}
+		if first == len(edits) {
+			break
+		}
+
+		end := first
+		for k := first; k < len(edits) && k <= end+2*diffContext; k++ {
+			if edits[k].op != ' ' {
+				end = k
+			}
+		}
+
+		from, to := max(first-diffContext, start), min(end+diffContext+1, len(edits))
+
+		oldLine, newLine := 1, 1
+		for _, e := range edits[:from] {
+			if e.op != '+' {
+				oldLine++
+			}
+			if e.op != '-' {
+				newLine++
+			}
+		}
+		oldCount, newCount := 0, 0
+		for _, e := range edits[from:to] {
+			if e.op != '+' {
+				oldCount++
+			}
+			if e.op != '-' {
+				newCount++
+			}
+		}
+
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", oldLine, oldCount, newLine, newCount)
+		for _, e := range edits[from:to] {
+			b.WriteByte(e.op)
+			b.WriteString(e.text)
+			b.WriteByte('\n')
+		}
+		start = to
+	}
+
+	return b.String()
+}
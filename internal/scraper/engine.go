@@ -0,0 +1,326 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// Engine runs sources: fetch, extract, store.
+type Engine struct {
+	Sources   *models.SourceModel
+	Items     *models.ScraperModel
+	Fetcher   *Fetcher
+	Runs      *models.ScrapeRunModel // Run history, optional
+	Snapshots *models.SnapshotModel  // Page snapshots, needed by monitor sources
+	Prices    *models.PriceModel     // Price history, needed by price sources
+	Alerts    *models.AlertModel     // Alert rules checked against new items, optional
+	Renderer  Renderer               // Headless browser for sources with Render set; nil fetches them over plain HTTP
+
+	// Chaos, when set, runs before every scrape and can delay it, fail it or panic;
+	// see package chaos
+	Chaos func(ctx context.Context, what string) error
+}
+
+// Run scrapes a single source and stores what it finds, returning the number of new items.
+// Items already stored from an earlier run are refreshed in place rather than duplicated.
+// Every attempt, failed or not, is written to the run history.
+func (e *Engine) Run(ctx context.Context, src *models.Source) (int, error) {
+	run := &models.ScrapeRun{SourceID: src.ID, StartedAt: time.Now()}
+
+	err := e.run(ctx, src, run)
+
+	if e.Runs != nil {
+		run.Duration = time.Since(run.StartedAt)
+		if err != nil {
+			msg := err.Error()
+			run.Error = &msg
+		}
+		if rerr := e.Runs.Insert(run); rerr != nil {
+			slog.Error("Failed to record scrape run", "source", src.ID, "err", rerr)
+		}
+	}
+
+	return run.ItemsNew, err
+}
+
+// run does the actual scrape, tallying what it finds into run.
+func (e *Engine) run(ctx context.Context, src *models.Source, run *models.ScrapeRun) error {
+	extract, extracts := extractors[src.Type]
+	collect, collects := collectors[src.Type]
+	if !extracts && !collects && src.Type != monitorType && src.Type != priceType {
+		return fmt.Errorf("source %q: unknown type %q", src.Name, src.Type)
+	}
+
+	if e.Chaos != nil {
+		if err := e.Chaos(ctx, "scrape of source "+src.Name); err != nil {
+			return err
+		}
+	}
+
+	page, err := e.fetch(ctx, src)
+	if err != nil {
+		run.Attempts = Attempts(err)
+		return err
+	}
+	run.Attempts = page.Attempts
+
+	switch src.Type {
+	case monitorType:
+		if err := e.watch(src, page, run); err != nil {
+			return fmt.Errorf("source %q: %w", src.Name, err)
+		}
+		return e.Sources.MarkRun(src.ID, time.Now())
+	case priceType:
+		if err := e.track(src, page, run); err != nil {
+			return fmt.Errorf("source %q: %w", src.Name, err)
+		}
+		return e.Sources.MarkRun(src.ID, time.Now())
+	}
+
+	var items []Item
+	if collects {
+		items, err = collect(ctx, e.Fetcher, page, json.RawMessage(src.Config))
+	} else {
+		items, err = extract(page, json.RawMessage(src.Config))
+	}
+	if err != nil {
+		return fmt.Errorf("source %q: %w", src.Name, err)
+	}
+	filter, err := CompileItemFilter(src.Include, src.Exclude)
+	if err != nil {
+		return fmt.Errorf("source %q: %w", src.Name, err)
+	}
+
+	rules := e.loadAlertRules(&src.ID)
+	run.ItemsFound = len(items)
+	run.Quality = measureQuality(items)
+	for _, item := range items {
+		// Noise the source's filters reject never reaches the database
+		if !filter.Keep(item) {
+			run.Dropped++
+			continue
+		}
+
+		// Dated items we already saw on an earlier run are skipped, which keeps feeds incremental.
+		// Collected rankings are refreshed in full, since old stories can climb into them.
+		if !collects && src.LastRunAt != nil && !item.Published.IsZero() && !item.Published.After(*src.LastRunAt) {
+			continue
+		}
+
+		row := newRow(item, &src.ID, page.URL, page.FetchedAt)
+		id, created, err := e.Items.Insert(row)
+		if err != nil {
+			return err
+		}
+		if created {
+			run.ItemsNew++
+			e.alert(rules, id, row)
+		}
+	}
+
+	// Only a completed run counts; failures leave last_run_at alone so they show up as stale
+	return e.Sources.MarkRun(src.ID, time.Now())
+}
+
+// fetch loads a source's page, in the headless browser when the source asks for it
+// and one is available, over plain HTTP otherwise.
+func (e *Engine) fetch(ctx context.Context, src *models.Source) (*Page, error) {
+	if !src.Render || e.Renderer == nil {
+		return e.Fetcher.Fetch(ctx, src.URL)
+	}
+
+	// The browser obeys robots.txt too
+	if e.Fetcher.Robots != nil {
+		if err := e.Fetcher.Robots.Wait(ctx, e.Fetcher, src.URL); err != nil {
+			return nil, err
+		}
+	}
+
+	page, err := e.Renderer.Render(ctx, src.URL)
+	if errors.Is(err, ErrNoBrowser) {
+		slog.Warn("Source wants rendering, fetching over plain HTTP instead", "source", src.Name, "err", err)
+		return e.Fetcher.Fetch(ctx, src.URL)
+	}
+	return page, err
+}
+
+// watch compares a monitor source's page with its snapshot. The first run only
+// records a baseline; after that, each change is stored as an item carrying the
+// diff, and the snapshot moves on to the new text.
+func (e *Engine) watch(src *models.Source, page *Page, run *models.ScrapeRun) error {
+	if e.Snapshots == nil {
+		return errors.New("monitor sources need a snapshot store")
+	}
+
+	mon, err := compileMonitor(json.RawMessage(src.Config))
+	if err != nil {
+		return err
+	}
+	text, err := mon.pageText(page)
+	if err != nil {
+		return err
+	}
+	snap := &models.Snapshot{SourceID: src.ID, Hash: textHash(text), Content: text, ChangedAt: page.FetchedAt, CheckedAt: page.FetchedAt}
+
+	prev, err := e.Snapshots.Get(src.ID)
+	if errors.Is(err, models.ErrNoRecord) {
+		return e.Snapshots.Save(snap)
+	} else if err != nil {
+		return err
+	}
+	if prev.Hash == snap.Hash {
+		return e.Snapshots.Checked(src.ID, page.FetchedAt)
+	}
+
+	diff, added, removed := lineDiff(prev.Content, text)
+
+	// The added lines are what's new on the page, so they make the item's text
+	value := strings.Join(added, "\n")
+	if value == "" {
+		value = "Removed:\n" + strings.Join(removed, "\n")
+	}
+	item := Item{
+		Title: fmt.Sprintf("%s changed (+%d/-%d lines)", src.Name, len(added), len(removed)),
+		Value: value,
+		URL:   page.URL,
+	}
+
+	row := newRow(item, &src.ID, page.URL, page.FetchedAt)
+	row.Diff = &diff
+	id, created, err := e.Items.Insert(row)
+	if err != nil {
+		return err
+	}
+	run.ItemsFound = 1
+	if created {
+		run.ItemsNew++
+		e.alert(e.loadAlertRules(&src.ID), id, row)
+	}
+
+	return e.Snapshots.Save(snap)
+}
+
+// track reads a price source's current price and adds it to the history.
+func (e *Engine) track(src *models.Source, page *Page, run *models.ScrapeRun) error {
+	if e.Prices == nil {
+		return errors.New("price sources need a price store")
+	}
+
+	cp, err := compilePrice(json.RawMessage(src.Config))
+	if err != nil {
+		return err
+	}
+	price, currency, err := cp.read(page)
+	if err != nil {
+		return err
+	}
+
+	prev, err := e.Prices.History(src.ID, 1)
+	if err != nil {
+		return err
+	}
+
+	run.ItemsFound = 1
+	err = e.Prices.Insert(&models.PricePoint{
+		SourceID:   src.ID,
+		Price:      price,
+		Currency:   models.NullString(currency),
+		ObservedAt: page.FetchedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	// A watch is read on every run, so rules fire when the price crosses into them
+	// rather than on every reading that stays there
+	reading := priceReading(src, page.URL, price, currency)
+	var rules []*AlertRule
+	for _, r := range e.loadAlertRules(&src.ID) {
+		if len(prev) > 0 {
+			before := priceReading(src, page.URL, prev[0].Price, models.StringValue(prev[0].Currency))
+			if r.Match(before.Title, before.Value, page.URL) {
+				continue
+			}
+		}
+		rules = append(rules, r)
+	}
+	e.alert(rules, 0, reading)
+	return nil
+}
+
+// priceReading presents a price watch's reading the way alert rules see items: the
+// source's name as the title and the price as the value.
+func priceReading(src *models.Source, url string, price float64, currency string) *models.ScraperItem {
+	value := strconv.FormatFloat(price, 'f', 2, 64)
+	if currency != "" {
+		value += " " + currency
+	}
+	return &models.ScraperItem{Title: src.Name, Value: value, URL: models.NullString(url), SourceID: &src.ID}
+}
+
+// Ingest stores items pushed from elsewhere (e.g. a script on another machine)
+// rather than fetched, crediting them to sourceID when it is set, in which case that
+// source's filters apply. It returns how many were new and how many the filters
+// dropped.
+func (e *Engine) Ingest(items []Item, sourceID *int) (created, dropped int, err error) {
+	var filter *ItemFilter
+	if sourceID != nil {
+		src, err := e.Sources.Get(*sourceID)
+		if err != nil {
+			return 0, 0, err
+		}
+		if filter, err = CompileItemFilter(src.Include, src.Exclude); err != nil {
+			return 0, 0, fmt.Errorf("source %q: %w", src.Name, err)
+		}
+	}
+
+	now := time.Now()
+	rules := e.loadAlertRules(sourceID)
+	for _, item := range items {
+		if !filter.Keep(item) {
+			dropped++
+			continue
+		}
+
+		row := newRow(item, sourceID, "", now)
+		id, isNew, err := e.Items.Insert(row)
+		if err != nil {
+			return created, dropped, err
+		}
+		if isNew {
+			created++
+			e.alert(rules, id, row)
+		}
+	}
+	return created, dropped, nil
+}
+
+// newRow turns an extracted item into a scraper row, filling in its excerpt.
+func newRow(item Item, sourceID *int, sourceURL string, fetchedAt time.Time) *models.ScraperItem {
+	if item.Excerpt == "" {
+		item.Excerpt = excerpt(item.Value)
+	}
+
+	row := &models.ScraperItem{
+		Title:     item.Title,
+		Value:     item.Value,
+		URL:       models.NullString(item.URL),
+		SourceID:  sourceID,
+		SourceURL: models.NullString(sourceURL),
+		Excerpt:   models.NullString(item.Excerpt),
+		ImageURL:  models.NullString(item.Image),
+		FetchedAt: fetchedAt,
+	}
+	if !item.Published.IsZero() {
+		row.PublishedAt = &item.Published
+	}
+	return row
+}
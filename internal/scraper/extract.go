@@ -0,0 +1,185 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Item is a single piece of data extracted from a page.
+type Item struct {
+	Title     string
+	Value     string
+	URL       string    // Empty when the item has no link of its own
+	Published time.Time // Zero when the source doesn't say
+	Excerpt   string    // Short summary; derived from Value when the extractor leaves it empty
+	Image     string    // Absolute URL of an associated picture, if any
+}
+
+// maxExcerpt is how many characters of text an excerpt keeps.
+const maxExcerpt = 280
+
+// excerpt shortens text to at most maxExcerpt characters, breaking between words.
+func excerpt(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= maxExcerpt {
+		return text
+	}
+
+	cut := string(runes[:maxExcerpt])
+	if i := strings.LastIndexByte(cut, ' '); i > maxExcerpt/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " .,;:") + "…"
+}
+
+// Extractor turns a fetched page into items, using the source's JSON config.
+type Extractor func(page *Page, config json.RawMessage) ([]Item, error)
+
+// extractors maps source types to their extraction strategy.
+var extractors = map[string]Extractor{
+	"html":      extractMeta,
+	"feed":      extractFeed,
+	"selectors": extractSelectors,
+}
+
+// Collector is for sources whose page only lists what to fetch next. What it returns
+// is a ranking rather than a stream, so every item is stored on each run, not only
+// those published since the last one.
+type Collector func(ctx context.Context, f *Fetcher, page *Page, config json.RawMessage) ([]Item, error)
+
+// collectors maps source types to their collection strategy.
+var collectors = map[string]Collector{
+	"hackernews": collectHN,
+}
+
+// monitorType sources aren't extracted into items; the engine watches their page
+// for changes instead.
+const monitorType = "monitor"
+
+// Types returns the source types the engine knows how to handle.
+func Types() []string {
+	return []string{"html", "feed", "selectors", "hackernews", monitorType, priceType}
+}
+
+// CheckConfig reports whether config is usable for a source of the given type, so
+// mistakes surface when the source is saved rather than on its first run.
+func CheckConfig(sourceType string, config json.RawMessage) error {
+	_, extracts := extractors[sourceType]
+	_, collects := collectors[sourceType]
+	if !extracts && !collects && sourceType != monitorType && sourceType != priceType {
+		return fmt.Errorf("unknown source type %q", sourceType)
+	}
+	if !json.Valid(config) {
+		return errors.New("config must be valid JSON")
+	}
+	switch sourceType {
+	case "selectors":
+		_, err := compileSelectors(config)
+		return err
+	case "hackernews":
+		_, err := parseHNConfig(config)
+		return err
+	case monitorType:
+		_, err := compileMonitor(config)
+		return err
+	case priceType:
+		_, err := compilePrice(config)
+		return err
+	}
+	return nil
+}
+
+// extractMeta reads a page's title and description, producing one item per fetch.
+// Handy for pages without a repeating structure, e.g. a project's landing page.
+func extractMeta(page *Page, _ json.RawMessage) ([]Item, error) {
+	doc, err := html.Parse(bytes.NewReader(page.Body))
+	if err != nil {
+		return nil, err
+	}
+
+	var title, ogTitle, description, image string
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.Title:
+				if title == "" {
+					title = textContent(n)
+				}
+			case atom.Meta:
+				key := attr(n, "property")
+				if key == "" {
+					key = attr(n, "name")
+				}
+				switch strings.ToLower(key) {
+				case "og:title":
+					ogTitle = attr(n, "content")
+				case "og:description":
+					description = attr(n, "content")
+				case "description":
+					if description == "" {
+						description = attr(n, "content")
+					}
+				case "og:image", "og:image:url", "twitter:image":
+					if image == "" {
+						image = attr(n, "content")
+					}
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	if ogTitle != "" {
+		title = ogTitle
+	}
+	if title == "" {
+		return nil, fmt.Errorf("no title found on %s", page.URL)
+	}
+
+	base, _ := url.Parse(page.URL)
+
+	return []Item{{Title: title, Value: description, URL: page.URL, Image: resolveLink(base, image)}}, nil
+}
+
+// textContent returns the whitespace-collapsed text beneath a node.
+func textContent(n *html.Node) string {
+	var b strings.Builder
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			b.WriteByte(' ')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return strings.TrimSpace(a.Val)
+		}
+	}
+	return ""
+}
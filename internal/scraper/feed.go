@@ -0,0 +1,241 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+// feedDoc covers RSS 2.0, RSS 1.0 (RDF) and Atom in one pass: each format only
+// fills the fields it uses, and the decoder matches on local names.
+type feedDoc struct {
+	XMLName xml.Name
+	Channel struct {
+		Items []feedEntry `xml:"item"`
+	} `xml:"channel"`
+	Items   []feedEntry `xml:"item"`  // RSS 1.0 puts items beside the channel
+	Entries []feedEntry `xml:"entry"` // Atom
+}
+
+type feedEntry struct {
+	Title       string      `xml:"title"`
+	Links       []feedLink  `xml:"link"`
+	GUID        string      `xml:"guid"`
+	Description string      `xml:"description"`
+	Encoded     string      `xml:"encoded"` // content:encoded
+	Summary     string      `xml:"summary"`
+	Media       []feedMedia `xml:"http://search.yahoo.com/mrss/ content"` // media:content, matched before Atom's <content>
+	Content     string      `xml:"content"`
+	PubDate     string      `xml:"pubDate"`
+	Date        string      `xml:"date"` // dc:date
+	Published   string      `xml:"published"`
+	Updated     string      `xml:"updated"`
+	Enclosures  []feedMedia `xml:"enclosure"`
+	Thumbnails  []feedMedia `xml:"thumbnail"` // media:thumbnail
+	Groups      []struct {
+		Thumbnails []feedMedia `xml:"thumbnail"`
+		Media      []feedMedia `xml:"http://search.yahoo.com/mrss/ content"`
+	} `xml:"group"` // media:group
+}
+
+// feedMedia is an RSS <enclosure> or a Media RSS thumbnail/content element.
+type feedMedia struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Medium string `xml:"medium,attr"`
+}
+
+// feedLink is an RSS <link>text</link> or an Atom <link href rel/>.
+type feedLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Text string `xml:",chardata"`
+}
+
+// feedDateLayouts are the date formats seen in the wild, RFC 822 variants first.
+var feedDateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	time.DateOnly,
+}
+
+// extractFeed reads an RSS or Atom feed, producing one item per feed entry.
+func extractFeed(page *Page, _ json.RawMessage) ([]Item, error) {
+	dec := xml.NewDecoder(bytes.NewReader(page.Body))
+	dec.CharsetReader = charset.NewReaderLabel
+	dec.Strict = false
+
+	var doc feedDoc
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parse feed %s: %w", page.URL, err)
+	}
+
+	entries := doc.Entries
+	entries = append(entries, doc.Channel.Items...)
+	entries = append(entries, doc.Items...)
+	if len(entries) == 0 && doc.XMLName.Local != "rss" && doc.XMLName.Local != "feed" && doc.XMLName.Local != "RDF" {
+		return nil, fmt.Errorf("%s is not an RSS or Atom feed", page.URL)
+	}
+
+	base, _ := url.Parse(page.URL)
+
+	var items []Item
+	for _, e := range entries {
+		item := Item{
+			Title:     strings.TrimSpace(e.Title),
+			Value:     feedText(firstNonEmpty(e.Summary, e.Description, e.Content, e.Encoded)),
+			URL:       resolveLink(base, e.link()),
+			Published: parseFeedDate(firstNonEmpty(e.Published, e.PubDate, e.Date, e.Updated)),
+			Image:     resolveLink(base, e.image()),
+		}
+		if item.Title == "" {
+			item.Title = item.URL
+		}
+		if item.Title == "" {
+			continue
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// link picks the entry's permalink: Atom's alternate link, then RSS's link text,
+// then a permalink GUID.
+func (e feedEntry) link() string {
+	for _, l := range e.Links {
+		if l.Href != "" && (l.Rel == "" || l.Rel == "alternate") {
+			return l.Href
+		}
+	}
+	for _, l := range e.Links {
+		if t := strings.TrimSpace(l.Text); t != "" {
+			return t
+		}
+	}
+	if strings.HasPrefix(e.GUID, "http://") || strings.HasPrefix(e.GUID, "https://") {
+		return strings.TrimSpace(e.GUID)
+	}
+	return ""
+}
+
+// image picks a picture for the entry: a thumbnail, then image media or enclosures,
+// then an Atom enclosure link, then the first <img> in the entry's HTML.
+func (e feedEntry) image() string {
+	thumbs, media := e.Thumbnails, append(e.Media, e.Enclosures...)
+	for _, g := range e.Groups {
+		thumbs = append(thumbs, g.Thumbnails...)
+		media = append(media, g.Media...)
+	}
+
+	for _, m := range thumbs {
+		if m.URL != "" {
+			return m.URL
+		}
+	}
+	for _, m := range media {
+		if m.URL != "" && (m.Medium == "image" || strings.HasPrefix(m.Type, "image/")) {
+			return m.URL
+		}
+	}
+	for _, l := range e.Links {
+		if l.Rel == "enclosure" && l.Href != "" {
+			return l.Href
+		}
+	}
+	return firstImage(firstNonEmpty(e.Encoded, e.Content, e.Description, e.Summary))
+}
+
+// firstImage returns the src of the first <img> in an HTML fragment.
+func firstImage(s string) string {
+	if !strings.Contains(s, "<img") {
+		return ""
+	}
+	nodes, err := html.ParseFragment(strings.NewReader(s), &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div})
+	if err != nil {
+		return ""
+	}
+
+	var find func(n *html.Node) string
+	find = func(n *html.Node) string {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Img {
+			if src := attr(n, "src"); src != "" {
+				return src
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if src := find(c); src != "" {
+				return src
+			}
+		}
+		return ""
+	}
+	for _, n := range nodes {
+		if src := find(n); src != "" {
+			return src
+		}
+	}
+	return ""
+}
+
+// resolveLink makes relative feed links absolute against the feed's own URL.
+func resolveLink(base *url.URL, link string) string {
+	if link == "" || base == nil {
+		return link
+	}
+	u, err := base.Parse(link)
+	if err != nil {
+		return link
+	}
+	return u.String()
+}
+
+func parseFeedDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range feedDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+// feedText reduces an (often HTML-escaped) summary to plain text.
+func feedText(s string) string {
+	nodes, err := html.ParseFragment(strings.NewReader(s), &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div})
+	if err != nil {
+		return strings.TrimSpace(s)
+	}
+
+	parts := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		if t := textContent(n); t != "" {
+			parts = append(parts, t)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,202 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Page is a fetched document, ready for extraction.
+type Page struct {
+	URL         string // Final URL after redirects
+	Status      int
+	ContentType string
+	Body        []byte
+	FetchedAt   time.Time
+	Attempts    int // Requests it took, 1 unless earlier ones failed transiently
+}
+
+// Fetcher pulls pages over plain HTTP.
+type Fetcher struct {
+	Client     *http.Client
+	UserAgent  string
+	MaxBytes   int64         // Bodies beyond this are truncated
+	Robots     *Robots       // robots.txt policies; nil fetches without consulting them
+	Retries    int           // Extra attempts after a transient failure (network error, 5xx, 429)
+	Backoff    time.Duration // Wait before the first retry, doubled for each one after
+	MaxBackoff time.Duration // Upper bound on a single wait, including a server's Retry-After
+}
+
+// NewFetcher returns a Fetcher with conservative defaults for a home server.
+func NewFetcher() *Fetcher {
+	return &Fetcher{
+		Client:     &http.Client{Timeout: 30 * time.Second},
+		UserAgent:  "SacrifStation/1.0 (+personal scraper)",
+		MaxBytes:   5 << 20,
+		Robots:     NewRobots(),
+		Retries:    2,
+		Backoff:    2 * time.Second,
+		MaxBackoff: 2 * time.Minute,
+	}
+}
+
+// StatusError is a response outside the 2xx range.
+type StatusError struct {
+	URL        string
+	Code       int
+	Status     string
+	RetryAfter time.Duration // From the Retry-After header, 0 if absent
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("fetch %s: unexpected status %s", e.URL, e.Status)
+}
+
+// FetchError is a fetch that failed after more than one attempt.
+type FetchError struct {
+	Attempts int
+	Err      error // From the last attempt
+}
+
+func (e *FetchError) Error() string {
+	return fmt.Sprintf("%v (gave up after %d attempts)", e.Err, e.Attempts)
+}
+
+func (e *FetchError) Unwrap() error { return e.Err }
+
+// Attempts reports how many requests a Fetch error took, 1 unless it was retried.
+func Attempts(err error) int {
+	var fe *FetchError
+	if errors.As(err, &fe) {
+		return fe.Attempts
+	}
+	return 1
+}
+
+// Fetch GETs a URL and reads its body. Non-2xx responses are errors, as are URLs
+// the site's robots.txt disallows (see ErrDisallowed). Transient failures are
+// retried up to Retries times with jittered exponential backoff; once retries have
+// been spent the error is a *FetchError.
+func (f *Fetcher) Fetch(ctx context.Context, url string) (*Page, error) {
+	for attempt := 1; ; attempt++ {
+		page, err := f.fetchOnce(ctx, url)
+		if err == nil {
+			page.Attempts = attempt
+			return page, nil
+		}
+
+		if attempt > f.Retries || !transient(ctx, err) {
+			if attempt > 1 {
+				err = &FetchError{Attempts: attempt, Err: err}
+			}
+			return nil, err
+		}
+
+		t := time.NewTimer(f.backoff(attempt, err))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, &FetchError{Attempts: attempt, Err: err}
+		case <-t.C:
+		}
+	}
+}
+
+func (f *Fetcher) fetchOnce(ctx context.Context, url string) (*Page, error) {
+	if f.Robots != nil {
+		if err := f.Robots.Wait(ctx, f, url); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", f.UserAgent)
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &StatusError{
+			URL:        url,
+			Code:       resp.StatusCode,
+			Status:     resp.Status,
+			RetryAfter: retryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.MaxBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Page{
+		URL:         resp.Request.URL.String(),
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        body,
+		FetchedAt:   time.Now(),
+	}, nil
+}
+
+// transient reports whether a failed attempt is worth repeating: network trouble and
+// server-side errors are, while client errors, robots.txt refusals and a cancelled
+// context are not.
+func transient(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, ErrDisallowed) {
+		return false
+	}
+
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Code >= 500 || se.Code == http.StatusTooManyRequests || se.Code == http.StatusRequestTimeout
+	}
+
+	// Anything else came from the transport or the body read: refused connections,
+	// timeouts, resets, DNS hiccups
+	return true
+}
+
+// backoff is the wait before retrying after the given attempt: a server's Retry-After
+// if it sent one, otherwise Backoff doubled per attempt with ±50% jitter so several
+// sources failing together don't retry in lockstep.
+func (f *Fetcher) backoff(attempt int, err error) time.Duration {
+	var d time.Duration
+
+	var se *StatusError
+	if errors.As(err, &se) && se.RetryAfter > 0 {
+		d = se.RetryAfter
+	} else {
+		d = f.Backoff << (attempt - 1)
+		d = d/2 + time.Duration(rand.Int63n(int64(d)+1))
+	}
+
+	if f.MaxBackoff > 0 && d > f.MaxBackoff {
+		d = f.MaxBackoff
+	}
+	return d
+}
+
+// retryAfter parses a Retry-After header, given either in seconds or as an HTTP date.
+func retryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
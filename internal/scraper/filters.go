@@ -0,0 +1,83 @@
+package scraper
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ItemFilter is a source's include and exclude filters, compiled. Each filter is one
+// line of text: a plain keyword matches case-insensitively anywhere in an item's
+// title, text or link, and a line wrapped in slashes is a regular expression, e.g.
+//
+//	sponsored
+//	/^\[ad\]/
+type ItemFilter struct {
+	include []func(string) bool
+	exclude []func(string) bool
+}
+
+// CompileItemFilter parses a source's include and exclude filters. With neither set
+// it returns nil, which keeps everything.
+func CompileItemFilter(include, exclude string) (*ItemFilter, error) {
+	f := &ItemFilter{}
+	var err error
+	if f.include, err = compileFilterLines("include", include); err != nil {
+		return nil, err
+	}
+	if f.exclude, err = compileFilterLines("exclude", exclude); err != nil {
+		return nil, err
+	}
+	if len(f.include) == 0 && len(f.exclude) == 0 {
+		return nil, nil
+	}
+	return f, nil
+}
+
+func compileFilterLines(kind, lines string) ([]func(string) bool, error) {
+	var out []func(string) bool
+	for _, line := range strings.Split(lines, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if len(line) > 2 && strings.HasPrefix(line, "/") && strings.HasSuffix(line, "/") {
+			re, err := regexp.Compile("(?i)" + line[1:len(line)-1])
+			if err != nil {
+				return nil, fmt.Errorf("%s filter %s: %w", kind, line, err)
+			}
+			out = append(out, re.MatchString)
+			continue
+		}
+
+		keyword := strings.ToLower(line)
+		out = append(out, func(text string) bool {
+			return strings.Contains(strings.ToLower(text), keyword)
+		})
+	}
+	return out, nil
+}
+
+// Keep reports whether an item gets past the filters: it must match an include
+// filter, if there are any, and no exclude filter. A nil filter keeps everything.
+func (f *ItemFilter) Keep(item Item) bool {
+	if f == nil {
+		return true
+	}
+
+	text := item.Title + "\n" + item.Value + "\n" + item.URL
+	if len(f.include) > 0 && !matchAny(f.include, text) {
+		return false
+	}
+	return !matchAny(f.exclude, text)
+}
+
+func matchAny(filters []func(string) bool, text string) bool {
+	for _, match := range filters {
+		if match(text) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// hnDiscussion is where a story's comments live on the site itself.
+const hnDiscussion = "https://news.ycombinator.com/item?id="
+
+// hnConfig is the config of a "hackernews" source, whose URL is one of the Hacker
+// News API story lists, e.g. https://hacker-news.firebaseio.com/v0/topstories.json
+// (or beststories, newstories, askstories, showstories).
+//
+//	{"limit": 30, "min_score": 100}
+//
+// Limit is how many stories from the top of the list to read (30 by default, at
+// most 100); stories below min_score are left out.
+type hnConfig struct {
+	Limit    int `json:"limit"`
+	MinScore int `json:"min_score"`
+}
+
+// hnStory is the part of an API item the station uses.
+type hnStory struct {
+	ID          int    `json:"id"`
+	Type        string `json:"type"`
+	By          string `json:"by"`
+	Time        int64  `json:"time"`
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	Score       int    `json:"score"`
+	Descendants int    `json:"descendants"`
+	Dead        bool   `json:"dead"`
+	Deleted     bool   `json:"deleted"`
+}
+
+func parseHNConfig(config json.RawMessage) (hnConfig, error) {
+	cfg := hnConfig{Limit: 30}
+	if len(bytes.TrimSpace(config)) > 0 {
+		if err := json.Unmarshal(config, &cfg); err != nil {
+			return cfg, fmt.Errorf("hackernews config: %w", err)
+		}
+	}
+	if cfg.Limit < 1 || cfg.Limit > 100 {
+		return cfg, errors.New("hackernews config: limit must be between 1 and 100")
+	}
+	return cfg, nil
+}
+
+// collectHN reads the story IDs on a list page, then fetches each story beside it
+// in the API (…/v0/item/<id>.json) and maps it to an item. A story that can't be
+// fetched is skipped rather than failing the run.
+func collectHN(ctx context.Context, f *Fetcher, page *Page, config json.RawMessage) ([]Item, error) {
+	cfg, err := parseHNConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int
+	if err := json.Unmarshal(page.Body, &ids); err != nil {
+		return nil, fmt.Errorf("not a Hacker News story list: %w", err)
+	}
+	if len(ids) > cfg.Limit {
+		ids = ids[:cfg.Limit]
+	}
+
+	base, err := url.Parse(page.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		items  []Item
+		failed int
+	)
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		story, err := fetchHNStory(ctx, f, base.JoinPath("..", "item", strconv.Itoa(id)+".json").String())
+		if err != nil {
+			slog.Warn("Hacker News story failed", "story", id, "err", err)
+			failed++
+			continue
+		}
+		if story.Dead || story.Deleted || story.Title == "" || story.Score < cfg.MinScore {
+			continue
+		}
+		items = append(items, story.item())
+	}
+
+	if failed > 0 && failed == len(ids) {
+		return nil, fmt.Errorf("all %d stories failed to fetch", failed)
+	}
+	return items, nil
+}
+
+func fetchHNStory(ctx context.Context, f *Fetcher, storyURL string) (*hnStory, error) {
+	page, err := f.Fetch(ctx, storyURL)
+	if err != nil {
+		return nil, err
+	}
+
+	story := &hnStory{}
+	if err := json.Unmarshal(page.Body, story); err != nil {
+		return nil, err
+	}
+	return story, nil
+}
+
+// item maps a story to an item. Text posts (Ask HN and the like) have no link of
+// their own, so they link to the discussion.
+func (s *hnStory) item() Item {
+	discussion := hnDiscussion + strconv.Itoa(s.ID)
+
+	link := s.URL
+	if link == "" {
+		link = discussion
+	}
+
+	return Item{
+		Title:     s.Title,
+		Value:     fmt.Sprintf("%d points by %s · %d comments\n%s", s.Score, s.By, s.Descendants, discussion),
+		URL:       link,
+		Published: time.Unix(s.Time, 0),
+		Excerpt:   fmt.Sprintf("%d points · %d comments", s.Score, s.Descendants),
+	}
+}
@@ -0,0 +1,142 @@
+package scraper
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// monitorConfig is the config of a "monitor" source, which watches one page for
+// changes instead of collecting items from it, e.g. a project's releases page.
+// Selector narrows the watch to part of the page; Ignore drops lines matching any of
+// its patterns, for counters and timestamps that change on every load.
+//
+//	{"selector": "main .releases", "ignore": ["^Last updated", "\\d+ views"]}
+type monitorConfig struct {
+	Selector string   `json:"selector"`
+	Ignore   []string `json:"ignore"`
+}
+
+type compiledMonitor struct {
+	sel    cascadia.Sel // nil watches the whole body
+	ignore []*regexp.Regexp
+}
+
+// compileMonitor parses and checks a monitor config; an empty config watches the
+// whole page.
+func compileMonitor(config json.RawMessage) (*compiledMonitor, error) {
+	var cfg monitorConfig
+	if len(bytes.TrimSpace(config)) > 0 {
+		if err := json.Unmarshal(config, &cfg); err != nil {
+			return nil, fmt.Errorf("monitor config: %w", err)
+		}
+	}
+
+	c := &compiledMonitor{}
+	if cfg.Selector != "" {
+		sel, err := cascadia.Parse(cfg.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("monitor config: selector %q: %w", cfg.Selector, err)
+		}
+		c.sel = sel
+	}
+	for _, pattern := range cfg.Ignore {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("monitor config: ignore %q: %w", pattern, err)
+		}
+		c.ignore = append(c.ignore, re)
+	}
+	return c, nil
+}
+
+// pageText reduces a page to the text a reader sees, one line per block of text
+// with whitespace collapsed, so that markup and formatting churn don't count as
+// changes.
+func (c *compiledMonitor) pageText(page *Page) (string, error) {
+	doc, err := html.Parse(bytes.NewReader(page.Body))
+	if err != nil {
+		return "", err
+	}
+
+	roots := []*html.Node{doc}
+	if c.sel != nil {
+		if roots = cascadia.QueryAll(doc, c.sel); len(roots) == 0 {
+			return "", errors.New("monitor selector matched nothing")
+		}
+	}
+
+	var lines []string
+	var line strings.Builder
+	flush := func() {
+		if text := strings.Join(strings.Fields(line.String()), " "); text != "" && !c.ignored(text) {
+			lines = append(lines, text)
+		}
+		line.Reset()
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			line.WriteString(n.Data)
+			return
+		case html.ElementNode:
+			switch n.DataAtom {
+			case atom.Script, atom.Style, atom.Noscript, atom.Template, atom.Svg, atom.Head:
+				return
+			}
+		}
+
+		block := n.Type == html.ElementNode && !inlineElements[n.DataAtom]
+		if block {
+			flush()
+		}
+		for ch := n.FirstChild; ch != nil; ch = ch.NextSibling {
+			walk(ch)
+		}
+		if block {
+			flush()
+		}
+	}
+	for _, root := range roots {
+		walk(root)
+	}
+	flush()
+
+	return strings.Join(lines, "\n"), nil
+}
+
+func (c *compiledMonitor) ignored(line string) bool {
+	for _, re := range c.ignore {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// inlineElements continue the line of text they sit in; every other element starts
+// a new one.
+var inlineElements = map[atom.Atom]bool{
+	atom.A: true, atom.Abbr: true, atom.B: true, atom.Bdi: true, atom.Bdo: true, atom.Cite: true,
+	atom.Code: true, atom.Data: true, atom.Del: true, atom.Dfn: true, atom.Em: true, atom.I: true,
+	atom.Ins: true, atom.Kbd: true, atom.Mark: true, atom.Q: true, atom.S: true, atom.Samp: true,
+	atom.Small: true, atom.Span: true, atom.Strong: true, atom.Sub: true, atom.Sup: true,
+	atom.Time: true, atom.U: true, atom.Var: true, atom.Label: true,
+}
+
+// textHash fingerprints normalized page text.
+func textHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,96 @@
+package scraper
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+// opmlDoc is an OPML 1.0 or 2.0 subscription list, as feed readers export them.
+type opmlDoc struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    struct {
+		Title       string `xml:"title"`
+		DateCreated string `xml:"dateCreated,omitempty"`
+	} `xml:"head"`
+	Outlines []opmlOutline `xml:"body>outline"`
+}
+
+// opmlOutline is a feed when it has an xmlUrl, and otherwise a folder of them.
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string        `xml:"htmlUrl,attr,omitempty"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// Subscription is one feed in an OPML file.
+type Subscription struct {
+	Title string
+	URL   string // Of the feed itself
+	Site  string // Of the site it belongs to, when the file says
+}
+
+// ReadOPML lists the feeds in an OPML file, folders flattened in document order.
+// Outlines without an http(s) xmlUrl are skipped.
+func ReadOPML(r io.Reader) ([]Subscription, error) {
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = charset.NewReaderLabel
+	dec.Strict = false
+
+	var doc opmlDoc
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parse OPML: %w", err)
+	}
+
+	var subs []Subscription
+	var walk func([]opmlOutline)
+	walk = func(outlines []opmlOutline) {
+		for _, o := range outlines {
+			walk(o.Outlines)
+			u, err := url.Parse(strings.TrimSpace(o.XMLURL))
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				continue
+			}
+			title := strings.TrimSpace(o.Title)
+			if title == "" {
+				title = strings.TrimSpace(o.Text)
+			}
+			if title == "" {
+				title = u.Host
+			}
+			subs = append(subs, Subscription{Title: title, URL: u.String(), Site: strings.TrimSpace(o.HTMLURL)})
+		}
+	}
+	walk(doc.Outlines)
+	return subs, nil
+}
+
+// WriteOPML writes subscriptions as an OPML 2.0 file a feed reader can import.
+func WriteOPML(w io.Writer, title string, subs []Subscription) error {
+	doc := opmlDoc{Version: "2.0"}
+	doc.Head.Title = title
+	doc.Head.DateCreated = time.Now().UTC().Format(time.RFC1123Z)
+	for _, s := range subs {
+		doc.Outlines = append(doc.Outlines, opmlOutline{Text: s.Title, Title: s.Title, Type: "rss", XMLURL: s.URL, HTMLURL: s.Site})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,193 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// priceType sources read one price from their page on every run and keep the
+// history instead of producing items.
+const priceType = "price"
+
+// priceConfig is the config of a "price" source. Price is a selector rule like the
+// ones "selectors" sources use, reading the element's content attribute when it has
+// one and its text otherwise; without it the price comes from the page's product
+// markup (schema.org JSON-LD or microdata, or Open Graph product tags). Currency
+// overrides whatever the page says.
+//
+//	{"price": ".product .price", "currency": "EUR"}
+type priceConfig struct {
+	Price    string `json:"price"`
+	Currency string `json:"currency"`
+}
+
+type compiledPrice struct {
+	rule     *selectorRule // nil reads the product markup
+	currency string
+}
+
+func compilePrice(config json.RawMessage) (*compiledPrice, error) {
+	var cfg priceConfig
+	if len(bytes.TrimSpace(config)) > 0 {
+		if err := json.Unmarshal(config, &cfg); err != nil {
+			return nil, fmt.Errorf("price config: %w", err)
+		}
+	}
+
+	c := &compiledPrice{currency: strings.TrimSpace(cfg.Currency)}
+	if cfg.Price != "" {
+		rule, err := compileRule(cfg.Price, "content")
+		if err != nil {
+			return nil, fmt.Errorf("price config: price %q: %w", cfg.Price, err)
+		}
+		c.rule = rule
+	}
+	return c, nil
+}
+
+// read finds the price on a page, along with its currency when known.
+func (c *compiledPrice) read(page *Page) (float64, string, error) {
+	doc, err := html.Parse(bytes.NewReader(page.Body))
+	if err != nil {
+		return 0, "", err
+	}
+
+	var raw, currency string
+	if c.rule != nil {
+		raw = c.rule.eval(doc)
+	} else {
+		raw, currency = productPrice(doc)
+	}
+	if raw == "" {
+		return 0, "", errors.New("no price found on the page")
+	}
+
+	price, symbol, err := parsePrice(raw)
+	if err != nil {
+		return 0, "", err
+	}
+	if currency == "" {
+		currency = symbol
+	}
+	if c.currency != "" {
+		currency = c.currency
+	}
+	return price, currency, nil
+}
+
+// Product markup, in the order it is trusted.
+var (
+	selPriceMicrodata = cascadia.MustCompile(`[itemprop="price"]`)
+	selCurrencyMicro  = cascadia.MustCompile(`[itemprop="priceCurrency"]`)
+	selPriceOG        = cascadia.MustCompile(`meta[property="product:price:amount"], meta[property="og:price:amount"]`)
+	selCurrencyOG     = cascadia.MustCompile(`meta[property="product:price:currency"], meta[property="og:price:currency"]`)
+	selJSONLD         = cascadia.MustCompile(`script[type="application/ld+json"]`)
+)
+
+// productPrice reads the price a page declares for search engines.
+func productPrice(doc *html.Node) (price, currency string) {
+	for _, n := range cascadia.QueryAll(doc, selJSONLD) {
+		if n.FirstChild == nil {
+			continue
+		}
+		var v any
+		if json.Unmarshal([]byte(n.FirstChild.Data), &v) == nil {
+			if price, currency = jsonLDPrice(v); price != "" {
+				return price, currency
+			}
+		}
+	}
+
+	read := func(n *html.Node) string {
+		if n == nil {
+			return ""
+		}
+		if n.DataAtom == atom.Meta || attr(n, "content") != "" {
+			return attr(n, "content")
+		}
+		return textContent(n)
+	}
+	if price = read(cascadia.Query(doc, selPriceMicrodata)); price != "" {
+		return price, read(cascadia.Query(doc, selCurrencyMicro))
+	}
+	return read(cascadia.Query(doc, selPriceOG)), read(cascadia.Query(doc, selCurrencyOG))
+}
+
+// jsonLDPrice looks through JSON-LD for the first offer with a price.
+func jsonLDPrice(v any) (price, currency string) {
+	switch v := v.(type) {
+	case []any:
+		for _, el := range v {
+			if price, currency = jsonLDPrice(el); price != "" {
+				return price, currency
+			}
+		}
+	case map[string]any:
+		if p, ok := v["price"]; ok {
+			if price = jsonScalar(p); price != "" {
+				return price, jsonScalar(v["priceCurrency"])
+			}
+		}
+		if p, ok := v["lowPrice"]; ok {
+			if price = jsonScalar(p); price != "" {
+				return price, jsonScalar(v["priceCurrency"])
+			}
+		}
+		for _, key := range []string{"offers", "@graph", "priceSpecification"} {
+			if el, ok := v[key]; ok {
+				if price, currency = jsonLDPrice(el); price != "" {
+					return price, currency
+				}
+			}
+		}
+	}
+	return "", ""
+}
+
+func jsonScalar(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+var (
+	reAmount   = regexp.MustCompile(`\d[\d.,' \x{a0}\x{202f}]*`)
+	reCurrency = regexp.MustCompile(`[$€£¥₹₩₽]|\b[A-Z]{3}\b`)
+)
+
+// parsePrice reads the first amount in text, whichever way its digits are grouped:
+// "1,299.99", "1.299,99", "1 299,99" and "1'299.99" all read as 1299.99. The
+// currency symbol or code next to it is returned when there is one.
+func parsePrice(text string) (float64, string, error) {
+	amount := strings.TrimRight(reAmount.FindString(text), ".,' \u00a0\u202f")
+	if amount == "" {
+		return 0, "", fmt.Errorf("no price in %q", text)
+	}
+
+	// The last separator is the decimal point when one or two digits follow it
+	digits := strings.NewReplacer("'", "", " ", "", "\u00a0", "", "\u202f", "").Replace(amount)
+	if i := strings.LastIndexAny(digits, ".,"); i >= 0 && len(digits)-i-1 <= 2 {
+		digits = strings.NewReplacer(".", "", ",", "").Replace(digits[:i]) + "." + digits[i+1:]
+	} else {
+		digits = strings.NewReplacer(".", "", ",", "").Replace(digits)
+	}
+
+	price, err := strconv.ParseFloat(digits, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("no price in %q", text)
+	}
+	return price, reCurrency.FindString(text), nil
+}
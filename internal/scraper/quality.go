@@ -0,0 +1,53 @@
+package scraper
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+var (
+	// Common UTF-8 text decoded as Latin-1 or Windows-1252 on the way in, e.g. "Ã©" for "é"
+	mojibake = regexp.MustCompile(`Ã[\x{80}-\x{BF}]|â€|Â[\x{A0}-\x{BF}]`)
+
+	// Entities an extractor should have decoded; seeing them means the text was escaped twice
+	rawEntity = regexp.MustCompile(`&(?:amp|lt|gt|quot|apos|nbsp|#[0-9]{2,6}|#[xX][0-9a-fA-F]{2,5});`)
+)
+
+// measureQuality tallies how many of a run's items look broken.
+func measureQuality(items []Item) *models.ExtractionQuality {
+	q := &models.ExtractionQuality{}
+	for _, item := range items {
+		flagged := false
+		if strings.TrimSpace(item.Title) == "" {
+			q.EmptyTitles++
+			flagged = true
+		}
+		if truncated(item.Value) {
+			q.Truncated++
+			flagged = true
+		}
+		if garbled(item.Title) || garbled(item.Value) {
+			q.Garbled++
+			flagged = true
+		}
+		if flagged {
+			q.Flagged++
+		}
+	}
+	return q
+}
+
+// truncated reports whether a value is blank or has been cut off with an ellipsis.
+func truncated(s string) bool {
+	s = strings.TrimRight(strings.TrimSpace(s), "])")
+	return s == "" || strings.HasSuffix(s, "…") || strings.HasSuffix(s, "...")
+}
+
+// garbled reports whether text shows signs of an encoding mix-up.
+func garbled(s string) bool {
+	return !utf8.ValidString(s) || strings.ContainsRune(s, utf8.RuneError) ||
+		mojibake.MatchString(s) || rawEntity.MatchString(s)
+}
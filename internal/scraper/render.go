@@ -0,0 +1,21 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+)
+
+// Renderer loads pages in a headless browser, for sources whose content is built
+// client-side and so missing from the HTML a plain fetch sees.
+type Renderer interface {
+	// Render loads a URL, lets its scripts run and returns the resulting DOM as the
+	// page body.
+	Render(ctx context.Context, url string) (*Page, error)
+	// Close shuts the browser down.
+	Close() error
+}
+
+// ErrNoBrowser means pages can't be rendered: the binary was built without the
+// chromedp tag, or no Chrome or Chromium could be started. Sources asking for
+// rendering are fetched over plain HTTP instead.
+var ErrNoBrowser = errors.New("headless browser unavailable")
@@ -0,0 +1,138 @@
+//go:build chromedp
+
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// Browser renders pages in a headless Chrome, found on the PATH or in the usual
+// install locations. It is started on the first Render, so a station without
+// rendered sources never launches it, and each page gets a tab of its own.
+type Browser struct {
+	UserAgent string
+	Timeout   time.Duration // For one page, scripts included
+	Settle    time.Duration // Extra wait after load for late scripts to finish drawing
+	MaxBytes  int           // DOMs beyond this are truncated
+
+	mu      sync.Mutex
+	ctx     context.Context // Root browser context, nil until started
+	cancels []context.CancelFunc
+}
+
+// NewBrowser returns a headless Chrome renderer with defaults for a home server.
+func NewBrowser(userAgent string) (Renderer, error) {
+	return &Browser{UserAgent: userAgent, Timeout: 45 * time.Second, Settle: time.Second, MaxBytes: 5 << 20}, nil
+}
+
+// start launches Chrome unless it is already running.
+func (b *Browser) start() (context.Context, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.ctx != nil && b.ctx.Err() == nil {
+		return b.ctx, nil
+	}
+	// A browser that crashed or was closed is cleaned up and replaced
+	for _, cancel := range b.cancels {
+		cancel()
+	}
+
+	opts := append(chromedp.DefaultExecAllocatorOptions[:], chromedp.UserAgent(b.UserAgent))
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), opts...)
+	ctx, cancelBrowser := chromedp.NewContext(allocCtx)
+
+	// Running no actions still launches the browser, which is where a missing Chrome shows up
+	if err := chromedp.Run(ctx); err != nil {
+		cancelBrowser()
+		cancelAlloc()
+		return nil, fmt.Errorf("%w: %v", ErrNoBrowser, err)
+	}
+
+	b.ctx, b.cancels = ctx, []context.CancelFunc{cancelBrowser, cancelAlloc}
+	return ctx, nil
+}
+
+// Render loads a URL in a fresh tab and returns its DOM once scripts have settled.
+// The page's own response status still counts: non-2xx answers are StatusErrors.
+func (b *Browser) Render(ctx context.Context, url string) (*Page, error) {
+	root, err := b.start()
+	if err != nil {
+		return nil, err
+	}
+
+	tab, cancelTab := chromedp.NewContext(root)
+	defer cancelTab()
+	tab, cancelTimeout := context.WithTimeout(tab, b.Timeout)
+	defer cancelTimeout()
+
+	// Closing the tab when the caller gives up, since the tab's context doesn't descend from ctx
+	stop := context.AfterFunc(ctx, cancelTab)
+	defer stop()
+
+	var (
+		mu     sync.Mutex
+		status int
+		ctype  string
+	)
+	chromedp.ListenTarget(tab, func(ev any) {
+		if res, ok := ev.(*network.EventResponseReceived); ok && res.Type == network.ResourceTypeDocument {
+			mu.Lock()
+			defer mu.Unlock()
+			// The last document response is the one after redirects
+			status, ctype = int(res.Response.Status), res.Response.MimeType
+		}
+	})
+
+	var location, dom string
+	err = chromedp.Run(tab,
+		chromedp.Navigate(url),
+		chromedp.WaitReady("body", chromedp.ByQuery),
+		chromedp.Sleep(b.Settle),
+		chromedp.Location(&location),
+		chromedp.OuterHTML("html", &dom, chromedp.ByQuery),
+	)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("render %s: %w", url, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if status != 0 && (status < 200 || status > 299) {
+		return nil, &StatusError{URL: url, Code: status, Status: fmt.Sprintf("%d %s", status, http.StatusText(status))}
+	}
+	if len(dom) > b.MaxBytes {
+		dom = dom[:b.MaxBytes]
+	}
+
+	return &Page{
+		URL:         location,
+		Status:      status,
+		ContentType: ctype,
+		Body:        []byte(dom),
+		FetchedAt:   time.Now(),
+		Attempts:    1,
+	}, nil
+}
+
+// Close shuts Chrome down if it was started.
+func (b *Browser) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, cancel := range b.cancels {
+		cancel()
+	}
+	b.ctx, b.cancels = nil, nil
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build !chromedp
+
+package scraper
+
+// NewBrowser would return a headless Chrome renderer, but this binary was built
+// without one; build with -tags chromedp to get it.
+func NewBrowser(userAgent string) (Renderer, error) {
+	return nil, ErrNoBrowser
+}
@@ -0,0 +1,276 @@
+package scraper
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrDisallowed is returned when a site's robots.txt forbids fetching a URL.
+var ErrDisallowed = errors.New("scraper: disallowed by robots.txt")
+
+// Robots fetches, caches and applies robots.txt policies per host, including
+// spacing requests to a host out by its Crawl-delay.
+type Robots struct {
+	TTL      time.Duration // How long a fetched robots.txt is trusted
+	ErrorTTL time.Duration // How long an unreachable robots.txt blocks the host before retrying
+	MaxDelay time.Duration // Crawl-delays above this are capped, so one site can't stall a run forever
+
+	mu    sync.Mutex
+	hosts map[string]*robotsHost
+}
+
+// NewRobots returns a policy cache that re-reads robots.txt daily.
+func NewRobots() *Robots {
+	return &Robots{
+		TTL:      24 * time.Hour,
+		ErrorTTL: 30 * time.Minute,
+		MaxDelay: time.Minute,
+	}
+}
+
+type robotsHost struct {
+	mu      sync.Mutex // Held while fetching the policy and while waiting out the crawl-delay
+	policy  *robotsPolicy
+	expires time.Time
+	next    time.Time // Earliest time the next request to this host may start
+}
+
+// robotsPolicy is the group of a robots.txt that applies to us.
+type robotsPolicy struct {
+	rules []robotsRule
+	delay time.Duration
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// disallowAll is what an unreachable or failing robots.txt means: stay away for now.
+var disallowAll = &robotsPolicy{rules: []robotsRule{{allow: false, pattern: "/"}}}
+
+// Wait blocks until f may fetch target: it loads the host's policy if it isn't cached,
+// returns ErrDisallowed if the path is off limits, and otherwise sleeps out whatever
+// remains of the host's crawl-delay.
+func (rb *Robots) Wait(ctx context.Context, f *Fetcher, target string) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil
+	}
+
+	h := rb.host(u.Scheme + "://" + u.Host)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.policy == nil || time.Now().After(h.expires) {
+		h.policy, h.expires = rb.load(ctx, f, u)
+	}
+
+	if !h.policy.allowed(u.EscapedPath(), u.RawQuery) {
+		return fmt.Errorf("fetch %s: %w", target, ErrDisallowed)
+	}
+
+	if wait := time.Until(h.next); wait > 0 {
+		t := time.NewTimer(wait)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+
+	delay := h.policy.delay
+	if rb.MaxDelay > 0 && delay > rb.MaxDelay {
+		delay = rb.MaxDelay
+	}
+	h.next = time.Now().Add(delay)
+
+	return nil
+}
+
+func (rb *Robots) host(origin string) *robotsHost {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	if rb.hosts == nil {
+		rb.hosts = map[string]*robotsHost{}
+	}
+	h, ok := rb.hosts[origin]
+	if !ok {
+		h = &robotsHost{}
+		rb.hosts[origin] = h
+	}
+	return h
+}
+
+// load fetches and parses a host's robots.txt. Following the usual convention, a
+// missing file (any 4xx) allows everything, while server errors and network failures
+// block the host until ErrorTTL has passed.
+func (rb *Robots) load(ctx context.Context, f *Fetcher, u *url.URL) (*robotsPolicy, time.Time) {
+	robotsURL := u.Scheme + "://" + u.Host + "/robots.txt"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return disallowAll, time.Now().Add(rb.ErrorTTL)
+	}
+	req.Header.Set("User-Agent", f.UserAgent)
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return disallowAll, time.Now().Add(rb.ErrorTTL)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		// The spec asks crawlers to read at least 500 KiB
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 512<<10))
+		if err != nil {
+			return disallowAll, time.Now().Add(rb.ErrorTTL)
+		}
+		return parseRobots(body, userAgentToken(f.UserAgent)), time.Now().Add(rb.TTL)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return disallowAll, time.Now().Add(rb.ErrorTTL)
+	default:
+		return &robotsPolicy{}, time.Now().Add(rb.TTL)
+	}
+}
+
+// userAgentToken is the product name robots.txt groups match against, e.g.
+// "sacrifstation" for "SacrifStation/1.0 (+personal scraper)".
+func userAgentToken(ua string) string {
+	token, _, _ := strings.Cut(ua, "/")
+	return strings.ToLower(strings.TrimSpace(token))
+}
+
+// parseRobots picks the group addressed to agent, falling back to the "*" group.
+// Consecutive User-agent lines share the rules that follow them.
+func parseRobots(body []byte, agent string) *robotsPolicy {
+	var (
+		ours, star *robotsPolicy
+		current    []*robotsPolicy // Groups the lines being read belong to
+		inRules    bool            // A rule has been seen since the last User-agent line
+	)
+
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if inRules {
+				current, inRules = nil, false
+			}
+			name := strings.ToLower(value)
+			switch {
+			case name == "*":
+				if star == nil {
+					star = &robotsPolicy{}
+				}
+				current = append(current, star)
+			case name != "" && strings.Contains(agent, name):
+				if ours == nil {
+					ours = &robotsPolicy{}
+				}
+				current = append(current, ours)
+			default:
+				// Someone else's group; its rules still end our run of User-agent lines
+				current = append(current, &robotsPolicy{})
+			}
+		case "allow", "disallow":
+			inRules = true
+			// An empty Disallow means "nothing is disallowed" and adds no rule
+			if value == "" {
+				continue
+			}
+			for _, p := range current {
+				p.rules = append(p.rules, robotsRule{allow: key == "allow", pattern: value})
+			}
+		case "crawl-delay":
+			inRules = true
+			secs, err := strconv.ParseFloat(value, 64)
+			if err != nil || secs < 0 {
+				continue
+			}
+			for _, p := range current {
+				p.delay = time.Duration(secs * float64(time.Second))
+			}
+		}
+	}
+
+	if ours != nil {
+		return ours
+	}
+	if star != nil {
+		return star
+	}
+	return &robotsPolicy{}
+}
+
+// allowed applies the longest matching rule to a path; on a tie Allow wins.
+func (p *robotsPolicy) allowed(path, query string) bool {
+	if path == "" {
+		path = "/"
+	}
+	if query != "" {
+		path += "?" + query
+	}
+
+	best, allow := -1, true
+	for _, r := range p.rules {
+		if !matchRobots(r.pattern, path) {
+			continue
+		}
+		if n := len(r.pattern); n > best || (n == best && r.allow) {
+			best, allow = n, r.allow
+		}
+	}
+	return allow
+}
+
+// matchRobots reports whether path matches a robots.txt pattern, where "*" matches
+// any run of characters and a trailing "$" anchors the end of the path.
+func matchRobots(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+
+	for i, part := range parts[1:] {
+		// The last literal part has to sit at the very end of an anchored pattern
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(rest, part)
+		}
+		idx := strings.Index(rest, part)
+		if idx < 0 {
+			return false
+		}
+		rest = rest[idx+len(part):]
+	}
+
+	return !anchored || rest == ""
+}
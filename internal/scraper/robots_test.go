@@ -0,0 +1,237 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMatchRobots(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"/", "/anything", true},
+		{"/private", "/private", true},
+		{"/private", "/private/deeper", true},
+		{"/private", "/privately", true}, // Plain patterns are prefixes
+		{"/private", "/public", false},
+		{"/private", "/Private", false}, // Paths are case-sensitive
+		{"/*.php", "/index.php", true},
+		{"/*.php", "/dir/page.php?x=1", true},
+		{"/*.php", "/index.html", false},
+		{"/*.php$", "/index.php", true},
+		{"/*.php$", "/index.php?x=1", false},
+		{"/*.php$", "/index.phpx", false},
+		{"/page$", "/page", true},
+		{"/page$", "/page/", false},
+		{"/a*b*c", "/axxbyyc", true},
+		{"/a*b*c", "/axxcyyb", false},
+		{"/a*$", "/abc", true},
+		{"/ab*b$", "/ab", false}, // The anchored tail can't reuse the prefix
+		{"*/search", "/en/search", true},
+		{"/*?sort=", "/list?sort=new", true},
+		{"/*?sort=", "/list?page=2", false},
+	}
+	for _, tt := range tests {
+		if got := matchRobots(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("matchRobots(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRobotsAllowed(t *testing.T) {
+	body := []byte(`
+User-agent: *
+Disallow: /shop
+Allow: /shop/catalogue
+Disallow: /shop/catalogue/drafts
+Allow: /tie
+Disallow: /tie
+Disallow: /*.pdf$
+Disallow: /*?session=
+Disallow:
+`)
+	p := parseRobots(body, "sacrifstation")
+
+	tests := []struct {
+		path, query string
+		want        bool
+	}{
+		{"/", "", true},
+		{"", "", true},
+		{"/shop", "", false},
+		{"/shop/cart", "", false},
+		{"/shop/catalogue", "", true},             // The longer Allow wins
+		{"/shop/catalogue/books", "", true},       // ...for everything under it
+		{"/shop/catalogue/drafts/new", "", false}, // ...until a longer Disallow
+		{"/tie", "", true},                        // Equal length: Allow wins
+		{"/files/manual.pdf", "", false},
+		{"/files/manual.pdf", "download=1", true}, // $ anchors past the query too
+		{"/list", "session=abc", false},
+		{"/list", "page=2", true},
+	}
+	for _, tt := range tests {
+		if got := p.allowed(tt.path, tt.query); got != tt.want {
+			t.Errorf("allowed(%q, %q) = %v, want %v", tt.path, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseRobotsGroups(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		blocked   []string
+		open      []string
+		wantDelay time.Duration
+	}{
+		{
+			name:    "empty file allows everything",
+			body:    "",
+			open:    []string{"/", "/anything"},
+			blocked: nil,
+		},
+		{
+			name:    "comments and junk only",
+			body:    "# nothing here\nnot a directive\n",
+			open:    []string{"/"},
+			blocked: nil,
+		},
+		{
+			name: "our group beats the star group",
+			body: `User-agent: *
+Disallow: /
+
+User-agent: SacrifStation
+Disallow: /private
+Crawl-delay: 2.5`,
+			open:      []string{"/", "/public"},
+			blocked:   []string{"/private"},
+			wantDelay: 2500 * time.Millisecond,
+		},
+		{
+			name: "star group when ours is missing",
+			body: `User-agent: Googlebot
+Disallow: /
+
+User-agent: *
+Disallow: /tmp
+Crawl-delay: 1`,
+			open:      []string{"/", "/google-only"},
+			blocked:   []string{"/tmp/x"},
+			wantDelay: time.Second,
+		},
+		{
+			name: "stacked user-agent lines share their rules",
+			body: `User-agent: otherbot
+User-agent: sacrifstation
+Disallow: /shared
+
+User-agent: *
+Disallow: /`,
+			open:    []string{"/"},
+			blocked: []string{"/shared"},
+		},
+		{
+			name: "a later user-agent line starts a new group",
+			body: `User-agent: sacrifstation
+Disallow: /ours
+
+User-agent: otherbot
+Disallow: /theirs`,
+			open:    []string{"/theirs"},
+			blocked: []string{"/ours"},
+		},
+		{
+			name: "someone else's group only",
+			body: `User-agent: otherbot
+Disallow: /`,
+			open: []string{"/"},
+		},
+		{
+			name: "groups addressed twice are merged",
+			body: `User-agent: sacrifstation
+Disallow: /a
+
+User-agent: otherbot
+Disallow: /b
+
+User-agent: sacrifstation
+Disallow: /c`,
+			open:    []string{"/b"},
+			blocked: []string{"/a", "/c"},
+		},
+		{
+			name: "directives are case-insensitive, with trailing comments",
+			body: `USER-AGENT: *
+DISALLOW: /x # not for crawlers`,
+			blocked: []string{"/x"},
+		},
+	}
+	for _, tt := range tests {
+		p := parseRobots([]byte(tt.body), "sacrifstation")
+		for _, path := range tt.open {
+			if !p.allowed(path, "") {
+				t.Errorf("%s: %s disallowed", tt.name, path)
+			}
+		}
+		for _, path := range tt.blocked {
+			if p.allowed(path, "") {
+				t.Errorf("%s: %s allowed", tt.name, path)
+			}
+		}
+		if p.delay != tt.wantDelay {
+			t.Errorf("%s: crawl-delay %s, want %s", tt.name, p.delay, tt.wantDelay)
+		}
+	}
+}
+
+func TestUserAgentToken(t *testing.T) {
+	if got := userAgentToken("SacrifStation/1.0 (+personal scraper)"); got != "sacrifstation" {
+		t.Errorf("userAgentToken = %q", got)
+	}
+}
+
+// Wait goes by the status robots.txt is served with: a missing file allows
+// everything, a failing server blocks the host for now.
+func TestRobotsWaitByStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		path    string
+		allowed bool
+	}{
+		{"served", http.StatusOK, "User-agent: *\nDisallow: /private\n", "/private/x", false},
+		{"served, path open", http.StatusOK, "User-agent: *\nDisallow: /private\n", "/public", true},
+		{"empty file", http.StatusOK, "", "/anything", true},
+		{"not found", http.StatusNotFound, "", "/anything", true},
+		{"forbidden", http.StatusForbidden, "", "/anything", true},
+		{"server error", http.StatusInternalServerError, "", "/anything", false},
+		{"rate limited", http.StatusTooManyRequests, "", "/anything", false},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/robots.txt" {
+				t.Errorf("%s: fetched %s", tt.name, r.URL.Path)
+			}
+			w.WriteHeader(tt.status)
+			w.Write([]byte(tt.body))
+		}))
+
+		f := NewFetcher()
+		f.Client = srv.Client()
+		err := f.Robots.Wait(context.Background(), f, srv.URL+tt.path)
+		switch {
+		case tt.allowed && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case !tt.allowed && !errors.Is(err, ErrDisallowed):
+			t.Errorf("%s: got %v, want ErrDisallowed", tt.name, err)
+		}
+		srv.Close()
+	}
+}
@@ -0,0 +1,258 @@
+package scraper
+
+import (
+	"context"
+	"log/slog"
+	"math/rand"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/jobs"
+	"github.com/federicopalou/sacrif-station/internal/models"
+)
+
+// Scheduler runs every source on its own interval inside the server process.
+// Next-run times are jittered so sources sharing an interval don't all fire at once.
+// Due sources are scraped by a bounded pool of workers, one host at a time each, so
+// a slow site only holds up the other sources on that site.
+type Scheduler struct {
+	Engine  *Engine
+	Tick    time.Duration // How often sources are checked for due-ness
+	Jitter  float64       // Fraction of each interval to randomise by, e.g. 0.1 for ±10%
+	Workers int           // Hosts scraped at once; 0 means 4
+	Window  *jobs.Window  // Time of day scheduled runs may start; nil means any time
+
+	mu       sync.Mutex
+	next     map[int]time.Time
+	busy     map[string]bool // Hosts being scraped right now
+	slots    chan struct{}
+	wg       sync.WaitGroup
+	tick     time.Duration
+	lastTick time.Time // When runDue last looked for due sources
+}
+
+// SchedulerStatus is a snapshot of what the scheduler is up to, for health checks.
+type SchedulerStatus struct {
+	Running    bool      // Run has started and is still ticking
+	Stalled    bool      // Started, but hasn't ticked for three intervals
+	LastTick   time.Time // Zero before the first tick
+	Hosts      int       // Hosts being scraped right now
+	WindowOpen bool      // Scheduled runs may start now
+}
+
+// Status reports whether the scheduler is alive and how busy it is.
+func (s *Scheduler) Status() SchedulerStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st := SchedulerStatus{LastTick: s.lastTick, Hosts: len(s.busy), WindowOpen: s.Window.Open(time.Now())}
+	if !s.lastTick.IsZero() {
+		st.Stalled = time.Since(s.lastTick) > 3*s.tick
+		st.Running = !st.Stalled
+	}
+	return st
+}
+
+// Run checks for due sources every Tick until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	tick := s.Tick
+	if tick <= 0 {
+		tick = 30 * time.Second
+	}
+
+	workers := s.Workers
+	if workers <= 0 {
+		workers = 4
+	}
+	s.mu.Lock()
+	s.slots = make(chan struct{}, workers)
+	s.busy = map[string]bool{}
+	s.tick = tick
+	s.mu.Unlock()
+
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	// Runs in flight see the cancelled ctx and wind down before we return
+	defer s.wg.Wait()
+
+	for {
+		s.runDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runDue hands every enabled source whose next run time has passed to the worker
+// pool, grouped by host. Hosts still being scraped from an earlier tick are left for
+// a later one, so no site is fetched by two workers at once. Outside the window
+// nothing starts; sources that fall due meanwhile run once it opens.
+func (s *Scheduler) runDue(ctx context.Context) {
+	s.mu.Lock()
+	s.lastTick = time.Now()
+	s.mu.Unlock()
+
+	if !s.Window.Open(time.Now()) {
+		return
+	}
+
+	sources, err := s.Engine.Sources.All()
+	if err != nil {
+		slog.Error("Scheduler failed to load sources", "err", err)
+		return
+	}
+
+	now := time.Now()
+	due := map[string][]*models.Source{}
+	var hosts []string
+	for _, src := range sources {
+		if !src.Enabled {
+			// Forgotten so that, once resumed, it is scheduled from its last run again
+			s.forget(src.ID)
+			continue
+		}
+		if now.Before(s.nextRun(src, now)) {
+			continue
+		}
+
+		host := sourceHost(src)
+		if _, ok := due[host]; !ok {
+			hosts = append(hosts, host)
+		}
+		due[host] = append(due[host], src)
+	}
+
+	for _, host := range hosts {
+		if ctx.Err() != nil {
+			return
+		}
+		if !s.claim(host) {
+			continue
+		}
+
+		s.wg.Add(1)
+		go func(host string, group []*models.Source) {
+			defer s.wg.Done()
+			defer s.release(host)
+
+			select {
+			case s.slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-s.slots }()
+
+			for _, src := range group {
+				// A run in progress finishes when the window closes, but the next one waits
+				if ctx.Err() != nil || !s.Window.Open(time.Now()) {
+					return
+				}
+				s.scrape(ctx, src)
+			}
+		}(host, due[host])
+	}
+}
+
+// scrape runs one source and schedules its next run. A run that panics is logged
+// and counted as failed, rather than taking the server down with it.
+func (s *Scheduler) scrape(ctx context.Context, src *models.Source) {
+	defer func() {
+		if p := recover(); p != nil {
+			slog.Error("Scheduled scrape panicked", "source", src.Name, "panic", p)
+			s.setNext(src.ID, time.Now().Add(s.jittered(src)))
+		}
+	}()
+
+	n, err := s.Engine.Run(ctx, src)
+	if err != nil {
+		slog.Error("Scheduled scrape failed", "source", src.Name, "err", err)
+	} else {
+		slog.Info("Scheduled scrape finished", "source", src.Name, "items", n)
+	}
+
+	// Failures wait a full interval too, so a broken site isn't hammered every tick
+	s.setNext(src.ID, time.Now().Add(s.jittered(src)))
+}
+
+// sourceHost is what sources are serialised on. Unparseable URLs get a key of their
+// own, since their fetch fails straight away.
+func sourceHost(src *models.Source) string {
+	u, err := url.Parse(src.URL)
+	if err != nil || u.Host == "" {
+		return "source:" + strconv.Itoa(src.ID)
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// claim marks a host busy, reporting false if it already was.
+func (s *Scheduler) claim(host string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.busy[host] {
+		return false
+	}
+	s.busy[host] = true
+	return true
+}
+
+func (s *Scheduler) release(host string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.busy, host)
+}
+
+// nextRun returns when a source is due, working it out from its last run the first
+// time we see it (e.g. right after a restart).
+func (s *Scheduler) nextRun(src *models.Source, now time.Time) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.next == nil {
+		s.next = map[int]time.Time{}
+	}
+
+	if t, ok := s.next[src.ID]; ok {
+		return t
+	}
+
+	var t time.Time
+	if src.LastRunAt != nil {
+		t = src.LastRunAt.Add(s.jittered(src))
+	} else {
+		// Never scraped: start soon, but stagger new sources over the first minute
+		t = now.Add(time.Duration(rand.Int63n(int64(time.Minute))))
+	}
+
+	s.next[src.ID] = t
+	return t
+}
+
+func (s *Scheduler) setNext(id int, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next[id] = t
+}
+
+func (s *Scheduler) forget(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.next, id)
+}
+
+// jittered returns the source's interval randomised by ±Jitter.
+func (s *Scheduler) jittered(src *models.Source) time.Duration {
+	interval := time.Duration(src.Interval) * time.Minute
+	if s.Jitter <= 0 {
+		return interval
+	}
+
+	spread := float64(interval) * s.Jitter
+	return interval + time.Duration((rand.Float64()*2-1)*spread)
+}
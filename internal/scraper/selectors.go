@@ -0,0 +1,158 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// selectorConfig is the config of a "selectors" source. Each field rule is a CSS
+// selector evaluated inside the item container, optionally followed by "@attr" to
+// read an attribute instead of the text, e.g. "h2 a @href". An empty rule (or the
+// bare "@attr" form) targets the container itself.
+//
+//	{"item": "article.post", "title": "h2", "link": "h2 a", "value": ".summary",
+//	 "image": "img", "date": "time @datetime"}
+//
+// Links default to @href, images to @src, and dates to @datetime, falling back to
+// the element's text when the attribute is missing.
+type selectorConfig struct {
+	Item  string `json:"item"`
+	Title string `json:"title"`
+	Link  string `json:"link"`
+	Value string `json:"value"`
+	Image string `json:"image"`
+	Date  string `json:"date"`
+}
+
+// selectorRule is a compiled field rule.
+type selectorRule struct {
+	sel  cascadia.Sel // nil targets the container itself
+	attr string
+}
+
+// compiledSelectors holds a selectorConfig ready to run.
+type compiledSelectors struct {
+	item                            cascadia.Sel
+	title, link, value, image, date *selectorRule
+}
+
+// compileSelectors parses and checks a selectors config.
+func compileSelectors(config json.RawMessage) (*compiledSelectors, error) {
+	var cfg selectorConfig
+	if err := json.Unmarshal(config, &cfg); err != nil {
+		return nil, fmt.Errorf("selectors config: %w", err)
+	}
+	if cfg.Item == "" || cfg.Title == "" {
+		return nil, errors.New(`selectors config needs at least "item" and "title"`)
+	}
+
+	c := &compiledSelectors{}
+	var err error
+	if c.item, err = cascadia.Parse(cfg.Item); err != nil {
+		return nil, fmt.Errorf("selectors config: item %q: %w", cfg.Item, err)
+	}
+
+	for _, f := range []struct {
+		name, rule, defaultAttr string
+		dest                    **selectorRule
+	}{
+		{"title", cfg.Title, "", &c.title},
+		{"link", cfg.Link, "href", &c.link},
+		{"value", cfg.Value, "", &c.value},
+		{"image", cfg.Image, "src", &c.image},
+		{"date", cfg.Date, "datetime", &c.date},
+	} {
+		if f.rule == "" {
+			continue
+		}
+		rule, err := compileRule(f.rule, f.defaultAttr)
+		if err != nil {
+			return nil, fmt.Errorf("selectors config: %s %q: %w", f.name, f.rule, err)
+		}
+		*f.dest = rule
+	}
+	return c, nil
+}
+
+func compileRule(rule, defaultAttr string) (*selectorRule, error) {
+	r := &selectorRule{attr: defaultAttr}
+
+	selector := strings.TrimSpace(rule)
+	if i := strings.LastIndex(selector, "@"); i >= 0 && !strings.ContainsAny(selector[i:], " ]") {
+		r.attr = selector[i+1:]
+		selector = strings.TrimSpace(selector[:i])
+	}
+	if selector == "" {
+		return r, nil
+	}
+
+	sel, err := cascadia.Parse(selector)
+	if err != nil {
+		return nil, err
+	}
+	r.sel = sel
+	return r, nil
+}
+
+// eval reads the rule's value inside the container: the attribute when one is set
+// and present, otherwise the text.
+func (r *selectorRule) eval(container *html.Node) string {
+	if r == nil {
+		return ""
+	}
+
+	n := container
+	if r.sel != nil {
+		if n = cascadia.Query(container, r.sel); n == nil {
+			return ""
+		}
+	}
+	if r.attr != "" {
+		if v := attr(n, r.attr); v != "" {
+			return v
+		}
+	}
+	return textContent(n)
+}
+
+// extractSelectors pulls one item per element matching the config's "item" selector.
+func extractSelectors(page *Page, config json.RawMessage) ([]Item, error) {
+	c, err := compileSelectors(config)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := html.Parse(bytes.NewReader(page.Body))
+	if err != nil {
+		return nil, err
+	}
+
+	base, _ := url.Parse(page.URL)
+
+	var items []Item
+	for _, n := range cascadia.QueryAll(doc, c.item) {
+		item := Item{
+			Title:     c.title.eval(n),
+			Value:     c.value.eval(n),
+			URL:       resolveLink(base, c.link.eval(n)),
+			Image:     resolveLink(base, c.image.eval(n)),
+			Published: parseFeedDate(c.date.eval(n)),
+		}
+		if item.Title == "" {
+			continue
+		}
+		items = append(items, item)
+	}
+
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no items matched on %s", page.URL)
+	}
+	return items, nil
+}
@@ -0,0 +1,183 @@
+// Package spam screens submissions to the station's public write paths. Each form
+// carries a signed token; a submission has to come back with the token intact and
+// unused, no earlier than a human could have typed it, without the honeypot field
+// filled in and, when enabled, with a proof-of-work nonce solving the token's challenge.
+package spam
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/bits"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/federicopalou/sacrif-station/internal/auth"
+	"github.com/federicopalou/sacrif-station/internal/clientip"
+)
+
+// Form fields every guarded form carries.
+const (
+	TokenField    = "form_token"
+	NonceField    = "pow_nonce"
+	HoneypotField = "website" // Hidden from people, irresistible to form-filling bots
+)
+
+// tokenSubject marks signed values as form tokens, so they can never pass for a session.
+const tokenSubject = "form:"
+
+// Rejection explains why a submission was refused.
+type Rejection struct {
+	Check   string // "honeypot", "token", "too-fast", "expired", "pow" or "replay"
+	Message string // Shown to the sender
+	Penalty int    // Reputation lost by the sender's origin
+	Silent  bool   // Pretend the submission went through, so bots learn nothing
+}
+
+func (r *Rejection) Error() string {
+	return "spam: " + r.Check + " check failed"
+}
+
+// sweepInterval is how often spent tokens that have expired anyway are forgotten.
+const sweepInterval = time.Minute
+
+// Guard issues and checks form tokens, and names the origins submissions come from.
+// Each token is good for one submission: the ones already accepted are remembered
+// until they expire.
+type Guard struct {
+	Signer    *auth.Signer
+	OriginKey []byte             // Keys the origin hashes, so they can't be reversed without it
+	Clients   *clientip.Resolver // Finds senders behind trusted proxies; nil trusts none
+	MinAge    time.Duration      // Submissions quicker than this are bots
+	MaxAge    time.Duration      // Tokens older than this have to be fetched again
+	PoWBits   int                // Leading zero bits the proof of work needs; 0 disables it
+
+	mu    sync.Mutex
+	spent map[string]time.Time // Token nonces accepted so far, with their expiry
+	swept time.Time
+}
+
+// NewGuard returns a Guard with a three second time-trap and two hour tokens.
+func NewGuard(signer *auth.Signer, originKey []byte, powBits int) *Guard {
+	return &Guard{Signer: signer, OriginKey: originKey, MinAge: 3 * time.Second, MaxAge: 2 * time.Hour, PoWBits: powBits}
+}
+
+// Challenge is what a form embeds: the token, and how hard its proof of work is.
+type Challenge struct {
+	Token   string
+	PoWBits int
+}
+
+// Issue returns a fresh challenge for a form being rendered now.
+func (g *Guard) Issue() (Challenge, error) {
+	nonce := make([]byte, 12)
+	rand.Read(nonce)
+
+	token, err := g.Signer.Sign(auth.Session{
+		Subject: tokenSubject + hex.EncodeToString(nonce),
+		Expires: time.Now().Add(g.MaxAge),
+	})
+	if err != nil {
+		return Challenge{}, err
+	}
+	return Challenge{Token: token, PoWBits: g.PoWBits}, nil
+}
+
+// Check screens a submitted form, returning a *Rejection if it fails any check.
+func (g *Guard) Check(form url.Values) *Rejection {
+	if strings.TrimSpace(form.Get(HoneypotField)) != "" {
+		return &Rejection{Check: "honeypot", Penalty: 10, Silent: true}
+	}
+
+	token := form.Get(TokenField)
+	sess, err := g.Signer.Verify(token)
+	if errors.Is(err, auth.ErrExpiredCookie) {
+		return &Rejection{Check: "expired", Message: "Form expired. Reload the page and try again.", Penalty: 1}
+	}
+	if err != nil || !strings.HasPrefix(sess.Subject, tokenSubject) {
+		return &Rejection{Check: "token", Message: "Signal rejected: missing or tampered form token.", Penalty: 3}
+	}
+
+	issued := sess.Expires.Add(-g.MaxAge)
+	if time.Since(issued) < g.MinAge {
+		return &Rejection{Check: "too-fast", Message: "Transmitted faster than humanly possible. Wait a moment and send again.", Penalty: 2}
+	}
+
+	if g.PoWBits > 0 && !Solves(token, form.Get(NonceField), g.PoWBits) {
+		return &Rejection{Check: "pow", Message: "Proof of work missing or wrong. Scripts must be enabled to transmit.", Penalty: 3}
+	}
+
+	// Spent last, so a submission turned away above can still be corrected and sent
+	if !g.spend(strings.TrimPrefix(sess.Subject, tokenSubject), sess.Expires) {
+		return &Rejection{Check: "replay", Message: "This form was already transmitted. Reload the page to send another.", Penalty: 2}
+	}
+
+	return nil
+}
+
+// spend marks a token's nonce as used, reporting false if it already was.
+func (g *Guard) spend(nonce string, expires time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(g.swept) >= sweepInterval {
+		for n, exp := range g.spent {
+			if now.After(exp) {
+				delete(g.spent, n)
+			}
+		}
+		g.swept = now
+	}
+
+	if _, ok := g.spent[nonce]; ok {
+		return false
+	}
+	if g.spent == nil {
+		g.spent = make(map[string]time.Time)
+	}
+	g.spent[nonce] = expires
+	return true
+}
+
+// Solves reports whether sha256(token + ":" + nonce) starts with at least n zero bits.
+func Solves(token, nonce string, n int) bool {
+	if nonce == "" {
+		return false
+	}
+	sum := sha256.Sum256([]byte(token + ":" + nonce))
+
+	zeros := 0
+	for _, b := range sum {
+		if b != 0 {
+			zeros += bits.LeadingZeros8(b)
+			break
+		}
+		zeros += 8
+	}
+	return zeros >= n
+}
+
+// Origin identifies a sender for rate limiting and reputation without storing their
+// address: an HMAC of the connecting IP under OriginKey. A plain hash of an IPv4
+// address is reversed by trying them all; this one needs the key as well.
+func (g *Guard) Origin(r *http.Request) string {
+	return g.OriginOf(g.Clients.IP(r))
+}
+
+// OriginOf hashes an IP address the same way Origin does, so the operator can
+// block an address by hand.
+func (g *Guard) OriginOf(ip string) string {
+	if parsed := net.ParseIP(strings.TrimSpace(ip)); parsed != nil {
+		ip = parsed.String()
+	}
+	mac := hmac.New(sha256.New, g.OriginKey)
+	mac.Write([]byte("origin:" + ip))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
@@ -0,0 +1,123 @@
+package spam
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/federicopalou/sacrif-station/internal/auth"
+	"github.com/federicopalou/sacrif-station/internal/clientip"
+)
+
+func testGuard(t *testing.T) *Guard {
+	t.Helper()
+	signer, err := auth.NewSigner(bytes.Repeat([]byte("k"), 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := NewGuard(signer, bytes.Repeat([]byte("o"), 32), 0)
+	g.MinAge = 0 // No waiting out the time-trap
+	return g
+}
+
+func TestCheckRejectsResubmittedForm(t *testing.T) {
+	g := testGuard(t)
+	c, err := g.Issue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	form := url.Values{TokenField: {c.Token}}
+
+	if rej := g.Check(form); rej != nil {
+		t.Fatalf("first submission rejected: %v", rej)
+	}
+	rej := g.Check(form)
+	if rej == nil || rej.Check != "replay" {
+		t.Fatalf("second submission: got %v, want a replay rejection", rej)
+	}
+
+	// A fresh form is still welcome
+	c, err = g.Issue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rej := g.Check(url.Values{TokenField: {c.Token}}); rej != nil {
+		t.Fatalf("fresh token rejected: %v", rej)
+	}
+}
+
+func TestCheckKeepsTokenOfRejectedSubmission(t *testing.T) {
+	g := testGuard(t)
+	g.PoWBits = 8
+	c, err := g.Issue()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	form := url.Values{TokenField: {c.Token}, NonceField: {nonce(c.Token, g.PoWBits, false)}}
+	if rej := g.Check(form); rej == nil || rej.Check != "pow" {
+		t.Fatalf("unsolved proof of work: got %v, want a pow rejection", rej)
+	}
+
+	// Solving it afterwards still gets the same form through
+	form.Set(NonceField, nonce(c.Token, g.PoWBits, true))
+	if rej := g.Check(form); rej != nil {
+		t.Fatalf("solved submission rejected: %v", rej)
+	}
+}
+
+// nonce finds a proof-of-work nonce for token that solves it, or one that doesn't.
+func nonce(token string, bits int, solved bool) string {
+	for n := 0; ; n++ {
+		if s := strconv.Itoa(n); Solves(token, s, bits) == solved {
+			return s
+		}
+	}
+}
+
+func TestOriginDependsOnKey(t *testing.T) {
+	g, other := testGuard(t), testGuard(t)
+	other.OriginKey = bytes.Repeat([]byte("p"), 32)
+
+	const ip = "203.0.113.7"
+	if g.OriginOf(ip) != g.OriginOf(ip) {
+		t.Fatal("the same address hashed to different origins")
+	}
+	if g.OriginOf(ip) == other.OriginOf(ip) {
+		t.Error("origin doesn't depend on the key")
+	}
+	if g.OriginOf(ip) == g.OriginOf("203.0.113.8") {
+		t.Error("different addresses share an origin")
+	}
+
+	// An address is the same origin however it was written or reached
+	r := httptest.NewRequest("POST", "/transmit", nil)
+	r.RemoteAddr = ip + ":51234"
+	if g.Origin(r) != g.OriginOf(" "+ip+" ") {
+		t.Error("Origin and OriginOf disagree")
+	}
+	if g.OriginOf("::ffff:"+ip) != g.OriginOf(ip) {
+		t.Error("an IPv4-mapped address is a different origin")
+	}
+}
+
+func TestOriginBehindTrustedProxy(t *testing.T) {
+	g := testGuard(t)
+	r := httptest.NewRequest("POST", "/transmit", nil)
+	r.RemoteAddr = "127.0.0.1:51234"
+	r.Header.Set("X-Forwarded-For", "203.0.113.7")
+
+	if g.Origin(r) != g.OriginOf("127.0.0.1") {
+		t.Error("believed X-Forwarded-For from an untrusted proxy")
+	}
+
+	var err error
+	if g.Clients, err = clientip.New("127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if g.Origin(r) != g.OriginOf("203.0.113.7") {
+		t.Error("a trusted proxy's visitor wasn't told apart from the proxy")
+	}
+}
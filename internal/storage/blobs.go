@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+)
+
+// ErrBadHash is returned for blob names that aren't a SHA-256 hex digest.
+var ErrBadHash = errors.New("storage: invalid blob hash")
+
+// Blobs is a content-addressed file store under the data root: each file is named
+// by the SHA-256 of its bytes, so identical uploads share a single copy on disk.
+type Blobs struct {
+	root *Root
+	dir  string
+}
+
+// Blobs returns the blob store kept in dir (relative to the data root).
+func (r *Root) Blobs(dir string) (*Blobs, error) {
+	if err := r.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &Blobs{root: r, dir: dir}, nil
+}
+
+// Put stores everything read from src and returns its hash and size. Storing bytes
+// that are already present leaves the existing copy in place.
+func (b *Blobs) Put(src io.Reader) (hash string, size int64, err error) {
+	suffix := make([]byte, 8)
+	rand.Read(suffix)
+	tmp := path.Join(b.dir, "tmp-"+hex.EncodeToString(suffix))
+
+	f, err := b.root.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return "", 0, err
+	}
+	defer b.root.Remove(tmp) // No-op once renamed into place
+
+	h := sha256.New()
+	size, err = io.Copy(io.MultiWriter(f, h), src)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	hash = hex.EncodeToString(h.Sum(nil))
+	name := b.name(hash)
+
+	if _, err := b.root.Stat(name); err == nil {
+		return hash, size, nil
+	}
+
+	if err := b.root.MkdirAll(path.Dir(name), 0o750); err != nil {
+		return "", 0, err
+	}
+	if err := b.root.Rename(tmp, name); err != nil {
+		return "", 0, err
+	}
+
+	return hash, size, nil
+}
+
+// Open returns the blob with the given hash.
+func (b *Blobs) Open(hash string) (*os.File, error) {
+	if !validHash(hash) {
+		return nil, ErrBadHash
+	}
+	return b.root.Open(b.name(hash))
+}
+
+// Exists reports whether a blob with the given hash is stored.
+func (b *Blobs) Exists(hash string) (bool, error) {
+	if !validHash(hash) {
+		return false, ErrBadHash
+	}
+
+	_, err := b.root.Stat(b.name(hash))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// name fans blobs out over 256 subdirectories so no single directory grows huge.
+func (b *Blobs) name(hash string) string {
+	return path.Join(b.dir, hash[:2], hash)
+}
+
+func validHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	for _, c := range hash {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
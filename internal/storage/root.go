@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrOutsideRoot is returned when a requested path would escape the data root.
+var ErrOutsideRoot = errors.New("storage: path escapes data root")
+
+// Root confines every file the station touches (databases, uploads, backups, caches)
+// to a single directory tree.
+type Root struct {
+	*os.Root
+	dir string
+}
+
+// Open creates the data directory if needed and returns a Root bound to it.
+func Open(dir string) (*Root, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(abs, 0o750); err != nil {
+		return nil, err
+	}
+
+	// Resolve symlinks once so later prefix checks compare real locations
+	abs, err = filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := os.OpenRoot(abs)
+	if err != nil {
+		return nil, err
+	}
+	return &Root{Root: r, dir: abs}, nil
+}
+
+// Dir returns the absolute path of the data root.
+func (r *Root) Dir() string {
+	return r.dir
+}
+
+// Path resolves name to an absolute path inside the root, for callers like SQLite
+// that need a real filename rather than an *os.File. Absolute names are accepted
+// only if they already point inside the root.
+func (r *Root) Path(name string) (string, error) {
+	rel := name
+	if filepath.IsAbs(name) {
+		var err error
+		rel, err = filepath.Rel(r.dir, filepath.Clean(name))
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", ErrOutsideRoot, name)
+		}
+	}
+
+	if !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("%w: %s", ErrOutsideRoot, name)
+	}
+
+	full := filepath.Join(r.dir, rel)
+
+	// A symlinked parent directory could still point elsewhere, so check where it really lands
+	parent, err := filepath.EvalSymlinks(filepath.Dir(full))
+	if err == nil {
+		if rel, err := filepath.Rel(r.dir, parent); err != nil || (rel != "." && !filepath.IsLocal(rel)) {
+			return "", fmt.Errorf("%w: %s", ErrOutsideRoot, name)
+		}
+	}
+
+	return full, nil
+}
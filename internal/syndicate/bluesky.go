@@ -0,0 +1,70 @@
+package syndicate
+
+import (
+	"context"
+	"path"
+	"strings"
+	"time"
+)
+
+// Bluesky posts to an account via the AT Protocol, authenticating with an app password.
+type Bluesky struct {
+	Service     string // PDS base URL, usually "https://bsky.social"
+	Handle      string
+	AppPassword string
+}
+
+// Name implements Poster.
+func (b *Bluesky) Name() string {
+	return "bluesky"
+}
+
+// Publish implements Poster.
+func (b *Bluesky) Publish(ctx context.Context, p Post) (string, error) {
+	service := strings.TrimRight(b.Service, "/")
+
+	var session struct {
+		AccessJwt string `json:"accessJwt"`
+		DID       string `json:"did"`
+		Handle    string `json:"handle"`
+	}
+	err := postJSON(ctx, service+"/xrpc/com.atproto.server.createSession", "",
+		map[string]string{"identifier": b.Handle, "password": b.AppPassword}, &session)
+	if err != nil {
+		return "", err
+	}
+
+	text := Compose(p, 300)
+
+	record := map[string]any{
+		"$type":     "app.bsky.feed.post",
+		"text":      text,
+		"createdAt": time.Now().UTC().Format(time.RFC3339),
+	}
+
+	// Bluesky doesn't autolink; the link needs a facet addressing its UTF-8 byte range
+	if start := strings.LastIndex(text, p.Link); start >= 0 && p.Link != "" {
+		record["facets"] = []any{map[string]any{
+			"index": map[string]int{"byteStart": start, "byteEnd": start + len(p.Link)},
+			"features": []any{map[string]string{
+				"$type": "app.bsky.richtext.facet#link",
+				"uri":   p.Link,
+			}},
+		}}
+	}
+
+	var created struct {
+		URI string `json:"uri"`
+	}
+	err = postJSON(ctx, service+"/xrpc/com.atproto.repo.createRecord", session.AccessJwt, map[string]any{
+		"repo":       session.DID,
+		"collection": "app.bsky.feed.post",
+		"record":     record,
+	}, &created)
+	if err != nil {
+		return "", err
+	}
+
+	// at://did/app.bsky.feed.post/<rkey> maps onto the public web URL
+	return "https://bsky.app/profile/" + session.Handle + "/post/" + path.Base(created.URI), nil
+}
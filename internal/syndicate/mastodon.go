@@ -0,0 +1,34 @@
+package syndicate
+
+import (
+	"context"
+	"strings"
+)
+
+// Mastodon posts statuses to an account on a Mastodon (or compatible) instance.
+type Mastodon struct {
+	Instance string // e.g. "https://mastodon.social"
+	Token    string // Access token with the write:statuses scope
+}
+
+// Name implements Poster.
+func (m *Mastodon) Name() string {
+	return "mastodon"
+}
+
+// Publish implements Poster.
+func (m *Mastodon) Publish(ctx context.Context, p Post) (string, error) {
+	body := map[string]string{
+		"status":     Compose(p, 500),
+		"visibility": "public",
+	}
+
+	var status struct {
+		URL string `json:"url"`
+	}
+	err := postJSON(ctx, strings.TrimRight(m.Instance, "/")+"/api/v1/statuses", m.Token, body, &status)
+	if err != nil {
+		return "", err
+	}
+	return status.URL, nil
+}
@@ -0,0 +1,79 @@
+package syndicate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Post is what gets cross-posted for a station entry.
+type Post struct {
+	Title   string
+	Excerpt string
+	Link    string // Permalink back to the station
+}
+
+// Poster publishes a Post to an external service and returns the URL of the copy.
+type Poster interface {
+	Name() string
+	Publish(ctx context.Context, p Post) (string, error)
+}
+
+// client is shared by every poster; cross-posting is never worth hanging a job for.
+var client = &http.Client{Timeout: 20 * time.Second}
+
+// Compose renders "title\n\nexcerpt\n\nlink" within a character limit, trimming the
+// excerpt first so the link always survives.
+func Compose(p Post, limit int) string {
+	head := p.Title
+	tail := "\n\n" + p.Link
+
+	room := limit - utf8.RuneCountInString(head) - utf8.RuneCountInString(tail) - 2
+	excerpt := strings.Join(strings.Fields(p.Excerpt), " ")
+	if room <= 1 {
+		excerpt = ""
+	} else if utf8.RuneCountInString(excerpt) > room {
+		excerpt = string([]rune(excerpt)[:room-1]) + "…"
+	}
+
+	if excerpt == "" {
+		return head + tail
+	}
+	return head + "\n\n" + excerpt + tail
+}
+
+// postJSON sends a JSON body and decodes a JSON response, treating non-2xx as an error.
+func postJSON(ctx context.Context, url, bearer string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %s: %s", url, resp.Status, bytes.TrimSpace(msg))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
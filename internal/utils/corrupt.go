@@ -15,8 +15,34 @@ func init() {
 	rand.Seed(time.Now().UnixNano())
 }
 
+// randSource is the randomness corrupt draws on: the shared generator, or a seeded
+// one for DecayText.
+type randSource interface {
+	Float64() float64
+	Intn(n int) int
+}
+
+// sharedRand is the package-level generator, which unlike a *rand.Rand is safe for
+// concurrent use.
+type sharedRand struct{}
+
+func (sharedRand) Float64() float64 { return rand.Float64() }
+func (sharedRand) Intn(n int) int   { return rand.Intn(n) }
+
 // CorruptText takes an input string and randomly corrupts it based on a severity percentage (0-100).
 func CorruptText(input string, severity int) string {
+	return corrupt(input, severity, sharedRand{})
+}
+
+// DecayText corrupts like CorruptText but the same seed always garbles the same way,
+// so text that decays with age stays put between reloads instead of flickering.
+func DecayText(input string, severity int, seed int64) string {
+	return corrupt(input, severity, rand.New(rand.NewSource(seed)))
+}
+
+// corrupt garbles input line by line, keeping the line breaks so Markdown still finds
+// its paragraphs and lists afterwards.
+func corrupt(input string, severity int, rnd randSource) string {
 	if severity <= 0 {
 		return input
 	}
@@ -24,6 +50,14 @@ func CorruptText(input string, severity int) string {
 		severity = 100
 	}
 
+	lines := strings.Split(input, "\n")
+	for i, line := range lines {
+		lines[i] = corruptLine(line, severity, rnd)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func corruptLine(input string, severity int, rnd randSource) string {
 	// Calculate a realistic probability based on severity (e.g., severity 10 means 10% chance per character)
 	probCharCorrupt := float64(severity) / 100.0
 	probWordCorrupt := float64(severity) / 500.0 // Word corruption is rarer
@@ -31,8 +65,8 @@ func CorruptText(input string, severity int) string {
 	words := strings.Fields(input)
 	for i, word := range words {
 		// Chance to corrupt the entire word
-		if rand.Float64() < probWordCorrupt {
-			words[i] = glitchWords[rand.Intn(len(glitchWords))]
+		if rnd.Float64() < probWordCorrupt {
+			words[i] = glitchWords[rnd.Intn(len(glitchWords))]
 			continue
 		}
 
@@ -43,8 +77,8 @@ func CorruptText(input string, severity int) string {
 			if char == ' ' || char == '.' || char == ',' {
 				continue
 			}
-			if rand.Float64() < probCharCorrupt {
-				runes[j] = glitchChars[rand.Intn(len(glitchChars))]
+			if rnd.Float64() < probCharCorrupt {
+				runes[j] = glitchChars[rnd.Intn(len(glitchChars))]
 			}
 		}
 		words[i] = string(runes)
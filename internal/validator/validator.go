@@ -0,0 +1,59 @@
+// Package validator collects per-field problems with submitted form input, so a form
+// can be shown again with every mistake marked at once rather than failing on the first.
+package validator
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Validator holds the problems found so far, keyed by form field name.
+type Validator struct {
+	FieldErrors map[string]string
+}
+
+// Valid reports whether no problem has been found.
+func (v *Validator) Valid() bool {
+	return len(v.FieldErrors) == 0
+}
+
+// AddFieldError records a problem with a field. Only the first one per field is
+// kept, since checks usually run from most to least basic.
+func (v *Validator) AddFieldError(key, message string) {
+	if v.FieldErrors == nil {
+		v.FieldErrors = map[string]string{}
+	}
+	if _, exists := v.FieldErrors[key]; !exists {
+		v.FieldErrors[key] = message
+	}
+}
+
+// CheckField records message against key unless ok.
+func (v *Validator) CheckField(ok bool, key, message string) {
+	if !ok {
+		v.AddFieldError(key, message)
+	}
+}
+
+// NotBlank reports whether s has anything but whitespace.
+func NotBlank(s string) bool {
+	return strings.TrimSpace(s) != ""
+}
+
+// MaxChars reports whether s is at most n characters (not bytes) long.
+func MaxChars(s string, n int) bool {
+	return utf8.RuneCountInString(s) <= n
+}
+
+// PermittedValue reports whether value is one of permitted.
+func PermittedValue[T comparable](value T, permitted ...T) bool {
+	return slices.Contains(permitted, value)
+}
+
+// WebURL reports whether s is an absolute http or https URL.
+func WebURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -0,0 +1,97 @@
+// Package webhook delivers signed event notifications to downstream endpoints.
+//
+// Every delivery is a JSON POST carrying these headers:
+//
+//	X-Sacrif-Event:     the event type, e.g. "entry.created"
+//	X-Sacrif-Delivery:  the delivery ID, the same on every redelivery
+//	X-Sacrif-Timestamp: Unix seconds when this attempt was signed
+//	X-Sacrif-Signature: "sha256=" + hex HMAC-SHA256(secret, timestamp + "." + body)
+//
+// Receivers recompute the signature with the shared secret, reject stale
+// timestamps, and use the delivery ID to ignore events they have already handled.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Header names set on every delivery.
+const (
+	HeaderEvent     = "X-Sacrif-Event"
+	HeaderDelivery  = "X-Sacrif-Delivery"
+	HeaderTimestamp = "X-Sacrif-Timestamp"
+	HeaderSignature = "X-Sacrif-Signature"
+)
+
+// Sign returns the X-Sacrif-Signature value for a body sent at timestamp. The
+// timestamp is part of the signed message so a captured request can't be replayed
+// later with a fresh header.
+func Sign(secret []byte, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is valid for the body sent at timestamp.
+func Verify(secret []byte, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Sender posts deliveries to endpoints.
+type Sender struct {
+	Secret    []byte
+	UserAgent string
+	Client    *http.Client
+}
+
+// NewSender returns a Sender signing with secret.
+func NewSender(secret string) *Sender {
+	return &Sender{
+		Secret:    []byte(secret),
+		UserAgent: "SacrifStation-Webhooks/1.0",
+		Client:    &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Send posts one delivery and returns the endpoint's status code. Anything but a 2xx
+// answer is an error, though the status is still returned so it can be recorded.
+func (s *Sender) Send(ctx context.Context, endpoint, event, deliveryID string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", s.UserAgent)
+	req.Header.Set(HeaderEvent, event)
+	req.Header.Set(HeaderDelivery, deliveryID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(s.Secret, timestamp, body))
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return resp.StatusCode, fmt.Errorf("%s returned %s: %s", endpoint, resp.Status, bytes.TrimSpace(msg))
+	}
+
+	// Drain so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode, nil
+}
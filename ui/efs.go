@@ -0,0 +1,11 @@
+// Package ui carries the station's front-end files, compiled into the binary so a
+// deployment is a single file.
+package ui
+
+import "embed"
+
+// Files holds ui/html, the page templates, and ui/static: stylesheets and scripts
+// served under /static/.
+//
+//go:embed "html" "static"
+var Files embed.FS
@@ -0,0 +1,27 @@
+// Find a nonce whose SHA-256 with the form token starts with enough zero bits
+document.getElementById("transmit-form").addEventListener("submit", async function (ev) {
+    const form = ev.target;
+    if (form.pow_nonce.value !== "") return;
+    ev.preventDefault();
+
+    const button = form.querySelector("button");
+    button.disabled = true;
+    button.textContent = "Aligning antenna...";
+
+    const bits = Number(form.dataset.powBits);
+    const encoder = new TextEncoder();
+    const token = form.form_token.value;
+    for (let nonce = 0; ; nonce++) {
+        const sum = new Uint8Array(await crypto.subtle.digest("SHA-256", encoder.encode(token + ":" + nonce)));
+        let zeros = 0;
+        for (const b of sum) {
+            if (b !== 0) { zeros += Math.clz32(b) - 24; break; }
+            zeros += 8;
+        }
+        if (zeros >= bits) {
+            form.pow_nonce.value = String(nonce);
+            break;
+        }
+    }
+    form.submit();
+});